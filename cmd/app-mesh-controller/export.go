@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/klog"
+
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/controller"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/metrics"
+)
+
+var (
+	exportMeshName  string
+	exportNamespace string
+	exportCmd       = &cobra.Command{
+		Use:   "export",
+		Short: "Export will output a mesh in App Mesh as Mesh, VirtualNode and VirtualService manifests",
+		Long: `Export describes a mesh and its virtual nodes, virtual services, virtual routers and routes in App Mesh
+and prints them as custom resource manifests. Applying the manifests in the given namespace lets the controller
+take over the existing App Mesh resources without recreating them.`,
+		Run: func(_ *cobra.Command, _ []string) {
+			cfg, err := getConfig()
+			if err != nil {
+				klog.Fatal(err)
+			}
+
+			cloud, err := aws.NewCloud(cfg.aws, metrics.NewRecorder(false))
+			if err != nil {
+				klog.Fatal(err)
+			}
+
+			objects, warnings, err := controller.Export(context.Background(), cloud, exportMeshName, exportNamespace, viper.GetString("naming-strategy"))
+			if err != nil {
+				klog.Fatalf("Error exporting mesh: %s", err)
+			}
+			for _, warning := range warnings {
+				klog.Warning(warning)
+			}
+			if err := controller.WriteManifests(os.Stdout, objects); err != nil {
+				klog.Fatal(err)
+			}
+		},
+	}
+)
+
+func init() {
+	exportCmd.Flags().StringVar(&exportMeshName, "mesh", "", "Name of the mesh to export")
+	exportCmd.Flags().StringVarP(&exportNamespace, "namespace", "n", "default", "Namespace of the exported virtual nodes and virtual services")
+	exportCmd.MarkFlagRequired("mesh")
+	rootCmd.AddCommand(exportCmd)
+}
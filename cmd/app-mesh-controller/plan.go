@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog"
+
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	meshclientset "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/controller"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/metrics"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Plan will output the App Mesh and Cloud Map changes the controller would make",
+	Long: `Plan runs the reconcile logic once against the resources in the cluster and App Mesh and Cloud Map,
+and prints the resources the controller would create, update or delete. Nothing in the cluster, App Mesh
+or Cloud Map is changed: only read-only calls are made.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		cfg, err := getConfig()
+		if err != nil {
+			klog.Fatal(err)
+		}
+
+		cloud, err := aws.NewCloud(cfg.aws, metrics.NewRecorder(false))
+		if err != nil {
+			klog.Fatal(err)
+		}
+
+		config, err := clientcmd.BuildConfigFromFlags(cfg.client.Master, cfg.client.Kubeconfig)
+		if err != nil {
+			klog.Fatal(err)
+		}
+
+		kubeclientset := kubernetes.NewForConfigOrDie(config)
+		clusterID, err := controller.ResolveClusterID(kubeclientset, viper.GetString("cluster-id"))
+		if err != nil {
+			klog.Fatal(err)
+		}
+
+		plan, err := controller.Plan(
+			cloud,
+			kubeclientset,
+			meshclientset.NewForConfigOrDie(config),
+			cfg.scope,
+			viper.GetString("drift-policy"),
+			clusterID,
+			viper.GetString("naming-strategy"),
+		)
+		if err != nil {
+			klog.Fatalf("Error planning changes: %s", err)
+		}
+		if err := plan.Write(os.Stdout); err != nil {
+			klog.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+}
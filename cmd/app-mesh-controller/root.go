@@ -31,31 +31,87 @@ var (
 	leaderElection          bool
 	leaderElectionID        string
 	leaderElectionNamespace string
+	warmStandby             bool
+	warmStandbySyncPeriod   time.Duration
+	watchNamespaces         []string
+	resourceLabelSelector   string
+	shardByMesh             bool
+	shardLeaseNamespace     string
+	shardLeaseDuration      time.Duration
+	awsAPIReadQPS           float64
+	awsAPIReadBurst         int
+	awsAPIWriteQPS          float64
+	awsAPIWriteBurst        int
+	awsAPIMaxBackoff        time.Duration
+	driftCheckInterval      time.Duration
+	driftPolicy             string
+	clusterID               string
+	namingStrategy          string
+	webhookAddress          string
+	webhookCertDir          string
 )
 
 func init() {
 	cobra.OnInitialize(initConfig)
-	rootCmd.Flags().StringVarP(&cfgFile, "config", "c", "", "Load configuration from `filename`")
-	rootCmd.Flags().StringVar(&master, "master", "", "Master address")
-	rootCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to your kubeconfig")
-	rootCmd.Flags().StringVar(&region, "aws-region", "", "AWS Region")
+	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "Load configuration from `filename`")
+	rootCmd.PersistentFlags().StringVar(&master, "master", "", "Master address")
+	rootCmd.PersistentFlags().StringVar(&kubeconfig, "kubeconfig", "", "Path to your kubeconfig")
+	rootCmd.PersistentFlags().StringVar(&region, "aws-region", "", "AWS Region")
+	rootCmd.PersistentFlags().Float64Var(&awsAPIReadQPS, "aws-api-read-qps", aws.DefaultReadQPS, "Maximum rate of describe and list calls to each AWS service. Zero disables the limit")
+	rootCmd.PersistentFlags().IntVar(&awsAPIReadBurst, "aws-api-read-burst", aws.DefaultReadBurst, "Maximum burst of describe and list calls to each AWS service")
+	rootCmd.PersistentFlags().Float64Var(&awsAPIWriteQPS, "aws-api-write-qps", aws.DefaultWriteQPS, "Maximum rate of create, update and delete calls to each AWS service. Zero disables the limit")
+	rootCmd.PersistentFlags().IntVar(&awsAPIWriteBurst, "aws-api-write-burst", aws.DefaultWriteBurst, "Maximum burst of create, update and delete calls to each AWS service")
+	rootCmd.PersistentFlags().DurationVar(&awsAPIMaxBackoff, "aws-api-max-throttle-backoff", aws.DefaultMaxThrottleBackoff, "Maximum time all AWS calls are paused after the AWS API throttles the controller")
 	rootCmd.Flags().IntVar(&threadiness, "threadiness", controller.DefaultThreadiness, "Worker concurrency.")
 	rootCmd.Flags().BoolVar(&leaderElection, "election", controller.DefaultElection, `Whether to do leader election for controller`)
 	rootCmd.Flags().StringVar(&leaderElectionID, "election-id", controller.DefaultElectionID, "Namespace of leader-election configmap for ingress controller")
 	rootCmd.Flags().StringVar(&leaderElectionNamespace, "election-namespace", controller.DefaultElectionNamespace, "Namespace of leader-election configmap for ingress controller. If unspecified, the namespace of this controller pod will be used")
+	rootCmd.Flags().BoolVar(&warmStandby, "warm-standby", controller.DefaultWarmStandby, "Whether replicas that are not leading keep a snapshot of App Mesh and Cloud Map state primed for takeover")
+	rootCmd.Flags().DurationVar(&warmStandbySyncPeriod, "warm-standby-sync-period", controller.DefaultWarmStandbySyncPeriod, "How often a warm standby refreshes its snapshot of App Mesh and Cloud Map state")
 
-	viper.BindPFlag("master", rootCmd.Flags().Lookup("master"))
-	viper.BindPFlag("kubeconfig", rootCmd.Flags().Lookup("kubeconfig"))
-	viper.BindPFlag("aws-region", rootCmd.Flags().Lookup("aws-region"))
+	rootCmd.PersistentFlags().StringSliceVar(&watchNamespaces, "watch-namespaces", nil, "Namespaces to watch for pods, virtual nodes and virtual services. If unspecified, all namespaces are watched")
+	rootCmd.PersistentFlags().StringVar(&resourceLabelSelector, "resource-label-selector", "", "Label selector for the meshes, virtual nodes and virtual services managed by this controller. If unspecified, all resources are managed")
+
+	rootCmd.Flags().DurationVar(&driftCheckInterval, "drift-check-interval", controller.DefaultDriftCheckInterval, "How often resources whose spec is unchanged are still reconciled against App Mesh. Zero reconciles them on every resync")
+	rootCmd.PersistentFlags().StringVar(&driftPolicy, "drift-policy", controller.DefaultDriftPolicy, "What to do when App Mesh resources differ from their spec: enforce overwrites them, report sets a Drifted condition and emits an event instead. Resources may override it with the appmesh.k8s.aws/driftPolicy annotation")
+	rootCmd.PersistentFlags().StringVar(&clusterID, "cluster-id", "", "Identifies this cluster in the tags of App Mesh resources the controller creates, so that resources of other clusters are not taken over. If unspecified, the UID of the kube-system namespace is used")
+	rootCmd.PersistentFlags().StringVar(&namingStrategy, "naming-strategy", controller.DefaultNamingStrategy, "How App Mesh names are derived from the names and namespaces of virtual nodes and virtual services: legacy appends the namespace, hashed also appends a hash so that names never collide. Changing it renames existing App Mesh resources")
+	rootCmd.Flags().BoolVar(&shardByMesh, "shard-by-mesh", false, "Whether replicas divide ownership of meshes by consistent hashing instead of electing a single leader")
+	rootCmd.Flags().StringVar(&shardLeaseNamespace, "shard-lease-namespace", "", "Namespace of the shard membership leases. If unspecified, the namespace of this controller pod will be used")
+	rootCmd.Flags().DurationVar(&shardLeaseDuration, "shard-lease-duration", controller.DefaultShardLeaseDuration, "How long a replica remains a shard member after it last renewed its lease")
+	rootCmd.Flags().StringVar(&webhookAddress, "webhook-address", controller.DefaultWebhookAddress, "Address the validating admission webhook of virtual nodes is served on")
+	rootCmd.Flags().StringVar(&webhookCertDir, "webhook-cert-dir", controller.DefaultWebhookCertDir, "Directory holding the tls.crt and tls.key of the admission webhook. The webhook is not served if they are missing")
+
+	viper.BindPFlag("master", rootCmd.PersistentFlags().Lookup("master"))
+	viper.BindPFlag("kubeconfig", rootCmd.PersistentFlags().Lookup("kubeconfig"))
+	viper.BindPFlag("aws-region", rootCmd.PersistentFlags().Lookup("aws-region"))
+	viper.BindPFlag("aws-api-read-qps", rootCmd.PersistentFlags().Lookup("aws-api-read-qps"))
+	viper.BindPFlag("aws-api-read-burst", rootCmd.PersistentFlags().Lookup("aws-api-read-burst"))
+	viper.BindPFlag("aws-api-write-qps", rootCmd.PersistentFlags().Lookup("aws-api-write-qps"))
+	viper.BindPFlag("aws-api-write-burst", rootCmd.PersistentFlags().Lookup("aws-api-write-burst"))
+	viper.BindPFlag("aws-api-max-throttle-backoff", rootCmd.PersistentFlags().Lookup("aws-api-max-throttle-backoff"))
 	viper.BindPFlag("election", rootCmd.Flags().Lookup("election"))
 	viper.BindPFlag("election-id", rootCmd.Flags().Lookup("election-id"))
 	viper.BindPFlag("election-namespace", rootCmd.Flags().Lookup("election-namespace"))
+	viper.BindPFlag("warm-standby", rootCmd.Flags().Lookup("warm-standby"))
+	viper.BindPFlag("warm-standby-sync-period", rootCmd.Flags().Lookup("warm-standby-sync-period"))
+	viper.BindPFlag("watch-namespaces", rootCmd.PersistentFlags().Lookup("watch-namespaces"))
+	viper.BindPFlag("resource-label-selector", rootCmd.PersistentFlags().Lookup("resource-label-selector"))
+	viper.BindPFlag("drift-check-interval", rootCmd.Flags().Lookup("drift-check-interval"))
+	viper.BindPFlag("drift-policy", rootCmd.PersistentFlags().Lookup("drift-policy"))
+	viper.BindPFlag("cluster-id", rootCmd.PersistentFlags().Lookup("cluster-id"))
+	viper.BindPFlag("naming-strategy", rootCmd.PersistentFlags().Lookup("naming-strategy"))
+	viper.BindPFlag("shard-by-mesh", rootCmd.Flags().Lookup("shard-by-mesh"))
+	viper.BindPFlag("shard-lease-namespace", rootCmd.Flags().Lookup("shard-lease-namespace"))
+	viper.BindPFlag("shard-lease-duration", rootCmd.Flags().Lookup("shard-lease-duration"))
+	viper.BindPFlag("webhook-address", rootCmd.Flags().Lookup("webhook-address"))
+	viper.BindPFlag("webhook-cert-dir", rootCmd.Flags().Lookup("webhook-cert-dir"))
 }
 
 func main() {
 	flag.CommandLine.Parse([]string{})
 
-	fs := rootCmd.Flags()
+	fs := rootCmd.PersistentFlags()
 	addKlogFlags(fs)
 
 	if err := rootCmd.Execute(); err != nil {
@@ -111,21 +167,49 @@ var rootCmd = &cobra.Command{
 		// creates clientset for our custom resources
 		meshclientset := meshclientset.NewForConfigOrDie(config)
 
-		kubeInformerFactory := kubeinformers.NewSharedInformerFactory(kubeclientset, time.Second*30)
-		meshInformerFactory := meshinformers.NewSharedInformerFactory(meshclientset, time.Second*30)
+		clusterID, err := controller.ResolveClusterID(kubeclientset, viper.GetString("cluster-id"))
+		if err != nil {
+			klog.Fatal(err)
+		}
+
+		kubeInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeclientset, time.Second*30,
+			kubeinformers.WithNamespace(cfg.scope.InformerNamespace()))
+		meshInformerFactory := meshinformers.NewSharedInformerFactoryWithOptions(meshclientset, time.Second*30,
+			meshinformers.WithNamespace(cfg.scope.InformerNamespace()),
+			meshinformers.WithTweakListOptions(cfg.scope.TweakListOptions))
+		// Reference grants and mesh defaults are not labeled like the resources they apply to, so they are only
+		// scoped by namespace
+		namespacedInformerFactory := meshinformers.NewSharedInformerFactoryWithOptions(meshclientset, time.Second*30,
+			meshinformers.WithNamespace(cfg.scope.InformerNamespace()))
+		// Secrets are only watched for the certificates virtual nodes source from them
+		secretInformerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeclientset, time.Second*30,
+			kubeinformers.WithNamespace(cfg.scope.InformerNamespace()),
+			kubeinformers.WithTweakListOptions(controller.TweakSecretListOptions))
 
 		c, err := controller.NewController(
 			cloud,
 			kubeclientset,
 			meshclientset,
 			kubeInformerFactory.Core().V1().Pods(),
+			secretInformerFactory.Core().V1().Secrets(),
+			kubeInformerFactory.Core().V1().Namespaces(),
 			meshInformerFactory.Appmesh().V1beta1().Meshes(),
 			meshInformerFactory.Appmesh().V1beta1().VirtualNodes(),
 			meshInformerFactory.Appmesh().V1beta1().VirtualServices(),
+			namespacedInformerFactory.Appmesh().V1beta1().ReferenceGrants(),
+			namespacedInformerFactory.Appmesh().V1beta1().MeshDefaultses(),
 			stats,
 			leaderElection,
 			leaderElectionID,
 			leaderElectionNamespace,
+			warmStandby,
+			warmStandbySyncPeriod,
+			cfg.scope,
+			cfg.shard,
+			viper.GetDuration("drift-check-interval"),
+			viper.GetString("drift-policy"),
+			clusterID,
+			viper.GetString("naming-strategy"),
 		)
 
 		if err != nil {
@@ -133,13 +217,21 @@ var rootCmd = &cobra.Command{
 		}
 
 		kubeInformerFactory.Start(stopCh)
+		secretInformerFactory.Start(stopCh)
 		meshInformerFactory.Start(stopCh)
+		namespacedInformerFactory.Start(stopCh)
 
 		httpServer := controller.NewServer(cfg.server)
 		go func() {
 			klog.Fatal(httpServer.ListenAndServe())
 		}()
 
+		go func() {
+			if err := c.RunWebhookServer(cfg.webhook); err != nil {
+				klog.Fatal(err)
+			}
+		}()
+
 		klog.Infof("Running controller with threadiness=%d", threadiness)
 		if err := c.Run(threadiness, stopCh); err != nil {
 			klog.Fatal(err)
@@ -148,9 +240,12 @@ var rootCmd = &cobra.Command{
 }
 
 type controllerConfig struct {
-	client controller.ClientOptions
-	server controller.ServerOptions
-	aws    aws.CloudOptions
+	client  controller.ClientOptions
+	server  controller.ServerOptions
+	webhook controller.WebhookOptions
+	aws     aws.CloudOptions
+	scope   controller.ScopeOptions
+	shard   controller.ShardOptions
 }
 
 func getConfig() (controllerConfig, error) {
@@ -165,8 +260,26 @@ func getConfig() (controllerConfig, error) {
 		server: controller.ServerOptions{
 			Address: viper.GetString("listenAddress"),
 		},
+		webhook: controller.WebhookOptions{
+			Address: viper.GetString("webhook-address"),
+			CertDir: viper.GetString("webhook-cert-dir"),
+		},
 		aws: aws.CloudOptions{
-			Region: viper.GetString("aws-region"),
+			Region:             viper.GetString("aws-region"),
+			ReadQPS:            viper.GetFloat64("aws-api-read-qps"),
+			ReadBurst:          viper.GetInt("aws-api-read-burst"),
+			WriteQPS:           viper.GetFloat64("aws-api-write-qps"),
+			WriteBurst:         viper.GetInt("aws-api-write-burst"),
+			MaxThrottleBackoff: viper.GetDuration("aws-api-max-throttle-backoff"),
+		},
+		scope: controller.ScopeOptions{
+			Namespaces:    viper.GetStringSlice("watch-namespaces"),
+			LabelSelector: viper.GetString("resource-label-selector"),
+		},
+		shard: controller.ShardOptions{
+			Enabled:        viper.GetBool("shard-by-mesh"),
+			LeaseNamespace: viper.GetString("shard-lease-namespace"),
+			LeaseDuration:  viper.GetDuration("shard-lease-duration"),
 		},
 	}, nil
 }
@@ -3,7 +3,7 @@ module github.com/aws/aws-app-mesh-controller-for-k8s
 go 1.13
 
 require (
-	github.com/aws/aws-sdk-go v1.29.13
+	github.com/aws/aws-sdk-go v1.37.2
 	github.com/deckarep/golang-set v1.7.1
 	github.com/evanphx/json-patch v4.5.0+incompatible
 	github.com/goccy/go-yaml v1.4.3 // indirect
@@ -22,7 +22,8 @@ require (
 	github.com/vektra/mockery v0.0.0-20181123154057-e78b021dcbb5
 	go.uber.org/zap v1.10.0
 	golang.org/x/exp v0.0.0-20200228211341-fcea875c7e85 // indirect
-	golang.org/x/sys v0.0.0-20200317113312-5766fd39f98d // indirect
+	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e
+	golang.org/x/time v0.0.0-20190308202827-9d24e82272b4
 	golang.org/x/tools v0.0.0-20200316212524-3e76bee198d8 // indirect
 	gonum.org/v1/gonum v0.7.0
 	gopkg.in/inf.v0 v0.9.1 // indirect
@@ -34,6 +35,7 @@ require (
 	k8s.io/client-go v11.0.0+incompatible
 	k8s.io/code-generator v0.17.2
 	k8s.io/klog v1.0.0
+	sigs.k8s.io/yaml v1.1.0
 )
 
 // Kubernetes 1.15.0
@@ -31,6 +31,10 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Mesh{},
 		&MeshList{},
+		&MeshDefaults{},
+		&MeshDefaultsList{},
+		&ReferenceGrant{},
+		&ReferenceGrantList{},
 		&VirtualService{},
 		&VirtualServiceList{},
 		&VirtualNode{},
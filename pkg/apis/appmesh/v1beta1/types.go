@@ -12,6 +12,11 @@ import (
 // +genclient
 // +genclient:nonNamespaced
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:scope=Cluster,categories=all;appmesh
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="ARN",type=string,JSONPath=`.status.meshArn`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.meshCondition[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Mesh is a specification for a Mesh resource
 type Mesh struct {
@@ -25,6 +30,8 @@ type Mesh struct {
 	Status MeshStatus `json:"status,omitempty"`
 }
 
+// MeshServiceDiscoveryType also accepts dns, which earlier releases of the CRD validated
+// +kubebuilder:validation:Enum=Dns;dns
 type MeshServiceDiscoveryType string
 
 const (
@@ -32,20 +39,57 @@ const (
 	Dns MeshServiceDiscoveryType = "Dns"
 )
 
+// DeletionPolicy decides what happens to the App Mesh resources of a custom resource that is deleted
+// +kubebuilder:validation:Enum=Delete;Retain
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the App Mesh resources along with the custom resource
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain keeps the App Mesh resources, and removes their ownership tags so that they can be adopted
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+)
+
 // MeshSpec is the spec for a Mesh resource
 type MeshSpec struct {
 	// +optional
 	EgressFilter *MeshEgressFilter `json:"egressFilter,omitempty"`
 	// +optional
 	ServiceDiscoveryType *MeshServiceDiscoveryType `json:"serviceDiscoveryType,omitempty"`
+	// DeletionPolicy applies to the mesh, and is the default of its virtual nodes and virtual services. Defaults to Delete.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// NamespaceSelector selects the namespaces whose virtual nodes and virtual services join the mesh when they do not set meshName
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// TLSPolicy is enforced on the virtual nodes of the mesh, which are not created or updated while they violate it
+	// +optional
+	TLSPolicy *MeshTLSPolicy `json:"tlsPolicy,omitempty"`
+}
+
+// MeshTLSPolicy restricts the TLS configuration of the virtual nodes in a mesh
+type MeshTLSPolicy struct {
+	// RequireStrictListenerTLS requires every listener to terminate TLS in STRICT mode
+	// +optional
+	RequireStrictListenerTLS bool `json:"requireStrictListenerTLS,omitempty"`
+	// RequireBackendValidation requires virtual nodes with backends to enforce TLS with a validated trust
+	// +optional
+	RequireBackendValidation bool `json:"requireBackendValidation,omitempty"`
+	// TrustedCertificateAuthorityArns restricts backend validation to ACM trust in the listed certificate authorities
+	// +optional
+	TrustedCertificateAuthorityArns []string `json:"trustedCertificateAuthorityArns,omitempty"`
 }
 
 // MeshStatus is the status for a Mesh resource
 type MeshStatus struct {
 	// MeshArn is the AppMesh Mesh object's Amazon Resource Name
 	// +optional
-	MeshArn    *string         `json:"meshArn,omitempty"`
+	MeshArn *string `json:"meshArn,omitempty"`
+	// +optional
 	Conditions []MeshCondition `json:"meshCondition"`
+	// ObservedGeneration is the generation of the spec last reconciled with App Mesh
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 const (
@@ -56,20 +100,33 @@ const (
 )
 
 type MeshEgressFilter struct {
+	// +kubebuilder:validation:Enum=ALLOW_ALL;DROP_ALL
 	Type string `json:"type"`
 }
 
+// +kubebuilder:validation:Enum=MeshActive;Drifted;Paused;Ready;Reconciled;Degraded
 type MeshConditionType string
 
 const (
 	// MeshActive is Active when the Appmesh Mesh has been created or found via the API
 	MeshActive MeshConditionType = "MeshActive"
+	// MeshDrifted is True when the Appmesh Mesh differs from the spec and the drift policy is report
+	MeshDrifted MeshConditionType = "Drifted"
+	// MeshPaused is True when the mesh has the pause annotation and is not reconciled
+	MeshPaused MeshConditionType = "Paused"
+	// MeshReady is True when the mesh is reconciled and active in App Mesh
+	MeshReady MeshConditionType = "Ready"
+	// MeshReconciled is True when the last reconcile of the mesh succeeded
+	MeshReconciled MeshConditionType = "Reconciled"
+	// MeshDegraded is True when the last reconcile of the mesh failed or the mesh drifted
+	MeshDegraded MeshConditionType = "Degraded"
 )
 
 type MeshCondition struct {
 	// Type of mesh condition.
 	Type MeshConditionType `json:"type"`
 	// Status of the condition, one of True, False, Unknown.
+	// +kubebuilder:validation:Enum="True";"False";Unknown
 	Status api.ConditionStatus `json:"status"`
 	// Last time the condition transitioned from one status to another.
 	// +optional
@@ -94,6 +151,12 @@ type MeshList struct {
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:categories=all;appmesh
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Mesh",type=string,JSONPath=`.spec.meshName`
+// +kubebuilder:printcolumn:name="ARN",type=string,JSONPath=`.status.virtualServiceArn`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // VirtualService is a specification for a VirtualService resource
 type VirtualService struct {
@@ -101,7 +164,7 @@ type VirtualService struct {
 	// +optional
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	// +optional
+	// +kubebuilder:validation:Required
 	Spec VirtualServiceSpec `json:"spec,omitempty"`
 	// +optional
 	Status VirtualServiceStatus `json:"status,omitempty"`
@@ -109,16 +172,26 @@ type VirtualService struct {
 
 // VirtualServiceSpec is the spec for a VirtualService resource
 type VirtualServiceSpec struct {
+	// MeshName defaults to the mesh of the namespace
+	// +optional
 	MeshName string `json:"meshName"`
 	// +optional
 	VirtualRouter *VirtualRouter `json:"virtualRouter,omitempty"`
 	// +optional
 	Routes []Route `json:"routes,omitempty"`
+	// DeletionPolicy applies to the virtual service, virtual router and routes. Defaults to the policy of the mesh.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
 }
 
 // VirtualRouter is the spec for a VirtualRouter resource
 type VirtualRouter struct {
-	Name      string                  `json:"name"`
+	Name string `json:"name"`
+	// AWSName overrides the App Mesh name the naming strategy of the controller derives from the name
+	// +optional
+	// +kubebuilder:validation:MaxLength=255
+	AWSName *string `json:"awsName,omitempty"`
+	// +optional
 	Listeners []VirtualRouterListener `json:"listeners,omitempty"`
 }
 
@@ -200,10 +273,13 @@ type HttpRetryPolicy struct {
 	TcpRetryPolicyEvents []TcpRetryPolicyEvent `json:"tcpRetryEvents,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=server-error;gateway-error;client-error;stream-error
 type HttpRetryPolicyEvent string
 
+// +kubebuilder:validation:Enum=connection-error
 type TcpRetryPolicyEvent string
 
+// +kubebuilder:validation:Enum=cancelled;deadline-exceeded;internal;resource-exhausted;unavailable
 type GrpcRetryPolicyEvent string
 
 const (
@@ -274,11 +350,20 @@ type GrpcRetryPolicy struct {
 
 type WeightedTarget struct {
 	VirtualNodeName string `json:"virtualNodeName"`
-	Weight          int64  `json:"weight"`
+	// Namespace of the virtual node, defaults to the namespace of the virtual service. Targets in other
+	// namespaces must be allowed by a ReferenceGrant there.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight int64 `json:"weight"`
 }
 
 // VirtualServiceStatus is the status for a VirtualService resource
 type VirtualServiceStatus struct {
+	// MeshName is the mesh the virtual service joined through its namespace, when it does not set spec.meshName
+	// +optional
+	MeshName string `json:"meshName,omitempty"`
 	// VirtualServiceArn is the AppMesh VirtualService object's Amazon Resource Name
 	// +optional
 	VirtualServiceArn *string `json:"virtualServiceArn,omitempty"`
@@ -287,10 +372,15 @@ type VirtualServiceStatus struct {
 	VirtualRouterArn *string `json:"virtualRouterArn,omitempty"`
 	// RouteArns is a list of AppMesh Route objects' Amazon Resource Names
 	// +optional
-	RouteArns  []string                  `json:"routeArns,omitempty"`
+	RouteArns []string `json:"routeArns,omitempty"`
+	// +optional
 	Conditions []VirtualServiceCondition `json:"conditions"`
+	// ObservedGeneration is the generation of the spec last reconciled with App Mesh
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=VirtualServiceActive;VirtualRouterActive;RoutesActive;MeshMarkedForDeletion;Drifted;OwnershipConflict;Paused;WaitingForDependency;ReferencesResolved;NameCollision;Ready;Reconciled;Degraded
 type VirtualServiceConditionType string
 
 const (
@@ -299,12 +389,31 @@ const (
 	VirtualRouterActive                 VirtualServiceConditionType = "VirtualRouterActive"
 	RoutesActive                        VirtualServiceConditionType = "RoutesActive"
 	VirtualServiceMeshMarkedForDeletion VirtualServiceConditionType = "MeshMarkedForDeletion"
+	// VirtualServiceDrifted is True when the Appmesh Service, Router or Routes differ from the spec and the drift policy is report
+	VirtualServiceDrifted VirtualServiceConditionType = "Drifted"
+	// VirtualServiceOwnershipConflict is True when the Appmesh Service, Router or Routes belong to another resource or cluster
+	VirtualServiceOwnershipConflict VirtualServiceConditionType = "OwnershipConflict"
+	// VirtualServicePaused is True when the virtual service or its mesh has the pause annotation and is not reconciled
+	VirtualServicePaused VirtualServiceConditionType = "Paused"
+	// VirtualServiceWaitingForDependency is True when routes wait for the virtual nodes they target to become active
+	VirtualServiceWaitingForDependency VirtualServiceConditionType = "WaitingForDependency"
+	// VirtualServiceReferencesResolved is False when routes target virtual nodes that do not exist
+	VirtualServiceReferencesResolved VirtualServiceConditionType = "ReferencesResolved"
+	// VirtualServiceNameCollision is True when another virtual service maps onto the App Mesh name of the virtual router
+	VirtualServiceNameCollision VirtualServiceConditionType = "NameCollision"
+	// VirtualServiceReady is True when the virtual service is reconciled and the Appmesh Service, Router and Routes are active
+	VirtualServiceReady VirtualServiceConditionType = "Ready"
+	// VirtualServiceReconciled is True when the last reconcile of the virtual service succeeded and nothing held it off
+	VirtualServiceReconciled VirtualServiceConditionType = "Reconciled"
+	// VirtualServiceDegraded is True when the last reconcile of the virtual service failed, or it drifted or conflicts with other resources
+	VirtualServiceDegraded VirtualServiceConditionType = "Degraded"
 )
 
 type VirtualServiceCondition struct {
 	// Type of mesh service condition.
 	Type VirtualServiceConditionType `json:"type"`
 	// Status of the condition, one of True, False, Unknown.
+	// +kubebuilder:validation:Enum="True";"False";Unknown
 	Status api.ConditionStatus `json:"status"`
 	// Last time the condition transitioned from one status to another.
 	// +optional
@@ -329,6 +438,12 @@ type VirtualServiceList struct {
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:categories=all;appmesh
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Mesh",type=string,JSONPath=`.spec.meshName`
+// +kubebuilder:printcolumn:name="ARN",type=string,JSONPath=`.status.virtualNodeArn`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // VirtualNode is a specification for a VirtualNode resource
 type VirtualNode struct {
@@ -336,7 +451,7 @@ type VirtualNode struct {
 	// +optional
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	// +optional
+	// +kubebuilder:validation:Required
 	Spec VirtualNodeSpec `json:"spec,omitempty"`
 	// +optional
 	Status VirtualNodeStatus `json:"status,omitempty"`
@@ -344,6 +459,8 @@ type VirtualNode struct {
 
 // VirtualNodeSpec is the spec for a VirtualNode resource
 type VirtualNodeSpec struct {
+	// MeshName defaults to the mesh of the namespace
+	// +optional
 	MeshName string `json:"meshName"`
 	// +optional
 	Listeners []Listener `json:"listeners,omitempty"`
@@ -355,6 +472,14 @@ type VirtualNodeSpec struct {
 	BackendDefaults *BackendDefaults `json:"backendDefaults,omitempty"`
 	// +optional
 	Logging *Logging `json:"logging,omitempty"`
+	// DeletionPolicy defaults to the policy of the mesh
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// AWSName overrides the App Mesh name the naming strategy of the controller derives from the name and
+	// namespace of the virtual node
+	// +optional
+	// +kubebuilder:validation:MaxLength=255
+	AWSName *string `json:"awsName,omitempty"`
 }
 
 type Listener struct {
@@ -366,7 +491,10 @@ type Listener struct {
 }
 
 type PortMapping struct {
-	Port     int64  `json:"port"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int64 `json:"port"`
+	// +kubebuilder:validation:Enum=tcp;http;grpc;http2
 	Protocol string `json:"protocol"`
 }
 
@@ -378,8 +506,11 @@ type HealthCheckPolicy struct {
 	// +optional
 	Path *string `json:"path,omitempty"`
 	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
 	Port *int64 `json:"port,omitempty"`
 	// +optional
+	// +kubebuilder:validation:Enum=tcp;http;http2;grpc
 	Protocol *string `json:"protocol,omitempty"`
 	// +optional
 	TimeoutMillis *int64 `json:"timeoutMillis,omitempty"`
@@ -423,6 +554,10 @@ type BackendDefaults struct {
 
 type VirtualServiceBackend struct {
 	VirtualServiceName string `json:"virtualServiceName"`
+	// Namespace of the virtual service, if it is managed in another namespace than the virtual node. Backends in
+	// other namespaces must be allowed by a ReferenceGrant there.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
 	// +optional
 	ClientPolicy *ClientPolicy `json:"clientPolicy,omitempty"`
 }
@@ -444,14 +579,34 @@ type FileAccessLog struct {
 
 // VirtualNodeStatus is the status for a VirtualNode resource
 type VirtualNodeStatus struct {
-	MeshArn *string `json:"meshArn,omitempty"`
+	// MeshName is the mesh the virtual node joined through its namespace, when it does not set spec.meshName
+	// +optional
+	MeshName string  `json:"meshName,omitempty"`
+	MeshArn  *string `json:"meshArn,omitempty"`
 	// VirtualNodeArn is the AppMesh VirtualNode object's Amazon Resource Name
 	// +optional
-	VirtualNodeArn *string                `json:"virtualNodeArn,omitempty"`
-	Conditions     []VirtualNodeCondition `json:"conditions"`
+	VirtualNodeArn *string `json:"virtualNodeArn,omitempty"`
+	// +optional
+	Conditions []VirtualNodeCondition `json:"conditions"`
 	// CloudMapService is AWS CloudMap Service object's info
 	// +optional
 	CloudMapService *CloudMapServiceStatus `json:"cloudmapService,omitempty"`
+	// ObservedGeneration is the generation of the spec last reconciled with App Mesh
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Certificates are the certificates the virtual node sources from Secrets
+	// +optional
+	Certificates []CertificateStatus `json:"certificates,omitempty"`
+}
+
+// CertificateStatus is the expiry of a certificate sourced from a Secret
+type CertificateStatus struct {
+	// SecretName is the name of the Secret
+	SecretName string `json:"secretName"`
+	// Key is the key of the certificate in the Secret
+	Key string `json:"key"`
+	// NotAfter is when the certificate, or the first certificate of its chain to expire, expires
+	NotAfter metav1.Time `json:"notAfter"`
 }
 
 // CloudMapServiceStatus is AWS CloudMap Service object's info
@@ -468,6 +623,18 @@ type CloudMapServiceStatus struct {
 
 type TlsValidationContext struct {
 	Trust TlsValidationContextTrust `json:"trust"`
+	// SubjectAlternativeNames restricts the certificates accepted to the ones with a matching SAN
+	// +optional
+	SubjectAlternativeNames *SubjectAlternativeNames `json:"subjectAlternativeNames,omitempty"`
+}
+
+type SubjectAlternativeNames struct {
+	Match SubjectAlternativeNameMatchers `json:"match"`
+}
+
+type SubjectAlternativeNameMatchers struct {
+	// +kubebuilder:validation:MinItems=1
+	Exact []string `json:"exact"`
 }
 
 type TlsValidationContextTrust struct {
@@ -475,6 +642,9 @@ type TlsValidationContextTrust struct {
 	ACM *TlsValidationContextAcmTrust `json:"acm,omitempty"`
 	// +optional
 	File *TlsValidationContextFileTrust `json:"file,omitempty"`
+	// SecretRef trusts the ca.crt of a Secret of type kubernetes.io/tls, and replaces file
+	// +optional
+	SecretRef *TlsSecretReference `json:"secretRef,omitempty"`
 }
 
 type TlsValidationContextAcmTrust struct {
@@ -485,11 +655,18 @@ type TlsValidationContextFileTrust struct {
 	CertificateChain string `json:"certificateChain"`
 }
 
+// TlsSecretReference names a Secret of type kubernetes.io/tls in the namespace of the virtual node. The sidecar
+// is expected to mount it at /etc/appmesh/secrets/<name>.
+type TlsSecretReference struct {
+	Name string `json:"name"`
+}
+
 // END General TLS Types
 
 // Listener TLS Types
 
 type ListenerTls struct {
+	// +kubebuilder:validation:Enum=DISABLED;PERMISSIVE;STRICT
 	Mode        string                 `json:"mode"`
 	Certificate ListenerTlsCertificate `json:"certificate"`
 }
@@ -499,6 +676,9 @@ type ListenerTlsCertificate struct {
 	ACM *ListenerTlsAcmCertificate `json:"acm,omitempty"`
 	// +optional
 	File *ListenerTlsFileCertificate `json:"file,omitempty"`
+	// SecretRef serves the tls.crt and tls.key of a Secret, and replaces file
+	// +optional
+	SecretRef *TlsSecretReference `json:"secretRef,omitempty"`
 }
 
 type ListenerTlsAcmCertificate struct {
@@ -525,22 +705,55 @@ type ClientPolicyTls struct {
 	// +optional
 	Ports      []int64              `json:"ports,omitempty"`
 	Validation TlsValidationContext `json:"validation"`
+	// Certificate is presented to backends for mutual TLS
+	// +optional
+	Certificate *ClientTlsCertificate `json:"certificate,omitempty"`
+}
+
+type ClientTlsCertificate struct {
+	// +optional
+	File *ListenerTlsFileCertificate `json:"file,omitempty"`
+	// SecretRef presents the tls.crt and tls.key of a Secret, and replaces file
+	// +optional
+	SecretRef *TlsSecretReference `json:"secretRef,omitempty"`
 }
 
 // END Client Policy Types
 
+// +kubebuilder:validation:Enum=VirtualNodeActive;MeshMarkedForDeletion;Drifted;OwnershipConflict;Paused;WaitingForDependency;ReferencesResolved;NameCollision;TLSPolicyCompliant;Ready;Reconciled;Degraded
 type VirtualNodeConditionType string
 
 const (
 	// VirtualNodeActive is Active when the Appmesh Node has been created or found via the API
 	VirtualNodeActive                VirtualNodeConditionType = "VirtualNodeActive"
 	VirtualNodeMeshMarkedForDeletion VirtualNodeConditionType = "MeshMarkedForDeletion"
+	// VirtualNodeDrifted is True when the Appmesh Node differs from the spec and the drift policy is report
+	VirtualNodeDrifted VirtualNodeConditionType = "Drifted"
+	// VirtualNodeOwnershipConflict is True when the Appmesh Node belongs to another resource or cluster
+	VirtualNodeOwnershipConflict VirtualNodeConditionType = "OwnershipConflict"
+	// VirtualNodePaused is True when the virtual node or its mesh has the pause annotation and is not reconciled
+	VirtualNodePaused VirtualNodeConditionType = "Paused"
+	// VirtualNodeWaitingForDependency is True when the virtual node waits for its backend virtual services to become active
+	VirtualNodeWaitingForDependency VirtualNodeConditionType = "WaitingForDependency"
+	// VirtualNodeReferencesResolved is False when backends name virtual services that do not exist
+	VirtualNodeReferencesResolved VirtualNodeConditionType = "ReferencesResolved"
+	// VirtualNodeNameCollision is True when another virtual node maps onto the same App Mesh name
+	VirtualNodeNameCollision VirtualNodeConditionType = "NameCollision"
+	// VirtualNodeTLSPolicyCompliant is False when the virtual node violates the TLS policy of its mesh
+	VirtualNodeTLSPolicyCompliant VirtualNodeConditionType = "TLSPolicyCompliant"
+	// VirtualNodeReady is True when the virtual node is reconciled and active in App Mesh
+	VirtualNodeReady VirtualNodeConditionType = "Ready"
+	// VirtualNodeReconciled is True when the last reconcile of the virtual node succeeded and nothing held it off
+	VirtualNodeReconciled VirtualNodeConditionType = "Reconciled"
+	// VirtualNodeDegraded is True when the last reconcile of the virtual node failed, or it drifted or conflicts with other resources
+	VirtualNodeDegraded VirtualNodeConditionType = "Degraded"
 )
 
 type VirtualNodeCondition struct {
 	// Type of mesh node condition.
 	Type VirtualNodeConditionType `json:"type"`
 	// Status of the condition, one of True, False, Unknown.
+	// +kubebuilder:validation:Enum="True";"False";Unknown
 	Status api.ConditionStatus `json:"status"`
 	// Last time the condition transitioned from one status to another.
 	// +optional
@@ -562,3 +775,97 @@ type VirtualNodeList struct {
 
 	Items []VirtualNode `json:"items"`
 }
+
+// ReferenceKind is the kind of resource a ReferenceGrant allows references to
+// +kubebuilder:validation:Enum=VirtualNode;VirtualService
+type ReferenceKind string
+
+const (
+	ReferenceKindVirtualNode    ReferenceKind = "VirtualNode"
+	ReferenceKindVirtualService ReferenceKind = "VirtualService"
+)
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:categories=appmesh
+
+// ReferenceGrant allows route targets and backends in other namespaces to reference virtual nodes and virtual
+// services in its namespace. References across namespaces are denied unless a grant allows them.
+type ReferenceGrant struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:Required
+	Spec ReferenceGrantSpec `json:"spec,omitempty"`
+}
+
+// ReferenceGrantSpec is the spec for a ReferenceGrant resource
+type ReferenceGrantSpec struct {
+	// From lists the namespaces references are allowed from
+	From []ReferenceGrantFrom `json:"from"`
+	// To lists the resources of the grant's namespace that may be referenced
+	To []ReferenceGrantTo `json:"to"`
+}
+
+type ReferenceGrantFrom struct {
+	Namespace string `json:"namespace"`
+}
+
+type ReferenceGrantTo struct {
+	Kind ReferenceKind `json:"kind"`
+	// Name of the resource, all resources of the kind when omitted
+	// +optional
+	Name *string `json:"name,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ReferenceGrantList is a list of ReferenceGrant resources
+type ReferenceGrantList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ReferenceGrant `json:"items"`
+}
+
+// +genclient
+// +genclient:noStatus
+// +resourceName=meshdefaults
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:path=meshdefaults,singular=meshdefaults,categories=appmesh
+
+// MeshDefaults supplies defaults for the virtual nodes in its namespace. Fields a virtual node sets are not
+// overridden.
+type MeshDefaults struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:Required
+	Spec MeshDefaultsSpec `json:"spec,omitempty"`
+}
+
+// MeshDefaultsSpec is the spec for a MeshDefaults resource
+type MeshDefaultsSpec struct {
+	// BackendDefaults of virtual nodes that do not set a client policy for their backends
+	// +optional
+	BackendDefaults *BackendDefaults `json:"backendDefaults,omitempty"`
+	// Logging of virtual nodes that do not set it
+	// +optional
+	Logging *Logging `json:"logging,omitempty"`
+	// HealthCheck fills in the health checks of listeners, listeners without one get it as a whole
+	// +optional
+	HealthCheck *HealthCheckPolicy `json:"healthCheck,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// MeshDefaultsList is a list of MeshDefaults resources
+type MeshDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []MeshDefaults `json:"items"`
+}
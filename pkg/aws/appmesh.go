@@ -3,6 +3,7 @@ package aws
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -35,8 +36,13 @@ const (
 	DescribeRouteTimeout          = 10
 	CreateRouteTimeout            = 10
 	ListRoutesTimeout             = 10
+	ListVirtualNodesTimeout       = 10
+	ListVirtualServicesTimeout    = 10
 	UpdateRouteTimeout            = 10
 	DeleteRouteTimeout            = 10
+	ListTagsForResourceTimeout    = 10
+	TagResourceTimeout            = 10
+	UntagResourceTimeout          = 10
 )
 
 type AppMeshAPI interface {
@@ -45,22 +51,27 @@ type AppMeshAPI interface {
 	UpdateMesh(context.Context, *appmeshv1beta1.Mesh) (*Mesh, error)
 	DeleteMesh(context.Context, string) (*Mesh, error)
 	GetVirtualNode(context.Context, string, string) (*VirtualNode, error)
-	CreateVirtualNode(context.Context, *appmeshv1beta1.VirtualNode) (*VirtualNode, error)
+	CreateVirtualNode(context.Context, *appmeshv1beta1.VirtualNode, map[string]string) (*VirtualNode, error)
 	UpdateVirtualNode(context.Context, *appmeshv1beta1.VirtualNode) (*VirtualNode, error)
 	DeleteVirtualNode(context.Context, string, string) (*VirtualNode, error)
+	ListVirtualNodes(context.Context, string) ([]*VirtualNode, error)
 	GetVirtualService(context.Context, string, string) (*VirtualService, error)
-	CreateVirtualService(context.Context, *appmeshv1beta1.VirtualService) (*VirtualService, error)
+	CreateVirtualService(context.Context, *appmeshv1beta1.VirtualService, map[string]string) (*VirtualService, error)
 	UpdateVirtualService(context.Context, *appmeshv1beta1.VirtualService) (*VirtualService, error)
 	DeleteVirtualService(context.Context, string, string) (*VirtualService, error)
+	ListVirtualServices(context.Context, string) ([]*VirtualService, error)
 	GetVirtualRouter(context.Context, string, string) (*VirtualRouter, error)
-	CreateVirtualRouter(context.Context, *appmeshv1beta1.VirtualRouter, string) (*VirtualRouter, error)
+	CreateVirtualRouter(context.Context, *appmeshv1beta1.VirtualRouter, string, map[string]string) (*VirtualRouter, error)
 	UpdateVirtualRouter(context.Context, *appmeshv1beta1.VirtualRouter, string) (*VirtualRouter, error)
 	DeleteVirtualRouter(context.Context, string, string) (*VirtualRouter, error)
 	GetRoute(context.Context, string, string, string) (*Route, error)
-	CreateRoute(context.Context, *appmeshv1beta1.Route, string, string) (*Route, error)
+	CreateRoute(context.Context, *appmeshv1beta1.Route, string, string, map[string]string) (*Route, error)
 	UpdateRoute(context.Context, *appmeshv1beta1.Route, string, string) (*Route, error)
 	GetRoutesForVirtualRouter(context.Context, string, string) (Routes, error)
 	DeleteRoute(context.Context, string, string, string) (*Route, error)
+	ListTagsForResource(context.Context, string) (map[string]string, error)
+	TagResource(context.Context, string, map[string]string) error
+	UntagResource(context.Context, string, []string) error
 }
 
 type Mesh struct {
@@ -109,13 +120,7 @@ func (c *Cloud) CreateMesh(ctx context.Context, mesh *appmeshv1beta1.Mesh) (*Mes
 
 	input := &appmesh.CreateMeshInput{
 		MeshName: aws.String(mesh.Name),
-		Spec:     &appmesh.MeshSpec{},
-	}
-
-	if mesh.Spec.EgressFilter != nil {
-		input.Spec.EgressFilter = &appmesh.EgressFilter{
-			Type: aws.String(mesh.Spec.EgressFilter.Type),
-		}
+		Spec:     BuildMeshSpec(mesh),
 	}
 
 	if output, err := c.appmesh.CreateMeshWithContext(ctx, input); err != nil {
@@ -141,13 +146,7 @@ func (c *Cloud) UpdateMesh(ctx context.Context, mesh *appmeshv1beta1.Mesh) (*Mes
 
 	input := &appmesh.UpdateMeshInput{
 		MeshName: aws.String(mesh.Name),
-		Spec:     &appmesh.MeshSpec{},
-	}
-
-	if mesh.Spec.EgressFilter != nil {
-		input.Spec.EgressFilter = &appmesh.EgressFilter{
-			Type: aws.String(mesh.Spec.EgressFilter.Type),
-		}
+		Spec:     BuildMeshSpec(mesh),
 	}
 
 	if output, err := c.appmesh.UpdateMeshWithContext(ctx, input); err != nil {
@@ -167,6 +166,7 @@ func (c *Cloud) DeleteMesh(ctx context.Context, name string) (*Mesh, error) {
 	defer func() {
 		c.stats.SetRequestDuration("mesh", name, "delete", time.Since(begin))
 	}()
+	defer c.describeCache.invalidateAll()
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*DeleteMeshTimeout)
 	defer cancel()
@@ -296,8 +296,19 @@ func (v *VirtualNode) BackendDefaults() *appmeshv1beta1.BackendDefaults {
 	return &crdBackendDefaults
 }
 
-// GetVirtualNode calls describe virtual node.
+// GetVirtualNode calls describe virtual node, coalescing concurrent calls and caching the result briefly.
 func (c *Cloud) GetVirtualNode(ctx context.Context, name string, meshName string) (*VirtualNode, error) {
+	value, err := c.describeCache.get("virtual_node", describeCacheKey("virtual_node", meshName, name), func() (interface{}, error) {
+		return c.describeVirtualNode(ctx, name, meshName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &VirtualNode{Data: value.(*VirtualNode).Data}, nil
+}
+
+// describeVirtualNode calls describe virtual node.
+func (c *Cloud) describeVirtualNode(ctx context.Context, name string, meshName string) (*VirtualNode, error) {
 	begin := time.Now()
 	defer func() {
 		c.stats.SetRequestDuration("virtual_node", name, "get", time.Since(begin))
@@ -323,12 +334,13 @@ func (c *Cloud) GetVirtualNode(ctx context.Context, name string, meshName string
 }
 
 // CreateVirtualNode converts the desired virtual node spec into CreateVirtualNodeInput and calls create
-// virtual node.
-func (c *Cloud) CreateVirtualNode(ctx context.Context, vnode *appmeshv1beta1.VirtualNode) (*VirtualNode, error) {
+// virtual node with the given tags.
+func (c *Cloud) CreateVirtualNode(ctx context.Context, vnode *appmeshv1beta1.VirtualNode, tags map[string]string) (*VirtualNode, error) {
 	begin := time.Now()
 	defer func() {
 		c.stats.SetRequestDuration("virtual_node", vnode.Name, "create", time.Since(begin))
 	}()
+	defer c.describeCache.invalidate(describeCacheKey("virtual_node", vnode.Spec.MeshName, vnode.Name))
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*CreateVirtualNodeTimeout)
 	defer cancel()
@@ -336,102 +348,8 @@ func (c *Cloud) CreateVirtualNode(ctx context.Context, vnode *appmeshv1beta1.Vir
 	input := &appmesh.CreateVirtualNodeInput{
 		VirtualNodeName: aws.String(vnode.Name),
 		MeshName:        aws.String(vnode.Spec.MeshName),
-		Spec:            &appmesh.VirtualNodeSpec{},
-	}
-
-	if vnode.Spec.Listeners != nil {
-		listeners := []*appmesh.Listener{}
-		for _, listener := range vnode.Spec.Listeners {
-			appmeshListener := &appmesh.Listener{
-				PortMapping: &appmesh.PortMapping{
-					Port:     aws.Int64(listener.PortMapping.Port),
-					Protocol: aws.String(listener.PortMapping.Protocol),
-				},
-			}
-			if listener.HealthCheck != nil {
-				appmeshHealthCheck := &appmesh.HealthCheckPolicy{
-					HealthyThreshold:   listener.HealthCheck.HealthyThreshold,
-					IntervalMillis:     listener.HealthCheck.IntervalMillis,
-					Path:               listener.HealthCheck.Path,
-					Port:               listener.HealthCheck.Port,
-					Protocol:           listener.HealthCheck.Protocol,
-					TimeoutMillis:      listener.HealthCheck.TimeoutMillis,
-					UnhealthyThreshold: listener.HealthCheck.UnhealthyThreshold,
-				}
-				appmeshListener.HealthCheck = appmeshHealthCheck
-			}
-			if listener.TLS != nil {
-				appmeshCert := &appmesh.ListenerTlsCertificate{}
-				if listener.TLS.Certificate.ACM != nil {
-					appmeshCert.SetAcm(&appmesh.ListenerTlsAcmCertificate{
-						CertificateArn: aws.String(listener.TLS.Certificate.ACM.CertificateArn),
-					})
-				}
-				if listener.TLS.Certificate.File != nil {
-					appmeshCert.SetFile(&appmesh.ListenerTlsFileCertificate{
-						CertificateChain: aws.String(listener.TLS.Certificate.File.CertificateChain),
-						PrivateKey:       aws.String(listener.TLS.Certificate.File.PrivateKey),
-					})
-				}
-				appmeshListener.SetTls(&appmesh.ListenerTls{
-					Mode:        aws.String(listener.TLS.Mode),
-					Certificate: appmeshCert,
-				})
-			}
-			listeners = append(listeners, appmeshListener)
-		}
-		input.Spec.SetListeners(listeners)
-	}
-
-	if vnode.Spec.Backends != nil {
-		backends := []*appmesh.Backend{}
-		for _, crdBackend := range vnode.Spec.Backends {
-			appmeshBackend := appmesh.Backend{
-				VirtualService: &appmesh.VirtualServiceBackend{
-					VirtualServiceName: aws.String(crdBackend.VirtualService.VirtualServiceName),
-				},
-			}
-			if crdBackend.VirtualService.ClientPolicy != nil {
-				appmeshBackend.VirtualService.SetClientPolicy(convertCrdClientPolicyToSdk(crdBackend.VirtualService.ClientPolicy))
-			}
-			backends = append(backends, &appmeshBackend)
-		}
-		input.Spec.SetBackends(backends)
-	}
-
-	if vnode.Spec.BackendDefaults != nil {
-		backendDefaults := appmesh.BackendDefaults{}
-		if vnode.Spec.BackendDefaults.ClientPolicy != nil {
-			backendDefaults.SetClientPolicy(convertCrdClientPolicyToSdk(vnode.Spec.BackendDefaults.ClientPolicy))
-		}
-		input.Spec.SetBackendDefaults(&backendDefaults)
-	}
-
-	if vnode.Spec.ServiceDiscovery != nil {
-		if vnode.Spec.ServiceDiscovery.Dns != nil {
-			serviceDiscovery := &appmesh.ServiceDiscovery{
-				Dns: &appmesh.DnsServiceDiscovery{
-					Hostname: aws.String(vnode.Spec.ServiceDiscovery.Dns.HostName),
-				},
-			}
-			input.Spec.SetServiceDiscovery(serviceDiscovery)
-		} else if vnode.Spec.ServiceDiscovery.CloudMap != nil {
-			input.Spec.SetServiceDiscovery(c.buildAwsCloudMapServiceDiscovery(vnode))
-		} else {
-			klog.Warningf("No service discovery set for virtual node %s", vnode.Name)
-		}
-	}
-
-	if vnode.Spec.Logging != nil &&
-		vnode.Spec.Logging.AccessLog != nil &&
-		vnode.Spec.Logging.AccessLog.File != nil {
-		input.Spec.SetLogging(&appmesh.Logging{
-			AccessLog: &appmesh.AccessLog{
-				File: &appmesh.FileAccessLog{
-					Path: aws.String(vnode.Spec.Logging.AccessLog.File.Path),
-				},
-			},
-		})
+		Spec:            BuildVirtualNodeSpec(vnode),
+		Tags:            buildTagRefs(tags),
 	}
 
 	if output, err := c.appmesh.CreateVirtualNodeWithContext(ctx, input); err != nil {
@@ -452,6 +370,7 @@ func (c *Cloud) UpdateVirtualNode(ctx context.Context, vnode *appmeshv1beta1.Vir
 	defer func() {
 		c.stats.SetRequestDuration("virtual_node", vnode.Name, "update", time.Since(begin))
 	}()
+	defer c.describeCache.invalidate(describeCacheKey("virtual_node", vnode.Spec.MeshName, vnode.Name))
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*UpdateVirtualNodeTimeout)
 	defer cancel()
@@ -459,104 +378,7 @@ func (c *Cloud) UpdateVirtualNode(ctx context.Context, vnode *appmeshv1beta1.Vir
 	input := &appmesh.UpdateVirtualNodeInput{
 		VirtualNodeName: aws.String(vnode.Name),
 		MeshName:        aws.String(vnode.Spec.MeshName),
-		Spec:            &appmesh.VirtualNodeSpec{},
-	}
-
-	if vnode.Spec.Listeners != nil {
-		listeners := []*appmesh.Listener{}
-		for _, crdListener := range vnode.Spec.Listeners {
-			sdkListener := appmesh.Listener{
-				PortMapping: &appmesh.PortMapping{
-					Port:     &crdListener.PortMapping.Port,
-					Protocol: aws.String(crdListener.PortMapping.Protocol),
-				},
-			}
-			if sdkListener.HealthCheck != nil {
-				sdkHealthCheck := appmesh.HealthCheckPolicy{
-					HealthyThreshold:   sdkListener.HealthCheck.HealthyThreshold,
-					IntervalMillis:     sdkListener.HealthCheck.IntervalMillis,
-					Path:               sdkListener.HealthCheck.Path,
-					Port:               sdkListener.HealthCheck.Port,
-					Protocol:           sdkListener.HealthCheck.Protocol,
-					TimeoutMillis:      sdkListener.HealthCheck.TimeoutMillis,
-					UnhealthyThreshold: sdkListener.HealthCheck.UnhealthyThreshold,
-				}
-				sdkListener.SetHealthCheck(&sdkHealthCheck)
-			}
-			if crdListener.TLS != nil {
-				sdkCertificate := appmesh.ListenerTlsCertificate{}
-				if crdListener.TLS.Certificate.ACM != nil {
-					sdkCertificate.SetAcm(&appmesh.ListenerTlsAcmCertificate{
-						CertificateArn: &crdListener.TLS.Certificate.ACM.CertificateArn,
-					})
-				}
-				if crdListener.TLS.Certificate.File != nil {
-					sdkCertificate.SetFile(&appmesh.ListenerTlsFileCertificate{
-						CertificateChain: &crdListener.TLS.Certificate.File.CertificateChain,
-						PrivateKey:       &crdListener.TLS.Certificate.File.PrivateKey,
-					})
-				}
-				sdkListenerTls := appmesh.ListenerTls{
-					Mode:        &crdListener.TLS.Mode,
-					Certificate: &sdkCertificate,
-				}
-				sdkListener.SetTls(&sdkListenerTls)
-			}
-			listeners = append(listeners, &sdkListener)
-		}
-		input.Spec.SetListeners(listeners)
-	}
-
-	if vnode.Spec.BackendDefaults != nil {
-		crdBackendDefaults := vnode.Spec.BackendDefaults
-		sdkBackendDefaults := appmesh.BackendDefaults{}
-		if crdBackendDefaults.ClientPolicy != nil {
-			sdkBackendDefaults.SetClientPolicy(convertCrdClientPolicyToSdk(crdBackendDefaults.ClientPolicy))
-		}
-		input.Spec.SetBackendDefaults(&sdkBackendDefaults)
-	}
-
-	if vnode.Spec.Backends != nil {
-		backends := []*appmesh.Backend{}
-		for _, crdBackend := range vnode.Spec.Backends {
-			sdkBackend := appmesh.Backend{
-				VirtualService: &appmesh.VirtualServiceBackend{
-					VirtualServiceName: aws.String(crdBackend.VirtualService.VirtualServiceName),
-				},
-			}
-			if crdBackend.VirtualService.ClientPolicy != nil {
-				sdkBackend.VirtualService.SetClientPolicy(convertCrdClientPolicyToSdk(crdBackend.VirtualService.ClientPolicy))
-			}
-			backends = append(backends, &sdkBackend)
-		}
-		input.Spec.SetBackends(backends)
-	}
-
-	if vnode.Spec.ServiceDiscovery != nil {
-		if vnode.Spec.ServiceDiscovery.Dns != nil {
-			serviceDiscovery := &appmesh.ServiceDiscovery{
-				Dns: &appmesh.DnsServiceDiscovery{
-					Hostname: aws.String(vnode.Spec.ServiceDiscovery.Dns.HostName),
-				},
-			}
-			input.Spec.SetServiceDiscovery(serviceDiscovery)
-		} else if vnode.Spec.ServiceDiscovery.CloudMap != nil {
-			input.Spec.SetServiceDiscovery(c.buildAwsCloudMapServiceDiscovery(vnode))
-		} else {
-			klog.Warningf("No service discovery set for virtual node %s", vnode.Name)
-		}
-	}
-
-	if vnode.Spec.Logging != nil &&
-		vnode.Spec.Logging.AccessLog != nil &&
-		vnode.Spec.Logging.AccessLog.File != nil {
-		input.Spec.SetLogging(&appmesh.Logging{
-			AccessLog: &appmesh.AccessLog{
-				File: &appmesh.FileAccessLog{
-					Path: aws.String(vnode.Spec.Logging.AccessLog.File.Path),
-				},
-			},
-		})
+		Spec:            BuildVirtualNodeSpec(vnode),
 	}
 
 	if output, err := c.appmesh.UpdateVirtualNodeWithContext(ctx, input); err != nil {
@@ -575,6 +397,7 @@ func (c *Cloud) DeleteVirtualNode(ctx context.Context, name string, meshName str
 	defer func() {
 		c.stats.SetRequestDuration("virtual_node", name, "delete", time.Since(begin))
 	}()
+	defer c.describeCache.invalidate(describeCacheKey("virtual_node", meshName, name))
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*DeleteVirtualNodeTimeout)
 	defer cancel()
@@ -595,6 +418,44 @@ func (c *Cloud) DeleteVirtualNode(ctx context.Context, name string, meshName str
 	}
 }
 
+// ListVirtualNodes lists and describes the virtual nodes of a mesh.
+func (c *Cloud) ListVirtualNodes(ctx context.Context, meshName string) ([]*VirtualNode, error) {
+	begin := time.Now()
+	defer func() {
+		c.stats.SetRequestDuration("virtual_node", meshName, "list", time.Since(begin))
+	}()
+
+	listctx, cancel := context.WithTimeout(ctx, time.Second*ListVirtualNodesTimeout)
+	defer cancel()
+
+	var names []string
+	input := &appmesh.ListVirtualNodesInput{
+		MeshName: aws.String(meshName),
+	}
+	err := c.appmesh.ListVirtualNodesPagesWithContext(listctx, input, func(output *appmesh.ListVirtualNodesOutput, lastPage bool) bool {
+		for _, ref := range output.VirtualNodes {
+			names = append(names, aws.StringValue(ref.VirtualNodeName))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vnodes := []*VirtualNode{}
+	for _, name := range names {
+		vnode, err := c.GetVirtualNode(ctx, name, meshName)
+		if err != nil {
+			if IsAWSErrNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		vnodes = append(vnodes, vnode)
+	}
+	return vnodes, nil
+}
+
 type VirtualService struct {
 	Data appmesh.VirtualServiceData
 }
@@ -650,8 +511,8 @@ func (c *Cloud) GetVirtualService(ctx context.Context, name string, meshName str
 }
 
 // CreateVirtualService converts the desired virtual service spec into CreateVirtualServiceInput and calls create
-// virtual service.
-func (c *Cloud) CreateVirtualService(ctx context.Context, vservice *appmeshv1beta1.VirtualService) (*VirtualService, error) {
+// virtual service with the given tags.
+func (c *Cloud) CreateVirtualService(ctx context.Context, vservice *appmeshv1beta1.VirtualService, tags map[string]string) (*VirtualService, error) {
 	begin := time.Now()
 	defer func() {
 		c.stats.SetRequestDuration("virtual_service", vservice.Name, "create", time.Since(begin))
@@ -671,6 +532,7 @@ func (c *Cloud) CreateVirtualService(ctx context.Context, vservice *appmeshv1bet
 				},
 			},
 		},
+		Tags: buildTagRefs(tags),
 	}
 
 	if output, err := c.appmesh.CreateVirtualServiceWithContext(ctx, input); err != nil {
@@ -742,6 +604,44 @@ func (c *Cloud) DeleteVirtualService(ctx context.Context, name string, meshName
 	}
 }
 
+// ListVirtualServices lists and describes the virtual services of a mesh.
+func (c *Cloud) ListVirtualServices(ctx context.Context, meshName string) ([]*VirtualService, error) {
+	begin := time.Now()
+	defer func() {
+		c.stats.SetRequestDuration("virtual_service", meshName, "list", time.Since(begin))
+	}()
+
+	listctx, cancel := context.WithTimeout(ctx, time.Second*ListVirtualServicesTimeout)
+	defer cancel()
+
+	var names []string
+	input := &appmesh.ListVirtualServicesInput{
+		MeshName: aws.String(meshName),
+	}
+	err := c.appmesh.ListVirtualServicesPagesWithContext(listctx, input, func(output *appmesh.ListVirtualServicesOutput, lastPage bool) bool {
+		for _, ref := range output.VirtualServices {
+			names = append(names, aws.StringValue(ref.VirtualServiceName))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	vservices := []*VirtualService{}
+	for _, name := range names {
+		vservice, err := c.GetVirtualService(ctx, name, meshName)
+		if err != nil {
+			if IsAWSErrNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		vservices = append(vservices, vservice)
+	}
+	return vservices, nil
+}
+
 type VirtualRouter struct {
 	Data appmesh.VirtualRouterData
 }
@@ -760,8 +660,19 @@ func (v *VirtualRouter) Status() string {
 	return ""
 }
 
-// GetVirtualRouter calls describe virtual router.
+// GetVirtualRouter calls describe virtual router, coalescing concurrent calls and caching the result briefly.
 func (c *Cloud) GetVirtualRouter(ctx context.Context, name string, meshName string) (*VirtualRouter, error) {
+	value, err := c.describeCache.get("virtual_router", describeCacheKey("virtual_router", meshName, name), func() (interface{}, error) {
+		return c.describeVirtualRouter(ctx, name, meshName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &VirtualRouter{Data: value.(*VirtualRouter).Data}, nil
+}
+
+// describeVirtualRouter calls describe virtual router.
+func (c *Cloud) describeVirtualRouter(ctx context.Context, name string, meshName string) (*VirtualRouter, error) {
 	begin := time.Now()
 	defer func() {
 		c.stats.SetRequestDuration("virtual_router", name, "get", time.Since(begin))
@@ -787,35 +698,22 @@ func (c *Cloud) GetVirtualRouter(ctx context.Context, name string, meshName stri
 }
 
 // CreateVirtualRouter converts the desired virtual service spec into CreateVirtualServiceInput and calls create
-// virtual router.
-func (c *Cloud) CreateVirtualRouter(ctx context.Context, vrouter *appmeshv1beta1.VirtualRouter, meshName string) (*VirtualRouter, error) {
+// virtual router with the given tags.
+func (c *Cloud) CreateVirtualRouter(ctx context.Context, vrouter *appmeshv1beta1.VirtualRouter, meshName string, tags map[string]string) (*VirtualRouter, error) {
 	begin := time.Now()
 	defer func() {
 		c.stats.SetRequestDuration("virtual_router", vrouter.Name, "create", time.Since(begin))
 	}()
+	defer c.describeCache.invalidate(describeCacheKey("virtual_router", meshName, vrouter.Name))
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*CreateVirtualRouterTimeout)
 	defer cancel()
 
-	listeners := []*appmesh.VirtualRouterListener{}
-	if vrouter.Listeners != nil {
-		for _, listener := range vrouter.Listeners {
-			listeners = append(listeners, &appmesh.VirtualRouterListener{
-				PortMapping: &appmesh.PortMapping{
-					Port:     &listener.PortMapping.Port,
-					Protocol: aws.String(listener.PortMapping.Protocol),
-				},
-			})
-		}
-	}
-
-	klog.Infof("Using %d vrouter listeners to build %d input listeners", len(vrouter.Listeners), len(listeners))
 	input := &appmesh.CreateVirtualRouterInput{
 		MeshName:          aws.String(meshName),
 		VirtualRouterName: aws.String(vrouter.Name),
-		Spec: &appmesh.VirtualRouterSpec{
-			Listeners: listeners,
-		},
+		Spec:              BuildVirtualRouterSpec(vrouter),
+		Tags:              buildTagRefs(tags),
 	}
 
 	if output, err := c.appmesh.CreateVirtualRouterWithContext(ctx, input); err != nil {
@@ -835,29 +733,15 @@ func (c *Cloud) UpdateVirtualRouter(ctx context.Context, vrouter *appmeshv1beta1
 	defer func() {
 		c.stats.SetRequestDuration("virtual_router", vrouter.Name, "update", time.Since(begin))
 	}()
+	defer c.describeCache.invalidate(describeCacheKey("virtual_router", meshName, vrouter.Name))
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*UpdateVirtualRouterTimeout)
 	defer cancel()
 
-	listeners := []*appmesh.VirtualRouterListener{}
-	if vrouter.Listeners != nil {
-		for _, listener := range vrouter.Listeners {
-			listeners = append(listeners, &appmesh.VirtualRouterListener{
-				PortMapping: &appmesh.PortMapping{
-					Port:     &listener.PortMapping.Port,
-					Protocol: aws.String(listener.PortMapping.Protocol),
-				},
-			})
-		}
-	}
-
-	klog.Infof("Using %d vrouter listeners to build %d input listeners", len(vrouter.Listeners), len(listeners))
 	input := &appmesh.UpdateVirtualRouterInput{
 		MeshName:          aws.String(meshName),
 		VirtualRouterName: aws.String(vrouter.Name),
-		Spec: &appmesh.VirtualRouterSpec{
-			Listeners: listeners,
-		},
+		Spec:              BuildVirtualRouterSpec(vrouter),
 	}
 
 	if output, err := c.appmesh.UpdateVirtualRouterWithContext(ctx, input); err != nil {
@@ -876,6 +760,7 @@ func (c *Cloud) DeleteVirtualRouter(ctx context.Context, name string, meshName s
 	defer func() {
 		c.stats.SetRequestDuration("virtual_router", name, "delete", time.Since(begin))
 	}()
+	defer c.describeCache.invalidate(describeCacheKey("virtual_router", meshName, name))
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*DeleteVirtualRouterTimeout)
 	defer cancel()
@@ -929,13 +814,21 @@ func (r *Route) WeightedTargets() []appmeshv1beta1.WeightedTarget {
 	var inputTargets []*appmesh.WeightedTarget
 
 	if r.Data.Spec.HttpRoute != nil {
-		inputTargets = r.Data.Spec.HttpRoute.Action.WeightedTargets
+		if r.Data.Spec.HttpRoute.Action != nil {
+			inputTargets = r.Data.Spec.HttpRoute.Action.WeightedTargets
+		}
 	} else if r.Data.Spec.TcpRoute != nil {
-		inputTargets = r.Data.Spec.TcpRoute.Action.WeightedTargets
+		if r.Data.Spec.TcpRoute.Action != nil {
+			inputTargets = r.Data.Spec.TcpRoute.Action.WeightedTargets
+		}
 	} else if r.Data.Spec.Http2Route != nil {
-		inputTargets = r.Data.Spec.Http2Route.Action.WeightedTargets
+		if r.Data.Spec.Http2Route.Action != nil {
+			inputTargets = r.Data.Spec.Http2Route.Action.WeightedTargets
+		}
 	} else if r.Data.Spec.GrpcRoute != nil {
-		inputTargets = r.Data.Spec.GrpcRoute.Action.WeightedTargets
+		if r.Data.Spec.GrpcRoute.Action != nil {
+			inputTargets = r.Data.Spec.GrpcRoute.Action.WeightedTargets
+		}
 	}
 
 	for _, t := range inputTargets {
@@ -1162,12 +1055,14 @@ func (c *Cloud) GetRoute(ctx context.Context, name string, routerName string, me
 	}
 }
 
-// CreateRoute converts the desired virtual service spec into CreateVirtualServiceInput and calls create route.
-func (c *Cloud) CreateRoute(ctx context.Context, route *appmeshv1beta1.Route, routerName string, meshName string) (*Route, error) {
+// CreateRoute converts the desired virtual service spec into CreateVirtualServiceInput and calls create route
+// with the given tags.
+func (c *Cloud) CreateRoute(ctx context.Context, route *appmeshv1beta1.Route, routerName string, meshName string, tags map[string]string) (*Route, error) {
 	begin := time.Now()
 	defer func() {
 		c.stats.SetRequestDuration("virtual_route", route.Name, "create", time.Since(begin))
 	}()
+	defer c.describeCache.invalidate(describeCacheKey("routes", meshName, routerName))
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*CreateRouteTimeout)
 	defer cancel()
@@ -1176,7 +1071,8 @@ func (c *Cloud) CreateRoute(ctx context.Context, route *appmeshv1beta1.Route, ro
 		MeshName:          aws.String(meshName),
 		RouteName:         aws.String(route.Name),
 		VirtualRouterName: aws.String(routerName),
-		Spec:              c.buildRouteSpec(route),
+		Spec:              BuildRouteSpec(route),
+		Tags:              buildTagRefs(tags),
 	}
 
 	if output, err := c.appmesh.CreateRouteWithContext(ctx, input); err != nil {
@@ -1190,7 +1086,19 @@ func (c *Cloud) CreateRoute(ctx context.Context, route *appmeshv1beta1.Route, ro
 	}
 }
 
+// GetRoutesForVirtualRouter lists and describes the routes of a virtual router, coalescing concurrent calls
+// and caching the result briefly.
 func (c *Cloud) GetRoutesForVirtualRouter(ctx context.Context, routerName string, meshName string) (Routes, error) {
+	value, err := c.describeCache.get("routes", describeCacheKey("routes", meshName, routerName), func() (interface{}, error) {
+		return c.describeRoutesForVirtualRouter(ctx, routerName, meshName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(Routes{}, value.(Routes)...), nil
+}
+
+func (c *Cloud) describeRoutesForVirtualRouter(ctx context.Context, routerName string, meshName string) (Routes, error) {
 	begin := time.Now()
 	defer func() {
 		c.stats.SetRequestDuration("virtual_router", routerName, "get", time.Since(begin))
@@ -1233,6 +1141,7 @@ func (c *Cloud) UpdateRoute(ctx context.Context, route *appmeshv1beta1.Route, ro
 	defer func() {
 		c.stats.SetRequestDuration("virtual_route", route.Name, "update", time.Since(begin))
 	}()
+	defer c.describeCache.invalidate(describeCacheKey("routes", meshName, routerName))
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*UpdateRouteTimeout)
 	defer cancel()
@@ -1241,7 +1150,7 @@ func (c *Cloud) UpdateRoute(ctx context.Context, route *appmeshv1beta1.Route, ro
 		MeshName:          aws.String(meshName),
 		RouteName:         aws.String(route.Name),
 		VirtualRouterName: aws.String(routerName),
-		Spec:              c.buildRouteSpec(route),
+		Spec:              BuildRouteSpec(route),
 	}
 
 	if output, err := c.appmesh.UpdateRouteWithContext(ctx, input); err != nil {
@@ -1260,6 +1169,7 @@ func (c *Cloud) DeleteRoute(ctx context.Context, name string, routerName string,
 	defer func() {
 		c.stats.SetRequestDuration("virtual_route", name, "delete", time.Since(begin))
 	}()
+	defer c.describeCache.invalidate(describeCacheKey("routes", meshName, routerName))
 
 	ctx, cancel := context.WithTimeout(ctx, time.Second*DeleteRouteTimeout)
 	defer cancel()
@@ -1281,7 +1191,93 @@ func (c *Cloud) DeleteRoute(ctx context.Context, name string, routerName string,
 	}
 }
 
-func (c *Cloud) buildAwsCloudMapServiceDiscovery(vnode *appmeshv1beta1.VirtualNode) *appmesh.ServiceDiscovery {
+// ListTagsForResource returns the tags of the App Mesh resource with the given ARN.
+func (c *Cloud) ListTagsForResource(ctx context.Context, arn string) (map[string]string, error) {
+	begin := time.Now()
+	defer func() {
+		c.stats.SetRequestDuration("tags", arn, "list", time.Since(begin))
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*ListTagsForResourceTimeout)
+	defer cancel()
+
+	input := &appmesh.ListTagsForResourceInput{
+		ResourceArn: aws.String(arn),
+	}
+
+	tags := map[string]string{}
+	err := c.appmesh.ListTagsForResourcePagesWithContext(ctx, input, func(page *appmesh.ListTagsForResourceOutput, lastPage bool) bool {
+		for _, tag := range page.Tags {
+			tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// TagResource adds the tags to the App Mesh resource with the given ARN, overwriting existing tags with the
+// same keys.
+func (c *Cloud) TagResource(ctx context.Context, arn string, tags map[string]string) error {
+	begin := time.Now()
+	defer func() {
+		c.stats.SetRequestDuration("tags", arn, "tag", time.Since(begin))
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*TagResourceTimeout)
+	defer cancel()
+
+	input := &appmesh.TagResourceInput{
+		ResourceArn: aws.String(arn),
+		Tags:        buildTagRefs(tags),
+	}
+
+	_, err := c.appmesh.TagResourceWithContext(ctx, input)
+	return err
+}
+
+// UntagResource removes the tags with the given keys from the App Mesh resource with the given ARN.
+func (c *Cloud) UntagResource(ctx context.Context, arn string, keys []string) error {
+	begin := time.Now()
+	defer func() {
+		c.stats.SetRequestDuration("tags", arn, "untag", time.Since(begin))
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*UntagResourceTimeout)
+	defer cancel()
+
+	input := &appmesh.UntagResourceInput{
+		ResourceArn: aws.String(arn),
+		TagKeys:     aws.StringSlice(keys),
+	}
+
+	_, err := c.appmesh.UntagResourceWithContext(ctx, input)
+	return err
+}
+
+// buildTagRefs converts tags into App Mesh tag references, sorted by key.
+func buildTagRefs(tags map[string]string) []*appmesh.TagRef {
+	if len(tags) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	refs := make([]*appmesh.TagRef, 0, len(keys))
+	for _, key := range keys {
+		refs = append(refs, &appmesh.TagRef{
+			Key:   aws.String(key),
+			Value: aws.String(tags[key]),
+		})
+	}
+	return refs
+}
+
+func buildAwsCloudMapServiceDiscovery(vnode *appmeshv1beta1.VirtualNode) *appmesh.ServiceDiscovery {
 	attr := []*appmesh.AwsCloudMapInstanceAttribute{}
 
 	//adding attributes defined by customer
@@ -1323,7 +1319,8 @@ func IsAWSErrResourceInUse(err error) bool {
 	return false
 }
 
-func (c *Cloud) buildRouteSpec(route *appmeshv1beta1.Route) *appmesh.RouteSpec {
+// BuildRouteSpec converts the route into the App Mesh route spec.
+func BuildRouteSpec(route *appmeshv1beta1.Route) *appmesh.RouteSpec {
 	if route == nil {
 		return nil
 	}
@@ -1332,11 +1329,11 @@ func (c *Cloud) buildRouteSpec(route *appmeshv1beta1.Route) *appmesh.RouteSpec {
 		return &appmesh.RouteSpec{
 			Priority: route.Priority,
 			HttpRoute: &appmesh.HttpRoute{
-				Match: c.buildHttpRouteMatch(route.Http.Match),
+				Match: buildHttpRouteMatch(route.Http.Match),
 				Action: &appmesh.HttpRouteAction{
-					WeightedTargets: c.buildWeightedTargets(route.Http.Action.WeightedTargets),
+					WeightedTargets: buildWeightedTargets(route.Http.Action.WeightedTargets),
 				},
-				RetryPolicy: c.buildHttpRetryPolicy(route.Http.RetryPolicy),
+				RetryPolicy: buildHttpRetryPolicy(route.Http.RetryPolicy),
 			},
 		}
 	}
@@ -1346,7 +1343,7 @@ func (c *Cloud) buildRouteSpec(route *appmeshv1beta1.Route) *appmesh.RouteSpec {
 			Priority: route.Priority,
 			TcpRoute: &appmesh.TcpRoute{
 				Action: &appmesh.TcpRouteAction{
-					WeightedTargets: c.buildWeightedTargets(route.Tcp.Action.WeightedTargets),
+					WeightedTargets: buildWeightedTargets(route.Tcp.Action.WeightedTargets),
 				},
 			},
 		}
@@ -1356,11 +1353,11 @@ func (c *Cloud) buildRouteSpec(route *appmeshv1beta1.Route) *appmesh.RouteSpec {
 		return &appmesh.RouteSpec{
 			Priority: route.Priority,
 			Http2Route: &appmesh.HttpRoute{
-				Match: c.buildHttpRouteMatch(route.Http2.Match),
+				Match: buildHttpRouteMatch(route.Http2.Match),
 				Action: &appmesh.HttpRouteAction{
-					WeightedTargets: c.buildWeightedTargets(route.Http2.Action.WeightedTargets),
+					WeightedTargets: buildWeightedTargets(route.Http2.Action.WeightedTargets),
 				},
-				RetryPolicy: c.buildHttpRetryPolicy(route.Http2.RetryPolicy),
+				RetryPolicy: buildHttpRetryPolicy(route.Http2.RetryPolicy),
 			},
 		}
 	}
@@ -1369,11 +1366,11 @@ func (c *Cloud) buildRouteSpec(route *appmeshv1beta1.Route) *appmesh.RouteSpec {
 		return &appmesh.RouteSpec{
 			Priority: route.Priority,
 			GrpcRoute: &appmesh.GrpcRoute{
-				Match: c.buildGrpcRouteMatch(route.Grpc.Match),
+				Match: buildGrpcRouteMatch(route.Grpc.Match),
 				Action: &appmesh.GrpcRouteAction{
-					WeightedTargets: c.buildWeightedTargets(route.Grpc.Action.WeightedTargets),
+					WeightedTargets: buildWeightedTargets(route.Grpc.Action.WeightedTargets),
 				},
-				RetryPolicy: c.buildGrpcRetryPolicy(route.Grpc.RetryPolicy),
+				RetryPolicy: buildGrpcRetryPolicy(route.Grpc.RetryPolicy),
 			},
 		}
 	}
@@ -1381,7 +1378,7 @@ func (c *Cloud) buildRouteSpec(route *appmeshv1beta1.Route) *appmesh.RouteSpec {
 	return nil
 }
 
-func (c *Cloud) buildWeightedTargets(input []appmeshv1beta1.WeightedTarget) []*appmesh.WeightedTarget {
+func buildWeightedTargets(input []appmeshv1beta1.WeightedTarget) []*appmesh.WeightedTarget {
 	targets := []*appmesh.WeightedTarget{}
 	for _, target := range input {
 		weight := target.Weight
@@ -1393,7 +1390,7 @@ func (c *Cloud) buildWeightedTargets(input []appmeshv1beta1.WeightedTarget) []*a
 	return targets
 }
 
-func (c *Cloud) buildHttpRouteMatch(input appmeshv1beta1.HttpRouteMatch) *appmesh.HttpRouteMatch {
+func buildHttpRouteMatch(input appmeshv1beta1.HttpRouteMatch) *appmesh.HttpRouteMatch {
 	appmeshRouteMatch := &appmesh.HttpRouteMatch{
 		Prefix: aws.String(input.Prefix),
 		Method: input.Method,
@@ -1403,14 +1400,14 @@ func (c *Cloud) buildHttpRouteMatch(input appmeshv1beta1.HttpRouteMatch) *appmes
 	if len(input.Headers) > 0 {
 		appmeshRouteMatch.Headers = []*appmesh.HttpRouteHeader{}
 		for _, h := range input.Headers {
-			appmeshRouteMatch.Headers = append(appmeshRouteMatch.Headers, c.buildHttpRouteHeader(h))
+			appmeshRouteMatch.Headers = append(appmeshRouteMatch.Headers, buildHttpRouteHeader(h))
 		}
 	}
 
 	return appmeshRouteMatch
 }
 
-func (c *Cloud) buildHttpRouteHeader(input appmeshv1beta1.HttpRouteHeader) *appmesh.HttpRouteHeader {
+func buildHttpRouteHeader(input appmeshv1beta1.HttpRouteHeader) *appmesh.HttpRouteHeader {
 	appmeshHeader := &appmesh.HttpRouteHeader{
 		Name:   aws.String(input.Name),
 		Invert: input.Invert,
@@ -1434,7 +1431,7 @@ func (c *Cloud) buildHttpRouteHeader(input appmeshv1beta1.HttpRouteHeader) *appm
 	return appmeshHeader
 }
 
-func (c *Cloud) buildHttpRetryPolicy(input *appmeshv1beta1.HttpRetryPolicy) *appmesh.HttpRetryPolicy {
+func buildHttpRetryPolicy(input *appmeshv1beta1.HttpRetryPolicy) *appmesh.HttpRetryPolicy {
 	if input == nil {
 		return nil
 	}
@@ -1461,7 +1458,7 @@ func (c *Cloud) buildHttpRetryPolicy(input *appmeshv1beta1.HttpRetryPolicy) *app
 	return appmeshRetryPolicy
 }
 
-func (c *Cloud) buildGrpcRetryPolicy(input *appmeshv1beta1.GrpcRetryPolicy) *appmesh.GrpcRetryPolicy {
+func buildGrpcRetryPolicy(input *appmeshv1beta1.GrpcRetryPolicy) *appmesh.GrpcRetryPolicy {
 	if input == nil {
 		return nil
 	}
@@ -1492,7 +1489,7 @@ func (c *Cloud) buildGrpcRetryPolicy(input *appmeshv1beta1.GrpcRetryPolicy) *app
 	return appmeshRetryPolicy
 }
 
-func (c *Cloud) buildGrpcRouteMatch(input appmeshv1beta1.GrpcRouteMatch) *appmesh.GrpcRouteMatch {
+func buildGrpcRouteMatch(input appmeshv1beta1.GrpcRouteMatch) *appmesh.GrpcRouteMatch {
 	appmeshRouteMatch := &appmesh.GrpcRouteMatch{
 		ServiceName: input.ServiceName,
 		MethodName:  input.MethodName,
@@ -1501,14 +1498,14 @@ func (c *Cloud) buildGrpcRouteMatch(input appmeshv1beta1.GrpcRouteMatch) *appmes
 	if len(input.Metadata) > 0 {
 		appmeshRouteMatch.Metadata = []*appmesh.GrpcRouteMetadata{}
 		for _, m := range input.Metadata {
-			appmeshRouteMatch.Metadata = append(appmeshRouteMatch.Metadata, c.buildGrpcRouteMetadata(m))
+			appmeshRouteMatch.Metadata = append(appmeshRouteMatch.Metadata, buildGrpcRouteMetadata(m))
 		}
 	}
 
 	return appmeshRouteMatch
 }
 
-func (c *Cloud) buildGrpcRouteMetadata(input appmeshv1beta1.GrpcRouteMetadata) *appmesh.GrpcRouteMetadata {
+func buildGrpcRouteMetadata(input appmeshv1beta1.GrpcRouteMetadata) *appmesh.GrpcRouteMetadata {
 	appmeshMetadata := &appmesh.GrpcRouteMetadata{
 		Name:   aws.String(input.Name),
 		Invert: input.Invert,
@@ -1569,9 +1566,25 @@ func convertCrdClientPolicyToSdk(crdClientPolicy *appmeshv1beta1.ClientPolicy) *
 				CertificateChain: aws.String(crdTrust.File.CertificateChain),
 			})
 		}
-		sdkClientPolicyTls.SetValidation(&appmesh.TlsValidationContext{
+		sdkValidation := appmesh.TlsValidationContext{
 			Trust: &sdkTrust,
-		})
+		}
+		if crdSans := crdClientPolicy.TLS.Validation.SubjectAlternativeNames; crdSans != nil {
+			sdkValidation.SetSubjectAlternativeNames(&appmesh.SubjectAlternativeNames{
+				Match: &appmesh.SubjectAlternativeNameMatchers{
+					Exact: aws.StringSlice(crdSans.Match.Exact),
+				},
+			})
+		}
+		sdkClientPolicyTls.SetValidation(&sdkValidation)
+		if crdCertificate := crdClientPolicy.TLS.Certificate; crdCertificate != nil && crdCertificate.File != nil {
+			sdkClientPolicyTls.SetCertificate(&appmesh.ClientTlsCertificate{
+				File: &appmesh.ListenerTlsFileCertificate{
+					CertificateChain: aws.String(crdCertificate.File.CertificateChain),
+					PrivateKey:       aws.String(crdCertificate.File.PrivateKey),
+				},
+			})
+		}
 		sdkClientPolicy.SetTls(&sdkClientPolicyTls)
 	}
 	return &sdkClientPolicy
@@ -1604,8 +1617,23 @@ func convertSdkClientPolicyToCrd(sdkClientPolicy *appmesh.ClientPolicy) *appmesh
 				}
 				crdTlsValidation.Trust = crdTrust
 			}
+			if sdkSans := sdkClientPolicy.Tls.Validation.SubjectAlternativeNames; sdkSans != nil && sdkSans.Match != nil {
+				crdTlsValidation.SubjectAlternativeNames = &appmeshv1beta1.SubjectAlternativeNames{
+					Match: appmeshv1beta1.SubjectAlternativeNameMatchers{
+						Exact: aws.StringValueSlice(sdkSans.Match.Exact),
+					},
+				}
+			}
 			crdTls.Validation = crdTlsValidation
 		}
+		if sdkCertificate := sdkClientPolicy.Tls.Certificate; sdkCertificate != nil && sdkCertificate.File != nil {
+			crdTls.Certificate = &appmeshv1beta1.ClientTlsCertificate{
+				File: &appmeshv1beta1.ListenerTlsFileCertificate{
+					CertificateChain: aws.StringValue(sdkCertificate.File.CertificateChain),
+					PrivateKey:       aws.StringValue(sdkCertificate.File.PrivateKey),
+				},
+			}
+		}
 		crdClientPolicy.TLS = &crdTls
 	}
 	return &crdClientPolicy
@@ -30,6 +30,7 @@ type Cloud struct {
 
 	namespaceIDCache cache.Store
 	serviceIDCache   cache.Store
+	describeCache    *describeCache
 
 	stats *metrics.Recorder
 }
@@ -57,7 +58,7 @@ type CloudMapNamespaceSummary struct {
 func NewCloud(opts CloudOptions, stats *metrics.Recorder) (CloudAPI, error) {
 	cfg := &aws.Config{Region: aws.String(opts.Region)}
 
-	session, err := newAWSSession(cfg, stats)
+	session, err := newAWSSession(cfg, stats, newThrottler(opts, stats))
 	if err != nil {
 		return nil, err
 	}
@@ -81,11 +82,12 @@ func NewCloud(opts CloudOptions, stats *metrics.Recorder) (CloudAPI, error) {
 		serviceIDCache: cache.NewTTLStore(func(obj interface{}) (string, error) {
 			return obj.(*cloudmapServiceCacheItem).key, nil
 		}, 60*time.Second),
-		stats: stats,
+		describeCache: newDescribeCache(describeCacheTTL, stats),
+		stats:         stats,
 	}, nil
 }
 
-func newAWSSession(cfg *aws.Config, stats *metrics.Recorder) (*session.Session, error) {
+func newAWSSession(cfg *aws.Config, stats *metrics.Recorder, throttler *throttler) (*session.Session, error) {
 	session, err := session.NewSession(cfg)
 	if err != nil {
 		stats.RecordAWSAPIRequestError("session", "NewSession", getAWSErrorCode(err))
@@ -102,6 +104,8 @@ func newAWSSession(cfg *aws.Config, stats *metrics.Recorder) (*session.Session,
 		}
 	})
 
+	throttler.register(&session.Handlers)
+
 	return session, nil
 }
 
@@ -1,5 +1,29 @@
 package aws
 
+import "time"
+
+const (
+	// DefaultReadQPS and DefaultReadBurst limit describe and list calls to each AWS service
+	DefaultReadQPS   = 10
+	DefaultReadBurst = 20
+	// DefaultWriteQPS and DefaultWriteBurst limit create, update, delete and (de)register calls to each AWS service
+	DefaultWriteQPS   = 5
+	DefaultWriteBurst = 10
+	// DefaultMaxThrottleBackoff bounds how long AWS calls are paused after the API throttles the controller
+	DefaultMaxThrottleBackoff = 30 * time.Second
+)
+
 type CloudOptions struct {
 	Region string
+
+	// ReadQPS and ReadBurst configure the token bucket shared by the read operations of each AWS service.
+	// A QPS of zero or less disables the limit.
+	ReadQPS   float64
+	ReadBurst int
+	// WriteQPS and WriteBurst configure the token bucket shared by the write operations of each AWS service.
+	// A QPS of zero or less disables the limit.
+	WriteQPS   float64
+	WriteBurst int
+	// MaxThrottleBackoff bounds the adaptive pause applied to all AWS calls after a throttling error.
+	MaxThrottleBackoff time.Duration
 }
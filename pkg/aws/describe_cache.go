@@ -0,0 +1,105 @@
+package aws
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/metrics"
+)
+
+const (
+	// describeCacheTTL is short on purpose: the cache only absorbs the bursts of describes issued by the pod,
+	// virtual node and virtual service workers for the same object, it is not a source of truth.
+	describeCacheTTL = 5 * time.Second
+
+	describeCacheHit       = "hit"
+	describeCacheMiss      = "miss"
+	describeCacheCoalesced = "coalesced"
+)
+
+type describeCacheItem struct {
+	key   string
+	value interface{}
+}
+
+// describeCache coalesces concurrent describe calls for the same App Mesh object and keeps their results
+// for a short time. Mutating calls invalidate the entries they affect.
+type describeCache struct {
+	store cache.Store
+	group singleflight.Group
+	stats *metrics.Recorder
+
+	mu sync.Mutex
+	// generation is bumped by every invalidation, so that a describe racing a mutation does not store a
+	// result that is already stale.
+	generation uint64
+}
+
+func newDescribeCache(ttl time.Duration, stats *metrics.Recorder) *describeCache {
+	return &describeCache{
+		store: cache.NewTTLStore(func(obj interface{}) (string, error) {
+			return obj.(*describeCacheItem).key, nil
+		}, ttl),
+		stats: stats,
+	}
+}
+
+func describeCacheKey(kind string, meshName string, name string) string {
+	return kind + "/" + meshName + "/" + name
+}
+
+// get returns the cached value for key, or calls describe once for all concurrent callers and caches its
+// result. Errors are never cached.
+func (d *describeCache) get(kind string, key string, describe func() (interface{}, error)) (interface{}, error) {
+	if item, exists, _ := d.store.GetByKey(key); exists {
+		d.stats.RecordDescribeCacheLookup(kind, describeCacheHit)
+		return item.(*describeCacheItem).value, nil
+	}
+
+	d.mu.Lock()
+	generation := d.generation
+	d.mu.Unlock()
+
+	value, err, shared := d.group.Do(key, func() (interface{}, error) {
+		value, err := describe()
+		if err != nil {
+			return nil, err
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.generation == generation {
+			_ = d.store.Add(&describeCacheItem{key: key, value: value})
+		}
+		return value, nil
+	})
+
+	if shared {
+		d.stats.RecordDescribeCacheLookup(kind, describeCacheCoalesced)
+	} else {
+		d.stats.RecordDescribeCacheLookup(kind, describeCacheMiss)
+	}
+	return value, err
+}
+
+// invalidate drops the cached value for key. Describes already in flight are not joined by later callers.
+func (d *describeCache) invalidate(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.generation++
+	d.group.Forget(key)
+	_ = d.store.Delete(&describeCacheItem{key: key})
+}
+
+// invalidateAll drops every cached value, e.g. when a mesh and everything in it is deleted.
+func (d *describeCache) invalidateAll() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.generation++
+	for _, key := range d.store.ListKeys() {
+		d.group.Forget(key)
+	}
+	_ = d.store.Replace(nil, "")
+}
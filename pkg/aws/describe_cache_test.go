@@ -0,0 +1,92 @@
+package aws
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/metrics"
+)
+
+func TestDescribeCache(t *testing.T) {
+	stats := metrics.NewRecorder(false)
+	key := describeCacheKey("virtual_node", "mesh", "node")
+
+	t.Run("hit after miss", func(t *testing.T) {
+		d := newDescribeCache(time.Minute, stats)
+		calls := 0
+		describe := func() (interface{}, error) {
+			calls++
+			return calls, nil
+		}
+		d.get("virtual_node", key, describe)
+		if value, _ := d.get("virtual_node", key, describe); value != 1 || calls != 1 {
+			t.Errorf("expected cached value 1 after one call, got %v after %d calls", value, calls)
+		}
+	})
+
+	t.Run("invalidate forces describe", func(t *testing.T) {
+		d := newDescribeCache(time.Minute, stats)
+		calls := 0
+		describe := func() (interface{}, error) {
+			calls++
+			return calls, nil
+		}
+		d.get("virtual_node", key, describe)
+		d.invalidate(key)
+		if value, _ := d.get("virtual_node", key, describe); value != 2 {
+			t.Errorf("expected fresh value 2, got %v", value)
+		}
+		d.invalidateAll()
+		if value, _ := d.get("virtual_node", key, describe); value != 3 {
+			t.Errorf("expected fresh value 3, got %v", value)
+		}
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		d := newDescribeCache(time.Minute, stats)
+		if _, err := d.get("virtual_node", key, func() (interface{}, error) { return nil, errors.New("boom") }); err == nil {
+			t.Errorf("expected error")
+		}
+		if value, err := d.get("virtual_node", key, func() (interface{}, error) { return 1, nil }); err != nil || value != 1 {
+			t.Errorf("expected describe after error, got %v, %v", value, err)
+		}
+	})
+
+	t.Run("describe racing invalidation is not stored", func(t *testing.T) {
+		d := newDescribeCache(time.Minute, stats)
+		d.get("virtual_node", key, func() (interface{}, error) {
+			d.invalidate(key)
+			return "stale", nil
+		})
+		if value, _ := d.get("virtual_node", key, func() (interface{}, error) { return "fresh", nil }); value != "fresh" {
+			t.Errorf("expected fresh value, got %v", value)
+		}
+	})
+
+	t.Run("concurrent describes are coalesced", func(t *testing.T) {
+		d := newDescribeCache(time.Minute, stats)
+		var calls int32
+		release := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.get("virtual_node", key, func() (interface{}, error) {
+					atomic.AddInt32(&calls, 1)
+					<-release
+					return 1, nil
+				})
+			}()
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		if calls != 1 {
+			t.Errorf("expected a single describe, got %d", calls)
+		}
+	})
+}
@@ -0,0 +1,536 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	"github.com/aws/aws-sdk-go/service/servicediscovery"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	PlannedCreate = "create"
+	PlannedUpdate = "update"
+	PlannedDelete = "delete"
+
+	ServiceAppMesh  = "appmesh"
+	ServiceCloudMap = "cloudmap"
+
+	// plannedID stands in for identifiers AWS would assign to resources that are only planned
+	plannedID = "(known after apply)"
+)
+
+// PlannedChange is a create, update or delete that a DryRunCloud skipped.
+type PlannedChange struct {
+	// Service is either appmesh or cloudmap
+	Service string
+	// Kind is the kind of resource, e.g. mesh, virtual_node, route or instance
+	Kind string
+	// Parent identifies what the resource belongs to: the mesh, mesh/router for routes, or
+	// namespace/service for Cloud Map instances. It is empty for meshes and Cloud Map services.
+	Parent string
+	Name   string
+	Action string
+}
+
+// DryRunCloud passes reads through to another CloudAPI and records mutations as planned changes instead of
+// making them. Reads return planned resources as if the changes had been made, so reconciling twice converges.
+type DryRunCloud struct {
+	cloud CloudAPI
+
+	mu      sync.Mutex
+	changes []PlannedChange
+	planned map[string]interface{}
+	deleted map[string]bool
+	// tags are the planned tags of resources by ARN, and untagged the keys planned to be removed
+	tags     map[string]map[string]string
+	untagged map[string]map[string]bool
+}
+
+// NewDryRunCloud wraps cloud so that nothing is ever created, updated or deleted.
+func NewDryRunCloud(cloud CloudAPI) *DryRunCloud {
+	return &DryRunCloud{
+		cloud:    cloud,
+		planned:  map[string]interface{}{},
+		deleted:  map[string]bool{},
+		tags:     map[string]map[string]string{},
+		untagged: map[string]map[string]bool{},
+	}
+}
+
+// Changes returns the planned changes in the order they were first planned.
+func (d *DryRunCloud) Changes() []PlannedChange {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]PlannedChange(nil), d.changes...)
+}
+
+func plannedKey(kind string, parent string, name string) string {
+	return kind + "/" + parent + "/" + name
+}
+
+// plan records a change and the state of the resource after it. A resource is only listed once, with the
+// first action planned for it, so that a planned create stays a create when it is reconciled again.
+func (d *DryRunCloud) plan(service string, kind string, parent string, name string, action string, state interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := plannedKey(kind, parent, name)
+	if action == PlannedDelete {
+		delete(d.planned, key)
+		d.deleted[key] = true
+	} else {
+		d.planned[key] = state
+		delete(d.deleted, key)
+	}
+	for _, change := range d.changes {
+		if change.Kind == kind && change.Parent == parent && change.Name == name {
+			return
+		}
+	}
+	d.changes = append(d.changes, PlannedChange{
+		Service: service,
+		Kind:    kind,
+		Parent:  parent,
+		Name:    name,
+		Action:  action,
+	})
+}
+
+// lookup returns the planned state of a resource, and whether the resource is planned to be deleted.
+func (d *DryRunCloud) lookup(kind string, parent string, name string) (interface{}, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := plannedKey(kind, parent, name)
+	return d.planned[key], d.deleted[key]
+}
+
+// plannedMetadata stands in for the metadata of resources that are only planned. Their ARNs are made up, but
+// unique, so that their planned tags can be told apart.
+func plannedMetadata(kind string, parent string, name string) *appmesh.ResourceMetadata {
+	return &appmesh.ResourceMetadata{
+		Arn: aws.String(plannedArn(kind, parent, name)),
+		Uid: aws.String(plannedID),
+	}
+}
+
+func plannedArn(kind string, parent string, name string) string {
+	return plannedID + " " + plannedKey(kind, parent, name)
+}
+
+// planTags records the tags of a planned resource. Tagging a resource that exists is a planned change of its
+// own.
+func (d *DryRunCloud) planTags(arn string, tags map[string]string) {
+	d.mu.Lock()
+	merged := map[string]string{}
+	for key, value := range d.tags[arn] {
+		merged[key] = value
+	}
+	for key, value := range tags {
+		merged[key] = value
+		delete(d.untagged[arn], key)
+	}
+	d.tags[arn] = merged
+	d.mu.Unlock()
+	if !strings.HasPrefix(arn, plannedID) {
+		d.plan(ServiceAppMesh, "tags", "", arn, PlannedUpdate, nil)
+	}
+}
+
+func (d *DryRunCloud) planUntag(arn string, keys []string) {
+	d.mu.Lock()
+	if d.untagged[arn] == nil {
+		d.untagged[arn] = map[string]bool{}
+	}
+	for _, key := range keys {
+		delete(d.tags[arn], key)
+		d.untagged[arn][key] = true
+	}
+	d.mu.Unlock()
+	if !strings.HasPrefix(arn, plannedID) {
+		d.plan(ServiceAppMesh, "tags", "", arn, PlannedUpdate, nil)
+	}
+}
+
+func plannedNotFound(kind string, name string) error {
+	return awserr.New(appmesh.ErrCodeNotFoundException, fmt.Sprintf("%s %s is planned to be deleted", kind, name), nil)
+}
+
+// GetMesh returns the planned mesh, or describes it.
+func (d *DryRunCloud) GetMesh(ctx context.Context, name string) (*Mesh, error) {
+	if state, deleted := d.lookup("mesh", "", name); deleted {
+		return nil, plannedNotFound("mesh", name)
+	} else if state != nil {
+		return state.(*Mesh), nil
+	}
+	return d.cloud.GetMesh(ctx, name)
+}
+
+// CreateMesh plans to create the mesh.
+func (d *DryRunCloud) CreateMesh(ctx context.Context, mesh *appmeshv1beta1.Mesh) (*Mesh, error) {
+	return d.planMesh(mesh, PlannedCreate), nil
+}
+
+// UpdateMesh plans to update the mesh.
+func (d *DryRunCloud) UpdateMesh(ctx context.Context, mesh *appmeshv1beta1.Mesh) (*Mesh, error) {
+	return d.planMesh(mesh, PlannedUpdate), nil
+}
+
+func (d *DryRunCloud) planMesh(mesh *appmeshv1beta1.Mesh, action string) *Mesh {
+	target := &Mesh{
+		Data: appmesh.MeshData{
+			MeshName: aws.String(mesh.Name),
+			Spec:     BuildMeshSpec(mesh),
+			Metadata: plannedMetadata("mesh", "", mesh.Name),
+			Status:   &appmesh.MeshStatus{Status: aws.String(appmesh.MeshStatusCodeActive)},
+		},
+	}
+	d.plan(ServiceAppMesh, "mesh", "", mesh.Name, action, target)
+	return target
+}
+
+// DeleteMesh plans to delete the mesh.
+func (d *DryRunCloud) DeleteMesh(ctx context.Context, name string) (*Mesh, error) {
+	target, err := d.GetMesh(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	d.plan(ServiceAppMesh, "mesh", "", name, PlannedDelete, nil)
+	return target, nil
+}
+
+// GetVirtualNode returns the planned virtual node, or describes it.
+func (d *DryRunCloud) GetVirtualNode(ctx context.Context, name string, meshName string) (*VirtualNode, error) {
+	if state, deleted := d.lookup("virtual_node", meshName, name); deleted {
+		return nil, plannedNotFound("virtual node", name)
+	} else if state != nil {
+		return state.(*VirtualNode), nil
+	}
+	return d.cloud.GetVirtualNode(ctx, name, meshName)
+}
+
+// CreateVirtualNode plans to create the virtual node.
+func (d *DryRunCloud) CreateVirtualNode(ctx context.Context, vnode *appmeshv1beta1.VirtualNode, tags map[string]string) (*VirtualNode, error) {
+	target := d.planVirtualNode(vnode, PlannedCreate)
+	d.planTags(aws.StringValue(target.Data.Metadata.Arn), tags)
+	return target, nil
+}
+
+// UpdateVirtualNode plans to update the virtual node.
+func (d *DryRunCloud) UpdateVirtualNode(ctx context.Context, vnode *appmeshv1beta1.VirtualNode) (*VirtualNode, error) {
+	return d.planVirtualNode(vnode, PlannedUpdate), nil
+}
+
+func (d *DryRunCloud) planVirtualNode(vnode *appmeshv1beta1.VirtualNode, action string) *VirtualNode {
+	target := &VirtualNode{
+		Data: appmesh.VirtualNodeData{
+			MeshName:        aws.String(vnode.Spec.MeshName),
+			VirtualNodeName: aws.String(vnode.Name),
+			Spec:            BuildVirtualNodeSpec(vnode),
+			Metadata:        plannedMetadata("virtual_node", vnode.Spec.MeshName, vnode.Name),
+			Status:          &appmesh.VirtualNodeStatus{Status: aws.String(appmesh.VirtualNodeStatusCodeActive)},
+		},
+	}
+	d.plan(ServiceAppMesh, "virtual_node", vnode.Spec.MeshName, vnode.Name, action, target)
+	return target
+}
+
+// DeleteVirtualNode plans to delete the virtual node.
+func (d *DryRunCloud) DeleteVirtualNode(ctx context.Context, name string, meshName string) (*VirtualNode, error) {
+	target, err := d.GetVirtualNode(ctx, name, meshName)
+	if err != nil {
+		return nil, err
+	}
+	d.plan(ServiceAppMesh, "virtual_node", meshName, name, PlannedDelete, nil)
+	return target, nil
+}
+
+// ListVirtualNodes lists the virtual nodes of the mesh.
+func (d *DryRunCloud) ListVirtualNodes(ctx context.Context, meshName string) ([]*VirtualNode, error) {
+	return d.cloud.ListVirtualNodes(ctx, meshName)
+}
+
+// GetVirtualService returns the planned virtual service, or describes it.
+func (d *DryRunCloud) GetVirtualService(ctx context.Context, name string, meshName string) (*VirtualService, error) {
+	if state, deleted := d.lookup("virtual_service", meshName, name); deleted {
+		return nil, plannedNotFound("virtual service", name)
+	} else if state != nil {
+		return state.(*VirtualService), nil
+	}
+	return d.cloud.GetVirtualService(ctx, name, meshName)
+}
+
+// CreateVirtualService plans to create the virtual service.
+func (d *DryRunCloud) CreateVirtualService(ctx context.Context, vservice *appmeshv1beta1.VirtualService, tags map[string]string) (*VirtualService, error) {
+	target := d.planVirtualService(vservice, PlannedCreate)
+	d.planTags(aws.StringValue(target.Data.Metadata.Arn), tags)
+	return target, nil
+}
+
+// UpdateVirtualService plans to update the virtual service.
+func (d *DryRunCloud) UpdateVirtualService(ctx context.Context, vservice *appmeshv1beta1.VirtualService) (*VirtualService, error) {
+	return d.planVirtualService(vservice, PlannedUpdate), nil
+}
+
+func (d *DryRunCloud) planVirtualService(vservice *appmeshv1beta1.VirtualService, action string) *VirtualService {
+	spec := &appmesh.VirtualServiceSpec{}
+	if vservice.Spec.VirtualRouter != nil {
+		spec.Provider = &appmesh.VirtualServiceProvider{
+			VirtualRouter: &appmesh.VirtualRouterServiceProvider{
+				VirtualRouterName: aws.String(vservice.Spec.VirtualRouter.Name),
+			},
+		}
+	}
+	target := &VirtualService{
+		Data: appmesh.VirtualServiceData{
+			MeshName:           aws.String(vservice.Spec.MeshName),
+			VirtualServiceName: aws.String(vservice.Name),
+			Spec:               spec,
+			Metadata:           plannedMetadata("virtual_service", vservice.Spec.MeshName, vservice.Name),
+			Status:             &appmesh.VirtualServiceStatus{Status: aws.String(appmesh.VirtualServiceStatusCodeActive)},
+		},
+	}
+	d.plan(ServiceAppMesh, "virtual_service", vservice.Spec.MeshName, vservice.Name, action, target)
+	return target
+}
+
+// DeleteVirtualService plans to delete the virtual service.
+func (d *DryRunCloud) DeleteVirtualService(ctx context.Context, name string, meshName string) (*VirtualService, error) {
+	target, err := d.GetVirtualService(ctx, name, meshName)
+	if err != nil {
+		return nil, err
+	}
+	d.plan(ServiceAppMesh, "virtual_service", meshName, name, PlannedDelete, nil)
+	return target, nil
+}
+
+// ListVirtualServices lists the virtual services of the mesh.
+func (d *DryRunCloud) ListVirtualServices(ctx context.Context, meshName string) ([]*VirtualService, error) {
+	return d.cloud.ListVirtualServices(ctx, meshName)
+}
+
+// GetVirtualRouter returns the planned virtual router, or describes it.
+func (d *DryRunCloud) GetVirtualRouter(ctx context.Context, name string, meshName string) (*VirtualRouter, error) {
+	if state, deleted := d.lookup("virtual_router", meshName, name); deleted {
+		return nil, plannedNotFound("virtual router", name)
+	} else if state != nil {
+		return state.(*VirtualRouter), nil
+	}
+	return d.cloud.GetVirtualRouter(ctx, name, meshName)
+}
+
+// CreateVirtualRouter plans to create the virtual router.
+func (d *DryRunCloud) CreateVirtualRouter(ctx context.Context, vrouter *appmeshv1beta1.VirtualRouter, meshName string, tags map[string]string) (*VirtualRouter, error) {
+	target := d.planVirtualRouter(vrouter, meshName, PlannedCreate)
+	d.planTags(aws.StringValue(target.Data.Metadata.Arn), tags)
+	return target, nil
+}
+
+// UpdateVirtualRouter plans to update the virtual router.
+func (d *DryRunCloud) UpdateVirtualRouter(ctx context.Context, vrouter *appmeshv1beta1.VirtualRouter, meshName string) (*VirtualRouter, error) {
+	return d.planVirtualRouter(vrouter, meshName, PlannedUpdate), nil
+}
+
+func (d *DryRunCloud) planVirtualRouter(vrouter *appmeshv1beta1.VirtualRouter, meshName string, action string) *VirtualRouter {
+	target := &VirtualRouter{
+		Data: appmesh.VirtualRouterData{
+			MeshName:          aws.String(meshName),
+			VirtualRouterName: aws.String(vrouter.Name),
+			Spec:              BuildVirtualRouterSpec(vrouter),
+			Metadata:          plannedMetadata("virtual_router", meshName, vrouter.Name),
+			Status:            &appmesh.VirtualRouterStatus{Status: aws.String(appmesh.VirtualRouterStatusCodeActive)},
+		},
+	}
+	d.plan(ServiceAppMesh, "virtual_router", meshName, vrouter.Name, action, target)
+	return target
+}
+
+// DeleteVirtualRouter plans to delete the virtual router.
+func (d *DryRunCloud) DeleteVirtualRouter(ctx context.Context, name string, meshName string) (*VirtualRouter, error) {
+	target, err := d.GetVirtualRouter(ctx, name, meshName)
+	if err != nil {
+		return nil, err
+	}
+	d.plan(ServiceAppMesh, "virtual_router", meshName, name, PlannedDelete, nil)
+	return target, nil
+}
+
+// GetRoute returns the planned route, or describes it.
+func (d *DryRunCloud) GetRoute(ctx context.Context, name string, routerName string, meshName string) (*Route, error) {
+	if state, deleted := d.lookup("route", meshName+"/"+routerName, name); deleted {
+		return nil, plannedNotFound("route", name)
+	} else if state != nil {
+		return state.(*Route), nil
+	}
+	return d.cloud.GetRoute(ctx, name, routerName, meshName)
+}
+
+// CreateRoute plans to create the route.
+func (d *DryRunCloud) CreateRoute(ctx context.Context, route *appmeshv1beta1.Route, routerName string, meshName string, tags map[string]string) (*Route, error) {
+	target := d.planRoute(route, routerName, meshName, PlannedCreate)
+	d.planTags(aws.StringValue(target.Data.Metadata.Arn), tags)
+	return target, nil
+}
+
+// UpdateRoute plans to update the route.
+func (d *DryRunCloud) UpdateRoute(ctx context.Context, route *appmeshv1beta1.Route, routerName string, meshName string) (*Route, error) {
+	return d.planRoute(route, routerName, meshName, PlannedUpdate), nil
+}
+
+func (d *DryRunCloud) planRoute(route *appmeshv1beta1.Route, routerName string, meshName string, action string) *Route {
+	target := &Route{
+		Data: appmesh.RouteData{
+			MeshName:          aws.String(meshName),
+			VirtualRouterName: aws.String(routerName),
+			RouteName:         aws.String(route.Name),
+			Spec:              BuildRouteSpec(route),
+			Metadata:          plannedMetadata("route", meshName+"/"+routerName, route.Name),
+			Status:            &appmesh.RouteStatus{Status: aws.String(appmesh.RouteStatusCodeActive)},
+		},
+	}
+	d.plan(ServiceAppMesh, "route", meshName+"/"+routerName, route.Name, action, target)
+	return target
+}
+
+// GetRoutesForVirtualRouter describes the routes of the virtual router with the planned changes applied.
+func (d *DryRunCloud) GetRoutesForVirtualRouter(ctx context.Context, routerName string, meshName string) (Routes, error) {
+	existing, err := d.cloud.GetRoutesForVirtualRouter(ctx, routerName, meshName)
+	if err != nil {
+		// A planned virtual router has no routes yet
+		state, _ := d.lookup("virtual_router", meshName, routerName)
+		if state == nil || !IsAWSErrNotFound(err) {
+			return nil, err
+		}
+	}
+
+	parent := meshName + "/" + routerName
+	routes := Routes{}
+	seen := map[string]bool{}
+	for _, route := range existing {
+		state, deleted := d.lookup("route", parent, route.Name())
+		seen[route.Name()] = true
+		if deleted {
+			continue
+		}
+		if state != nil {
+			route = *state.(*Route)
+		}
+		routes = append(routes, route)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, change := range d.changes {
+		if change.Kind != "route" || change.Parent != parent || seen[change.Name] {
+			continue
+		}
+		if state, ok := d.planned[plannedKey("route", parent, change.Name)]; ok {
+			routes = append(routes, *state.(*Route))
+		}
+	}
+	return routes, nil
+}
+
+// DeleteRoute plans to delete the route.
+func (d *DryRunCloud) DeleteRoute(ctx context.Context, name string, routerName string, meshName string) (*Route, error) {
+	target, err := d.GetRoute(ctx, name, routerName, meshName)
+	if err != nil {
+		return nil, err
+	}
+	d.plan(ServiceAppMesh, "route", meshName+"/"+routerName, name, PlannedDelete, nil)
+	return target, nil
+}
+
+// ListTagsForResource returns the planned tags of the resource, or lists its tags.
+func (d *DryRunCloud) ListTagsForResource(ctx context.Context, arn string) (map[string]string, error) {
+	d.mu.Lock()
+	planned, untagged := d.tags[arn], d.untagged[arn]
+	d.mu.Unlock()
+	if strings.HasPrefix(arn, plannedID) {
+		return planned, nil
+	}
+	tags, err := d.cloud.ListTagsForResource(ctx, arn)
+	if err != nil {
+		return tags, err
+	}
+	for key := range untagged {
+		delete(tags, key)
+	}
+	for key, value := range planned {
+		tags[key] = value
+	}
+	return tags, nil
+}
+
+// TagResource plans to tag the resource.
+func (d *DryRunCloud) TagResource(ctx context.Context, arn string, tags map[string]string) error {
+	d.planTags(arn, tags)
+	return nil
+}
+
+// UntagResource plans to untag the resource.
+func (d *DryRunCloud) UntagResource(ctx context.Context, arn string, keys []string) error {
+	d.planUntag(arn, keys)
+	return nil
+}
+
+// CloudMapCreateService looks the service up, and plans to create it if it does not exist.
+func (d *DryRunCloud) CloudMapCreateService(ctx context.Context, cloudmapConfig *appmesh.AwsCloudMapServiceDiscovery, creatorRequestID string) (*CloudMapServiceSummary, error) {
+	name := cloudMapServiceName(cloudmapConfig)
+	if state, _ := d.lookup("service", "", name); state != nil {
+		return state.(*CloudMapServiceSummary), nil
+	}
+	// Only the Cloud Map client can look services up by name without creating them
+	if cloud, ok := d.cloud.(*Cloud); ok {
+		if summary, err := cloud.getService(ctx, cloudmapConfig); err == nil {
+			return summary, nil
+		}
+	}
+	summary := &CloudMapServiceSummary{
+		NamespaceID: plannedID,
+		ServiceID:   plannedID,
+	}
+	d.plan(ServiceCloudMap, "service", "", name, PlannedCreate, summary)
+	return summary, nil
+}
+
+// CloudMapGetService gets the service.
+func (d *DryRunCloud) CloudMapGetService(ctx context.Context, serviceID string) (*CloudMapServiceSummary, error) {
+	return d.cloud.CloudMapGetService(ctx, serviceID)
+}
+
+// RegisterInstance plans to register the pod unless it already is registered.
+func (d *DryRunCloud) RegisterInstance(ctx context.Context, instanceID string, pod *corev1.Pod, cloudmapConfig *appmesh.AwsCloudMapServiceDiscovery) error {
+	if pod.Status.Phase != corev1.PodRunning {
+		return nil
+	}
+	if instances, err := d.cloud.ListInstances(ctx, cloudmapConfig); err == nil {
+		for _, instance := range instances {
+			if aws.StringValue(instance.Id) == instanceID {
+				return nil
+			}
+		}
+	}
+	d.plan(ServiceCloudMap, "instance", cloudMapServiceName(cloudmapConfig), instanceID, PlannedCreate, instanceID)
+	return nil
+}
+
+// DeregisterInstance plans to deregister the instance.
+func (d *DryRunCloud) DeregisterInstance(ctx context.Context, instanceID string, cloudmapConfig *appmesh.AwsCloudMapServiceDiscovery) error {
+	d.plan(ServiceCloudMap, "instance", cloudMapServiceName(cloudmapConfig), instanceID, PlannedDelete, nil)
+	return nil
+}
+
+// ListInstances lists the instances of the service.
+func (d *DryRunCloud) ListInstances(ctx context.Context, cloudmapConfig *appmesh.AwsCloudMapServiceDiscovery) ([]*servicediscovery.InstanceSummary, error) {
+	return d.cloud.ListInstances(ctx, cloudmapConfig)
+}
+
+func cloudMapServiceName(cloudmapConfig *appmesh.AwsCloudMapServiceDiscovery) string {
+	return aws.StringValue(cloudmapConfig.NamespaceName) + "/" + aws.StringValue(cloudmapConfig.ServiceName)
+}
@@ -0,0 +1,95 @@
+package aws
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+)
+
+// stubCloud has an existing route "old" on router "router" and nothing else.
+type stubCloud struct {
+	CloudAPI
+}
+
+func (stubCloud) GetVirtualRouter(ctx context.Context, name string, meshName string) (*VirtualRouter, error) {
+	return nil, awserr.New(appmesh.ErrCodeNotFoundException, "not found", nil)
+}
+
+func (stubCloud) GetRoute(ctx context.Context, name string, routerName string, meshName string) (*Route, error) {
+	if routerName != "router" || name != "old" {
+		return nil, awserr.New(appmesh.ErrCodeNotFoundException, "not found", nil)
+	}
+	return &Route{Data: appmesh.RouteData{RouteName: aws.String("old")}}, nil
+}
+
+func (stubCloud) GetRoutesForVirtualRouter(ctx context.Context, routerName string, meshName string) (Routes, error) {
+	if routerName != "router" {
+		return nil, awserr.New(appmesh.ErrCodeNotFoundException, "not found", nil)
+	}
+	return Routes{{Data: appmesh.RouteData{RouteName: aws.String("old")}}}, nil
+}
+
+func (stubCloud) ListTagsForResource(ctx context.Context, arn string) (map[string]string, error) {
+	return map[string]string{"owner": "other", "team": "color"}, nil
+}
+
+func TestDryRunCloud(t *testing.T) {
+	ctx := context.Background()
+	d := NewDryRunCloud(stubCloud{})
+
+	vrouter := &appmeshv1beta1.VirtualRouter{Name: "new-router"}
+	d.CreateVirtualRouter(ctx, vrouter, "mesh", nil)
+	d.UpdateVirtualRouter(ctx, vrouter, "mesh")
+	if target, err := d.GetVirtualRouter(ctx, "new-router", "mesh"); err != nil || target.Status() != appmesh.VirtualRouterStatusCodeActive {
+		t.Errorf("expected planned router to be active, got %v, %v", target, err)
+	}
+	if routes, err := d.GetRoutesForVirtualRouter(ctx, "new-router", "mesh"); err != nil || len(routes) != 0 {
+		t.Errorf("expected no routes for planned router, got %v, %v", routes, err)
+	}
+
+	owner := map[string]string{"owner": "uid"}
+	created, _ := d.CreateRoute(ctx, &appmeshv1beta1.Route{Name: "new"}, "router", "mesh", owner)
+	d.DeleteRoute(ctx, "old", "router", "mesh")
+	routes, err := d.GetRoutesForVirtualRouter(ctx, "router", "mesh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := routes.RouteNamesSet(); names.Cardinality() != 1 || !names.Contains("new") {
+		t.Errorf("expected only the planned route, got %v", names)
+	}
+	if tags, err := d.ListTagsForResource(ctx, aws.StringValue(created.Data.Metadata.Arn)); err != nil || !reflect.DeepEqual(tags, owner) {
+		t.Errorf("expected the planned route to have tags %v, got %v, %v", owner, tags, err)
+	}
+
+	want := []PlannedChange{
+		{Service: ServiceAppMesh, Kind: "virtual_router", Parent: "mesh", Name: "new-router", Action: PlannedCreate},
+		{Service: ServiceAppMesh, Kind: "route", Parent: "mesh/router", Name: "new", Action: PlannedCreate},
+		{Service: ServiceAppMesh, Kind: "route", Parent: "mesh/router", Name: "old", Action: PlannedDelete},
+	}
+	if got := d.Changes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got changes %+v, want %+v", got, want)
+	}
+
+	// Tagging a planned resource is part of its creation, tagging an existing one is a change of its own
+	d.TagResource(ctx, aws.StringValue(created.Data.Metadata.Arn), owner)
+	d.TagResource(ctx, "arn:old", owner)
+	want = append(want, PlannedChange{Service: ServiceAppMesh, Kind: "tags", Name: "arn:old", Action: PlannedUpdate})
+	if got := d.Changes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got changes %+v, want %+v", got, want)
+	}
+
+	// Untagging hides the existing tags until they are planned again
+	d.UntagResource(ctx, "arn:old", []string{"owner"})
+	if tags, err := d.ListTagsForResource(ctx, "arn:old"); err != nil || !reflect.DeepEqual(tags, map[string]string{"team": "color"}) {
+		t.Errorf("expected the owner tag to be planned away, got %v, %v", tags, err)
+	}
+	d.TagResource(ctx, "arn:old", owner)
+	if tags, err := d.ListTagsForResource(ctx, "arn:old"); err != nil || !reflect.DeepEqual(tags, map[string]string{"owner": "uid", "team": "color"}) {
+		t.Errorf("expected the owner tag to be planned again, got %v, %v", tags, err)
+	}
+}
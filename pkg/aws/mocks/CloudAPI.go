@@ -84,13 +84,13 @@ func (_m *CloudAPI) CreateMesh(_a0 context.Context, _a1 *v1beta1.Mesh) (*aws.Mes
 	return r0, r1
 }
 
-// CreateRoute provides a mock function with given fields: _a0, _a1, _a2, _a3
-func (_m *CloudAPI) CreateRoute(_a0 context.Context, _a1 *v1beta1.Route, _a2 string, _a3 string) (*aws.Route, error) {
-	ret := _m.Called(_a0, _a1, _a2, _a3)
+// CreateRoute provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4
+func (_m *CloudAPI) CreateRoute(_a0 context.Context, _a1 *v1beta1.Route, _a2 string, _a3 string, _a4 map[string]string) (*aws.Route, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4)
 
 	var r0 *aws.Route
-	if rf, ok := ret.Get(0).(func(context.Context, *v1beta1.Route, string, string) *aws.Route); ok {
-		r0 = rf(_a0, _a1, _a2, _a3)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1beta1.Route, string, string, map[string]string) *aws.Route); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*aws.Route)
@@ -98,8 +98,8 @@ func (_m *CloudAPI) CreateRoute(_a0 context.Context, _a1 *v1beta1.Route, _a2 str
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, *v1beta1.Route, string, string) error); ok {
-		r1 = rf(_a0, _a1, _a2, _a3)
+	if rf, ok := ret.Get(1).(func(context.Context, *v1beta1.Route, string, string, map[string]string) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3, _a4)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -107,13 +107,13 @@ func (_m *CloudAPI) CreateRoute(_a0 context.Context, _a1 *v1beta1.Route, _a2 str
 	return r0, r1
 }
 
-// CreateVirtualNode provides a mock function with given fields: _a0, _a1
-func (_m *CloudAPI) CreateVirtualNode(_a0 context.Context, _a1 *v1beta1.VirtualNode) (*aws.VirtualNode, error) {
-	ret := _m.Called(_a0, _a1)
+// CreateVirtualNode provides a mock function with given fields: _a0, _a1, _a2
+func (_m *CloudAPI) CreateVirtualNode(_a0 context.Context, _a1 *v1beta1.VirtualNode, _a2 map[string]string) (*aws.VirtualNode, error) {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 *aws.VirtualNode
-	if rf, ok := ret.Get(0).(func(context.Context, *v1beta1.VirtualNode) *aws.VirtualNode); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1beta1.VirtualNode, map[string]string) *aws.VirtualNode); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*aws.VirtualNode)
@@ -121,8 +121,8 @@ func (_m *CloudAPI) CreateVirtualNode(_a0 context.Context, _a1 *v1beta1.VirtualN
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, *v1beta1.VirtualNode) error); ok {
-		r1 = rf(_a0, _a1)
+	if rf, ok := ret.Get(1).(func(context.Context, *v1beta1.VirtualNode, map[string]string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -130,13 +130,13 @@ func (_m *CloudAPI) CreateVirtualNode(_a0 context.Context, _a1 *v1beta1.VirtualN
 	return r0, r1
 }
 
-// CreateVirtualRouter provides a mock function with given fields: _a0, _a1, _a2
-func (_m *CloudAPI) CreateVirtualRouter(_a0 context.Context, _a1 *v1beta1.VirtualRouter, _a2 string) (*aws.VirtualRouter, error) {
-	ret := _m.Called(_a0, _a1, _a2)
+// CreateVirtualRouter provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *CloudAPI) CreateVirtualRouter(_a0 context.Context, _a1 *v1beta1.VirtualRouter, _a2 string, _a3 map[string]string) (*aws.VirtualRouter, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
 
 	var r0 *aws.VirtualRouter
-	if rf, ok := ret.Get(0).(func(context.Context, *v1beta1.VirtualRouter, string) *aws.VirtualRouter); ok {
-		r0 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1beta1.VirtualRouter, string, map[string]string) *aws.VirtualRouter); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*aws.VirtualRouter)
@@ -144,8 +144,8 @@ func (_m *CloudAPI) CreateVirtualRouter(_a0 context.Context, _a1 *v1beta1.Virtua
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, *v1beta1.VirtualRouter, string) error); ok {
-		r1 = rf(_a0, _a1, _a2)
+	if rf, ok := ret.Get(1).(func(context.Context, *v1beta1.VirtualRouter, string, map[string]string) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -153,13 +153,13 @@ func (_m *CloudAPI) CreateVirtualRouter(_a0 context.Context, _a1 *v1beta1.Virtua
 	return r0, r1
 }
 
-// CreateVirtualService provides a mock function with given fields: _a0, _a1
-func (_m *CloudAPI) CreateVirtualService(_a0 context.Context, _a1 *v1beta1.VirtualService) (*aws.VirtualService, error) {
-	ret := _m.Called(_a0, _a1)
+// CreateVirtualService provides a mock function with given fields: _a0, _a1, _a2
+func (_m *CloudAPI) CreateVirtualService(_a0 context.Context, _a1 *v1beta1.VirtualService, _a2 map[string]string) (*aws.VirtualService, error) {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 *aws.VirtualService
-	if rf, ok := ret.Get(0).(func(context.Context, *v1beta1.VirtualService) *aws.VirtualService); ok {
-		r0 = rf(_a0, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1beta1.VirtualService, map[string]string) *aws.VirtualService); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*aws.VirtualService)
@@ -167,8 +167,8 @@ func (_m *CloudAPI) CreateVirtualService(_a0 context.Context, _a1 *v1beta1.Virtu
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, *v1beta1.VirtualService) error); ok {
-		r1 = rf(_a0, _a1)
+	if rf, ok := ret.Get(1).(func(context.Context, *v1beta1.VirtualService, map[string]string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -466,6 +466,75 @@ func (_m *CloudAPI) ListInstances(_a0 context.Context, _a1 *appmesh.AwsCloudMapS
 	return r0, r1
 }
 
+// ListTagsForResource provides a mock function with given fields: _a0, _a1
+func (_m *CloudAPI) ListTagsForResource(_a0 context.Context, _a1 string) (map[string]string, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 map[string]string
+	if rf, ok := ret.Get(0).(func(context.Context, string) map[string]string); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListVirtualNodes provides a mock function with given fields: _a0, _a1
+func (_m *CloudAPI) ListVirtualNodes(_a0 context.Context, _a1 string) ([]*aws.VirtualNode, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []*aws.VirtualNode
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*aws.VirtualNode); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*aws.VirtualNode)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListVirtualServices provides a mock function with given fields: _a0, _a1
+func (_m *CloudAPI) ListVirtualServices(_a0 context.Context, _a1 string) ([]*aws.VirtualService, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []*aws.VirtualService
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*aws.VirtualService); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*aws.VirtualService)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RegisterInstance provides a mock function with given fields: _a0, _a1, _a2, _a3
 func (_m *CloudAPI) RegisterInstance(_a0 context.Context, _a1 string, _a2 *v1.Pod, _a3 *appmesh.AwsCloudMapServiceDiscovery) error {
 	ret := _m.Called(_a0, _a1, _a2, _a3)
@@ -480,6 +549,34 @@ func (_m *CloudAPI) RegisterInstance(_a0 context.Context, _a1 string, _a2 *v1.Po
 	return r0
 }
 
+// TagResource provides a mock function with given fields: _a0, _a1, _a2
+func (_m *CloudAPI) TagResource(_a0 context.Context, _a1 string, _a2 map[string]string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, map[string]string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UntagResource provides a mock function with given fields: _a0, _a1, _a2
+func (_m *CloudAPI) UntagResource(_a0 context.Context, _a1 string, _a2 []string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateMesh provides a mock function with given fields: _a0, _a1
 func (_m *CloudAPI) UpdateMesh(_a0 context.Context, _a1 *v1beta1.Mesh) (*aws.Mesh, error) {
 	ret := _m.Called(_a0, _a1)
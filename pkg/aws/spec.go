@@ -0,0 +1,264 @@
+package aws
+
+import (
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	"k8s.io/klog"
+)
+
+// The Build*Spec functions convert our API types into App Mesh specs, and the Spec methods convert App Mesh
+// resources back into our API types. Create and update share the same conversion, so that comparing a resource
+// with its spec only has to account for what App Mesh itself changes.
+
+// BuildMeshSpec converts the mesh into the App Mesh mesh spec.
+func BuildMeshSpec(mesh *appmeshv1beta1.Mesh) *appmesh.MeshSpec {
+	spec := &appmesh.MeshSpec{}
+	if mesh.Spec.EgressFilter != nil {
+		spec.EgressFilter = &appmesh.EgressFilter{
+			Type: aws.String(mesh.Spec.EgressFilter.Type),
+		}
+	}
+	return spec
+}
+
+// Spec converts into our API type
+func (v *Mesh) Spec() appmeshv1beta1.MeshSpec {
+	spec := appmeshv1beta1.MeshSpec{}
+	if v.Data.Spec != nil && v.Data.Spec.EgressFilter != nil {
+		spec.EgressFilter = &appmeshv1beta1.MeshEgressFilter{
+			Type: aws.StringValue(v.Data.Spec.EgressFilter.Type),
+		}
+	}
+	return spec
+}
+
+// BuildVirtualNodeSpec converts the virtual node into the App Mesh virtual node spec.
+func BuildVirtualNodeSpec(vnode *appmeshv1beta1.VirtualNode) *appmesh.VirtualNodeSpec {
+	spec := &appmesh.VirtualNodeSpec{}
+
+	if vnode.Spec.Listeners != nil {
+		listeners := []*appmesh.Listener{}
+		for _, crdListener := range vnode.Spec.Listeners {
+			listeners = append(listeners, buildListener(crdListener))
+		}
+		spec.SetListeners(listeners)
+	}
+
+	if vnode.Spec.Backends != nil {
+		backends := []*appmesh.Backend{}
+		for _, crdBackend := range vnode.Spec.Backends {
+			sdkBackend := &appmesh.Backend{
+				VirtualService: &appmesh.VirtualServiceBackend{
+					VirtualServiceName: aws.String(crdBackend.VirtualService.VirtualServiceName),
+				},
+			}
+			if crdBackend.VirtualService.ClientPolicy != nil {
+				sdkBackend.VirtualService.SetClientPolicy(convertCrdClientPolicyToSdk(crdBackend.VirtualService.ClientPolicy))
+			}
+			backends = append(backends, sdkBackend)
+		}
+		spec.SetBackends(backends)
+	}
+
+	if vnode.Spec.BackendDefaults != nil {
+		backendDefaults := &appmesh.BackendDefaults{}
+		if vnode.Spec.BackendDefaults.ClientPolicy != nil {
+			backendDefaults.SetClientPolicy(convertCrdClientPolicyToSdk(vnode.Spec.BackendDefaults.ClientPolicy))
+		}
+		spec.SetBackendDefaults(backendDefaults)
+	}
+
+	if vnode.Spec.ServiceDiscovery != nil {
+		if vnode.Spec.ServiceDiscovery.Dns != nil {
+			spec.SetServiceDiscovery(&appmesh.ServiceDiscovery{
+				Dns: &appmesh.DnsServiceDiscovery{
+					Hostname: aws.String(vnode.Spec.ServiceDiscovery.Dns.HostName),
+				},
+			})
+		} else if vnode.Spec.ServiceDiscovery.CloudMap != nil {
+			spec.SetServiceDiscovery(buildAwsCloudMapServiceDiscovery(vnode))
+		} else {
+			klog.Warningf("No service discovery set for virtual node %s", vnode.Name)
+		}
+	}
+
+	if vnode.Spec.Logging != nil &&
+		vnode.Spec.Logging.AccessLog != nil &&
+		vnode.Spec.Logging.AccessLog.File != nil {
+		spec.SetLogging(&appmesh.Logging{
+			AccessLog: &appmesh.AccessLog{
+				File: &appmesh.FileAccessLog{
+					Path: aws.String(vnode.Spec.Logging.AccessLog.File.Path),
+				},
+			},
+		})
+	}
+
+	return spec
+}
+
+func buildListener(crdListener appmeshv1beta1.Listener) *appmesh.Listener {
+	sdkListener := &appmesh.Listener{
+		PortMapping: &appmesh.PortMapping{
+			Port:     aws.Int64(crdListener.PortMapping.Port),
+			Protocol: aws.String(crdListener.PortMapping.Protocol),
+		},
+	}
+	if crdListener.HealthCheck != nil {
+		sdkListener.SetHealthCheck(&appmesh.HealthCheckPolicy{
+			HealthyThreshold:   crdListener.HealthCheck.HealthyThreshold,
+			IntervalMillis:     crdListener.HealthCheck.IntervalMillis,
+			Path:               crdListener.HealthCheck.Path,
+			Port:               crdListener.HealthCheck.Port,
+			Protocol:           crdListener.HealthCheck.Protocol,
+			TimeoutMillis:      crdListener.HealthCheck.TimeoutMillis,
+			UnhealthyThreshold: crdListener.HealthCheck.UnhealthyThreshold,
+		})
+	}
+	if crdListener.TLS != nil {
+		sdkCertificate := &appmesh.ListenerTlsCertificate{}
+		if crdListener.TLS.Certificate.ACM != nil {
+			sdkCertificate.SetAcm(&appmesh.ListenerTlsAcmCertificate{
+				CertificateArn: aws.String(crdListener.TLS.Certificate.ACM.CertificateArn),
+			})
+		}
+		if crdListener.TLS.Certificate.File != nil {
+			sdkCertificate.SetFile(&appmesh.ListenerTlsFileCertificate{
+				CertificateChain: aws.String(crdListener.TLS.Certificate.File.CertificateChain),
+				PrivateKey:       aws.String(crdListener.TLS.Certificate.File.PrivateKey),
+			})
+		}
+		sdkListener.SetTls(&appmesh.ListenerTls{
+			Mode:        aws.String(crdListener.TLS.Mode),
+			Certificate: sdkCertificate,
+		})
+	}
+	return sdkListener
+}
+
+// Spec converts into our API type
+func (v *VirtualNode) Spec() appmeshv1beta1.VirtualNodeSpec {
+	spec := appmeshv1beta1.VirtualNodeSpec{
+		MeshName: aws.StringValue(v.Data.MeshName),
+	}
+	if v.Data.Spec == nil {
+		return spec
+	}
+	spec.Listeners = v.Listeners()
+	spec.Backends = v.Backends()
+	spec.BackendDefaults = v.BackendDefaults()
+
+	if sd := v.Data.Spec.ServiceDiscovery; sd != nil {
+		spec.ServiceDiscovery = &appmeshv1beta1.ServiceDiscovery{}
+		if sd.Dns != nil {
+			spec.ServiceDiscovery.Dns = &appmeshv1beta1.DnsServiceDiscovery{
+				HostName: aws.StringValue(sd.Dns.Hostname),
+			}
+		}
+		if sd.AwsCloudMap != nil {
+			attributes := map[string]string{}
+			for _, attr := range sd.AwsCloudMap.Attributes {
+				attributes[aws.StringValue(attr.Key)] = aws.StringValue(attr.Value)
+			}
+			spec.ServiceDiscovery.CloudMap = &appmeshv1beta1.CloudMapServiceDiscovery{
+				ServiceName:   aws.StringValue(sd.AwsCloudMap.ServiceName),
+				NamespaceName: aws.StringValue(sd.AwsCloudMap.NamespaceName),
+				Attributes:    attributes,
+			}
+		}
+	}
+
+	if logging := v.Data.Spec.Logging; logging != nil {
+		spec.Logging = &appmeshv1beta1.Logging{}
+		if logging.AccessLog != nil {
+			spec.Logging.AccessLog = &appmeshv1beta1.AccessLog{}
+			if logging.AccessLog.File != nil {
+				spec.Logging.AccessLog.File = &appmeshv1beta1.FileAccessLog{
+					Path: aws.StringValue(logging.AccessLog.File.Path),
+				}
+			}
+		}
+	}
+	return spec
+}
+
+// BuildVirtualRouterSpec converts the virtual router into the App Mesh virtual router spec.
+func BuildVirtualRouterSpec(vrouter *appmeshv1beta1.VirtualRouter) *appmesh.VirtualRouterSpec {
+	listeners := []*appmesh.VirtualRouterListener{}
+	for _, listener := range vrouter.Listeners {
+		listeners = append(listeners, &appmesh.VirtualRouterListener{
+			PortMapping: &appmesh.PortMapping{
+				Port:     aws.Int64(listener.PortMapping.Port),
+				Protocol: aws.String(listener.PortMapping.Protocol),
+			},
+		})
+	}
+	return &appmesh.VirtualRouterSpec{
+		Listeners: listeners,
+	}
+}
+
+// Spec converts into our API type
+func (v *VirtualRouter) Spec() appmeshv1beta1.VirtualRouter {
+	vrouter := appmeshv1beta1.VirtualRouter{
+		Name: v.Name(),
+	}
+	if v.Data.Spec == nil {
+		return vrouter
+	}
+	for _, listener := range v.Data.Spec.Listeners {
+		if listener.PortMapping == nil {
+			continue
+		}
+		vrouter.Listeners = append(vrouter.Listeners, appmeshv1beta1.VirtualRouterListener{
+			PortMapping: appmeshv1beta1.PortMapping{
+				Port:     aws.Int64Value(listener.PortMapping.Port),
+				Protocol: aws.StringValue(listener.PortMapping.Protocol),
+			},
+		})
+	}
+	return vrouter
+}
+
+// Spec converts into our API type
+func (r *Route) Spec() appmeshv1beta1.Route {
+	route := appmeshv1beta1.Route{
+		Name: r.Name(),
+	}
+	if r.Data.Spec == nil {
+		return route
+	}
+	route.Priority = r.Data.Spec.Priority
+
+	if r.Data.Spec.HttpRoute != nil {
+		route.Http = &appmeshv1beta1.HttpRoute{
+			Action:      appmeshv1beta1.HttpRouteAction{WeightedTargets: r.WeightedTargets()},
+			RetryPolicy: r.HttpRouteRetryPolicy(),
+		}
+		if match := r.HttpRouteMatch(); match != nil {
+			route.Http.Match = *match
+		}
+	} else if r.Data.Spec.TcpRoute != nil {
+		route.Tcp = &appmeshv1beta1.TcpRoute{
+			Action: appmeshv1beta1.TcpRouteAction{WeightedTargets: r.WeightedTargets()},
+		}
+	} else if r.Data.Spec.Http2Route != nil {
+		route.Http2 = &appmeshv1beta1.HttpRoute{
+			Action:      appmeshv1beta1.HttpRouteAction{WeightedTargets: r.WeightedTargets()},
+			RetryPolicy: r.Http2RouteRetryPolicy(),
+		}
+		if match := r.Http2RouteMatch(); match != nil {
+			route.Http2.Match = *match
+		}
+	} else if r.Data.Spec.GrpcRoute != nil {
+		route.Grpc = &appmeshv1beta1.GrpcRoute{
+			Action:      appmeshv1beta1.GrpcRouteAction{WeightedTargets: r.WeightedTargets()},
+			RetryPolicy: r.GrpcRouteRetryPolicy(),
+		}
+		if match := r.GrpcRouteMatch(); match != nil {
+			route.Grpc.Match = *match
+		}
+	}
+	return route
+}
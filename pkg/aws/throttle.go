@@ -0,0 +1,155 @@
+package aws
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/metrics"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"golang.org/x/time/rate"
+	"k8s.io/klog"
+)
+
+const (
+	operationClassRead  = "read"
+	operationClassWrite = "write"
+
+	// minThrottleBackoff is the pause applied after the first throttling error
+	minThrottleBackoff = 500 * time.Millisecond
+)
+
+// throttler limits the rate of AWS API calls with a token bucket per service and operation class, and pauses
+// every call once the API starts throttling. Since all workers share the session, a pause holds back all of
+// them rather than leaving each to be retried by its workqueue.
+type throttler struct {
+	opts  CloudOptions
+	stats *metrics.Recorder
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	// backoff grows on each throttling error and decays on each successful call
+	backoff     time.Duration
+	pausedUntil time.Time
+}
+
+func newThrottler(opts CloudOptions, stats *metrics.Recorder) *throttler {
+	if opts.MaxThrottleBackoff <= 0 {
+		opts.MaxThrottleBackoff = DefaultMaxThrottleBackoff
+	}
+	return &throttler{
+		opts:     opts,
+		stats:    stats,
+		limiters: map[string]*rate.Limiter{},
+	}
+}
+
+// operationClass groups operations sharing a token bucket.
+func operationClass(operation string) string {
+	for _, prefix := range []string{"Get", "Describe", "List", "Discover"} {
+		if strings.HasPrefix(operation, prefix) {
+			return operationClassRead
+		}
+	}
+	return operationClassWrite
+}
+
+// limiter returns the token bucket for the operation, or nil if its class is not limited.
+func (t *throttler) limiter(service string, operation string) *rate.Limiter {
+	class := operationClass(operation)
+	qps, burst := t.opts.WriteQPS, t.opts.WriteBurst
+	if class == operationClassRead {
+		qps, burst = t.opts.ReadQPS, t.opts.ReadBurst
+	}
+	if qps <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := service + "/" + class
+	limiter, ok := t.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(qps), burst)
+		t.limiters[key] = limiter
+	}
+	return limiter
+}
+
+// pause returns how long calls must still wait after a throttling error.
+func (t *throttler) pause() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return time.Until(t.pausedUntil)
+}
+
+// wait blocks until the global pause is over and the operation's token bucket admits the call.
+func (t *throttler) wait(ctx aws.Context, service string, operation string) error {
+	if d := t.pause(); d > 0 {
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if limiter := t.limiter(service, operation); limiter != nil {
+		return limiter.Wait(ctx)
+	}
+	return nil
+}
+
+// observe adapts the backoff to the outcome of a call.
+func (t *throttler) observe(service string, operation string, err error) {
+	throttled := err != nil && request.IsErrorThrottle(err)
+	if err != nil && !throttled {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !throttled {
+		t.backoff /= 2
+		if t.backoff < minThrottleBackoff {
+			t.backoff = 0
+		}
+		return
+	}
+
+	t.backoff *= 2
+	if t.backoff < minThrottleBackoff {
+		t.backoff = minThrottleBackoff
+	}
+	if t.backoff > t.opts.MaxThrottleBackoff {
+		t.backoff = t.opts.MaxThrottleBackoff
+	}
+	t.pausedUntil = time.Now().Add(t.backoff)
+	t.stats.RecordAWSAPIThrottle(service, operation)
+	klog.Warningf("%s %s was throttled, pausing AWS calls for %s", service, operation, t.backoff)
+}
+
+// register installs the throttler on the session handlers. Waiting happens before signing so that every
+// attempt, including the SDK's own retries, draws from the token bucket.
+func (t *throttler) register(handlers *request.Handlers) {
+	handlers.Sign.PushFront(func(r *request.Request) {
+		if err := t.wait(r.Context(), r.ClientInfo.ServiceName, r.Operation.Name); err != nil {
+			r.Error = awserr.New(request.CanceledErrorCode, "request canceled while waiting for rate limiter", err)
+		}
+	})
+
+	handlers.Retry.PushFront(func(r *request.Request) {
+		t.observe(r.ClientInfo.ServiceName, r.Operation.Name, r.Error)
+	})
+
+	handlers.Complete.PushBack(func(r *request.Request) {
+		if r.Error == nil {
+			t.observe(r.ClientInfo.ServiceName, r.Operation.Name, nil)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/metrics"
+)
+
+func TestOperationClass(t *testing.T) {
+	var classtests = []struct {
+		operation string
+		class     string
+	}{
+		{"DescribeVirtualNode", operationClassRead},
+		{"ListRoutes", operationClassRead},
+		{"GetInstancesHealthStatus", operationClassRead},
+		{"CreateVirtualNode", operationClassWrite},
+		{"RegisterInstance", operationClassWrite},
+	}
+
+	for _, tt := range classtests {
+		t.Run(tt.operation, func(t *testing.T) {
+			if class := operationClass(tt.operation); class != tt.class {
+				t.Errorf("got %s, want %s", class, tt.class)
+			}
+		})
+	}
+}
+
+func TestThrottler(t *testing.T) {
+	stats := metrics.NewRecorder(false)
+	throttled := awserr.New("TooManyRequestsException", "rate exceeded", nil)
+
+	t.Run("backoff grows and decays", func(t *testing.T) {
+		th := newThrottler(CloudOptions{MaxThrottleBackoff: 2 * time.Second}, stats)
+		var backoffs []time.Duration
+		for i := 0; i < 4; i++ {
+			th.observe("appmesh", "CreateRoute", throttled)
+			backoffs = append(backoffs, th.backoff)
+		}
+		want := []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 2 * time.Second}
+		for i := range want {
+			if backoffs[i] != want[i] {
+				t.Errorf("backoff %d: got %s, want %s", i, backoffs[i], want[i])
+			}
+		}
+		if th.pause() <= 0 {
+			t.Errorf("expected calls to be paused")
+		}
+
+		th.observe("appmesh", "CreateRoute", errors.New("not a throttle"))
+		if th.backoff != 2*time.Second {
+			t.Errorf("expected other errors to leave backoff unchanged, got %s", th.backoff)
+		}
+		for i := 0; i < 3; i++ {
+			th.observe("appmesh", "CreateRoute", nil)
+		}
+		if th.backoff != 0 {
+			t.Errorf("expected backoff to decay to zero, got %s", th.backoff)
+		}
+	})
+
+	t.Run("wait honours pause and context", func(t *testing.T) {
+		th := newThrottler(CloudOptions{}, stats)
+		th.observe("appmesh", "CreateRoute", throttled)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if err := th.wait(ctx, "appmesh", "CreateRoute"); err == nil {
+			t.Errorf("expected wait to be cut short by the context")
+		}
+	})
+
+	t.Run("limiters are per service and class", func(t *testing.T) {
+		th := newThrottler(CloudOptions{ReadQPS: 1, ReadBurst: 1}, stats)
+		if th.limiter("appmesh", "CreateRoute") != nil {
+			t.Errorf("expected unlimited write class")
+		}
+		if th.limiter("appmesh", "DescribeRoute") != th.limiter("appmesh", "ListRoutes") {
+			t.Errorf("expected read operations to share a limiter")
+		}
+		if th.limiter("appmesh", "DescribeRoute") == th.limiter("servicediscovery", "ListInstances") {
+			t.Errorf("expected services to have separate limiters")
+		}
+	})
+}
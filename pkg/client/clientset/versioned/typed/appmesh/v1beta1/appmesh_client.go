@@ -23,6 +23,8 @@ import (
 type AppmeshV1beta1Interface interface {
 	RESTClient() rest.Interface
 	MeshesGetter
+	MeshDefaultsesGetter
+	ReferenceGrantsGetter
 	VirtualNodesGetter
 	VirtualServicesGetter
 }
@@ -36,6 +38,14 @@ func (c *AppmeshV1beta1Client) Meshes() MeshInterface {
 	return newMeshes(c)
 }
 
+func (c *AppmeshV1beta1Client) MeshDefaultses(namespace string) MeshDefaultsInterface {
+	return newMeshDefaultses(c, namespace)
+}
+
+func (c *AppmeshV1beta1Client) ReferenceGrants(namespace string) ReferenceGrantInterface {
+	return newReferenceGrants(c, namespace)
+}
+
 func (c *AppmeshV1beta1Client) VirtualNodes(namespace string) VirtualNodeInterface {
 	return newVirtualNodes(c, namespace)
 }
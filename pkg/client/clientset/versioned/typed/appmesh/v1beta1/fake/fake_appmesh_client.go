@@ -28,6 +28,14 @@ func (c *FakeAppmeshV1beta1) Meshes() v1beta1.MeshInterface {
 	return &FakeMeshes{c}
 }
 
+func (c *FakeAppmeshV1beta1) MeshDefaultses(namespace string) v1beta1.MeshDefaultsInterface {
+	return &FakeMeshDefaultses{c, namespace}
+}
+
+func (c *FakeAppmeshV1beta1) ReferenceGrants(namespace string) v1beta1.ReferenceGrantInterface {
+	return &FakeReferenceGrants{c, namespace}
+}
+
 func (c *FakeAppmeshV1beta1) VirtualNodes(namespace string) v1beta1.VirtualNodeInterface {
 	return &FakeVirtualNodes{c, namespace}
 }
@@ -0,0 +1,124 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeMeshDefaultses implements MeshDefaultsInterface
+type FakeMeshDefaultses struct {
+	Fake *FakeAppmeshV1beta1
+	ns   string
+}
+
+var meshdefaultsesResource = schema.GroupVersionResource{Group: "appmesh.k8s.aws", Version: "v1beta1", Resource: "meshdefaults"}
+
+var meshdefaultsesKind = schema.GroupVersionKind{Group: "appmesh.k8s.aws", Version: "v1beta1", Kind: "MeshDefaults"}
+
+// Get takes name of the meshDefaults, and returns the corresponding meshDefaults object, and an error if there is any.
+func (c *FakeMeshDefaultses) Get(name string, options v1.GetOptions) (result *v1beta1.MeshDefaults, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(meshdefaultsesResource, c.ns, name), &v1beta1.MeshDefaults{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.MeshDefaults), err
+}
+
+// List takes label and field selectors, and returns the list of MeshDefaultses that match those selectors.
+func (c *FakeMeshDefaultses) List(opts v1.ListOptions) (result *v1beta1.MeshDefaultsList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(meshdefaultsesResource, meshdefaultsesKind, c.ns, opts), &v1beta1.MeshDefaultsList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1beta1.MeshDefaultsList{ListMeta: obj.(*v1beta1.MeshDefaultsList).ListMeta}
+	for _, item := range obj.(*v1beta1.MeshDefaultsList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested meshDefaultses.
+func (c *FakeMeshDefaultses) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(meshdefaultsesResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a meshDefaults and creates it.  Returns the server's representation of the meshDefaults, and an error, if there is any.
+func (c *FakeMeshDefaultses) Create(meshDefaults *v1beta1.MeshDefaults) (result *v1beta1.MeshDefaults, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(meshdefaultsesResource, c.ns, meshDefaults), &v1beta1.MeshDefaults{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.MeshDefaults), err
+}
+
+// Update takes the representation of a meshDefaults and updates it. Returns the server's representation of the meshDefaults, and an error, if there is any.
+func (c *FakeMeshDefaultses) Update(meshDefaults *v1beta1.MeshDefaults) (result *v1beta1.MeshDefaults, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(meshdefaultsesResource, c.ns, meshDefaults), &v1beta1.MeshDefaults{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.MeshDefaults), err
+}
+
+// Delete takes name of the meshDefaults and deletes it. Returns an error if one occurs.
+func (c *FakeMeshDefaultses) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(meshdefaultsesResource, c.ns, name), &v1beta1.MeshDefaults{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeMeshDefaultses) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(meshdefaultsesResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1beta1.MeshDefaultsList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched meshDefaults.
+func (c *FakeMeshDefaultses) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1beta1.MeshDefaults, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(meshdefaultsesResource, c.ns, name, pt, data, subresources...), &v1beta1.MeshDefaults{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.MeshDefaults), err
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeReferenceGrants implements ReferenceGrantInterface
+type FakeReferenceGrants struct {
+	Fake *FakeAppmeshV1beta1
+	ns   string
+}
+
+var referencegrantsResource = schema.GroupVersionResource{Group: "appmesh.k8s.aws", Version: "v1beta1", Resource: "referencegrants"}
+
+var referencegrantsKind = schema.GroupVersionKind{Group: "appmesh.k8s.aws", Version: "v1beta1", Kind: "ReferenceGrant"}
+
+// Get takes name of the referenceGrant, and returns the corresponding referenceGrant object, and an error if there is any.
+func (c *FakeReferenceGrants) Get(name string, options v1.GetOptions) (result *v1beta1.ReferenceGrant, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(referencegrantsResource, c.ns, name), &v1beta1.ReferenceGrant{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.ReferenceGrant), err
+}
+
+// List takes label and field selectors, and returns the list of ReferenceGrants that match those selectors.
+func (c *FakeReferenceGrants) List(opts v1.ListOptions) (result *v1beta1.ReferenceGrantList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(referencegrantsResource, referencegrantsKind, c.ns, opts), &v1beta1.ReferenceGrantList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1beta1.ReferenceGrantList{ListMeta: obj.(*v1beta1.ReferenceGrantList).ListMeta}
+	for _, item := range obj.(*v1beta1.ReferenceGrantList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested referenceGrants.
+func (c *FakeReferenceGrants) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(referencegrantsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a referenceGrant and creates it.  Returns the server's representation of the referenceGrant, and an error, if there is any.
+func (c *FakeReferenceGrants) Create(referenceGrant *v1beta1.ReferenceGrant) (result *v1beta1.ReferenceGrant, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(referencegrantsResource, c.ns, referenceGrant), &v1beta1.ReferenceGrant{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.ReferenceGrant), err
+}
+
+// Update takes the representation of a referenceGrant and updates it. Returns the server's representation of the referenceGrant, and an error, if there is any.
+func (c *FakeReferenceGrants) Update(referenceGrant *v1beta1.ReferenceGrant) (result *v1beta1.ReferenceGrant, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(referencegrantsResource, c.ns, referenceGrant), &v1beta1.ReferenceGrant{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.ReferenceGrant), err
+}
+
+// Delete takes name of the referenceGrant and deletes it. Returns an error if one occurs.
+func (c *FakeReferenceGrants) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(referencegrantsResource, c.ns, name), &v1beta1.ReferenceGrant{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeReferenceGrants) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(referencegrantsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1beta1.ReferenceGrantList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched referenceGrant.
+func (c *FakeReferenceGrants) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1beta1.ReferenceGrant, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(referencegrantsResource, c.ns, name, pt, data, subresources...), &v1beta1.ReferenceGrant{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.ReferenceGrant), err
+}
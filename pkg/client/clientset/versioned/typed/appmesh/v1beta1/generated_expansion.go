@@ -16,6 +16,10 @@ package v1beta1
 
 type MeshExpansion interface{}
 
+type MeshDefaultsExpansion interface{}
+
+type ReferenceGrantExpansion interface{}
+
 type VirtualNodeExpansion interface{}
 
 type VirtualServiceExpansion interface{}
@@ -0,0 +1,170 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"time"
+
+	v1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	scheme "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// MeshDefaultsesGetter has a method to return a MeshDefaultsInterface.
+// A group's client should implement this interface.
+type MeshDefaultsesGetter interface {
+	MeshDefaultses(namespace string) MeshDefaultsInterface
+}
+
+// MeshDefaultsInterface has methods to work with MeshDefaults resources.
+type MeshDefaultsInterface interface {
+	Create(*v1beta1.MeshDefaults) (*v1beta1.MeshDefaults, error)
+	Update(*v1beta1.MeshDefaults) (*v1beta1.MeshDefaults, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1beta1.MeshDefaults, error)
+	List(opts v1.ListOptions) (*v1beta1.MeshDefaultsList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1beta1.MeshDefaults, err error)
+	MeshDefaultsExpansion
+}
+
+// meshDefaultses implements MeshDefaultsInterface
+type meshDefaultses struct {
+	client rest.Interface
+	ns     string
+}
+
+// newMeshDefaultses returns a MeshDefaultses
+func newMeshDefaultses(c *AppmeshV1beta1Client, namespace string) *meshDefaultses {
+	return &meshDefaultses{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the meshDefaults, and returns the corresponding meshDefaults object, and an error if there is any.
+func (c *meshDefaultses) Get(name string, options v1.GetOptions) (result *v1beta1.MeshDefaults, err error) {
+	result = &v1beta1.MeshDefaults{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("meshdefaults").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of MeshDefaultses that match those selectors.
+func (c *meshDefaultses) List(opts v1.ListOptions) (result *v1beta1.MeshDefaultsList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1beta1.MeshDefaultsList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("meshdefaults").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested meshDefaultses.
+func (c *meshDefaultses) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("meshdefaults").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a meshDefaults and creates it.  Returns the server's representation of the meshDefaults, and an error, if there is any.
+func (c *meshDefaultses) Create(meshDefaults *v1beta1.MeshDefaults) (result *v1beta1.MeshDefaults, err error) {
+	result = &v1beta1.MeshDefaults{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("meshdefaults").
+		Body(meshDefaults).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a meshDefaults and updates it. Returns the server's representation of the meshDefaults, and an error, if there is any.
+func (c *meshDefaultses) Update(meshDefaults *v1beta1.MeshDefaults) (result *v1beta1.MeshDefaults, err error) {
+	result = &v1beta1.MeshDefaults{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("meshdefaults").
+		Name(meshDefaults.Name).
+		Body(meshDefaults).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the meshDefaults and deletes it. Returns an error if one occurs.
+func (c *meshDefaultses) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("meshdefaults").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *meshDefaultses) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("meshdefaults").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched meshDefaults.
+func (c *meshDefaultses) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1beta1.MeshDefaults, err error) {
+	result = &v1beta1.MeshDefaults{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("meshdefaults").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
@@ -11,6 +11,22 @@ type AppmeshV1beta1Interface struct {
 	mock.Mock
 }
 
+// MeshDefaultses provides a mock function with given fields: namespace
+func (_m *AppmeshV1beta1Interface) MeshDefaultses(namespace string) v1beta1.MeshDefaultsInterface {
+	ret := _m.Called(namespace)
+
+	var r0 v1beta1.MeshDefaultsInterface
+	if rf, ok := ret.Get(0).(func(string) v1beta1.MeshDefaultsInterface); ok {
+		r0 = rf(namespace)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(v1beta1.MeshDefaultsInterface)
+		}
+	}
+
+	return r0
+}
+
 // Meshes provides a mock function with given fields:
 func (_m *AppmeshV1beta1Interface) Meshes() v1beta1.MeshInterface {
 	ret := _m.Called()
@@ -43,6 +59,22 @@ func (_m *AppmeshV1beta1Interface) RESTClient() rest.Interface {
 	return r0
 }
 
+// ReferenceGrants provides a mock function with given fields: namespace
+func (_m *AppmeshV1beta1Interface) ReferenceGrants(namespace string) v1beta1.ReferenceGrantInterface {
+	ret := _m.Called(namespace)
+
+	var r0 v1beta1.ReferenceGrantInterface
+	if rf, ok := ret.Get(0).(func(string) v1beta1.ReferenceGrantInterface); ok {
+		r0 = rf(namespace)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(v1beta1.ReferenceGrantInterface)
+		}
+	}
+
+	return r0
+}
+
 // VirtualNodes provides a mock function with given fields: namespace
 func (_m *AppmeshV1beta1Interface) VirtualNodes(namespace string) v1beta1.VirtualNodeInterface {
 	ret := _m.Called(namespace)
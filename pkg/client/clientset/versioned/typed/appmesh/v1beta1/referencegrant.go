@@ -0,0 +1,170 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	"time"
+
+	v1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	scheme "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// ReferenceGrantsGetter has a method to return a ReferenceGrantInterface.
+// A group's client should implement this interface.
+type ReferenceGrantsGetter interface {
+	ReferenceGrants(namespace string) ReferenceGrantInterface
+}
+
+// ReferenceGrantInterface has methods to work with ReferenceGrant resources.
+type ReferenceGrantInterface interface {
+	Create(*v1beta1.ReferenceGrant) (*v1beta1.ReferenceGrant, error)
+	Update(*v1beta1.ReferenceGrant) (*v1beta1.ReferenceGrant, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1beta1.ReferenceGrant, error)
+	List(opts v1.ListOptions) (*v1beta1.ReferenceGrantList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1beta1.ReferenceGrant, err error)
+	ReferenceGrantExpansion
+}
+
+// referenceGrants implements ReferenceGrantInterface
+type referenceGrants struct {
+	client rest.Interface
+	ns     string
+}
+
+// newReferenceGrants returns a ReferenceGrants
+func newReferenceGrants(c *AppmeshV1beta1Client, namespace string) *referenceGrants {
+	return &referenceGrants{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the referenceGrant, and returns the corresponding referenceGrant object, and an error if there is any.
+func (c *referenceGrants) Get(name string, options v1.GetOptions) (result *v1beta1.ReferenceGrant, err error) {
+	result = &v1beta1.ReferenceGrant{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("referencegrants").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of ReferenceGrants that match those selectors.
+func (c *referenceGrants) List(opts v1.ListOptions) (result *v1beta1.ReferenceGrantList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1beta1.ReferenceGrantList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("referencegrants").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested referenceGrants.
+func (c *referenceGrants) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("referencegrants").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a referenceGrant and creates it.  Returns the server's representation of the referenceGrant, and an error, if there is any.
+func (c *referenceGrants) Create(referenceGrant *v1beta1.ReferenceGrant) (result *v1beta1.ReferenceGrant, err error) {
+	result = &v1beta1.ReferenceGrant{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("referencegrants").
+		Body(referenceGrant).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a referenceGrant and updates it. Returns the server's representation of the referenceGrant, and an error, if there is any.
+func (c *referenceGrants) Update(referenceGrant *v1beta1.ReferenceGrant) (result *v1beta1.ReferenceGrant, err error) {
+	result = &v1beta1.ReferenceGrant{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("referencegrants").
+		Name(referenceGrant.Name).
+		Body(referenceGrant).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the referenceGrant and deletes it. Returns an error if one occurs.
+func (c *referenceGrants) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("referencegrants").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *referenceGrants) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("referencegrants").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched referenceGrant.
+func (c *referenceGrants) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1beta1.ReferenceGrant, err error) {
+	result = &v1beta1.ReferenceGrant{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("referencegrants").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
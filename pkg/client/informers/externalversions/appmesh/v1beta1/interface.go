@@ -22,6 +22,10 @@ import (
 type Interface interface {
 	// Meshes returns a MeshInformer.
 	Meshes() MeshInformer
+	// MeshDefaultses returns a MeshDefaultsInformer.
+	MeshDefaultses() MeshDefaultsInformer
+	// ReferenceGrants returns a ReferenceGrantInformer.
+	ReferenceGrants() ReferenceGrantInformer
 	// VirtualNodes returns a VirtualNodeInformer.
 	VirtualNodes() VirtualNodeInformer
 	// VirtualServices returns a VirtualServiceInformer.
@@ -44,6 +48,16 @@ func (v *version) Meshes() MeshInformer {
 	return &meshInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
 
+// MeshDefaultses returns a MeshDefaultsInformer.
+func (v *version) MeshDefaultses() MeshDefaultsInformer {
+	return &meshDefaultsInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// ReferenceGrants returns a ReferenceGrantInformer.
+func (v *version) ReferenceGrants() ReferenceGrantInformer {
+	return &referenceGrantInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
 // VirtualNodes returns a VirtualNodeInformer.
 func (v *version) VirtualNodes() VirtualNodeInformer {
 	return &virtualNodeInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -51,6 +51,10 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=appmesh.k8s.aws, Version=v1beta1
 	case v1beta1.SchemeGroupVersion.WithResource("meshes"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Appmesh().V1beta1().Meshes().Informer()}, nil
+	case v1beta1.SchemeGroupVersion.WithResource("meshdefaults"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Appmesh().V1beta1().MeshDefaultses().Informer()}, nil
+	case v1beta1.SchemeGroupVersion.WithResource("referencegrants"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Appmesh().V1beta1().ReferenceGrants().Informer()}, nil
 	case v1beta1.SchemeGroupVersion.WithResource("virtualnodes"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Appmesh().V1beta1().VirtualNodes().Informer()}, nil
 	case v1beta1.SchemeGroupVersion.WithResource("virtualservices"):
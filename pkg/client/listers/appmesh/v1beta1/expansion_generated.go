@@ -18,6 +18,22 @@ package v1beta1
 // MeshLister.
 type MeshListerExpansion interface{}
 
+// MeshDefaultsListerExpansion allows custom methods to be added to
+// MeshDefaultsLister.
+type MeshDefaultsListerExpansion interface{}
+
+// MeshDefaultsNamespaceListerExpansion allows custom methods to be added to
+// MeshDefaultsNamespaceLister.
+type MeshDefaultsNamespaceListerExpansion interface{}
+
+// ReferenceGrantListerExpansion allows custom methods to be added to
+// ReferenceGrantLister.
+type ReferenceGrantListerExpansion interface{}
+
+// ReferenceGrantNamespaceListerExpansion allows custom methods to be added to
+// ReferenceGrantNamespaceLister.
+type ReferenceGrantNamespaceListerExpansion interface{}
+
 // VirtualNodeListerExpansion allows custom methods to be added to
 // VirtualNodeLister.
 type VirtualNodeListerExpansion interface{}
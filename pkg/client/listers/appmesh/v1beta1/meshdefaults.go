@@ -0,0 +1,90 @@
+// Copyright 2019 Amazon.com, Inc. or its affiliates. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License"). You may
+// not use this file except in compliance with the License. A copy of the
+// License is located at
+//
+//	http://aws.amazon.com/apache2.0/
+//
+// or in the "license" file accompanying this file. This file is distributed
+// on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either
+// express or implied. See the License for the specific language governing
+// permissions and limitations under the License.
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1beta1
+
+import (
+	v1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// MeshDefaultsLister helps list MeshDefaultses.
+type MeshDefaultsLister interface {
+	// List lists all MeshDefaultses in the indexer.
+	List(selector labels.Selector) (ret []*v1beta1.MeshDefaults, err error)
+	// MeshDefaultses returns an object that can list and get MeshDefaultses.
+	MeshDefaultses(namespace string) MeshDefaultsNamespaceLister
+	MeshDefaultsListerExpansion
+}
+
+// meshDefaultsLister implements the MeshDefaultsLister interface.
+type meshDefaultsLister struct {
+	indexer cache.Indexer
+}
+
+// NewMeshDefaultsLister returns a new MeshDefaultsLister.
+func NewMeshDefaultsLister(indexer cache.Indexer) MeshDefaultsLister {
+	return &meshDefaultsLister{indexer: indexer}
+}
+
+// List lists all MeshDefaultses in the indexer.
+func (s *meshDefaultsLister) List(selector labels.Selector) (ret []*v1beta1.MeshDefaults, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1beta1.MeshDefaults))
+	})
+	return ret, err
+}
+
+// MeshDefaultses returns an object that can list and get MeshDefaultses.
+func (s *meshDefaultsLister) MeshDefaultses(namespace string) MeshDefaultsNamespaceLister {
+	return meshDefaultsNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// MeshDefaultsNamespaceLister helps list and get MeshDefaultses.
+type MeshDefaultsNamespaceLister interface {
+	// List lists all MeshDefaultses in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1beta1.MeshDefaults, err error)
+	// Get retrieves the MeshDefaults from the indexer for a given namespace and name.
+	Get(name string) (*v1beta1.MeshDefaults, error)
+	MeshDefaultsNamespaceListerExpansion
+}
+
+// meshDefaultsNamespaceLister implements the MeshDefaultsNamespaceLister
+// interface.
+type meshDefaultsNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all MeshDefaultses in the indexer for a given namespace.
+func (s meshDefaultsNamespaceLister) List(selector labels.Selector) (ret []*v1beta1.MeshDefaults, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1beta1.MeshDefaults))
+	})
+	return ret, err
+}
+
+// Get retrieves the MeshDefaults from the indexer for a given namespace and name.
+func (s meshDefaultsNamespaceLister) Get(name string) (*v1beta1.MeshDefaults, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1beta1.Resource("meshdefaults"), name)
+	}
+	return obj.(*v1beta1.MeshDefaults), nil
+}
@@ -0,0 +1,388 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// Condition types that meshes, virtual nodes and virtual services share, so that readiness can be derived the
+// same way for all of them.
+const (
+	conditionReady      = "Ready"
+	conditionReconciled = "Reconciled"
+	conditionDegraded   = "Degraded"
+
+	conditionPaused                = "Paused"
+	conditionMeshMarkedForDeletion = "MeshMarkedForDeletion"
+	conditionNameCollision         = "NameCollision"
+	conditionOwnershipConflict     = "OwnershipConflict"
+	conditionWaitingForDependency  = "WaitingForDependency"
+	conditionReferencesResolved    = "ReferencesResolved"
+	conditionTLSPolicyCompliant    = "TLSPolicyCompliant"
+	conditionDrifted               = "Drifted"
+)
+
+// Reasons of the Ready, Reconciled and Degraded conditions. Reasons of reconcile errors name the step that
+// failed, the message carries the error and its AWS error code.
+const (
+	reasonActive             = "Active"
+	reasonNotActive          = "NotActive"
+	reasonReconcileSucceeded = "ReconcileSucceeded"
+	reasonAsExpected         = "AsExpected"
+
+	reasonReconcileFailed        = "ReconcileFailed"
+	reasonInvalidSpec            = "InvalidSpec"
+	reasonMeshNotFound           = "MeshNotFound"
+	reasonMeshNotActive          = "MeshNotActive"
+	reasonMeshNotSelected        = "MeshNotSelected"
+	reasonFinalizerUpdateFailed  = "FinalizerUpdateFailed"
+	reasonDescribeFailed         = "DescribeFailed"
+	reasonCreateFailed           = "CreateFailed"
+	reasonUpdateFailed           = "UpdateFailed"
+	reasonStatusUpdateFailed     = "StatusUpdateFailed"
+	reasonServiceDiscoveryFailed = "ServiceDiscoveryFailed"
+	reasonDeleteFailed           = "DeleteFailed"
+	reasonRegisterFailed         = "RegisterFailed"
+	reasonDeregisterFailed       = "DeregisterFailed"
+)
+
+// reconcileError is an error that ends a reconcile, with the reason the Ready, Reconciled and Degraded
+// conditions report for it, and the error it was caused by.
+type reconcileError struct {
+	reason  string
+	cause   error
+	message string
+}
+
+func (e *reconcileError) Error() string {
+	return e.message
+}
+
+// reconcileErrorf returns an error with the given reason and message, caused by cause.
+func reconcileErrorf(reason string, cause error, format string, args ...interface{}) error {
+	return &reconcileError{reason: reason, cause: cause, message: fmt.Sprintf(format, args...)}
+}
+
+// awsCause returns the error that caused err, following reconcile errors down to the error they wrap.
+func awsCause(err error) error {
+	for {
+		rerr, ok := err.(*reconcileError)
+		if !ok || rerr.cause == nil {
+			return err
+		}
+		err = rerr.cause
+	}
+}
+
+// awsErrorCode returns the AWS error code of err or of the error that caused it, or "" if it is not an AWS error.
+func awsErrorCode(err error) string {
+	if aerr, ok := awsCause(err).(awserr.Error); ok {
+		return aerr.Code()
+	}
+	return ""
+}
+
+// errorCondition returns the reason and message the Ready, Reconciled and Degraded conditions report for a
+// reconcile error. The status code and request ID of AWS request failures are left out of the message, since
+// the request ID differs on every call and would change the conditions, and so patch the status, on every retry.
+func errorCondition(err error) (string, string) {
+	reason := reasonReconcileFailed
+	if rerr, ok := err.(*reconcileError); ok {
+		reason = rerr.reason
+	}
+	message := err.Error()
+	if rerr, ok := awsCause(err).(awserr.RequestFailure); ok {
+		message = strings.Replace(message, rerr.Error(), awserr.SprintError(rerr.Code(), rerr.Message(), "", rerr.OrigErr()), -1)
+	}
+	if code := awsErrorCode(err); code != "" {
+		message = fmt.Sprintf("%s (AWS error code %s)", message, code)
+	}
+	return reason, message
+}
+
+// statusCondition is a mesh, virtual node or virtual service condition without the type of its kind.
+type statusCondition struct {
+	Type    string
+	Status  api.ConditionStatus
+	Reason  string
+	Message string
+}
+
+// readinessInput is what the Ready, Reconciled and Degraded conditions of a resource are derived from.
+type readinessInput struct {
+	// err is the error the last reconcile returned
+	err error
+	// conditions are the other conditions of the resource
+	conditions []statusCondition
+	// activeTypes are the conditions that are True when App Mesh reports the resources as active
+	activeTypes []string
+	// holdsUnresolved is whether unresolved references hold off the resource, rather than only references that
+	// are not granted
+	holdsUnresolved bool
+}
+
+func findStatusCondition(conditions []statusCondition, conditionType string) statusCondition {
+	for _, condition := range conditions {
+		if condition.Type == conditionType {
+			return condition
+		}
+	}
+	return statusCondition{}
+}
+
+// heldBy returns the condition that held off the last reconcile, if any.
+func (in readinessInput) heldBy() (statusCondition, bool) {
+	for _, conditionType := range []string{conditionPaused, conditionMeshMarkedForDeletion, conditionOwnershipConflict, conditionWaitingForDependency} {
+		if condition := findStatusCondition(in.conditions, conditionType); condition.Status == api.ConditionTrue {
+			return condition, true
+		}
+	}
+	// Only the newer objects that collide on an App Mesh name yield it, the oldest is still reconciled
+	if collision := findStatusCondition(in.conditions, conditionNameCollision); collision.Status == api.ConditionTrue && collision.Reason == nameYieldedReason {
+		return collision, true
+	}
+	if tls := findStatusCondition(in.conditions, conditionTLSPolicyCompliant); tls.Status == api.ConditionFalse {
+		return tls, true
+	}
+	references := findStatusCondition(in.conditions, conditionReferencesResolved)
+	if references.Status == api.ConditionFalse && (references.Reason == referenceNotGrantedReason || in.holdsUnresolved) {
+		return references, true
+	}
+	return statusCondition{}, false
+}
+
+// degradedBy returns the condition that degrades the resource although it was reconciled, if any.
+func (in readinessInput) degradedBy() (statusCondition, bool) {
+	for _, conditionType := range []string{conditionNameCollision, conditionOwnershipConflict, conditionDrifted} {
+		if condition := findStatusCondition(in.conditions, conditionType); condition.Status == api.ConditionTrue {
+			return condition, true
+		}
+	}
+	return statusCondition{}, false
+}
+
+// readinessConditions derives the Ready, Reconciled and Degraded conditions of a resource. A failed reconcile
+// makes the resource not ready, not reconciled and degraded with the reason of the error. Otherwise the
+// resource is reconciled unless a condition held it off, degraded when it drifted or conflicts with others,
+// and ready when it is reconciled and App Mesh reports it as active.
+func readinessConditions(in readinessInput) []statusCondition {
+	if in.err != nil {
+		reason, message := errorCondition(in.err)
+		return []statusCondition{
+			{Type: conditionReady, Status: api.ConditionFalse, Reason: reason, Message: message},
+			{Type: conditionReconciled, Status: api.ConditionFalse, Reason: reason, Message: message},
+			{Type: conditionDegraded, Status: api.ConditionTrue, Reason: reason, Message: message},
+		}
+	}
+
+	reconciled := statusCondition{Type: conditionReconciled, Status: api.ConditionTrue, Reason: reasonReconcileSucceeded}
+	if held, ok := in.heldBy(); ok {
+		reconciled = statusCondition{Type: conditionReconciled, Status: api.ConditionFalse, Reason: held.Reason, Message: held.Message}
+	}
+
+	degraded := statusCondition{Type: conditionDegraded, Status: api.ConditionFalse, Reason: reasonAsExpected}
+	if cause, ok := in.degradedBy(); ok {
+		degraded = statusCondition{Type: conditionDegraded, Status: api.ConditionTrue, Reason: cause.Reason, Message: cause.Message}
+	}
+
+	ready := statusCondition{Type: conditionReady, Status: api.ConditionTrue, Reason: reasonActive}
+	if reconciled.Status != api.ConditionTrue {
+		ready = statusCondition{Type: conditionReady, Status: api.ConditionFalse, Reason: reconciled.Reason, Message: reconciled.Message}
+	} else {
+		var inactive []string
+		for _, activeType := range in.activeTypes {
+			if findStatusCondition(in.conditions, activeType).Status != api.ConditionTrue {
+				inactive = append(inactive, activeType)
+			}
+		}
+		if len(inactive) > 0 {
+			ready = statusCondition{Type: conditionReady, Status: api.ConditionFalse, Reason: reasonNotActive, Message: fmt.Sprintf("Not true: %s", strings.Join(inactive, ", "))}
+		}
+	}
+
+	return []statusCondition{ready, reconciled, degraded}
+}
+
+func meshStatusConditions(conditions []appmeshv1beta1.MeshCondition) []statusCondition {
+	var converted []statusCondition
+	for _, condition := range conditions {
+		converted = append(converted, statusCondition{
+			Type:    string(condition.Type),
+			Status:  condition.Status,
+			Reason:  awssdk.StringValue(condition.Reason),
+			Message: awssdk.StringValue(condition.Message),
+		})
+	}
+	return converted
+}
+
+func vnodeStatusConditions(conditions []appmeshv1beta1.VirtualNodeCondition) []statusCondition {
+	var converted []statusCondition
+	for _, condition := range conditions {
+		converted = append(converted, statusCondition{
+			Type:    string(condition.Type),
+			Status:  condition.Status,
+			Reason:  awssdk.StringValue(condition.Reason),
+			Message: awssdk.StringValue(condition.Message),
+		})
+	}
+	return converted
+}
+
+func vserviceStatusConditions(conditions []appmeshv1beta1.VirtualServiceCondition) []statusCondition {
+	var converted []statusCondition
+	for _, condition := range conditions {
+		converted = append(converted, statusCondition{
+			Type:    string(condition.Type),
+			Status:  condition.Status,
+			Reason:  awssdk.StringValue(condition.Reason),
+			Message: awssdk.StringValue(condition.Message),
+		})
+	}
+	return converted
+}
+
+// transitionTime returns the transition time of a condition that changes from current to status.
+func transitionTime(current api.ConditionStatus, status api.ConditionStatus, last *metav1.Time) *metav1.Time {
+	if current == status && last != nil {
+		return last
+	}
+	now := metav1.Now()
+	return &now
+}
+
+// updateMeshReadiness sets the Ready, Reconciled and Degraded conditions of the mesh after a reconcile that
+// returned err, and returns the error writing them, if any.
+func (c *Controller) updateMeshReadiness(mesh *appmeshv1beta1.Mesh, err error) error {
+	if mesh == nil || !mesh.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	changed := false
+	for _, condition := range readinessConditions(readinessInput{
+		err:         err,
+		conditions:  meshStatusConditions(mesh.Status.Conditions),
+		activeTypes: []string{string(appmeshv1beta1.MeshActive)},
+	}) {
+		conditionType := appmeshv1beta1.MeshConditionType(condition.Type)
+		current := getMeshCondition(conditionType, mesh.Status)
+		if current.Status == condition.Status && awssdk.StringValue(current.Reason) == condition.Reason && awssdk.StringValue(current.Message) == condition.Message {
+			continue
+		}
+		changed = true
+		mesh.Status.Conditions = setMeshConditionInList(mesh.Status.Conditions, appmeshv1beta1.MeshCondition{
+			Type:               conditionType,
+			Status:             condition.Status,
+			LastTransitionTime: transitionTime(current.Status, condition.Status, current.LastTransitionTime),
+			Reason:             awssdk.String(condition.Reason),
+			Message:            awssdk.String(condition.Message),
+		})
+	}
+	if !changed {
+		return nil
+	}
+	if err := c.setMeshStatusConditions(mesh, mesh.Status.Conditions); err != nil {
+		klog.Errorf("Error updating readiness of mesh %s: %s", mesh.Name, err)
+		return err
+	}
+	return nil
+}
+
+// updateVNodeReadiness sets the Ready, Reconciled and Degraded conditions of the virtual node after a reconcile
+// that returned err, and returns the error writing them, if any.
+func (c *Controller) updateVNodeReadiness(vnode *appmeshv1beta1.VirtualNode, err error) error {
+	if vnode == nil || !vnode.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	changed := false
+	for _, condition := range readinessConditions(readinessInput{
+		err:         err,
+		conditions:  vnodeStatusConditions(vnode.Status.Conditions),
+		activeTypes: []string{string(appmeshv1beta1.VirtualNodeActive)},
+	}) {
+		conditionType := appmeshv1beta1.VirtualNodeConditionType(condition.Type)
+		current := getVNodeCondition(conditionType, vnode.Status)
+		if current.Status == condition.Status && awssdk.StringValue(current.Reason) == condition.Reason && awssdk.StringValue(current.Message) == condition.Message {
+			continue
+		}
+		changed = true
+		vnode.Status.Conditions = setVNodeConditionInList(vnode.Status.Conditions, appmeshv1beta1.VirtualNodeCondition{
+			Type:               conditionType,
+			Status:             condition.Status,
+			LastTransitionTime: transitionTime(current.Status, condition.Status, current.LastTransitionTime),
+			Reason:             awssdk.String(condition.Reason),
+			Message:            awssdk.String(condition.Message),
+		})
+	}
+	if !changed {
+		return nil
+	}
+	if err := c.setVirtualNodeStatusConditions(vnode, vnode.Status.Conditions); err != nil {
+		klog.Errorf("Error updating readiness of virtual node %s/%s: %s", vnode.Namespace, vnode.Name, err)
+		return err
+	}
+	return nil
+}
+
+// updateVServiceReadiness sets the Ready, Reconciled and Degraded conditions of the virtual service after a
+// reconcile that returned err, and returns the error writing them, if any.
+func (c *Controller) updateVServiceReadiness(vservice *appmeshv1beta1.VirtualService, err error) error {
+	if vservice == nil || !vservice.DeletionTimestamp.IsZero() {
+		return nil
+	}
+	changed := false
+	for _, condition := range readinessConditions(readinessInput{
+		err:             err,
+		conditions:      vserviceStatusConditions(vservice.Status.Conditions),
+		activeTypes:     []string{string(appmeshv1beta1.VirtualServiceActive), string(appmeshv1beta1.VirtualRouterActive), string(appmeshv1beta1.RoutesActive)},
+		holdsUnresolved: waitForRouteTargets(vservice),
+	}) {
+		conditionType := appmeshv1beta1.VirtualServiceConditionType(condition.Type)
+		current := c.getVServiceCondition(conditionType, vservice.Status)
+		if current.Status == condition.Status && awssdk.StringValue(current.Reason) == condition.Reason && awssdk.StringValue(current.Message) == condition.Message {
+			continue
+		}
+		changed = true
+		vservice.Status.Conditions = setVServiceConditionInList(vservice.Status.Conditions, appmeshv1beta1.VirtualServiceCondition{
+			Type:               conditionType,
+			Status:             condition.Status,
+			LastTransitionTime: transitionTime(current.Status, condition.Status, current.LastTransitionTime),
+			Reason:             awssdk.String(condition.Reason),
+			Message:            awssdk.String(condition.Message),
+		})
+	}
+	if !changed {
+		return nil
+	}
+	if err := c.setVirtualServiceStatusConditions(vservice, vservice.Status.Conditions); err != nil {
+		klog.Errorf("Error updating readiness of virtual service %s/%s: %s", vservice.Namespace, vservice.Name, err)
+		return err
+	}
+	return nil
+}
+
+func setVNodeConditionInList(conditions []appmeshv1beta1.VirtualNodeCondition, condition appmeshv1beta1.VirtualNodeCondition) []appmeshv1beta1.VirtualNodeCondition {
+	for i := range conditions {
+		if conditions[i].Type == condition.Type {
+			conditions[i] = condition
+			return conditions
+		}
+	}
+	return append(conditions, condition)
+}
+
+func setVServiceConditionInList(conditions []appmeshv1beta1.VirtualServiceCondition, condition appmeshv1beta1.VirtualServiceCondition) []appmeshv1beta1.VirtualServiceCondition {
+	for i := range conditions {
+		if conditions[i].Type == condition.Type {
+			conditions[i] = condition
+			return conditions
+		}
+	}
+	return append(conditions, condition)
+}
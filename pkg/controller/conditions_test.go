@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned/fake"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestErrorCondition(t *testing.T) {
+	cause := awserr.New(appmesh.ErrCodeForbiddenException, "not allowed", nil)
+	reason, message := errorCondition(reconcileErrorf(reasonCreateFailed, cause, "error creating virtual node: %s", cause))
+	if reason != reasonCreateFailed {
+		t.Errorf("got reason %s, want %s", reason, reasonCreateFailed)
+	}
+	if want := fmt.Sprintf("error creating virtual node: %s (AWS error code ForbiddenException)", cause); message != want {
+		t.Errorf("got message %q, want %q", message, want)
+	}
+
+	// Request IDs differ on every retry, so they are left out to keep the conditions unchanged
+	for _, requestID := range []string{"1", "2"} {
+		failure := awserr.NewRequestFailure(awserr.New(appmesh.ErrCodeTooManyRequestsException, "slow down", nil), 429, requestID)
+		_, message = errorCondition(reconcileErrorf(reasonUpdateFailed, failure, "error updating virtual node: %s", failure))
+		if want := "error updating virtual node: TooManyRequestsException: slow down (AWS error code TooManyRequestsException)"; message != want {
+			t.Errorf("got message %q, want %q", message, want)
+		}
+	}
+
+	reason, message = errorCondition(fmt.Errorf("boom"))
+	if reason != reasonReconcileFailed || message != "boom" {
+		t.Errorf("unexpected reason %s and message %s for a plain error", reason, message)
+	}
+}
+
+func TestReadinessConditions(t *testing.T) {
+	active := statusCondition{Type: string(appmeshv1beta1.VirtualNodeActive), Status: api.ConditionTrue}
+	var tests = []struct {
+		name       string
+		in         readinessInput
+		ready      api.ConditionStatus
+		reconciled api.ConditionStatus
+		degraded   api.ConditionStatus
+		reason     string
+	}{
+		{
+			name:       "active",
+			in:         readinessInput{conditions: []statusCondition{active}},
+			ready:      api.ConditionTrue,
+			reconciled: api.ConditionTrue,
+			degraded:   api.ConditionFalse,
+			reason:     reasonActive,
+		},
+		{
+			name:       "not active",
+			in:         readinessInput{conditions: []statusCondition{{Type: active.Type, Status: api.ConditionFalse}}},
+			ready:      api.ConditionFalse,
+			reconciled: api.ConditionTrue,
+			degraded:   api.ConditionFalse,
+			reason:     reasonNotActive,
+		},
+		{
+			name:       "error",
+			in:         readinessInput{err: reconcileErrorf(reasonUpdateFailed, nil, "error updating virtual node"), conditions: []statusCondition{active}},
+			ready:      api.ConditionFalse,
+			reconciled: api.ConditionFalse,
+			degraded:   api.ConditionTrue,
+			reason:     reasonUpdateFailed,
+		},
+		{
+			name:       "paused",
+			in:         readinessInput{conditions: []statusCondition{active, {Type: conditionPaused, Status: api.ConditionTrue, Reason: pausedReason}}},
+			ready:      api.ConditionFalse,
+			reconciled: api.ConditionFalse,
+			degraded:   api.ConditionFalse,
+			reason:     pausedReason,
+		},
+		{
+			name:       "drifted",
+			in:         readinessInput{conditions: []statusCondition{active, {Type: conditionDrifted, Status: api.ConditionTrue, Reason: driftDetectedReason}}},
+			ready:      api.ConditionTrue,
+			reconciled: api.ConditionTrue,
+			degraded:   api.ConditionTrue,
+			reason:     reasonActive,
+		},
+		{
+			name:       "name kept",
+			in:         readinessInput{conditions: []statusCondition{active, {Type: conditionNameCollision, Status: api.ConditionTrue, Reason: nameCollisionReason}}},
+			ready:      api.ConditionTrue,
+			reconciled: api.ConditionTrue,
+			degraded:   api.ConditionTrue,
+			reason:     reasonActive,
+		},
+		{
+			name:       "name yielded",
+			in:         readinessInput{conditions: []statusCondition{active, {Type: conditionNameCollision, Status: api.ConditionTrue, Reason: nameYieldedReason}}},
+			ready:      api.ConditionFalse,
+			reconciled: api.ConditionFalse,
+			degraded:   api.ConditionTrue,
+			reason:     nameYieldedReason,
+		},
+		{
+			name:       "unresolved references",
+			in:         readinessInput{conditions: []statusCondition{active, {Type: conditionReferencesResolved, Status: api.ConditionFalse, Reason: unresolvedReferencesReason}}},
+			ready:      api.ConditionTrue,
+			reconciled: api.ConditionTrue,
+			degraded:   api.ConditionFalse,
+			reason:     reasonActive,
+		},
+		{
+			name:       "unresolved references held",
+			in:         readinessInput{conditions: []statusCondition{active, {Type: conditionReferencesResolved, Status: api.ConditionFalse, Reason: unresolvedReferencesReason}}, holdsUnresolved: true},
+			ready:      api.ConditionFalse,
+			reconciled: api.ConditionFalse,
+			degraded:   api.ConditionFalse,
+			reason:     unresolvedReferencesReason,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.in.activeTypes = []string{active.Type}
+			conditions := readinessConditions(tt.in)
+			statuses := []api.ConditionStatus{conditions[0].Status, conditions[1].Status, conditions[2].Status}
+			if want := []api.ConditionStatus{tt.ready, tt.reconciled, tt.degraded}; !reflect.DeepEqual(statuses, want) {
+				t.Errorf("got Ready, Reconciled, Degraded %v, want %v", statuses, want)
+			}
+			if conditions[0].Reason != tt.reason {
+				t.Errorf("got Ready reason %s, want %s", conditions[0].Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestUpdateVNodeReadiness(t *testing.T) {
+	vnode := newDependencyVNode("red", api.ConditionTrue)
+	clientset := fake.NewSimpleClientset(vnode)
+	c := &Controller{meshclientset: clientset}
+
+	c.updateVNodeReadiness(vnode, nil)
+	updated, err := clientset.AppmeshV1beta1().VirtualNodes("ns").Get("red", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if condition := getVNodeCondition(appmeshv1beta1.VirtualNodeReady, updated.Status); condition.Status != api.ConditionTrue || condition.LastTransitionTime == nil {
+		t.Errorf("expected the virtual node to be ready, got %+v", condition)
+	}
+
+	clientset.ClearActions()
+	c.updateVNodeReadiness(vnode, nil)
+	if actions := clientset.Actions(); len(actions) != 0 {
+		t.Errorf("expected unchanged readiness not to update the status, got %v", actions)
+	}
+}
@@ -1,5 +1,11 @@
 package controller
 
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
 type ClientOptions struct {
 	Master     string
 	Kubeconfig string
@@ -8,3 +14,43 @@ type ClientOptions struct {
 type ServerOptions struct {
 	Address string
 }
+
+// WebhookOptions configures the validating admission webhook of virtual nodes.
+type WebhookOptions struct {
+	// Address is the address the webhook is served on over TLS.
+	Address string
+	// CertDir holds the tls.crt and tls.key of the serving certificate. The webhook is not served without them.
+	CertDir string
+}
+
+// ScopeOptions limits the objects a controller manages, so that several controllers can share a cluster.
+type ScopeOptions struct {
+	// Namespaces are the namespaces watched for pods, virtual nodes and virtual services. Empty means all namespaces.
+	Namespaces []string
+	// LabelSelector selects the App Mesh custom resources to manage. Empty means all resources.
+	LabelSelector string
+}
+
+// InformerNamespace returns the namespace informers can be restricted to. Informers only support a single
+// namespace, so watching several namespaces falls back to cluster-wide informers filtered by the controller.
+func (o ScopeOptions) InformerNamespace() string {
+	if len(o.Namespaces) == 1 {
+		return o.Namespaces[0]
+	}
+	return metav1.NamespaceAll
+}
+
+// TweakListOptions restricts list and watch requests for App Mesh custom resources to the label selector.
+func (o ScopeOptions) TweakListOptions(options *metav1.ListOptions) {
+	options.LabelSelector = o.LabelSelector
+}
+
+// ShardOptions configures replicas to divide ownership of meshes between them instead of electing a single leader.
+type ShardOptions struct {
+	// Enabled turns on sharding by mesh. Leader election is not used while sharding.
+	Enabled bool
+	// LeaseNamespace is the namespace of the membership leases. Empty means the namespace of the controller pod.
+	LeaseNamespace string
+	// LeaseDuration is how long a replica remains a member after it last renewed its lease.
+	LeaseDuration time.Duration
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"time"
 
 	"k8s.io/apimachinery/pkg/util/uuid"
@@ -37,6 +38,9 @@ const (
 	DefaultElection          = true
 	DefaultElectionID        = "app-mesh-controller-leader"
 	DefaultElectionNamespace = ""
+	DefaultWarmStandby       = false
+	// DefaultWarmStandbySyncPeriod is how often a standby replica refreshes its state snapshot
+	DefaultWarmStandbySyncPeriod = 1 * time.Minute
 
 	controllerAgentName                 = "app-mesh-controller"
 	meshDeletionFinalizerName           = "meshDeletion.finalizers.appmesh.k8s.aws"
@@ -52,8 +56,13 @@ type Controller struct {
 	// meshclientset is a clientset for our own API group
 	meshclientset meshclientset.Interface
 
-	podsLister corev1listers.PodLister
-	podsSynced cache.InformerSynced
+	podsLister    corev1listers.PodLister
+	podsSynced    cache.InformerSynced
+	secretsLister corev1listers.SecretLister
+	secretsSynced cache.InformerSynced
+	// namespaceLister reads the labels that make namespaces join meshes
+	namespaceLister corev1listers.NamespaceLister
+	namespaceSynced cache.InformerSynced
 
 	meshLister           meshlisters.MeshLister
 	meshIndex            cache.Indexer
@@ -64,6 +73,10 @@ type Controller struct {
 	virtualServiceLister meshlisters.VirtualServiceLister
 	virtualServiceIndex  cache.Indexer
 	virtualServiceSynced cache.InformerSynced
+	referenceGrantLister meshlisters.ReferenceGrantLister
+	referenceGrantSynced cache.InformerSynced
+	meshDefaultsLister   meshlisters.MeshDefaultsLister
+	meshDefaultsSynced   cache.InformerSynced
 
 	// workqueue is a rate limited work queue. This is used to queue work to be
 	// processed instead of performing it as soon as a change happens. This
@@ -79,6 +92,10 @@ type Controller struct {
 	// Kubernetes API.
 	recorder record.EventRecorder
 
+	// events deduplicates the warnings and instance registrations recorded
+	// by recorder.
+	events *eventDeduper
+
 	// stats records mesh Prometheus metrics
 	stats *metrics.Recorder
 
@@ -93,6 +110,44 @@ type Controller struct {
 	// LeaderElectionNamespace determines the namespace in which the leader
 	// election configmap will be created.
 	leaderElectionNamespace string
+
+	// warmStandby determines whether replicas that are not leading keep a
+	// snapshot of App Mesh and Cloud Map state primed for a takeover.
+	warmStandby bool
+
+	// standbySyncPeriod is how often a warm standby refreshes its snapshot.
+	standbySyncPeriod time.Duration
+
+	// snapshot is the state primed by a warm standby.
+	snapshot *stateSnapshot
+
+	// scope limits the namespaces and resources this controller manages.
+	scope *scope
+
+	// sharding configures replicas to divide ownership of meshes instead of
+	// electing a single leader.
+	sharding ShardOptions
+
+	// shards decides which meshes this replica owns. It is nil without sharding.
+	shards *shardMembership
+
+	// driftChecks lets resyncs of objects with an unchanged spec skip App Mesh
+	// until their drift check is due.
+	driftChecks *driftChecks
+
+	// driftPolicy is either enforce, to overwrite App Mesh resources that
+	// drifted from their spec, or report. Objects may override it.
+	driftPolicy string
+
+	// clusterID identifies this cluster in the tags of App Mesh resources
+	// the controller creates. Ownership is not checked without it.
+	clusterID string
+
+	// owners remembers App Mesh resources known to belong to a custom resource.
+	owners *owners
+
+	// naming maps custom resources onto App Mesh names.
+	naming namingStrategy
 }
 
 func NewController(
@@ -100,17 +155,44 @@ func NewController(
 	kubeclientset kubernetes.Interface,
 	meshclientset meshclientset.Interface,
 	podInformer coreinformers.PodInformer,
+	secretInformer coreinformers.SecretInformer,
+	namespaceInformer coreinformers.NamespaceInformer,
 	meshInformer meshinformers.MeshInformer,
 	virtualNodeInformer meshinformers.VirtualNodeInformer,
 	virtualServiceInformer meshinformers.VirtualServiceInformer,
+	referenceGrantInformer meshinformers.ReferenceGrantInformer,
+	meshDefaultsInformer meshinformers.MeshDefaultsInformer,
 	stats *metrics.Recorder,
 	leaderElection bool,
 	leaderElectionID string,
-	leaderElectionNamespace string) (*Controller, error) {
+	leaderElectionNamespace string,
+	warmStandby bool,
+	standbySyncPeriod time.Duration,
+	scopeOptions ScopeOptions,
+	shardOptions ShardOptions,
+	driftCheckInterval time.Duration,
+	driftPolicy string,
+	clusterID string,
+	namingStrategy string) (*Controller, error) {
+
+	scope, err := newScope(scopeOptions)
+	if err != nil {
+		return nil, err
+	}
+	if shardOptions.Enabled && shardOptions.LeaseDuration <= 0 {
+		return nil, fmt.Errorf("invalid shard lease duration %s", shardOptions.LeaseDuration)
+	}
+	if !ValidDriftPolicy(driftPolicy) {
+		return nil, fmt.Errorf("invalid drift policy %q, must be %s or %s", driftPolicy, DriftPolicyEnforce, DriftPolicyReport)
+	}
+	naming, err := newNamingStrategy(namingStrategy)
+	if err != nil {
+		return nil, err
+	}
 
 	utilruntime.Must(meshscheme.AddToScheme(scheme.Scheme))
 	klog.V(4).Info("Creating event broadcaster")
-	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster := newEventBroadcaster()
 	eventBroadcaster.StartLogging(klog.Infof)
 	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeclientset.CoreV1().Events("")})
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
@@ -122,63 +204,135 @@ func NewController(
 		meshclientset:           meshclientset,
 		podsLister:              podInformer.Lister(),
 		podsSynced:              podInformer.Informer().HasSynced,
+		secretsLister:           secretInformer.Lister(),
+		secretsSynced:           secretInformer.Informer().HasSynced,
+		namespaceLister:         namespaceInformer.Lister(),
+		namespaceSynced:         namespaceInformer.Informer().HasSynced,
 		meshLister:              meshInformer.Lister(),
 		meshSynced:              meshInformer.Informer().HasSynced,
 		virtualNodeLister:       virtualNodeInformer.Lister(),
 		virtualNodeSynced:       virtualNodeInformer.Informer().HasSynced,
 		virtualServiceLister:    virtualServiceInformer.Lister(),
 		virtualServiceSynced:    virtualServiceInformer.Informer().HasSynced,
+		referenceGrantLister:    referenceGrantInformer.Lister(),
+		referenceGrantSynced:    referenceGrantInformer.Informer().HasSynced,
+		meshDefaultsLister:      meshDefaultsInformer.Lister(),
+		meshDefaultsSynced:      meshDefaultsInformer.Informer().HasSynced,
 		mq:                      workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
 		nq:                      workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
 		sq:                      workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
 		pq:                      workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
 		recorder:                recorder,
+		events:                  newEventDeduper(eventDedupeWindow),
 		stats:                   stats,
 		leaderElection:          leaderElection,
 		leaderElectionID:        leaderElectionID,
 		leaderElectionNamespace: leaderElectionNamespace,
-	}
+		warmStandby:             warmStandby,
+		standbySyncPeriod:       standbySyncPeriod,
+		snapshot:                newStateSnapshot(),
+		scope:                   scope,
+		sharding:                shardOptions,
+		driftChecks:             newDriftChecks(driftCheckInterval),
+		driftPolicy:             driftPolicy,
+		clusterID:               clusterID,
+		owners:                  newOwners(),
+		naming:                  naming,
+	}
+	if shardOptions.Enabled {
+		controller.shards = newShardMembership(shardIdentity())
+	}
+
+	podInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.scope.containsNamespacedObject,
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    controller.podAdded,
+			UpdateFunc: controller.podUpdated,
+			DeleteFunc: controller.podDeleted,
+		},
+	})
+
+	secretInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.scope.containsNamespacedObject,
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    controller.secretAdded,
+			UpdateFunc: controller.secretUpdated,
+			DeleteFunc: controller.secretDeleted,
+		},
+	})
 
-	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    controller.podAdded,
-		UpdateFunc: controller.podUpdated,
-		DeleteFunc: controller.podDeleted,
+	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: controller.namespaceUpdated,
 	})
 
-	meshInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    controller.meshAdded,
-		UpdateFunc: controller.meshUpdated,
-		DeleteFunc: controller.meshDeleted,
+	meshInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.scope.containsResource,
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    controller.meshAdded,
+			UpdateFunc: controller.meshUpdated,
+			DeleteFunc: controller.meshDeleted,
+		},
 	})
 
-	virtualNodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    controller.virtualNodeAdded,
-		UpdateFunc: controller.virtualNodeUpdated,
-		DeleteFunc: controller.virtualNodeDeleted,
+	virtualNodeInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.scope.containsResource,
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    controller.virtualNodeAdded,
+			UpdateFunc: controller.virtualNodeUpdated,
+			DeleteFunc: controller.virtualNodeDeleted,
+		},
 	})
 
 	if err := virtualNodeInformer.Informer().GetIndexer().AddIndexers(cache.Indexers{
-		"meshName": indexVNodesByMeshName,
+		"meshName":                 indexVNodesByMeshName,
+		backendVirtualServiceIndex: indexVNodesByBackendVirtualService,
+		awsNameIndex:               controller.indexByAWSName,
+		secretRefIndex:             indexVNodesBySecretRef,
 	}); err != nil {
 		return nil, fmt.Errorf("failed to add meshName index: %s", err)
 	}
 
 	controller.virtualNodeIndex = virtualNodeInformer.Informer().GetIndexer()
 
-	virtualServiceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    controller.virtualServiceAdded,
-		UpdateFunc: controller.virtualServiceUpdated,
-		DeleteFunc: controller.virtualServiceDeleted,
+	virtualServiceInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.scope.containsResource,
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    controller.virtualServiceAdded,
+			UpdateFunc: controller.virtualServiceUpdated,
+			DeleteFunc: controller.virtualServiceDeleted,
+		},
 	})
 
 	if err := virtualServiceInformer.Informer().GetIndexer().AddIndexers(cache.Indexers{
-		"meshName": indexVServicesByMeshName,
+		"meshName":              indexVServicesByMeshName,
+		targetVirtualNodeIndex:  indexVServicesByTargetVirtualNode,
+		virtualServiceNameIndex: indexVServicesByName,
+		awsNameIndex:            controller.indexByAWSName,
 	}); err != nil {
 		return nil, fmt.Errorf("failed to add meshName index: %s", err)
 	}
 
 	controller.virtualServiceIndex = virtualServiceInformer.Informer().GetIndexer()
 
+	// Grants are not labeled like the resources they allow references to, so they are only scoped by namespace
+	referenceGrantInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.scope.containsNamespacedObject,
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    controller.referenceGrantAdded,
+			UpdateFunc: controller.referenceGrantUpdated,
+			DeleteFunc: controller.referenceGrantDeleted,
+		},
+	})
+
+	meshDefaultsInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.scope.containsNamespacedObject,
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    controller.meshDefaultsAdded,
+			UpdateFunc: controller.meshDefaultsUpdated,
+			DeleteFunc: controller.meshDefaultsDeleted,
+		},
+	})
+
 	controller.meshIndex = meshInformer.Informer().GetIndexer()
 
 	return controller, nil
@@ -189,11 +343,12 @@ func indexVNodesByMeshName(obj interface{}) ([]string, error) {
 	if !ok {
 		return []string{}, nil
 	}
-	// MeshName must be set
-	if len(node.Spec.MeshName) == 0 {
+	// The mesh must be set or resolved
+	meshName := vnodeMeshName(node)
+	if len(meshName) == 0 {
 		return []string{}, nil
 	}
-	return []string{node.Spec.MeshName}, nil
+	return []string{meshName}, nil
 }
 
 func indexVServicesByMeshName(obj interface{}) ([]string, error) {
@@ -201,11 +356,12 @@ func indexVServicesByMeshName(obj interface{}) ([]string, error) {
 	if !ok {
 		return []string{}, nil
 	}
-	// MeshName must be set
-	if len(node.Spec.MeshName) == 0 {
+	// The mesh must be set or resolved
+	meshName := vserviceMeshName(node)
+	if len(meshName) == 0 {
 		return []string{}, nil
 	}
-	return []string{node.Spec.MeshName}, nil
+	return []string{meshName}, nil
 }
 
 func (c *Controller) Run(threadiness int, stopCh chan struct{}) error {
@@ -220,7 +376,7 @@ func (c *Controller) Run(threadiness int, stopCh chan struct{}) error {
 	// Start the informer factories to begin populating the informer caches
 	// Wait for the caches to be synced before starting workers
 	klog.Info("Waiting for informer caches to sync")
-	if ok := cache.WaitForCacheSync(stopCh, c.podsSynced, c.meshSynced); !ok {
+	if ok := cache.WaitForCacheSync(stopCh, c.podsSynced, c.secretsSynced, c.namespaceSynced, c.meshSynced, c.virtualNodeSynced, c.virtualServiceSynced, c.referenceGrantSynced, c.meshDefaultsSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
@@ -232,6 +388,9 @@ func (c *Controller) Run(threadiness int, stopCh chan struct{}) error {
 		case <-ctx.Done():
 		}
 	}()
+	if c.sharding.Enabled {
+		return c.runWorkersWithSharding(ctx, threadiness)
+	}
 	if c.leaderElection {
 		return c.runWorkersWithLeaderElection(ctx, threadiness)
 	}
@@ -260,6 +419,13 @@ func (c *Controller) runWorkersWithLeaderElection(ctx context.Context, threadine
 	if err != nil {
 		return err
 	}
+	// Standbys keep their snapshot primed until they start leading
+	standbyCtx, stopStandby := context.WithCancel(ctx)
+	defer stopStandby()
+	if c.warmStandby {
+		go c.runStandby(standbyCtx)
+	}
+
 	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
 		Lock:          leaderElectionLock,
 		LeaseDuration: 60 * time.Second,
@@ -267,6 +433,7 @@ func (c *Controller) runWorkersWithLeaderElection(ctx context.Context, threadine
 		RetryPeriod:   5 * time.Second,
 		Callbacks: leaderelection.LeaderCallbacks{
 			OnStartedLeading: func(ctx context.Context) {
+				stopStandby()
 				c.runWorkers(ctx, threadiness)
 			},
 			OnStoppedLeading: func() {
@@ -343,6 +510,9 @@ func (c *Controller) meshAdded(obj interface{}) {
 	// If a mesh is created, process all objects with the meshName.
 	c.enqueueVNodesForMesh(meshName)
 	c.enqueueVServicesForMesh(meshName)
+	if mesh.Spec.NamespaceSelector != nil {
+		c.enqueueWithoutMesh(corev1.NamespaceAll)
+	}
 }
 
 func (c *Controller) enqueueVNodesForMesh(name string) {
@@ -352,12 +522,13 @@ func (c *Controller) enqueueVNodesForMesh(name string) {
 	} else {
 		for _, obj := range objects {
 			vnode, ok := obj.(*appmeshv1beta1.VirtualNode)
-			if !ok {
+			if !ok || !c.scope.containsResource(vnode) {
 				continue
 			}
 
 			key, err := cache.MetaNamespaceKeyFunc(obj)
 			if err == nil {
+				c.driftChecks.forget(driftCheckVirtualNode, key)
 				c.nq.Add(key)
 			} else {
 				continue
@@ -374,13 +545,14 @@ func (c *Controller) enqueueVServicesForMesh(name string) {
 	} else {
 		for _, obj := range objects {
 			vservice, ok := obj.(*appmeshv1beta1.VirtualService)
-			if !ok {
+			if !ok || !c.scope.containsResource(vservice) {
 				continue
 			}
 
 			key, err := cache.MetaNamespaceKeyFunc(obj)
 			if err == nil {
-				c.nq.Add(key)
+				c.driftChecks.forget(driftCheckVirtualService, key)
+				c.sq.Add(key)
 			} else {
 				continue
 			}
@@ -395,6 +567,18 @@ func (c *Controller) meshUpdated(old interface{}, new interface{}) {
 		klog.V(4).Infof("Mesh Updated: %s", key)
 		c.mq.Add(key)
 	}
+
+	// Virtual nodes and virtual services follow the pause annotation and TLS policy of their mesh
+	oldMesh, oldOk := old.(*appmeshv1beta1.Mesh)
+	newMesh, newOk := new.(*appmeshv1beta1.Mesh)
+	if oldOk && newOk && (pauseRequested(oldMesh) != pauseRequested(newMesh) || !reflect.DeepEqual(oldMesh.Spec.TLSPolicy, newMesh.Spec.TLSPolicy)) {
+		c.enqueueVNodesForMesh(newMesh.Name)
+		c.enqueueVServicesForMesh(newMesh.Name)
+	}
+	// Objects without a mesh name may join a mesh that selects their namespace
+	if oldOk && newOk && !reflect.DeepEqual(oldMesh.Spec.NamespaceSelector, newMesh.Spec.NamespaceSelector) {
+		c.enqueueWithoutMesh(corev1.NamespaceAll)
+	}
 }
 
 func (c *Controller) meshDeleted(obj interface{}) {
@@ -411,6 +595,10 @@ func (c *Controller) virtualNodeAdded(obj interface{}) {
 		klog.V(4).Infof("Virtual Node Added: %s", key)
 		c.nq.Add(key)
 	}
+	if vnode, ok := obj.(*appmeshv1beta1.VirtualNode); ok {
+		c.enqueueVServicesForVNode(vnode)
+		c.enqueueNameCollisions(c.virtualNodeIndex, c.nq, driftCheckVirtualNode, vnode)
+	}
 }
 
 func (c *Controller) virtualNodeUpdated(old interface{}, new interface{}) {
@@ -419,6 +607,16 @@ func (c *Controller) virtualNodeUpdated(old interface{}, new interface{}) {
 		klog.V(4).Infof("Virtual Node Updated: %s", key)
 		c.nq.Add(key)
 	}
+	oldVNode, oldOk := old.(*appmeshv1beta1.VirtualNode)
+	newVNode, newOk := new.(*appmeshv1beta1.VirtualNode)
+	if oldOk && newOk && vnodeDependencyChanged(oldVNode, newVNode) {
+		c.enqueueVServicesForVNode(newVNode)
+	}
+	// A changed spec may have moved the virtual node onto or off the App Mesh name of others
+	if oldOk && newOk && oldVNode.Generation != newVNode.Generation {
+		c.enqueueNameCollisions(c.virtualNodeIndex, c.nq, driftCheckVirtualNode, oldVNode)
+		c.enqueueNameCollisions(c.virtualNodeIndex, c.nq, driftCheckVirtualNode, newVNode)
+	}
 }
 
 func (c *Controller) virtualNodeDeleted(obj interface{}) {
@@ -427,6 +625,13 @@ func (c *Controller) virtualNodeDeleted(obj interface{}) {
 		klog.V(4).Infof("Virtual Node Deleted: %s", key)
 		c.nq.Add(key)
 	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if vnode, ok := obj.(*appmeshv1beta1.VirtualNode); ok {
+		c.enqueueVServicesForVNode(vnode)
+		c.enqueueNameCollisions(c.virtualNodeIndex, c.nq, driftCheckVirtualNode, vnode)
+	}
 }
 
 func (c *Controller) virtualServiceAdded(obj interface{}) {
@@ -435,6 +640,10 @@ func (c *Controller) virtualServiceAdded(obj interface{}) {
 		klog.V(4).Infof("Virtual Service Added: %s", key)
 		c.sq.Add(key)
 	}
+	if vservice, ok := obj.(*appmeshv1beta1.VirtualService); ok {
+		c.enqueueVNodesForVService(vservice)
+		c.enqueueNameCollisions(c.virtualServiceIndex, c.sq, driftCheckVirtualService, vservice)
+	}
 }
 
 func (c *Controller) virtualServiceUpdated(old interface{}, new interface{}) {
@@ -443,6 +652,15 @@ func (c *Controller) virtualServiceUpdated(old interface{}, new interface{}) {
 		klog.V(4).Infof("Virtual Service Updated: %s", key)
 		c.sq.Add(key)
 	}
+	oldVService, oldOk := old.(*appmeshv1beta1.VirtualService)
+	newVService, newOk := new.(*appmeshv1beta1.VirtualService)
+	if oldOk && newOk && c.vserviceDependencyChanged(oldVService, newVService) {
+		c.enqueueVNodesForVService(newVService)
+	}
+	if oldOk && newOk && oldVService.Generation != newVService.Generation {
+		c.enqueueNameCollisions(c.virtualServiceIndex, c.sq, driftCheckVirtualService, oldVService)
+		c.enqueueNameCollisions(c.virtualServiceIndex, c.sq, driftCheckVirtualService, newVService)
+	}
 }
 
 func (c *Controller) virtualServiceDeleted(obj interface{}) {
@@ -451,6 +669,13 @@ func (c *Controller) virtualServiceDeleted(obj interface{}) {
 		klog.V(4).Infof("Virtual Service Deleted: %s", key)
 		c.sq.Add(key)
 	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if vservice, ok := obj.(*appmeshv1beta1.VirtualService); ok {
+		c.enqueueVNodesForVService(vservice)
+		c.enqueueNameCollisions(c.virtualServiceIndex, c.sq, driftCheckVirtualService, vservice)
+	}
 }
 
 func (c *Controller) meshWorker() {
@@ -527,6 +752,8 @@ func processNextWorkItem(queue workqueue.RateLimitingInterface, syncHandler func
 	}(obj)
 
 	if err != nil {
+		// Retry with backoff, rather than relying on the status update of the failure to requeue the item
+		queue.AddRateLimited(obj)
 		runtime.HandleError(err)
 		return true
 	}
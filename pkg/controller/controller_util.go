@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
 )
 
 const inClusterNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
@@ -68,6 +71,20 @@ func namespacedResourceName(resourceName string, defaultResourceNamespace string
 	return resourceName + "-" + defaultResourceNamespace
 }
 
+// boolAnnotation reports whether obj has the annotation set to true. Invalid values are logged and ignored.
+func boolAnnotation(obj metav1.Object, annotation string) bool {
+	value, ok := obj.GetAnnotations()[annotation]
+	if !ok {
+		return false
+	}
+	set, err := strconv.ParseBool(value)
+	if err != nil {
+		klog.Errorf("Ignoring invalid %s annotation %q on %s", annotation, value, obj.GetName())
+		return false
+	}
+	return set
+}
+
 // getInClusterNamespace returns the namespace of the controller pod.
 func getInClusterNamespace() (string, error) {
 	// Check whether the namespace file exists.
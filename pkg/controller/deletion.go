@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// effectiveDeletionPolicy returns the deletion policy of a virtual node or virtual service, falling back to the
+// policy of its mesh and then to Delete. Unknown policies retain, since a typo should not delete anything.
+func (c *Controller) effectiveDeletionPolicy(policy appmeshv1beta1.DeletionPolicy, meshName string) appmeshv1beta1.DeletionPolicy {
+	if policy == "" && c.meshLister != nil {
+		if mesh, err := c.meshLister.Get(meshName); err == nil {
+			policy = mesh.Spec.DeletionPolicy
+		}
+	}
+	switch policy {
+	case "", appmeshv1beta1.DeletionPolicyDelete:
+		return appmeshv1beta1.DeletionPolicyDelete
+	case appmeshv1beta1.DeletionPolicyRetain:
+		return appmeshv1beta1.DeletionPolicyRetain
+	default:
+		klog.Errorf("Unknown deletion policy %s, retaining App Mesh resources", policy)
+		return appmeshv1beta1.DeletionPolicyRetain
+	}
+}
+
+// release removes the ownership tags from a retained App Mesh resource, so that the adopt annotation is not needed
+// to hand it over to another cluster or resource later. Resources that do not belong to obj keep their tags.
+func (c *Controller) release(ctx context.Context, obj metav1.Object, kind string, name string, metadata *appmesh.ResourceMetadata) error {
+	if !c.ownershipEnabled() || metadata == nil {
+		return nil
+	}
+	if err := c.checkOwnership(ctx, obj, kind, name, metadata, false); err != nil {
+		if isOwnershipConflict(err) {
+			klog.V(4).Infof("Not untagging retained %s %s: %s", kind, name, err)
+			return nil
+		}
+		return err
+	}
+	arn := awssdk.StringValue(metadata.Arn)
+	if err := c.cloud.UntagResource(ctx, arn, []string{tagKeyClusterID, tagKeyOwnerUID}); err != nil {
+		return fmt.Errorf("error untagging %s %s: %s", kind, name, err)
+	}
+	c.owners.forget(arn)
+	klog.Infof("Retained %s %s", kind, name)
+	return nil
+}
+
+// releaseVirtualNode releases the App Mesh virtual node of a virtual node with the Retain policy.
+func (c *Controller) releaseVirtualNode(ctx context.Context, vnode *appmeshv1beta1.VirtualNode, copy *appmeshv1beta1.VirtualNode) error {
+	if !c.ownershipEnabled() {
+		return nil
+	}
+	target, err := c.describeVirtualNode(ctx, vnode.Name, vnodeMeshName(vnode))
+	if err != nil {
+		if aws.IsAWSErrNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return c.release(ctx, copy, "virtual node", vnode.Name, target.Data.Metadata)
+}
+
+// releaseVServiceResources releases the App Mesh routes, virtual service and virtual router of a virtual service
+// with the Retain policy.
+func (c *Controller) releaseVServiceResources(ctx context.Context, vservice *appmeshv1beta1.VirtualService) error {
+	if !c.ownershipEnabled() {
+		return nil
+	}
+	routerName := vservice.Spec.VirtualRouter.Name
+	meshName := vserviceMeshName(vservice)
+
+	for _, r := range vservice.Spec.Routes {
+		target, err := c.cloud.GetRoute(ctx, r.Name, routerName, meshName)
+		if err == nil {
+			err = c.release(ctx, vservice, "route", r.Name, target.Data.Metadata)
+		}
+		if err != nil && !aws.IsAWSErrNotFound(err) {
+			return fmt.Errorf("failed to release route %s for virtual service %s during deletion: %s", r.Name, vservice.Name, err)
+		}
+	}
+
+	if target, err := c.describeVirtualService(ctx, vservice.Name, meshName); err == nil {
+		if err := c.release(ctx, vservice, "virtual service", vservice.Name, target.Data.Metadata); err != nil {
+			return fmt.Errorf("failed to release virtual service %s during deletion: %s", vservice.Name, err)
+		}
+	} else if !aws.IsAWSErrNotFound(err) {
+		return fmt.Errorf("failed to release virtual service %s during deletion: %s", vservice.Name, err)
+	}
+
+	if target, err := c.describeVirtualRouter(ctx, routerName, meshName); err == nil {
+		if err := c.release(ctx, vservice, "virtual router", routerName, target.Data.Metadata); err != nil {
+			return fmt.Errorf("failed to release virtual router %s for virtual service %s during deletion: %s", routerName, vservice.Name, err)
+		}
+	} else if !aws.IsAWSErrNotFound(err) {
+		return fmt.Errorf("failed to release virtual router %s for virtual service %s during deletion: %s", routerName, vservice.Name, err)
+	}
+	return nil
+}
+
+// meshResourcesRemaining counts the virtual nodes and virtual services of the mesh that still exist. A retained
+// mesh keeps its finalizer until they are gone, so that they still see its deletion policy when they are deleted.
+func (c *Controller) meshResourcesRemaining(name string) (int, error) {
+	remaining := 0
+	vnodes, err := c.virtualNodeIndex.ByIndex("meshName", name)
+	if err != nil {
+		return 0, fmt.Errorf("meshName index error for %s: %s", name, err)
+	}
+	for _, obj := range vnodes {
+		if vnode, ok := obj.(*appmeshv1beta1.VirtualNode); ok && c.scope.containsResource(vnode) {
+			remaining++
+		}
+	}
+	vservices, err := c.virtualServiceIndex.ByIndex("meshName", name)
+	if err != nil {
+		return 0, fmt.Errorf("meshName index error for %s: %s", name, err)
+	}
+	for _, obj := range vservices {
+		if vservice, ok := obj.(*appmeshv1beta1.VirtualService); ok && c.scope.containsResource(vservice) {
+			remaining++
+		}
+	}
+	return remaining, nil
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	ctrlawsmocks "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws/mocks"
+	meshfake "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned/fake"
+	meshlisters "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/listers/appmesh/v1beta1"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newMeshLister(meshes ...*appmeshv1beta1.Mesh) meshlisters.MeshLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, mesh := range meshes {
+		indexer.Add(mesh)
+	}
+	return meshlisters.NewMeshLister(indexer)
+}
+
+func TestEffectiveDeletionPolicy(t *testing.T) {
+	retainedMesh := &appmeshv1beta1.Mesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "retained"},
+		Spec:       appmeshv1beta1.MeshSpec{DeletionPolicy: appmeshv1beta1.DeletionPolicyRetain},
+	}
+	c := &Controller{meshLister: newMeshLister(retainedMesh, &appmeshv1beta1.Mesh{ObjectMeta: metav1.ObjectMeta{Name: "default"}})}
+
+	var tests = []struct {
+		name     string
+		policy   appmeshv1beta1.DeletionPolicy
+		meshName string
+		want     appmeshv1beta1.DeletionPolicy
+	}{
+		{name: "default", meshName: "default", want: appmeshv1beta1.DeletionPolicyDelete},
+		{name: "mesh default", meshName: "retained", want: appmeshv1beta1.DeletionPolicyRetain},
+		{name: "overrides mesh", policy: appmeshv1beta1.DeletionPolicyDelete, meshName: "retained", want: appmeshv1beta1.DeletionPolicyDelete},
+		{name: "explicit", policy: appmeshv1beta1.DeletionPolicyRetain, meshName: "default", want: appmeshv1beta1.DeletionPolicyRetain},
+		{name: "missing mesh", meshName: "missing", want: appmeshv1beta1.DeletionPolicyDelete},
+		{name: "unknown", policy: "retain", meshName: "default", want: appmeshv1beta1.DeletionPolicyRetain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.effectiveDeletionPolicy(tt.policy, tt.meshName); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleVNodeDeleteRetain(t *testing.T) {
+	var tests = []struct {
+		name         string
+		tags         map[string]string
+		wantUntagged bool
+	}{
+		{name: "owned", tags: map[string]string{tagKeyClusterID: "cluster", tagKeyOwnerUID: "uid"}, wantUntagged: true},
+		{name: "other owner", tags: map[string]string{tagKeyClusterID: "other", tagKeyOwnerUID: "uid"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			vnode := &appmeshv1beta1.VirtualNode{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-ns", Namespace: "ns", UID: "uid", Finalizers: []string{virtualNodeDeletionFinalizerName}},
+				Spec:       appmeshv1beta1.VirtualNodeSpec{MeshName: "mesh", DeletionPolicy: appmeshv1beta1.DeletionPolicyRetain},
+			}
+			meshclientset := meshfake.NewSimpleClientset(vnode)
+
+			// DeleteVirtualNode is not expected, so the mock fails the test if it is called
+			cloud := new(ctrlawsmocks.CloudAPI)
+			cloud.On("GetVirtualNode", ctx, "node-ns", "mesh").Return(&aws.VirtualNode{
+				Data: appmesh.VirtualNodeData{Metadata: &appmesh.ResourceMetadata{Arn: awssdk.String("arn")}},
+			}, nil)
+			cloud.On("ListTagsForResource", ctx, "arn").Return(tt.tags, nil)
+			if tt.wantUntagged {
+				cloud.On("UntagResource", ctx, "arn", []string{tagKeyClusterID, tagKeyOwnerUID}).Return(nil)
+			}
+			c := &Controller{cloud: cloud, meshclientset: meshclientset, clusterID: "cluster", owners: newOwners()}
+
+			if err := c.handleVNodeDelete(ctx, vnode, vnode.DeepCopy()); err != nil {
+				t.Fatal(err)
+			}
+			cloud.AssertExpectations(t)
+			updated, err := meshclientset.AppmeshV1beta1().VirtualNodes("ns").Get("node-ns", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(updated.Finalizers) != 0 {
+				t.Errorf("expected the finalizer to be removed, got %v", updated.Finalizers)
+			}
+		})
+	}
+}
+
+func TestHandleMeshDeleteRetain(t *testing.T) {
+	ctx := context.Background()
+	mesh := &appmeshv1beta1.Mesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "mesh", Finalizers: []string{meshDeletionFinalizerName}},
+		Spec:       appmeshv1beta1.MeshSpec{DeletionPolicy: appmeshv1beta1.DeletionPolicyRetain},
+	}
+	vnode := &appmeshv1beta1.VirtualNode{
+		ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "ns"},
+		Spec:       appmeshv1beta1.VirtualNodeSpec{MeshName: "mesh"},
+	}
+	meshclientset := meshfake.NewSimpleClientset(vnode)
+	tracker := meshclientset.Tracker()
+	if err := tracker.Create(appmeshv1beta1.SchemeGroupVersion.WithResource("meshes"), mesh, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	newIndex := func(objs ...interface{}) cache.Indexer {
+		index := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{"meshName": indexVNodesByMeshName})
+		for _, obj := range objs {
+			index.Add(obj)
+		}
+		return index
+	}
+	// DeleteMesh is not expected, so the mock fails the test if it is called
+	c := &Controller{
+		cloud:               new(ctrlawsmocks.CloudAPI),
+		meshclientset:       meshclientset,
+		meshLister:          newMeshLister(mesh),
+		virtualNodeIndex:    newIndex(vnode),
+		virtualServiceIndex: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{"meshName": indexVServicesByMeshName}),
+	}
+
+	// The virtual node falls back to the policy of the mesh, so the mesh waits for it to be deleted
+	if err := c.handleMeshDelete(ctx, mesh.DeepCopy()); err == nil {
+		t.Fatal("expected the mesh to wait for its virtual node")
+	}
+
+	c.virtualNodeIndex = newIndex()
+	if err := c.handleMeshDelete(ctx, mesh.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := meshclientset.AppmeshV1beta1().Meshes().Get("mesh", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.Finalizers) != 0 {
+		t.Errorf("expected the finalizer to be removed, got %v", updated.Finalizers)
+	}
+}
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	api "k8s.io/api/core/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+const (
+	// targetVirtualNodeIndex indexes virtual services by the namespace/name keys of the virtual nodes their
+	// routes target
+	targetVirtualNodeIndex = "targetVirtualNode"
+	// backendVirtualServiceIndex indexes virtual nodes by the mesh/name keys of their backend virtual services
+	backendVirtualServiceIndex = "backendVirtualService"
+	// virtualServiceNameIndex indexes virtual services by mesh/name, since their names are unique within a mesh
+	// rather than a namespace
+	virtualServiceNameIndex = "virtualServiceName"
+
+	waitingForDependencyReason = "WaitingForDependency"
+	dependenciesReadyReason    = "DependenciesReady"
+)
+
+// routeTargets returns the weighted targets of the route, whatever its protocol.
+func routeTargets(route appmeshv1beta1.Route) []appmeshv1beta1.WeightedTarget {
+	switch {
+	case route.Http != nil:
+		return route.Http.Action.WeightedTargets
+	case route.Http2 != nil:
+		return route.Http2.Action.WeightedTargets
+	case route.Grpc != nil:
+		return route.Grpc.Action.WeightedTargets
+	case route.Tcp != nil:
+		return route.Tcp.Action.WeightedTargets
+	}
+	return nil
+}
+
+func meshResourceKey(meshName string, name string) string {
+	return meshName + "/" + name
+}
+
+func indexVServicesByTargetVirtualNode(obj interface{}) ([]string, error) {
+	vservice, ok := obj.(*appmeshv1beta1.VirtualService)
+	if !ok {
+		return []string{}, nil
+	}
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, route := range vservice.Spec.Routes {
+		for _, target := range routeTargets(route) {
+			name, namespace := targetReference(target, vservice.Namespace)
+			key := namespace + "/" + name
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
+
+func indexVNodesByBackendVirtualService(obj interface{}) ([]string, error) {
+	vnode, ok := obj.(*appmeshv1beta1.VirtualNode)
+	if !ok || len(vnodeMeshName(vnode)) == 0 {
+		return []string{}, nil
+	}
+	keys := []string{}
+	for _, backend := range vnode.Spec.Backends {
+		keys = append(keys, meshResourceKey(vnodeMeshName(vnode), backend.VirtualService.VirtualServiceName))
+	}
+	return keys, nil
+}
+
+func indexVServicesByName(obj interface{}) ([]string, error) {
+	vservice, ok := obj.(*appmeshv1beta1.VirtualService)
+	if !ok || len(vserviceMeshName(vservice)) == 0 {
+		return []string{}, nil
+	}
+	return []string{meshResourceKey(vserviceMeshName(vservice), vservice.Name)}, nil
+}
+
+// vnodeDependencyChanged reports whether a virtual node changed in a way that matters to the virtual services
+// targeting it: its spec, or whether it is active in App Mesh.
+func vnodeDependencyChanged(old *appmeshv1beta1.VirtualNode, new *appmeshv1beta1.VirtualNode) bool {
+	return old.Generation != new.Generation ||
+		getVNodeCondition(appmeshv1beta1.VirtualNodeActive, old.Status).Status != getVNodeCondition(appmeshv1beta1.VirtualNodeActive, new.Status).Status
+}
+
+// vserviceDependencyChanged reports whether a virtual service changed in a way that matters to the virtual nodes
+// using it as a backend: its spec, or whether it is active in App Mesh.
+func (c *Controller) vserviceDependencyChanged(old *appmeshv1beta1.VirtualService, new *appmeshv1beta1.VirtualService) bool {
+	return old.Generation != new.Generation ||
+		c.getVServiceCondition(appmeshv1beta1.VirtualServiceActive, old.Status).Status != c.getVServiceCondition(appmeshv1beta1.VirtualServiceActive, new.Status).Status
+}
+
+// enqueueVServicesForVNode enqueues the virtual services with routes that target the virtual node.
+func (c *Controller) enqueueVServicesForVNode(vnode *appmeshv1beta1.VirtualNode) {
+	objects, err := c.virtualServiceIndex.ByIndex(targetVirtualNodeIndex, vnode.Namespace+"/"+vnode.Name)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("%s index error for %s: %s", targetVirtualNodeIndex, vnode.Name, err))
+		return
+	}
+	for _, obj := range objects {
+		if !c.scope.containsResource(obj) {
+			continue
+		}
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			continue
+		}
+		c.driftChecks.forget(driftCheckVirtualService, key)
+		c.sq.Add(key)
+		klog.V(4).Infof("Enqueued virtual service %s, which targets virtual node %s", key, vnode.Name)
+	}
+}
+
+// enqueueVNodesForVService enqueues the virtual nodes with the virtual service as a backend.
+func (c *Controller) enqueueVNodesForVService(vservice *appmeshv1beta1.VirtualService) {
+	objects, err := c.virtualNodeIndex.ByIndex(backendVirtualServiceIndex, meshResourceKey(vserviceMeshName(vservice), vservice.Name))
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("%s index error for %s: %s", backendVirtualServiceIndex, vservice.Name, err))
+		return
+	}
+	for _, obj := range objects {
+		if !c.scope.containsResource(obj) {
+			continue
+		}
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			continue
+		}
+		c.driftChecks.forget(driftCheckVirtualNode, key)
+		c.nq.Add(key)
+		klog.V(4).Infof("Enqueued virtual node %s, which has virtual service %s as a backend", key, vservice.Name)
+	}
+}
+
+// vnodeWaitingFor returns the backend virtual services of the virtual node that have a custom resource, but are
+// not active in App Mesh yet. App Mesh rejects virtual nodes with backends that do not exist.
+func (c *Controller) vnodeWaitingFor(vnode *appmeshv1beta1.VirtualNode) []string {
+	if c.virtualServiceIndex == nil {
+		return nil
+	}
+	var waiting []string
+	for _, backend := range vnode.Spec.Backends {
+		name := backend.VirtualService.VirtualServiceName
+		objects, err := c.virtualServiceIndex.ByIndex(virtualServiceNameIndex, meshResourceKey(vnodeMeshName(vnode), name))
+		if err != nil {
+			klog.Errorf("%s index error for %s: %s", virtualServiceNameIndex, name, err)
+			continue
+		}
+		for _, obj := range objects {
+			vservice, ok := obj.(*appmeshv1beta1.VirtualService)
+			if ok && c.getVServiceCondition(appmeshv1beta1.VirtualServiceActive, vservice.Status).Status != api.ConditionTrue {
+				waiting = append(waiting, "virtual service "+name)
+			}
+		}
+	}
+	return sortedUnique(waiting)
+}
+
+// vserviceWaitingFor returns the virtual nodes targeted by routes of the virtual service that have a custom
+// resource, but are not active in App Mesh yet. App Mesh rejects routes to virtual nodes that do not exist.
+func (c *Controller) vserviceWaitingFor(vservice *appmeshv1beta1.VirtualService) []string {
+	if c.virtualNodeLister == nil {
+		return nil
+	}
+	var waiting []string
+	for _, route := range vservice.Spec.Routes {
+		for _, target := range routeTargets(route) {
+			name, namespace := targetReference(target, vservice.Namespace)
+			vnode, err := c.virtualNodeLister.VirtualNodes(namespace).Get(name)
+			if err != nil {
+				continue
+			}
+			if getVNodeCondition(appmeshv1beta1.VirtualNodeActive, vnode.Status).Status != api.ConditionTrue {
+				waiting = append(waiting, "virtual node "+name)
+			}
+		}
+	}
+	return sortedUnique(waiting)
+}
+
+func sortedUnique(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Strings(values)
+	unique := values[:1]
+	for _, value := range values[1:] {
+		if value != unique[len(unique)-1] {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// dependencyCondition returns the status, reason and message of the WaitingForDependency condition.
+func dependencyCondition(waiting []string) (api.ConditionStatus, string, string) {
+	if len(waiting) == 0 {
+		return api.ConditionFalse, dependenciesReadyReason, ""
+	}
+	return api.ConditionTrue, waitingForDependencyReason, fmt.Sprintf("Waiting for %s to become active", strings.Join(waiting, ", "))
+}
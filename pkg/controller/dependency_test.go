@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	meshlisters "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/listers/appmesh/v1beta1"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func newDependencyVService(active api.ConditionStatus) *appmeshv1beta1.VirtualService {
+	return &appmeshv1beta1.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{Name: "color.ns.svc.cluster.local", Namespace: "ns"},
+		Spec: appmeshv1beta1.VirtualServiceSpec{
+			MeshName: "mesh",
+			Routes: []appmeshv1beta1.Route{
+				{Name: "http", Http: &appmeshv1beta1.HttpRoute{Action: appmeshv1beta1.HttpRouteAction{
+					WeightedTargets: []appmeshv1beta1.WeightedTarget{{VirtualNodeName: "red"}, {VirtualNodeName: "blue"}},
+				}}},
+				{Name: "grpc", Grpc: &appmeshv1beta1.GrpcRoute{Action: appmeshv1beta1.GrpcRouteAction{
+					WeightedTargets: []appmeshv1beta1.WeightedTarget{{VirtualNodeName: "red"}},
+				}}},
+			},
+		},
+		Status: appmeshv1beta1.VirtualServiceStatus{Conditions: []appmeshv1beta1.VirtualServiceCondition{
+			{Type: appmeshv1beta1.VirtualServiceActive, Status: active},
+		}},
+	}
+}
+
+func newDependencyVNode(name string, active api.ConditionStatus) *appmeshv1beta1.VirtualNode {
+	return &appmeshv1beta1.VirtualNode{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+		Spec: appmeshv1beta1.VirtualNodeSpec{
+			MeshName: "mesh",
+			Backends: []appmeshv1beta1.Backend{{VirtualService: appmeshv1beta1.VirtualServiceBackend{VirtualServiceName: "color.ns.svc.cluster.local"}}},
+		},
+		Status: appmeshv1beta1.VirtualNodeStatus{Conditions: []appmeshv1beta1.VirtualNodeCondition{
+			{Type: appmeshv1beta1.VirtualNodeActive, Status: active},
+		}},
+	}
+}
+
+func newDependencyController(vservices []*appmeshv1beta1.VirtualService, vnodes []*appmeshv1beta1.VirtualNode) *Controller {
+	vserviceIndex := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		targetVirtualNodeIndex:  indexVServicesByTargetVirtualNode,
+		virtualServiceNameIndex: indexVServicesByName,
+	})
+	for _, vservice := range vservices {
+		vserviceIndex.Add(vservice)
+	}
+	vnodeIndex := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		backendVirtualServiceIndex: indexVNodesByBackendVirtualService,
+	})
+	for _, vnode := range vnodes {
+		vnodeIndex.Add(vnode)
+	}
+	return &Controller{
+		virtualServiceIndex: vserviceIndex,
+		virtualNodeIndex:    vnodeIndex,
+		virtualNodeLister:   meshlisters.NewVirtualNodeLister(vnodeIndex),
+		nq:                  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		sq:                  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+}
+
+func TestDependencyIndexes(t *testing.T) {
+	if keys, _ := indexVServicesByTargetVirtualNode(newDependencyVService(api.ConditionTrue)); !reflect.DeepEqual(keys, []string{"ns/red", "ns/blue"}) {
+		t.Errorf("unexpected target keys %v", keys)
+	}
+	if keys, _ := indexVServicesByName(newDependencyVService(api.ConditionTrue)); !reflect.DeepEqual(keys, []string{"mesh/color.ns.svc.cluster.local"}) {
+		t.Errorf("unexpected name keys %v", keys)
+	}
+	if keys, _ := indexVNodesByBackendVirtualService(newDependencyVNode("red", api.ConditionTrue)); !reflect.DeepEqual(keys, []string{"mesh/color.ns.svc.cluster.local"}) {
+		t.Errorf("unexpected backend keys %v", keys)
+	}
+}
+
+func TestWaitingFor(t *testing.T) {
+	c := newDependencyController(
+		[]*appmeshv1beta1.VirtualService{newDependencyVService(api.ConditionFalse)},
+		[]*appmeshv1beta1.VirtualNode{newDependencyVNode("red", api.ConditionTrue), newDependencyVNode("blue", "")},
+	)
+
+	if waiting := c.vserviceWaitingFor(newDependencyVService(api.ConditionFalse)); !reflect.DeepEqual(waiting, []string{"virtual node blue"}) {
+		t.Errorf("expected the virtual service to wait for blue, got %v", waiting)
+	}
+	if waiting := c.vnodeWaitingFor(newDependencyVNode("red", api.ConditionTrue)); !reflect.DeepEqual(waiting, []string{"virtual service color.ns.svc.cluster.local"}) {
+		t.Errorf("expected the virtual node to wait for its backend, got %v", waiting)
+	}
+
+	// References without a custom resource are left to App Mesh
+	vnode := newDependencyVNode("green", "")
+	vnode.Spec.Backends[0].VirtualService.VirtualServiceName = "external.example.com"
+	if waiting := c.vnodeWaitingFor(vnode); len(waiting) != 0 {
+		t.Errorf("expected no waiting for a backend without a custom resource, got %v", waiting)
+	}
+
+	status, reason, message := dependencyCondition([]string{"virtual node blue"})
+	if status != api.ConditionTrue || reason != waitingForDependencyReason || message != "Waiting for virtual node blue to become active" {
+		t.Errorf("unexpected condition %s, %s, %s", status, reason, message)
+	}
+}
+
+func TestEnqueueDependents(t *testing.T) {
+	vservice := newDependencyVService(api.ConditionFalse)
+	red := newDependencyVNode("red", "")
+	c := newDependencyController([]*appmeshv1beta1.VirtualService{vservice}, []*appmeshv1beta1.VirtualNode{red})
+
+	// Status updates that don't change whether the virtual node is active don't enqueue anything
+	c.virtualNodeUpdated(red, red.DeepCopy())
+	if c.sq.Len() != 0 {
+		t.Fatalf("expected no virtual services to be enqueued, got %d", c.sq.Len())
+	}
+
+	activeRed := newDependencyVNode("red", api.ConditionTrue)
+	c.virtualNodeUpdated(red, activeRed)
+	if key, _ := c.sq.Get(); key != "ns/color.ns.svc.cluster.local" {
+		t.Errorf("expected the virtual service targeting red to be enqueued, got %v", key)
+	}
+
+	c.nq = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	c.virtualServiceUpdated(vservice, newDependencyVService(api.ConditionTrue))
+	if key, _ := c.nq.Get(); key != "ns/red" {
+		t.Errorf("expected red, which has the virtual service as a backend, to be enqueued, got %v", key)
+	}
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"strings"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog"
+)
+
+const (
+	// DriftPolicyEnforce overwrites App Mesh resources that drifted from their spec
+	DriftPolicyEnforce = "enforce"
+	// DriftPolicyReport leaves drifted App Mesh resources alone and reports the drift instead
+	DriftPolicyReport = "report"
+	// DefaultDriftPolicy keeps overwriting out-of-band edits, as the controller always did
+	DefaultDriftPolicy = DriftPolicyEnforce
+
+	// annotationDriftPolicy overrides the global drift policy for a mesh, virtual node or virtual service
+	annotationDriftPolicy = "appmesh.k8s.aws/driftPolicy"
+
+	driftDetectedReason = "DriftDetected"
+	inSyncReason        = "InSync"
+
+	// maxDriftMessageLength keeps conditions and events readable when many fields drifted
+	maxDriftMessageLength = 1024
+)
+
+// ValidDriftPolicy reports whether policy is a known drift policy.
+func ValidDriftPolicy(policy string) bool {
+	return policy == DriftPolicyEnforce || policy == DriftPolicyReport
+}
+
+// driftPolicyFor returns the drift policy of the object, which is the global policy unless the object
+// overrides it with an annotation.
+func (c *Controller) driftPolicyFor(obj metav1.Object) string {
+	if policy, ok := obj.GetAnnotations()[annotationDriftPolicy]; ok {
+		if ValidDriftPolicy(policy) {
+			return policy
+		}
+		klog.Errorf("Ignoring invalid %s annotation %q on %s", annotationDriftPolicy, policy, obj.GetName())
+	}
+	if c.driftPolicy == "" {
+		return DefaultDriftPolicy
+	}
+	return c.driftPolicy
+}
+
+// reportDrift records drift of an App Mesh resource with an event and a metric.
+func (c *Controller) reportDrift(obj runtime.Object, kind string, meshName string, name string, drift []string) {
+	message := driftMessage(drift)
+	klog.Infof("%s %s in mesh %s drifted from its spec: %s", kind, name, meshName, message)
+	c.stats.RecordDrift(kind, meshName, name)
+	if c.recorder != nil {
+		c.recorder.Eventf(obj, api.EventTypeWarning, driftDetectedReason, "%s %s drifted from its spec: %s", kind, name, message)
+	}
+}
+
+func driftMessage(drift []string) string {
+	message := strings.Join(drift, "; ")
+	if len(message) > maxDriftMessageLength {
+		message = message[:maxDriftMessageLength-3] + "..."
+	}
+	return message
+}
+
+// driftCondition returns the status, reason and message of the Drifted condition for the given drift.
+func driftCondition(drift []string) (api.ConditionStatus, string, string) {
+	if len(drift) == 0 {
+		return api.ConditionFalse, inSyncReason, ""
+	}
+	return api.ConditionTrue, driftDetectedReason, driftMessage(drift)
+}
+
+// setMeshDrifted sets the Drifted condition of the mesh. A mesh that never drifted gets no condition.
+func (c *Controller) setMeshDrifted(mesh *appmeshv1beta1.Mesh, drift []string) error {
+	status, reason, message := driftCondition(drift)
+	return c.setMeshReasonCondition(mesh, appmeshv1beta1.MeshDrifted, status, reason, message)
+}
+
+// setVNodeDrifted sets the Drifted condition of the virtual node. A virtual node that never drifted gets no
+// condition.
+func (c *Controller) setVNodeDrifted(vnode *appmeshv1beta1.VirtualNode, drift []string) (*appmeshv1beta1.VirtualNode, error) {
+	status, reason, message := driftCondition(drift)
+	return c.setVNodeReasonCondition(vnode, appmeshv1beta1.VirtualNodeDrifted, status, reason, message)
+}
+
+// setVServiceDrifted sets the Drifted condition of the virtual service. A virtual service that never drifted
+// gets no condition.
+func (c *Controller) setVServiceDrifted(vservice *appmeshv1beta1.VirtualService, drift []string) (*appmeshv1beta1.VirtualService, error) {
+	status, reason, message := driftCondition(drift)
+	return c.setVServiceReasonCondition(vservice, appmeshv1beta1.VirtualServiceDrifted, status, reason, message)
+}
+
+// virtualServiceDrift returns the fields in which the App Mesh virtual service differs from the desired spec.
+func virtualServiceDrift(desired *appmeshv1beta1.VirtualService, target *aws.VirtualService) []string {
+	var desiredRouter string
+	if desired.Spec.VirtualRouter != nil {
+		desiredRouter = desired.Spec.VirtualRouter.Name
+	}
+	return diffFields("spec.virtualRouter.name", desiredRouter, target.VirtualRouterName())
+}
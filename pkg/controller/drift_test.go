@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDriftPolicyFor(t *testing.T) {
+	var tests = []struct {
+		name        string
+		global      string
+		annotations map[string]string
+		want        string
+	}{
+		{name: "default", want: DriftPolicyEnforce},
+		{name: "global", global: DriftPolicyReport, want: DriftPolicyReport},
+		{
+			name:        "annotation overrides global",
+			global:      DriftPolicyReport,
+			annotations: map[string]string{annotationDriftPolicy: DriftPolicyEnforce},
+			want:        DriftPolicyEnforce,
+		},
+		{
+			name:        "invalid annotation is ignored",
+			global:      DriftPolicyReport,
+			annotations: map[string]string{annotationDriftPolicy: "ignore"},
+			want:        DriftPolicyReport,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Controller{driftPolicy: tt.global}
+			obj := &appmeshv1beta1.VirtualNode{ObjectMeta: metav1.ObjectMeta{Name: "node", Annotations: tt.annotations}}
+			if got := c.driftPolicyFor(obj); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDriftCondition(t *testing.T) {
+	if status, reason, message := driftCondition(nil); status != api.ConditionFalse || reason != inSyncReason || message != "" {
+		t.Errorf("unexpected condition without drift: %s %s %s", status, reason, message)
+	}
+	status, reason, message := driftCondition([]string{"a", "b"})
+	if status != api.ConditionTrue || reason != driftDetectedReason || message != "a; b" {
+		t.Errorf("unexpected condition with drift: %s %s %s", status, reason, message)
+	}
+}
@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+)
+
+const (
+	createdReason              = "Created"
+	updatedReason              = "Updated"
+	deletedReason              = "Deleted"
+	instanceRegisteredReason   = "InstanceRegistered"
+	instanceDeregisteredReason = "InstanceDeregistered"
+
+	// eventDedupeWindow is how long a warning is not recorded again for the same object, reason and AWS error
+	// code. Failed reconciles are requeued with the backoff of the work queues, and would otherwise record an
+	// event on every retry.
+	eventDedupeWindow = 10 * time.Minute
+	// maxDedupedEvents bounds the memory the deduper keeps, expired entries are pruned beyond it
+	maxDedupedEvents = 4096
+
+	// eventBurst and eventQPS rate-limit the events recorded for each object, on top of the deduper
+	eventBurst = 10
+	eventQPS   = 1.0 / 60
+)
+
+// newEventBroadcaster returns a broadcaster that aggregates similar events and rate-limits the events of each
+// object.
+func newEventBroadcaster() record.EventBroadcaster {
+	return record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{
+		BurstSize: eventBurst,
+		QPS:       eventQPS,
+	})
+}
+
+// eventDeduper suppresses events that repeat an event recorded within a window.
+type eventDeduper struct {
+	window time.Duration
+
+	lock     sync.Mutex
+	recorded map[string]time.Time
+}
+
+func newEventDeduper(window time.Duration) *eventDeduper {
+	return &eventDeduper{
+		window:   window,
+		recorded: map[string]time.Time{},
+	}
+}
+
+// allow reports whether an event with the given key may be recorded at now, and remembers it if so. A nil
+// deduper allows every event.
+func (d *eventDeduper) allow(key string, now time.Time) bool {
+	if d == nil {
+		return true
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if last, ok := d.recorded[key]; ok && now.Sub(last) < d.window {
+		return false
+	}
+	if len(d.recorded) >= maxDedupedEvents {
+		for k, last := range d.recorded {
+			if now.Sub(last) >= d.window {
+				delete(d.recorded, k)
+			}
+		}
+	}
+	d.recorded[key] = now
+	return true
+}
+
+// eventKey identifies the object an event is recorded for, along with what the event is about.
+func eventKey(obj runtime.Object, reason string, about string) string {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		key = fmt.Sprintf("%T", obj)
+	}
+	return fmt.Sprintf("%T/%s/%s/%s", obj, key, reason, about)
+}
+
+// recordMutation records a Normal event on obj for a create, update or delete call against App Mesh or Cloud
+// Map.
+func (c *Controller) recordMutation(obj runtime.Object, reason string, format string, args ...interface{}) {
+	if c.recorder == nil || obj == nil {
+		return
+	}
+	c.recorder.Eventf(obj, api.EventTypeNormal, reason, format, args...)
+}
+
+// recordInstanceEvent records a Normal event for registering or deregistering an instance in Cloud Map on the
+// virtual node custom resource that owns the instance and, while it exists, on the pod of the instance. Instances
+// are synced periodically, so repeated events are deduplicated.
+func (c *Controller) recordInstanceEvent(vnode *appmeshv1beta1.VirtualNode, pod *api.Pod, reason string, format string, args ...interface{}) {
+	if c.recorder == nil {
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	for _, obj := range instanceEventObjects(vnode, pod) {
+		if c.events.allow(eventKey(obj, reason, message), time.Now()) {
+			c.recorder.Event(obj, api.EventTypeNormal, reason, message)
+		}
+	}
+}
+
+// recordInstanceFailure records a Warning event for a failed Cloud Map call for an instance on the same objects
+// as recordInstanceEvent.
+func (c *Controller) recordInstanceFailure(vnode *appmeshv1beta1.VirtualNode, pod *api.Pod, err error) {
+	for _, obj := range instanceEventObjects(vnode, pod) {
+		c.recordFailure(obj, err)
+	}
+}
+
+// instanceEventObjects returns the objects events about an instance are recorded on, leaving out the ones that
+// are not known.
+func instanceEventObjects(vnode *appmeshv1beta1.VirtualNode, pod *api.Pod) []runtime.Object {
+	var objects []runtime.Object
+	if vnode != nil {
+		objects = append(objects, vnode)
+	}
+	if pod != nil {
+		objects = append(objects, pod)
+	}
+	return objects
+}
+
+// recordFailure records a Warning event on obj for a failed reconcile or AWS call, with the reason and AWS error
+// code of err. A failure with the same reason and AWS error code is recorded once per eventDedupeWindow, since
+// AWS error messages carry request IDs that differ on every retry.
+func (c *Controller) recordFailure(obj runtime.Object, err error) {
+	if err == nil || c.recorder == nil || obj == nil {
+		return
+	}
+	reason, message := errorCondition(err)
+	about := awsErrorCode(err)
+	if about == "" {
+		about = message
+	}
+	if !c.events.allow(eventKey(obj, reason, about), time.Now()) {
+		klog.V(4).Infof("Not recording repeated %s event: %s", reason, message)
+		return
+	}
+	c.recorder.Event(obj, api.EventTypeWarning, reason, message)
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	api "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEventDeduper(t *testing.T) {
+	d := newEventDeduper(time.Minute)
+	now := time.Unix(1000, 0)
+
+	if !d.allow("a", now) {
+		t.Error("expected the first event to be allowed")
+	}
+	if d.allow("a", now.Add(30*time.Second)) {
+		t.Error("expected a repeated event within the window to be suppressed")
+	}
+	if !d.allow("b", now.Add(30*time.Second)) {
+		t.Error("expected another event to be allowed")
+	}
+	if !d.allow("a", now.Add(time.Minute)) {
+		t.Error("expected the event to be allowed again after the window")
+	}
+
+	var nilDeduper *eventDeduper
+	if !nilDeduper.allow("a", now) || !nilDeduper.allow("a", now) {
+		t.Error("expected a nil deduper to allow every event")
+	}
+}
+
+func TestRecordFailure(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	c := &Controller{recorder: recorder, events: newEventDeduper(eventDedupeWindow)}
+	vnode := newDependencyVNode("red", api.ConditionTrue)
+
+	first := awserr.New(appmesh.ErrCodeTooManyRequestsException, "request id: 1", nil)
+	second := awserr.New(appmesh.ErrCodeTooManyRequestsException, "request id: 2", nil)
+	c.recordFailure(vnode, reconcileErrorf(reasonUpdateFailed, first, "error updating virtual node: %s", first))
+	c.recordFailure(vnode, reconcileErrorf(reasonUpdateFailed, second, "error updating virtual node: %s", second))
+	c.recordFailure(vnode, nil)
+
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected the repeated failure to be recorded once, got %d events", len(recorder.Events))
+	}
+	want := "Warning UpdateFailed error updating virtual node: " + first.Error() + " (AWS error code TooManyRequestsException)"
+	if event := <-recorder.Events; event != want {
+		t.Errorf("got event %q, want %q", event, want)
+	}
+
+	c.recordMutation(vnode, createdReason, "Created App Mesh virtual node %s", "red-ns")
+	c.recordMutation(vnode, createdReason, "Created App Mesh virtual node %s", "red-ns")
+	if len(recorder.Events) != 2 {
+		t.Errorf("expected every mutation to be recorded, got %d events", len(recorder.Events))
+	}
+
+	(&Controller{}).recordFailure(vnode, first)
+}
@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// Export describes a mesh and its virtual nodes and virtual services in App Mesh and converts them into custom
+// resources in the given namespace. Resources are named so that the controller, using the given naming strategy,
+// maps them back onto the existing App Mesh resources and, since they are annotated for adoption, takes them over
+// instead of creating new ones. Virtual nodes and virtual routers whose App Mesh names no custom resource name maps
+// onto keep them through awsName. Resources that cannot be expressed as custom resources are left out, and the
+// reasons are returned as warnings.
+func Export(ctx context.Context, cloud aws.CloudAPI, meshName string, namespace string, namingStrategy string) ([]runtime.Object, []string, error) {
+	naming, err := newNamingStrategy(namingStrategy)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := cloud.GetMesh(ctx, meshName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error describing mesh %s: %s", meshName, err)
+	}
+	vnodes, err := cloud.ListVirtualNodes(ctx, meshName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing virtual nodes of mesh %s: %s", meshName, err)
+	}
+	vservices, err := cloud.ListVirtualServices(ctx, meshName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error listing virtual services of mesh %s: %s", meshName, err)
+	}
+
+	var warnings []string
+	objects := []runtime.Object{exportMesh(target)}
+
+	// Routes target virtual nodes by the names of their custom resources
+	vnodeNames := map[string]string{}
+	exportedNames := map[string]bool{}
+	sort.Slice(vnodes, func(i, j int) bool { return vnodes[i].Name() < vnodes[j].Name() })
+	for _, vnode := range vnodes {
+		exported, err := exportVirtualNode(vnode, meshName, namespace, naming)
+		if err == nil && exportedNames[exported.Name] {
+			err = fmt.Errorf("another virtual node is already exported as %s", exported.Name)
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipping virtual node %s: %s", vnode.Name(), err))
+			continue
+		}
+		vnodeNames[vnode.Name()] = exported.Name
+		exportedNames[exported.Name] = true
+		objects = append(objects, exported)
+	}
+
+	sort.Slice(vservices, func(i, j int) bool { return vservices[i].Name() < vservices[j].Name() })
+	for _, vservice := range vservices {
+		exported, warning, err := exportVirtualService(ctx, cloud, vservice, meshName, namespace, naming, vnodeNames)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipping virtual service %s: %s", vservice.Name(), err))
+			continue
+		}
+		if warning != "" {
+			warnings = append(warnings, fmt.Sprintf("virtual service %s: %s", vservice.Name(), warning))
+		}
+		objects = append(objects, exported)
+	}
+
+	return objects, warnings, nil
+}
+
+func exportMesh(target *aws.Mesh) *appmeshv1beta1.Mesh {
+	return &appmeshv1beta1.Mesh{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appmeshv1beta1.SchemeGroupVersion.String(),
+			Kind:       "Mesh",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: target.Name(),
+		},
+		Spec: target.Spec(),
+	}
+}
+
+func exportVirtualNode(target *aws.VirtualNode, meshName string, namespace string, naming namingStrategy) (*appmeshv1beta1.VirtualNode, error) {
+	name, awsName, err := exportName(naming, target.Name(), namespace)
+	if err != nil {
+		return nil, err
+	}
+	spec := target.Spec()
+	spec.MeshName = meshName
+	spec.AWSName = awsName
+	return &appmeshv1beta1.VirtualNode{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appmeshv1beta1.SchemeGroupVersion.String(),
+			Kind:       "VirtualNode",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Annotations: map[string]string{annotationAdopt: "true"},
+		},
+		Spec: spec,
+	}, nil
+}
+
+// exportVirtualService converts the virtual service along with its virtual router and routes. Virtual service
+// names are not namespaced, but the names of routers, routes and the virtual nodes routes target are. vnodeNames
+// maps the App Mesh names of exported virtual nodes onto the names of their custom resources.
+func exportVirtualService(ctx context.Context, cloud aws.CloudAPI, target *aws.VirtualService, meshName string, namespace string, naming namingStrategy, vnodeNames map[string]string) (*appmeshv1beta1.VirtualService, string, error) {
+	vservice := &appmeshv1beta1.VirtualService{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appmeshv1beta1.SchemeGroupVersion.String(),
+			Kind:       "VirtualService",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        target.Name(),
+			Namespace:   namespace,
+			Annotations: map[string]string{annotationAdopt: "true"},
+		},
+		Spec: appmeshv1beta1.VirtualServiceSpec{
+			MeshName: meshName,
+		},
+	}
+
+	if target.Data.Spec != nil && target.Data.Spec.Provider != nil && target.Data.Spec.Provider.VirtualNode != nil {
+		return nil, "", fmt.Errorf("it is provided by a virtual node, but virtual services only support virtual router providers")
+	}
+	routerName := target.VirtualRouterName()
+	if routerName == "" {
+		return vservice, "it has no provider, the controller will add a virtual router", nil
+	}
+
+	router, err := cloud.GetVirtualRouter(ctx, routerName, meshName)
+	if err != nil {
+		return nil, "", fmt.Errorf("error describing virtual router %s: %s", routerName, err)
+	}
+	vrouter := router.Spec()
+	if vrouter.Name, vrouter.AWSName, err = exportName(naming, vrouter.Name, namespace); err != nil {
+		return nil, "", err
+	}
+	vservice.Spec.VirtualRouter = &vrouter
+
+	routes, err := cloud.GetRoutesForVirtualRouter(ctx, routerName, meshName)
+	if err != nil {
+		return nil, "", fmt.Errorf("error describing routes of virtual router %s: %s", routerName, err)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Name() < routes[j].Name() })
+	for i := range routes {
+		route := routes[i].Spec()
+		if route.Name, err = unnamespacedResourceName(route.Name, namespace); err != nil {
+			return nil, "", err
+		}
+		targets := routeTargets(route)
+		for j := range targets {
+			if name, ok := vnodeNames[targets[j].VirtualNodeName]; ok {
+				targets[j].VirtualNodeName = name
+				continue
+			}
+			name, awsName, err := exportName(naming, targets[j].VirtualNodeName, namespace)
+			if err == nil && awsName != nil {
+				err = fmt.Errorf("route %s targets virtual node %s, which is not exported and no name maps onto", route.Name, targets[j].VirtualNodeName)
+			}
+			if err != nil {
+				return nil, "", err
+			}
+			targets[j].VirtualNodeName = name
+		}
+		vservice.Spec.Routes = append(vservice.Spec.Routes, route)
+	}
+
+	return vservice, "", nil
+}
+
+// unnamespacedResourceName undoes namespacedResourceName: it returns a name that namespacedResourceName maps
+// back onto the App Mesh name in the given namespace. Names ending in "-namespace" lose that suffix, other
+// names are only reachable through the "." form. App Mesh names without a "-" cannot be produced at all.
+func unnamespacedResourceName(appMeshName string, namespace string) (string, error) {
+	var name string
+	if suffix := "-" + namespace; strings.HasSuffix(appMeshName, suffix) && len(appMeshName) > len(suffix) {
+		name = strings.TrimSuffix(appMeshName, suffix)
+	} else if strings.Contains(appMeshName, "-") {
+		name = strings.ReplaceAll(appMeshName, "-", ".")
+	} else {
+		return "", fmt.Errorf("name %s cannot be managed from namespace %s, since the controller would add a -%s suffix", appMeshName, namespace, namespace)
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", fmt.Errorf("name %s has no valid custom resource name in namespace %s: %s", appMeshName, namespace, strings.Join(errs, ", "))
+	}
+	return name, nil
+}
+
+// exportName returns the name of the custom resource in the namespace that maps onto the App Mesh name of a
+// virtual node or virtual router. If the naming strategy maps no valid name onto it, the name is derived from the
+// App Mesh name and the App Mesh name is returned as well, to be kept through awsName.
+func exportName(naming namingStrategy, appMeshName string, namespace string) (string, *string, error) {
+	for _, name := range reversedNames(appMeshName, namespace) {
+		if len(validation.IsDNS1123Subdomain(name)) == 0 && naming.awsName(name, namespace) == appMeshName {
+			return name, nil, nil
+		}
+	}
+	name := strings.Trim(invalidNameCharacters.ReplaceAllString(strings.ToLower(appMeshName), "-"), "-.")
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", nil, fmt.Errorf("name %s has no valid custom resource name: %s", appMeshName, strings.Join(errs, ", "))
+	}
+	return name, awssdk.String(appMeshName), nil
+}
+
+// invalidNameCharacters matches the characters App Mesh allows in names but custom resource names do not
+var invalidNameCharacters = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// reversedNames returns the names that the legacy and hashed naming strategies may have mapped onto the App Mesh
+// name in the namespace.
+func reversedNames(appMeshName string, namespace string) []string {
+	var names []string
+	if name, err := unnamespacedResourceName(appMeshName, namespace); err == nil {
+		names = append(names, name)
+	}
+	if i := len(appMeshName) - hashSuffixLength - 1; i > 0 && appMeshName[i] == '-' {
+		if name := strings.TrimSuffix(appMeshName[:i], "-"+namespace); name != appMeshName[:i] && name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// WriteManifests writes the objects as a multi-document YAML stream, leaving out their status.
+func WriteManifests(w io.Writer, objects []runtime.Object) error {
+	for _, obj := range objects {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		unstructured.RemoveNestedField(content, "status")
+		unstructured.RemoveNestedField(content, "metadata", "creationTimestamp")
+		manifest, err := yaml.Marshal(content)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", manifest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
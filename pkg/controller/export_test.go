@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	ctrlawsmocks "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws/mocks"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestUnnamespacedResourceName(t *testing.T) {
+	var tests = []struct {
+		appMeshName string
+		want        string
+		wantErr     bool
+	}{
+		{appMeshName: "colorteller-prod", want: "colorteller"},
+		{appMeshName: "color-teller-prod", want: "color-teller"},
+		{appMeshName: "colorteller-red", want: "colorteller.red"},
+		{appMeshName: "gateway", wantErr: true},
+		{appMeshName: "-prod", wantErr: true},
+		{appMeshName: "color--teller", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.appMeshName, func(t *testing.T) {
+			got, err := unnamespacedResourceName(tt.appMeshName, "prod")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if roundTrip := namespacedResourceName(got, "prod"); roundTrip != tt.appMeshName {
+				t.Errorf("namespacedResourceName(%s) is %s, want %s", got, roundTrip, tt.appMeshName)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	vnode := newAWSVirtualNode([]int64{8080}, []string{"http"}, []string{"color.prod.svc.cluster.local"}, "colorteller.prod.svc.cluster.local", nil)
+	vnode.Data.MeshName = awssdk.String("mesh")
+	vnode.Data.VirtualNodeName = awssdk.String("colorteller-prod")
+	gateway := newAWSVirtualNode(nil, nil, nil, "gateway.local", nil)
+	gateway.Data.MeshName = awssdk.String("mesh")
+	gateway.Data.VirtualNodeName = awssdk.String("gateway")
+
+	cloud := new(ctrlawsmocks.CloudAPI)
+	cloud.On("GetMesh", mock.Anything, "mesh").Return(&aws.Mesh{Data: appmesh.MeshData{MeshName: awssdk.String("mesh")}}, nil)
+	cloud.On("ListVirtualNodes", mock.Anything, "mesh").Return([]*aws.VirtualNode{vnode, gateway}, nil)
+	cloud.On("ListVirtualServices", mock.Anything, "mesh").Return([]*aws.VirtualService{{
+		Data: appmesh.VirtualServiceData{
+			VirtualServiceName: awssdk.String("color.prod.svc.cluster.local"),
+			Spec: &appmesh.VirtualServiceSpec{
+				Provider: &appmesh.VirtualServiceProvider{
+					VirtualRouter: &appmesh.VirtualRouterServiceProvider{VirtualRouterName: awssdk.String("color-router-prod")},
+				},
+			},
+		},
+	}}, nil)
+	cloud.On("GetVirtualRouter", mock.Anything, "color-router-prod", "mesh").Return(&aws.VirtualRouter{
+		Data: appmesh.VirtualRouterData{VirtualRouterName: awssdk.String("color-router-prod"), Spec: &appmesh.VirtualRouterSpec{}},
+	}, nil)
+	desiredRoute := appmeshv1beta1.Route{
+		Name: "color-route-prod",
+		Http: &appmeshv1beta1.HttpRoute{
+			Match: appmeshv1beta1.HttpRouteMatch{Prefix: "/"},
+			Action: appmeshv1beta1.HttpRouteAction{
+				WeightedTargets: []appmeshv1beta1.WeightedTarget{{VirtualNodeName: "colorteller-prod", Weight: 1}},
+			},
+		},
+	}
+	awsRoute := aws.Route{
+		Data: appmesh.RouteData{RouteName: awssdk.String("color-route-prod"), Spec: aws.BuildRouteSpec(&desiredRoute)},
+	}
+	cloud.On("GetRoutesForVirtualRouter", mock.Anything, "color-router-prod", "mesh").Return(aws.Routes{awsRoute}, nil)
+
+	objects, warnings, err := Export(context.Background(), cloud, "mesh", "prod", NamingStrategyLegacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %q", warnings)
+	}
+	if len(objects) != 4 {
+		t.Fatalf("expected a mesh, two virtual nodes and a virtual service, got %d objects", len(objects))
+	}
+
+	// The controller must map the exported virtual node back onto the existing one without changing it
+	exportedNode := objects[1].(*appmeshv1beta1.VirtualNode).DeepCopy()
+	(&Controller{}).mutateVirtualNodeForProcessing(exportedNode)
+	if exportedNode.Name != "colorteller-prod" {
+		t.Errorf("exported virtual node maps onto %s", exportedNode.Name)
+	}
+	if diff := vnodeSpecDiff(exportedNode, vnode); len(diff) > 0 {
+		t.Errorf("exported virtual node differs: %q", diff)
+	}
+
+	// No name maps onto the gateway in the namespace, so it keeps its App Mesh name through awsName
+	exportedGateway := objects[2].(*appmeshv1beta1.VirtualNode)
+	if exportedGateway.Name != "gateway" || awssdk.StringValue(exportedGateway.Spec.AWSName) != "gateway" {
+		t.Errorf("expected the gateway to be exported with its App Mesh name, got %s with awsName %v", exportedGateway.Name, awssdk.StringValue(exportedGateway.Spec.AWSName))
+	}
+
+	exportedService := objects[3].(*appmeshv1beta1.VirtualService)
+	if name := (&Controller{}).virtualRouterAWSName(exportedService); name != "color-router-prod" {
+		t.Errorf("exported virtual router maps onto %s", name)
+	}
+	route := exportedService.Spec.Routes[0]
+	route.Name = namespacedResourceName(route.Name, exportedService.Namespace)
+	route.Http.Action.WeightedTargets[0].VirtualNodeName = namespacedResourceName(route.Http.Action.WeightedTargets[0].VirtualNodeName, exportedService.Namespace)
+	if diff := routeDiff(route, awsRoute); len(diff) > 0 {
+		t.Errorf("exported route differs: %q", diff)
+	}
+
+	var out bytes.Buffer
+	if err := WriteManifests(&out, objects); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"kind: Mesh\n", "kind: VirtualNode\n", "name: colorteller\n", "kind: VirtualService\n", "name: color-router\n", "appmesh.k8s.aws/adopt: \"true\"\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected manifests to contain %q, got\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "status:") {
+		t.Errorf("expected manifests without status, got\n%s", out.String())
+	}
+}
+
+func TestExportName(t *testing.T) {
+	hashed := hashedNaming{}.awsName("colorteller", "prod")
+	var tests = []struct {
+		name        string
+		strategy    string
+		appMeshName string
+		want        string
+		wantAWSName bool
+	}{
+		{name: "legacy", strategy: NamingStrategyLegacy, appMeshName: "colorteller-prod", want: "colorteller"},
+		{name: "legacy other namespace", strategy: NamingStrategyLegacy, appMeshName: "colorteller-red", want: "colorteller.red"},
+		{name: "legacy without namespace", strategy: NamingStrategyLegacy, appMeshName: "gateway", want: "gateway", wantAWSName: true},
+		{name: "legacy invalid name", strategy: NamingStrategyLegacy, appMeshName: "Color_Teller", want: "color-teller", wantAWSName: true},
+		{name: "hashed", strategy: NamingStrategyHashed, appMeshName: hashed, want: "colorteller"},
+		{name: "hashed legacy name", strategy: NamingStrategyHashed, appMeshName: "colorteller-prod", want: "colorteller-prod", wantAWSName: true},
+		{name: "hashed wrong hash", strategy: NamingStrategyHashed, appMeshName: "colorteller-prod-00000000", want: "colorteller-prod-00000000", wantAWSName: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			naming, err := newNamingStrategy(tt.strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			name, awsName, err := exportName(naming, tt.appMeshName, "prod")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != tt.want || (awsName != nil) != tt.wantAWSName {
+				t.Errorf("got %s with awsName %v, want %s with awsName %t", name, awssdk.StringValue(awsName), tt.want, tt.wantAWSName)
+			}
+
+			// The controller must map the exported name back onto the App Mesh name
+			c := &Controller{naming: naming}
+			vnode := &appmeshv1beta1.VirtualNode{}
+			vnode.Name, vnode.Namespace, vnode.Spec.AWSName = name, "prod", awsName
+			if mapped := c.virtualNodeAWSName(vnode); mapped != tt.appMeshName {
+				t.Errorf("exported name maps onto %s, want %s", mapped, tt.appMeshName)
+			}
+		})
+	}
+}
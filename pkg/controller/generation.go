@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultDriftCheckInterval is how often an object whose spec has not changed is still reconciled against
+// App Mesh, to catch changes made outside of the controller.
+const DefaultDriftCheckInterval = 5 * time.Minute
+
+const (
+	driftCheckMesh           = "Mesh"
+	driftCheckVirtualNode    = "VirtualNode"
+	driftCheckVirtualService = "VirtualService"
+)
+
+// driftChecks remembers when objects were last reconciled against App Mesh. Together with the observed
+// generation in their status it lets informer resyncs skip the AWS calls for objects whose spec is unchanged.
+type driftChecks struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	checked map[string]time.Time
+}
+
+func newDriftChecks(interval time.Duration) *driftChecks {
+	return &driftChecks{
+		interval: interval,
+		checked:  map[string]time.Time{},
+	}
+}
+
+// due reports whether the object must be reconciled against App Mesh. That is the case when its spec changed
+// since it was last reconciled, when this process has not reconciled it yet, or when its drift check is due.
+// A nil tracker or an interval of zero or less reconciles on every resync.
+func (d *driftChecks) due(kind string, key string, generation int64, observedGeneration int64) bool {
+	if d == nil || d.interval <= 0 || generation != observedGeneration {
+		return true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	last, ok := d.checked[kind+"/"+key]
+	return !ok || time.Since(last) >= d.interval
+}
+
+// reconciled records that the object was just reconciled against App Mesh.
+func (d *driftChecks) reconciled(kind string, key string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.checked[kind+"/"+key] = time.Now()
+}
+
+// forget makes the next reconcile of the object go to App Mesh, e.g. after its mesh was recreated.
+func (d *driftChecks) forget(kind string, key string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.checked, kind+"/"+key)
+}
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDriftChecks(t *testing.T) {
+	d := newDriftChecks(time.Hour)
+
+	if !d.due(driftCheckVirtualNode, "ns/node", 1, 1) {
+		t.Errorf("expected object not reconciled by this process to be due")
+	}
+	d.reconciled(driftCheckVirtualNode, "ns/node")
+	if d.due(driftCheckVirtualNode, "ns/node", 1, 1) {
+		t.Errorf("expected unchanged object to be skipped")
+	}
+	if !d.due(driftCheckVirtualNode, "ns/node", 2, 1) {
+		t.Errorf("expected changed spec to be due")
+	}
+	if !d.due(driftCheckVirtualService, "ns/node", 1, 1) {
+		t.Errorf("expected kinds to be tracked separately")
+	}
+	d.forget(driftCheckVirtualNode, "ns/node")
+	if !d.due(driftCheckVirtualNode, "ns/node", 1, 1) {
+		t.Errorf("expected forgotten object to be due")
+	}
+
+	d.checked[driftCheckMesh+"/mesh"] = time.Now().Add(-2 * time.Hour)
+	if !d.due(driftCheckMesh, "mesh", 1, 1) {
+		t.Errorf("expected drift check to be due after the interval")
+	}
+
+	var disabled *driftChecks
+	disabled.reconciled(driftCheckMesh, "mesh")
+	if !disabled.due(driftCheckMesh, "mesh", 1, 1) || !newDriftChecks(0).due(driftCheckMesh, "mesh", 1, 1) {
+		t.Errorf("expected every resync to be due without drift checks")
+	}
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+const referenceNotGrantedReason = "ReferenceNotGranted"
+
+// targetReference returns the name and namespace of the virtual node a weighted target of a virtual service in
+// namespace references. A name that contains a "." is read as name.namespace, like the naming strategies do when
+// they resolve it, so it takes precedence over the namespace field.
+func targetReference(target appmeshv1beta1.WeightedTarget, namespace string) (string, string) {
+	if i := strings.Index(target.VirtualNodeName, "."); i >= 0 {
+		return target.VirtualNodeName[:i], target.VirtualNodeName[i+1:]
+	}
+	if target.Namespace != "" {
+		return target.VirtualNodeName, target.Namespace
+	}
+	return target.VirtualNodeName, namespace
+}
+
+// backendNamespace returns the namespace of the virtual service a backend of the virtual node references. Virtual
+// service names are unique within a mesh rather than a namespace, so the namespace of the virtual service custom
+// resource with the name takes precedence over the one the backend declares.
+func (c *Controller) backendNamespace(vnode *appmeshv1beta1.VirtualNode, backend appmeshv1beta1.Backend) string {
+	declared := vnode.Namespace
+	if backend.VirtualService.Namespace != "" {
+		declared = backend.VirtualService.Namespace
+	}
+	if c.virtualServiceIndex == nil {
+		return declared
+	}
+	key := meshResourceKey(vnodeMeshName(vnode), backend.VirtualService.VirtualServiceName)
+	objects, err := c.virtualServiceIndex.ByIndex(virtualServiceNameIndex, key)
+	if err != nil {
+		klog.Errorf("%s index error for %s: %s", virtualServiceNameIndex, key, err)
+		return declared
+	}
+	owner := ""
+	for _, obj := range objects {
+		vservice, ok := obj.(*appmeshv1beta1.VirtualService)
+		if !ok {
+			continue
+		}
+		// Should several namespaces claim the name, the one the grant check is least likely to allow is used
+		if owner == "" || owner == vnode.Namespace {
+			owner = vservice.Namespace
+		}
+	}
+	if owner == "" {
+		return declared
+	}
+	return owner
+}
+
+// grantAllows reports whether the grant allows resources in namespace from to reference the resource of kind
+// with the given name in the grant's namespace.
+func grantAllows(grant *appmeshv1beta1.ReferenceGrant, from string, kind appmeshv1beta1.ReferenceKind, name string) bool {
+	fromAllowed := false
+	for _, f := range grant.Spec.From {
+		if f.Namespace == from {
+			fromAllowed = true
+			break
+		}
+	}
+	if !fromAllowed {
+		return false
+	}
+	for _, to := range grant.Spec.To {
+		if to.Kind == kind && (to.Name == nil || awssdk.StringValue(to.Name) == name) {
+			return true
+		}
+	}
+	return false
+}
+
+// referenceAllowed reports whether a resource in namespace from may reference the resource of kind with the
+// given name in namespace to. References within a namespace are always allowed, references across namespaces
+// only when a ReferenceGrant in namespace to allows them.
+func (c *Controller) referenceAllowed(from string, to string, kind appmeshv1beta1.ReferenceKind, name string) bool {
+	if from == to {
+		return true
+	}
+	if c.referenceGrantLister == nil {
+		return false
+	}
+	grants, err := c.referenceGrantLister.ReferenceGrants(to).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Error listing reference grants in namespace %s: %s", to, err)
+		return false
+	}
+	for _, grant := range grants {
+		if grantAllows(grant, from, kind, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// deniedVNodeReferences returns the backends of the virtual node in other namespaces that no grant allows.
+func (c *Controller) deniedVNodeReferences(vnode *appmeshv1beta1.VirtualNode) []string {
+	var denied []string
+	for _, backend := range vnode.Spec.Backends {
+		namespace := c.backendNamespace(vnode, backend)
+		name := backend.VirtualService.VirtualServiceName
+		if !c.referenceAllowed(vnode.Namespace, namespace, appmeshv1beta1.ReferenceKindVirtualService, name) {
+			denied = append(denied, fmt.Sprintf("virtual service %s/%s", namespace, name))
+		}
+	}
+	return sortedUnique(denied)
+}
+
+// deniedVServiceReferences returns the route targets of the virtual service in other namespaces that no grant
+// allows.
+func (c *Controller) deniedVServiceReferences(vservice *appmeshv1beta1.VirtualService) []string {
+	var denied []string
+	for _, route := range vservice.Spec.Routes {
+		for _, target := range routeTargets(route) {
+			name, namespace := targetReference(target, vservice.Namespace)
+			if !c.referenceAllowed(vservice.Namespace, namespace, appmeshv1beta1.ReferenceKindVirtualNode, name) {
+				denied = append(denied, fmt.Sprintf("virtual node %s/%s", namespace, name))
+			}
+		}
+	}
+	return sortedUnique(denied)
+}
+
+func (c *Controller) referenceGrantAdded(obj interface{}) {
+	c.enqueueForReferenceGrant(obj)
+}
+
+func (c *Controller) referenceGrantUpdated(old interface{}, new interface{}) {
+	c.enqueueForReferenceGrant(old)
+	c.enqueueForReferenceGrant(new)
+}
+
+func (c *Controller) referenceGrantDeleted(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	c.enqueueForReferenceGrant(obj)
+}
+
+// enqueueForReferenceGrant enqueues the virtual nodes and virtual services in other namespaces that reference
+// resources in the namespace of the grant, since the grant may have allowed or denied them.
+func (c *Controller) enqueueForReferenceGrant(obj interface{}) {
+	grant, ok := obj.(*appmeshv1beta1.ReferenceGrant)
+	if !ok {
+		return
+	}
+	namespace := grant.Namespace
+
+	vnodes, err := c.listVirtualNodes()
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("error listing virtual nodes for reference grant %s/%s: %s", namespace, grant.Name, err))
+		return
+	}
+	for _, vnode := range vnodes {
+		if vnode.Namespace == namespace {
+			continue
+		}
+		for _, backend := range vnode.Spec.Backends {
+			if c.backendNamespace(vnode, backend) == namespace {
+				key := vnode.Namespace + "/" + vnode.Name
+				c.driftChecks.forget(driftCheckVirtualNode, key)
+				c.nq.Add(key)
+				klog.V(4).Infof("Enqueued virtual node %s for reference grant %s/%s", key, namespace, grant.Name)
+				break
+			}
+		}
+	}
+
+	vservices, err := c.listVirtualServices()
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("error listing virtual services for reference grant %s/%s: %s", namespace, grant.Name, err))
+		return
+	}
+	for _, vservice := range vservices {
+		if vservice.Namespace == namespace {
+			continue
+		}
+		for _, route := range vservice.Spec.Routes {
+			if referencesNamespace(route, vservice.Namespace, namespace) {
+				key := vservice.Namespace + "/" + vservice.Name
+				c.driftChecks.forget(driftCheckVirtualService, key)
+				c.sq.Add(key)
+				klog.V(4).Infof("Enqueued virtual service %s for reference grant %s/%s", key, namespace, grant.Name)
+				break
+			}
+		}
+	}
+}
+
+// referencesNamespace reports whether the route of a virtual service in namespace targets a virtual node in
+// targetNs.
+func referencesNamespace(route appmeshv1beta1.Route, namespace string, targetNs string) bool {
+	for _, target := range routeTargets(route) {
+		if _, ns := targetReference(target, namespace); ns == targetNs {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	meshlisters "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/listers/appmesh/v1beta1"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newReferenceGrant(namespace string, from string, to ...appmeshv1beta1.ReferenceGrantTo) *appmeshv1beta1.ReferenceGrant {
+	return &appmeshv1beta1.ReferenceGrant{
+		ObjectMeta: metav1.ObjectMeta{Name: "grant", Namespace: namespace},
+		Spec: appmeshv1beta1.ReferenceGrantSpec{
+			From: []appmeshv1beta1.ReferenceGrantFrom{{Namespace: from}},
+			To:   to,
+		},
+	}
+}
+
+func TestGrantAllows(t *testing.T) {
+	var tests = []struct {
+		name  string
+		to    appmeshv1beta1.ReferenceGrantTo
+		from  string
+		kind  appmeshv1beta1.ReferenceKind
+		allow bool
+	}{
+		{name: "kind", to: appmeshv1beta1.ReferenceGrantTo{Kind: appmeshv1beta1.ReferenceKindVirtualNode}, from: "ns", kind: appmeshv1beta1.ReferenceKindVirtualNode, allow: true},
+		{name: "name", to: appmeshv1beta1.ReferenceGrantTo{Kind: appmeshv1beta1.ReferenceKindVirtualNode, Name: awssdk.String("red")}, from: "ns", kind: appmeshv1beta1.ReferenceKindVirtualNode, allow: true},
+		{name: "other name", to: appmeshv1beta1.ReferenceGrantTo{Kind: appmeshv1beta1.ReferenceKindVirtualNode, Name: awssdk.String("blue")}, from: "ns", kind: appmeshv1beta1.ReferenceKindVirtualNode},
+		{name: "other kind", to: appmeshv1beta1.ReferenceGrantTo{Kind: appmeshv1beta1.ReferenceKindVirtualService}, from: "ns", kind: appmeshv1beta1.ReferenceKindVirtualNode},
+		{name: "other namespace", to: appmeshv1beta1.ReferenceGrantTo{Kind: appmeshv1beta1.ReferenceKindVirtualNode}, from: "other", kind: appmeshv1beta1.ReferenceKindVirtualNode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grant := newReferenceGrant("colors", "ns", tt.to)
+			if allow := grantAllows(grant, tt.from, tt.kind, "red"); allow != tt.allow {
+				t.Errorf("got %t, want %t", allow, tt.allow)
+			}
+		})
+	}
+}
+
+func TestDeniedReferences(t *testing.T) {
+	grantIndex := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	grantIndex.Add(newReferenceGrant("colors", "ns", appmeshv1beta1.ReferenceGrantTo{Kind: appmeshv1beta1.ReferenceKindVirtualNode, Name: awssdk.String("red")}))
+	c := &Controller{referenceGrantLister: meshlisters.NewReferenceGrantLister(grantIndex)}
+
+	vservice := newDependencyVService(api.ConditionTrue)
+	vservice.Spec.Routes[0].Http.Action.WeightedTargets = []appmeshv1beta1.WeightedTarget{
+		{VirtualNodeName: "red", Namespace: "colors"},
+		{VirtualNodeName: "blue", Namespace: "colors"},
+		{VirtualNodeName: "green"},
+	}
+	if denied := c.deniedVServiceReferences(vservice); !reflect.DeepEqual(denied, []string{"virtual node colors/blue"}) {
+		t.Errorf("expected only blue to be denied, got %v", denied)
+	}
+
+	vnode := newDependencyVNode("red", api.ConditionTrue)
+	vnode.Spec.Backends[0].VirtualService.Namespace = "colors"
+	if denied := c.deniedVNodeReferences(vnode); !reflect.DeepEqual(denied, []string{"virtual service colors/color.ns.svc.cluster.local"}) {
+		t.Errorf("expected the backend to be denied, got %v", denied)
+	}
+
+	status, reason, message := referencesCondition(nil, []string{"virtual node colors/blue"})
+	if status != api.ConditionFalse || reason != referenceNotGrantedReason || message != "References not granted by a ReferenceGrant: virtual node colors/blue" {
+		t.Errorf("unexpected condition %s, %s, %s", status, reason, message)
+	}
+}
+
+func TestDeniedReferencesWithoutNamespace(t *testing.T) {
+	grantIndex := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	vserviceIndex := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{virtualServiceNameIndex: indexVServicesByName})
+	backend := newDependencyVService(api.ConditionTrue)
+	backend.Namespace = "colors"
+	vserviceIndex.Add(backend)
+	c := &Controller{
+		referenceGrantLister: meshlisters.NewReferenceGrantLister(grantIndex),
+		virtualServiceIndex:  vserviceIndex,
+	}
+
+	// A dotted name reaches the virtual node in another namespace without the namespace field
+	vservice := newDependencyVService(api.ConditionTrue)
+	vservice.Spec.Routes[0].Http.Action.WeightedTargets = []appmeshv1beta1.WeightedTarget{{VirtualNodeName: "red.colors"}}
+	vservice.Spec.Routes = vservice.Spec.Routes[:1]
+	if denied := c.deniedVServiceReferences(vservice); !reflect.DeepEqual(denied, []string{"virtual node colors/red"}) {
+		t.Errorf("expected the dotted target to be denied, got %v", denied)
+	}
+
+	// Virtual service names are unique within the mesh, so the backend reaches the one in colors
+	vnode := newDependencyVNode("red", api.ConditionTrue)
+	if denied := c.deniedVNodeReferences(vnode); !reflect.DeepEqual(denied, []string{"virtual service colors/color.ns.svc.cluster.local"}) {
+		t.Errorf("expected the backend owned by another namespace to be denied, got %v", denied)
+	}
+
+	grantIndex.Add(newReferenceGrant("colors", "ns",
+		appmeshv1beta1.ReferenceGrantTo{Kind: appmeshv1beta1.ReferenceKindVirtualNode, Name: awssdk.String("red")},
+		appmeshv1beta1.ReferenceGrantTo{Kind: appmeshv1beta1.ReferenceKindVirtualService},
+	))
+	if denied := c.deniedVServiceReferences(vservice); len(denied) != 0 {
+		t.Errorf("expected the granted target to be allowed, got %v", denied)
+	}
+	if denied := c.deniedVNodeReferences(vnode); len(denied) != 0 {
+		t.Errorf("expected the granted backend to be allowed, got %v", denied)
+	}
+}
+
+func TestTargetReference(t *testing.T) {
+	var tests = []struct {
+		target    appmeshv1beta1.WeightedTarget
+		name      string
+		namespace string
+	}{
+		{target: appmeshv1beta1.WeightedTarget{VirtualNodeName: "red"}, name: "red", namespace: "ns"},
+		{target: appmeshv1beta1.WeightedTarget{VirtualNodeName: "red", Namespace: "colors"}, name: "red", namespace: "colors"},
+		{target: appmeshv1beta1.WeightedTarget{VirtualNodeName: "red.colors"}, name: "red", namespace: "colors"},
+		{target: appmeshv1beta1.WeightedTarget{VirtualNodeName: "red.colors", Namespace: "ns"}, name: "red", namespace: "colors"},
+	}
+
+	for _, tt := range tests {
+		if name, namespace := targetReference(tt.target, "ns"); name != tt.name || namespace != tt.namespace {
+			t.Errorf("%+v: got %s/%s, want %s/%s", tt.target, namespace, name, tt.namespace, tt.name)
+		}
+	}
+}
+
+func TestEnqueueForReferenceGrant(t *testing.T) {
+	vservice := newDependencyVService(api.ConditionTrue)
+	vservice.Spec.Routes[0].Http.Action.WeightedTargets[0].Namespace = "colors"
+	c := newDependencyController([]*appmeshv1beta1.VirtualService{vservice}, nil)
+	c.virtualServiceLister = meshlisters.NewVirtualServiceLister(c.virtualServiceIndex)
+
+	if keys, _ := indexVServicesByTargetVirtualNode(vservice); !reflect.DeepEqual(keys, []string{"colors/red", "ns/blue", "ns/red"}) {
+		t.Errorf("unexpected target keys %v", keys)
+	}
+
+	c.referenceGrantAdded(newReferenceGrant("colors", "ns"))
+	if key, _ := c.sq.Get(); key != "ns/color.ns.svc.cluster.local" {
+		t.Errorf("expected the virtual service targeting colors to be enqueued, got %v", key)
+	}
+	if c.nq.Len() != 0 {
+		t.Errorf("expected no virtual nodes to be enqueued, got %d", c.nq.Len())
+	}
+}
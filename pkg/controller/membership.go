@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog"
+)
+
+// labelMeshMembership on a namespace names the mesh its virtual nodes and virtual services join when they do not
+// set meshName. It takes precedence over the namespace selectors of meshes.
+const labelMeshMembership = "appmesh.k8s.aws/mesh"
+
+// namespaceMesh returns the mesh that virtual nodes and virtual services in the namespace join when they do not
+// set meshName. That is the mesh named by the membership label of the namespace, or else the one mesh whose
+// namespace selector matches it.
+func (c *Controller) namespaceMesh(namespace string) (string, error) {
+	ns, err := c.namespaceLister.Get(namespace)
+	if err != nil {
+		return "", reconcileErrorf(reasonMeshNotSelected, err, "error getting namespace %s: %s", namespace, err)
+	}
+	if meshName := ns.Labels[labelMeshMembership]; meshName != "" {
+		return meshName, nil
+	}
+
+	meshes, err := c.meshLister.List(labels.Everything())
+	if err != nil {
+		return "", reconcileErrorf(reasonMeshNotSelected, err, "error listing meshes: %s", err)
+	}
+	var selected []string
+	for _, mesh := range meshes {
+		if mesh.Spec.NamespaceSelector == nil || !mesh.DeletionTimestamp.IsZero() || !c.scope.containsResource(mesh) {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(mesh.Spec.NamespaceSelector)
+		if err != nil {
+			klog.Errorf("Ignoring invalid namespace selector of mesh %s: %s", mesh.Name, err)
+			continue
+		}
+		if selector.Matches(labels.Set(ns.Labels)) {
+			selected = append(selected, mesh.Name)
+		}
+	}
+	sort.Strings(selected)
+
+	switch len(selected) {
+	case 0:
+		return "", reconcileErrorf(reasonMeshNotSelected, nil, "meshName is not set and namespace %s belongs to no mesh, label it with %s or select it with the namespaceSelector of a mesh", namespace, labelMeshMembership)
+	case 1:
+		return selected[0], nil
+	default:
+		return "", reconcileErrorf(reasonMeshNotSelected, nil, "meshName is not set and namespace %s is selected by meshes %s, label it with %s to choose one", namespace, strings.Join(selected, ", "), labelMeshMembership)
+	}
+}
+
+// vnodeMeshName returns the mesh of a virtual node, which is the one it sets or else the one it joined through
+// its namespace. It is empty until the mesh of the namespace is resolved.
+func vnodeMeshName(vnode *appmeshv1beta1.VirtualNode) string {
+	if vnode.Spec.MeshName != "" {
+		return vnode.Spec.MeshName
+	}
+	return vnode.Status.MeshName
+}
+
+// vserviceMeshName returns the mesh of a virtual service, like vnodeMeshName.
+func vserviceMeshName(vservice *appmeshv1beta1.VirtualService) string {
+	if vservice.Spec.MeshName != "" {
+		return vservice.Spec.MeshName
+	}
+	return vservice.Status.MeshName
+}
+
+// shardOwnsMember reports whether this replica reconciles an object in the mesh, along with the mesh it is
+// checked against. Objects that have not joined a mesh yet are checked against the mesh of their namespace, so
+// that the replica owning that mesh records their membership. Objects whose namespace no mesh selects are left to
+// the replica the ring assigns the empty mesh name, so that a single replica reports them.
+func (c *Controller) shardOwnsMember(meshName string, namespace string) (string, bool) {
+	if c.shards == nil {
+		return meshName, true
+	}
+	if meshName == "" {
+		resolved, err := c.namespaceMesh(namespace)
+		if err != nil {
+			return "", c.shards.assigned("")
+		}
+		meshName = resolved
+	}
+	return meshName, c.shards.owns(meshName)
+}
+
+// resolveVNodeMeshMembership records the mesh of the namespace of a virtual node that does not set a mesh name in
+// its status. The spec is left to its owner. The mesh is kept once recorded, so that the virtual node does not move
+// to another mesh when labels or selectors change. The status patch enqueues the virtual node again.
+func (c *Controller) resolveVNodeMeshMembership(vnode *appmeshv1beta1.VirtualNode) error {
+	meshName, err := c.namespaceMesh(vnode.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := c.patchVNodeStatus(vnode, map[string]interface{}{meshNameStatusField: meshName}); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error recording mesh %s of virtual node %s: %s", meshName, vnode.Name, err)
+	}
+	vnode.Status.MeshName = meshName
+	klog.Infof("Virtual node %s/%s joined mesh %s of its namespace", vnode.Namespace, vnode.Name, meshName)
+	return nil
+}
+
+// resolveVServiceMeshMembership records the mesh of the namespace of a virtual service that does not set a mesh
+// name in its status, like resolveVNodeMeshMembership.
+func (c *Controller) resolveVServiceMeshMembership(vservice *appmeshv1beta1.VirtualService) error {
+	meshName, err := c.namespaceMesh(vservice.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := c.patchVServiceStatus(vservice, map[string]interface{}{meshNameStatusField: meshName}); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error recording mesh %s of virtual service %s: %s", meshName, vservice.Name, err)
+	}
+	vservice.Status.MeshName = meshName
+	klog.Infof("Virtual service %s/%s joined mesh %s of its namespace", vservice.Namespace, vservice.Name, meshName)
+	return nil
+}
+
+// enqueueWithoutMesh enqueues the virtual nodes and virtual services in the namespace that have not joined a mesh
+// yet, since a mesh may have started selecting it. An empty namespace enqueues them in all namespaces.
+func (c *Controller) enqueueWithoutMesh(namespace string) {
+	vnodes, err := c.listVirtualNodes()
+	if err != nil {
+		klog.Errorf("error listing virtual nodes without a mesh: %s", err)
+	}
+	for _, vnode := range vnodes {
+		if vnodeMeshName(vnode) == "" && (namespace == "" || vnode.Namespace == namespace) {
+			c.nq.Add(vnode.Namespace + "/" + vnode.Name)
+		}
+	}
+	vservices, err := c.listVirtualServices()
+	if err != nil {
+		klog.Errorf("error listing virtual services without a mesh: %s", err)
+	}
+	for _, vservice := range vservices {
+		if vserviceMeshName(vservice) == "" && (namespace == "" || vservice.Namespace == namespace) {
+			c.sq.Add(vservice.Namespace + "/" + vservice.Name)
+		}
+	}
+}
+
+func (c *Controller) namespaceUpdated(old interface{}, new interface{}) {
+	oldNamespace, ok := old.(*corev1.Namespace)
+	if !ok {
+		return
+	}
+	newNamespace, ok := new.(*corev1.Namespace)
+	if !ok {
+		return
+	}
+	// Relabeling a namespace may make it join a mesh
+	if !c.scope.containsNamespace(newNamespace.Name) || reflect.DeepEqual(oldNamespace.Labels, newNamespace.Labels) {
+		return
+	}
+	c.enqueueWithoutMesh(newNamespace.Name)
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned/fake"
+	meshlisters "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/listers/appmesh/v1beta1"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func newNamespaceLister(namespaces ...*api.Namespace) corev1listers.NamespaceLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, ns := range namespaces {
+		indexer.Add(ns)
+	}
+	return corev1listers.NewNamespaceLister(indexer)
+}
+
+func newSelectingMesh(name string, matchLabels map[string]string) *appmeshv1beta1.Mesh {
+	return &appmeshv1beta1.Mesh{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: appmeshv1beta1.MeshSpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: matchLabels},
+		},
+	}
+}
+
+func TestNamespaceMesh(t *testing.T) {
+	var tests = []struct {
+		name     string
+		labels   map[string]string
+		meshes   []*appmeshv1beta1.Mesh
+		expected string
+	}{
+		{name: "label", labels: map[string]string{labelMeshMembership: "blue", "mesh": "red"}, meshes: []*appmeshv1beta1.Mesh{newSelectingMesh("red", map[string]string{"mesh": "red"})}, expected: "blue"},
+		{name: "selector", labels: map[string]string{"mesh": "red"}, meshes: []*appmeshv1beta1.Mesh{newSelectingMesh("red", map[string]string{"mesh": "red"}), newSelectingMesh("blue", map[string]string{"mesh": "blue"})}, expected: "red"},
+		{name: "no mesh", labels: map[string]string{"mesh": "green"}, meshes: []*appmeshv1beta1.Mesh{newSelectingMesh("red", map[string]string{"mesh": "red"})}},
+		{name: "several meshes", labels: map[string]string{"mesh": "red"}, meshes: []*appmeshv1beta1.Mesh{newSelectingMesh("red", map[string]string{"mesh": "red"}), newSelectingMesh("blue", map[string]string{})}},
+		{name: "mesh without selector", labels: map[string]string{"mesh": "red"}, meshes: []*appmeshv1beta1.Mesh{{ObjectMeta: metav1.ObjectMeta{Name: "red"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meshIndex := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			for _, mesh := range tt.meshes {
+				meshIndex.Add(mesh)
+			}
+			c := &Controller{
+				namespaceLister: newNamespaceLister(&api.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Labels: tt.labels}}),
+				meshLister:      meshlisters.NewMeshLister(meshIndex),
+			}
+
+			meshName, err := c.namespaceMesh("ns")
+			if meshName != tt.expected {
+				t.Errorf("got mesh %q, want %q", meshName, tt.expected)
+			}
+			if tt.expected == "" {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if reason, _ := errorCondition(err); reason != reasonMeshNotSelected {
+					t.Errorf("expected reason %s, got %s", reasonMeshNotSelected, reason)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveVNodeMeshMembership(t *testing.T) {
+	vnode := newDependencyVNode("red", api.ConditionTrue)
+	vnode.Spec.MeshName = ""
+	clientset := fake.NewSimpleClientset(vnode)
+	c := &Controller{
+		namespaceLister: newNamespaceLister(&api.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Labels: map[string]string{labelMeshMembership: "mesh"}}}),
+		meshclientset:   clientset,
+	}
+
+	if err := c.resolveVNodeMeshMembership(vnode); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := clientset.AppmeshV1beta1().VirtualNodes("ns").Get("red", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status.MeshName != "mesh" || vnodeMeshName(updated) != "mesh" {
+		t.Errorf("expected the virtual node to join mesh in its status, got %q", updated.Status.MeshName)
+	}
+	if updated.Spec.MeshName != "" {
+		t.Errorf("expected the spec to be left alone, got mesh name %q", updated.Spec.MeshName)
+	}
+
+	// The mesh name set in the spec wins over the one recorded in the status
+	updated.Spec.MeshName = "other"
+	if meshName := vnodeMeshName(updated); meshName != "other" {
+		t.Errorf("expected the mesh name of the spec, got %q", meshName)
+	}
+}
+
+func TestNamespaceUpdated(t *testing.T) {
+	meshless := newDependencyVNode("red", api.ConditionTrue)
+	meshless.Spec.MeshName = ""
+	joined := newDependencyVNode("blue", api.ConditionTrue)
+	joined.Spec.MeshName = ""
+	joined.Status.MeshName = "mesh"
+	elsewhere := newDependencyVNode("green", api.ConditionTrue)
+	elsewhere.Namespace = "other"
+	elsewhere.Spec.MeshName = ""
+	vnodeIndex := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, vnode := range []*appmeshv1beta1.VirtualNode{meshless, joined, elsewhere} {
+		vnodeIndex.Add(vnode)
+	}
+	c := &Controller{
+		virtualNodeLister:    meshlisters.NewVirtualNodeLister(vnodeIndex),
+		virtualServiceLister: meshlisters.NewVirtualServiceLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+		nq:                   workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		sq:                   workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+	old := &api.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}}
+
+	c.namespaceUpdated(old, old.DeepCopy())
+	if c.nq.Len() != 0 {
+		t.Fatalf("expected an unchanged namespace not to enqueue virtual nodes, got %d", c.nq.Len())
+	}
+
+	relabeled := old.DeepCopy()
+	relabeled.Labels = map[string]string{labelMeshMembership: "mesh"}
+	c.namespaceUpdated(old, relabeled)
+	if c.nq.Len() != 1 {
+		t.Fatalf("expected one virtual node to be enqueued, got %d", c.nq.Len())
+	}
+	if key, _ := c.nq.Get(); key != "ns/red" {
+		t.Errorf("expected the virtual node without a mesh to be enqueued, got %v", key)
+	}
+}
+
+func TestMergeMeshDefaults(t *testing.T) {
+	defaults := &appmeshv1beta1.MeshDefaults{
+		Spec: appmeshv1beta1.MeshDefaultsSpec{
+			BackendDefaults: &appmeshv1beta1.BackendDefaults{ClientPolicy: &appmeshv1beta1.ClientPolicy{
+				TLS: &appmeshv1beta1.ClientPolicyTls{Enforce: awssdk.Bool(true)},
+			}},
+			Logging: &appmeshv1beta1.Logging{AccessLog: &appmeshv1beta1.AccessLog{File: &appmeshv1beta1.FileAccessLog{Path: "/dev/stdout"}}},
+			HealthCheck: &appmeshv1beta1.HealthCheckPolicy{
+				IntervalMillis: awssdk.Int64(10000),
+				Path:           awssdk.String("/ping"),
+			},
+		},
+	}
+	vnode := &appmeshv1beta1.VirtualNode{
+		Spec: appmeshv1beta1.VirtualNodeSpec{
+			Logging: &appmeshv1beta1.Logging{AccessLog: &appmeshv1beta1.AccessLog{File: &appmeshv1beta1.FileAccessLog{Path: "/tmp/access"}}},
+			Listeners: []appmeshv1beta1.Listener{
+				{PortMapping: appmeshv1beta1.PortMapping{Port: 8080, Protocol: "http"}},
+				{
+					PortMapping: appmeshv1beta1.PortMapping{Port: 9090, Protocol: "http"},
+					HealthCheck: &appmeshv1beta1.HealthCheckPolicy{Path: awssdk.String("/health")},
+				},
+			},
+		},
+	}
+
+	mergeMeshDefaults(vnode, defaults)
+
+	if vnode.Spec.BackendDefaults == nil || !awssdk.BoolValue(vnode.Spec.BackendDefaults.ClientPolicy.TLS.Enforce) {
+		t.Errorf("expected the backend defaults to be merged, got %+v", vnode.Spec.BackendDefaults)
+	}
+	if vnode.Spec.BackendDefaults == defaults.Spec.BackendDefaults {
+		t.Errorf("expected the backend defaults to be copied")
+	}
+	if vnode.Spec.Logging.AccessLog.File.Path != "/tmp/access" {
+		t.Errorf("expected the logging of the virtual node to be kept, got %s", vnode.Spec.Logging.AccessLog.File.Path)
+	}
+	if path := awssdk.StringValue(vnode.Spec.Listeners[0].HealthCheck.Path); path != "/ping" {
+		t.Errorf("expected the listener without health check to get the default one, got path %s", path)
+	}
+	healthCheck := vnode.Spec.Listeners[1].HealthCheck
+	if awssdk.StringValue(healthCheck.Path) != "/health" || awssdk.Int64Value(healthCheck.IntervalMillis) != 10000 {
+		t.Errorf("expected the health check to be filled in, got %+v", healthCheck)
+	}
+}
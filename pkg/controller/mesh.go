@@ -14,7 +14,7 @@ import (
 	"k8s.io/klog"
 )
 
-func (c *Controller) handleMesh(key string) error {
+func (c *Controller) handleMesh(key string) (err error) {
 	ctx := context.Background()
 
 	_, name, err := cache.SplitMetaNamespaceKey(key)
@@ -29,9 +29,25 @@ func (c *Controller) handleMesh(key string) error {
 	if err != nil {
 		return err
 	}
+	if !c.shards.owns(shared.Name) {
+		klog.V(4).Infof("Skipping mesh %s owned by another shard", key)
+		return nil
+	}
 
 	// Make copy here so we never update the shared copy
 	mesh := shared.DeepCopy()
+	defer func() {
+		if !isConflict(err) {
+			c.recordFailure(mesh, err)
+			if readinessErr := c.updateMeshReadiness(mesh, err); err == nil && isConflict(readinessErr) {
+				err = readinessErr
+			}
+		}
+		// A conflict means the reconcile worked on an outdated copy, it starts over from a fresh one once requeued
+		if isConflict(err) {
+			c.driftChecks.forget(driftCheckMesh, key)
+		}
+	}()
 
 	// Resources with finalizers are not deleted immediately,
 	// instead the deletion timestamp is set when a client deletes them.
@@ -44,53 +60,70 @@ func (c *Controller) handleMesh(key string) error {
 	// This is not a delete, add the deletion finalizer if it doesn't exist
 	if yes, _ := containsFinalizer(mesh, meshDeletionFinalizerName); !yes {
 		if err = addFinalizer(mesh, meshDeletionFinalizerName); err != nil {
-			return fmt.Errorf("error adding finalizer %s to mesh %s: %s", meshDeletionFinalizerName, mesh.Name, err)
+			return reconcileErrorf(reasonFinalizerUpdateFailed, err, "error adding finalizer %s to mesh %s: %s", meshDeletionFinalizerName, mesh.Name, err)
 		}
 		if err := c.updateMeshResource(mesh); err != nil {
-			return fmt.Errorf("error adding finalizer %s to mesh %s: %s", meshDeletionFinalizerName, mesh.Name, err)
+			return reconcileErrorf(reasonFinalizerUpdateFailed, err, "error adding finalizer %s to mesh %s: %s", meshDeletionFinalizerName, mesh.Name, err)
 		}
 	}
 
+	if paused, err := c.handleMeshPause(mesh, key); err != nil || paused {
+		return err
+	}
+
+	if !c.driftChecks.due(driftCheckMesh, key, mesh.Generation, mesh.Status.ObservedGeneration) {
+		klog.V(4).Infof("Skipping unchanged mesh %s until its next drift check", key)
+		return nil
+	}
+
 	// Create mesh if it does not exist
-	if targetMesh, err := c.cloud.GetMesh(ctx, mesh.Name); err != nil {
+	if targetMesh, err := c.describeMesh(ctx, mesh.Name); err != nil {
 		if aws.IsAWSErrNotFound(err) {
 			if targetMesh, err = c.cloud.CreateMesh(ctx, mesh); err != nil {
-				return fmt.Errorf("error creating mesh: %s", err)
+				return reconcileErrorf(reasonCreateFailed, err, "error creating mesh: %s", err)
 			}
 			klog.Infof("Created mesh %s", targetMesh.Name())
+			c.recordMutation(mesh, createdReason, "Created App Mesh mesh %s", targetMesh.Name())
 		} else {
-			return fmt.Errorf("error describing mesh: %s", err)
+			return reconcileErrorf(reasonDescribeFailed, err, "error describing mesh: %s", err)
 		}
 	} else {
+		var drift []string
 		if c.meshNeedsUpdate(mesh, targetMesh) {
-			if targetMesh, err = c.cloud.UpdateMesh(ctx, mesh); err != nil {
-				return fmt.Errorf("error updating mesh: %s", err)
+			if c.driftPolicyFor(mesh) == DriftPolicyReport {
+				drift = meshSpecDiff(mesh, targetMesh)
+				c.reportDrift(mesh, driftCheckMesh, mesh.Name, mesh.Name, drift)
+			} else {
+				if targetMesh, err = c.cloud.UpdateMesh(ctx, mesh); err != nil {
+					return reconcileErrorf(reasonUpdateFailed, err, "error updating mesh: %s", err)
+				}
+				klog.Infof("Updated mesh %s", mesh.Name)
+				c.recordMutation(mesh, updatedReason, "Updated App Mesh mesh %s", mesh.Name)
 			}
-			klog.Infof("Updated mesh %s", mesh.Name)
+		}
+		if err := c.setMeshArn(mesh, targetMesh); err != nil {
+			return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating mesh status: %s", err)
 		}
 		if err := c.updateMeshActive(mesh); err != nil {
-			return fmt.Errorf("error updating mesh status: %s", err)
+			return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating mesh status: %s", err)
+		}
+		if err := c.setMeshDrifted(mesh, drift); err != nil {
+			return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating mesh status: %s", err)
 		}
 	}
 
 	c.stats.SetMeshActive(mesh.Name)
 
+	if err := c.setMeshObservedGeneration(mesh); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating mesh status: %s", err)
+	}
+	c.driftChecks.reconciled(driftCheckMesh, key)
+
 	return nil
 }
 
 func (c *Controller) meshNeedsUpdate(desired *appmeshv1beta1.Mesh, target *aws.Mesh) bool {
-	if desired.Spec.EgressFilter != nil {
-		if target.Data.Spec.EgressFilter == nil {
-			return true
-		}
-		if desired.Spec.EgressFilter.Type != awssdk.StringValue(target.Data.Spec.EgressFilter.Type) {
-			return true
-		}
-	} else if target.Data.Spec.EgressFilter != nil {
-		return true
-	}
-
-	return false
+	return len(meshSpecDiff(desired, target)) > 0
 }
 
 func (c *Controller) updateMeshResource(mesh *appmeshv1beta1.Mesh) error {
@@ -103,27 +136,35 @@ func (c *Controller) updateMeshActive(mesh *appmeshv1beta1.Mesh) error {
 }
 
 func (c *Controller) updateMeshCondition(mesh *appmeshv1beta1.Mesh, conditionType appmeshv1beta1.MeshConditionType, status api.ConditionStatus) error {
-	now := metav1.Now()
 	condition := getMeshCondition(conditionType, mesh.Status)
-	if condition == (appmeshv1beta1.MeshCondition{}) {
-		// condition does not exist
-		newCondition := appmeshv1beta1.MeshCondition{
-			Type:               conditionType,
-			Status:             status,
-			LastTransitionTime: &now,
-		}
-		mesh.Status.Conditions = append(mesh.Status.Conditions, newCondition)
-	} else if condition.Status == status {
+	if condition != (appmeshv1beta1.MeshCondition{}) && condition.Status == status {
 		// Already is set to status
 		return nil
-	} else {
-		// condition exists and not set to status
-		condition.Status = status
-		condition.LastTransitionTime = &now
 	}
 
-	_, err := c.meshclientset.AppmeshV1beta1().Meshes().UpdateStatus(mesh)
-	return err
+	now := metav1.Now()
+	condition.Type = conditionType
+	condition.Status = status
+	condition.LastTransitionTime = &now
+	mesh.Status.Conditions = setMeshConditionInList(mesh.Status.Conditions, condition)
+	return c.setMeshStatusConditions(mesh, mesh.Status.Conditions)
+}
+
+// setMeshObservedGeneration records the generation of the mesh spec that was reconciled with App Mesh
+func (c *Controller) setMeshObservedGeneration(mesh *appmeshv1beta1.Mesh) error {
+	if mesh.Status.ObservedGeneration == mesh.Generation {
+		return nil
+	}
+	return c.patchMeshStatus(mesh, map[string]interface{}{observedGenerationField: mesh.Generation})
+}
+
+// setMeshArn records the ARN of the App Mesh mesh in the status of mesh
+func (c *Controller) setMeshArn(mesh *appmeshv1beta1.Mesh, target *aws.Mesh) error {
+	if awssdk.StringValue(mesh.Status.MeshArn) == awssdk.StringValue(target.Data.Metadata.Arn) {
+		return nil
+	}
+	mesh.Status.MeshArn = target.Data.Metadata.Arn
+	return c.patchMeshStatus(mesh, map[string]interface{}{meshArnStatusField: mesh.Status.MeshArn})
 }
 
 func checkMeshActive(mesh *appmeshv1beta1.Mesh) bool {
@@ -131,6 +172,47 @@ func checkMeshActive(mesh *appmeshv1beta1.Mesh) bool {
 	return condition.Status == api.ConditionTrue
 }
 
+// setMeshReasonCondition sets a condition with a reason and message on the mesh. A condition that would be
+// False is not added if the mesh does not have it yet.
+func (c *Controller) setMeshReasonCondition(mesh *appmeshv1beta1.Mesh, conditionType appmeshv1beta1.MeshConditionType, status api.ConditionStatus, reason string, message string) error {
+	current := getMeshCondition(conditionType, mesh.Status)
+	if current == (appmeshv1beta1.MeshCondition{}) && status == api.ConditionFalse {
+		return nil
+	}
+	if current.Status == status && awssdk.StringValue(current.Reason) == reason && awssdk.StringValue(current.Message) == message {
+		return nil
+	}
+
+	now := metav1.Now()
+	condition := appmeshv1beta1.MeshCondition{
+		Type:               conditionType,
+		Status:             status,
+		LastTransitionTime: current.LastTransitionTime,
+		Reason:             awssdk.String(reason),
+		Message:            awssdk.String(message),
+	}
+	if current.Status != status {
+		condition.LastTransitionTime = &now
+	}
+
+	mesh.Status.Conditions = setMeshConditionInList(mesh.Status.Conditions, condition)
+	return c.setMeshStatusConditions(mesh, mesh.Status.Conditions)
+}
+
+func (c *Controller) setMeshStatusConditions(mesh *appmeshv1beta1.Mesh, conditions []appmeshv1beta1.MeshCondition) error {
+	return c.patchMeshStatus(mesh, map[string]interface{}{meshConditionsField: conditions})
+}
+
+func setMeshConditionInList(conditions []appmeshv1beta1.MeshCondition, condition appmeshv1beta1.MeshCondition) []appmeshv1beta1.MeshCondition {
+	for i := range conditions {
+		if conditions[i].Type == condition.Type {
+			conditions[i] = condition
+			return conditions
+		}
+	}
+	return append(conditions, condition)
+}
+
 func getMeshCondition(conditionType appmeshv1beta1.MeshConditionType, status appmeshv1beta1.MeshStatus) appmeshv1beta1.MeshCondition {
 
 	for _, condition := range status.Conditions {
@@ -150,11 +232,20 @@ func (c *Controller) handleMeshDelete(ctx context.Context, mesh *appmeshv1beta1.
 			klog.Error(err)
 		}
 
-		if _, err := c.cloud.DeleteMesh(ctx, mesh.Name); err != nil {
+		if c.effectiveDeletionPolicy(mesh.Spec.DeletionPolicy, mesh.Name) == appmeshv1beta1.DeletionPolicyRetain {
+			if remaining, err := c.meshResourcesRemaining(mesh.Name); err != nil {
+				return err
+			} else if remaining > 0 {
+				return fmt.Errorf("retaining mesh %s once its %d virtual nodes and virtual services are deleted", mesh.Name, remaining)
+			}
+			klog.Infof("Retained mesh %s", mesh.Name)
+		} else if _, err := c.cloud.DeleteMesh(ctx, mesh.Name); err != nil {
 			if !aws.IsAWSErrNotFound(err) {
 				// Don't remove the finalizer if the mesh still exists
-				return fmt.Errorf("failed to clean up mesh %s during deletion finalizer: %s", mesh.Name, err)
+				return reconcileErrorf(reasonDeleteFailed, err, "failed to clean up mesh %s during deletion finalizer: %s", mesh.Name, err)
 			}
+		} else {
+			c.recordMutation(mesh, deletedReason, "Deleted App Mesh mesh %s", mesh.Name)
 		}
 		if err := removeFinalizer(mesh, meshDeletionFinalizerName); err != nil {
 			return fmt.Errorf("error removing finalizer %s to mesh %s during deletion: %s", meshDeletionFinalizerName, mesh.Name, err)
@@ -174,7 +265,7 @@ func (c *Controller) markResourcesForMeshDeletion(name string) error {
 	} else {
 		for _, obj := range objects {
 			vnode, ok := obj.(*appmeshv1beta1.VirtualNode)
-			if !ok {
+			if !ok || !c.scope.containsResource(vnode) {
 				continue
 			}
 
@@ -193,7 +284,7 @@ func (c *Controller) markResourcesForMeshDeletion(name string) error {
 	} else {
 		for _, obj := range objects {
 			vservice, ok := obj.(*appmeshv1beta1.VirtualService)
-			if !ok {
+			if !ok || !c.scope.containsResource(vservice) {
 				continue
 			}
 
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+// namespaceMeshDefaults returns the MeshDefaults of a namespace, or nil if it has none. A namespace is expected to
+// have at most one, the first by name is used otherwise.
+func (c *Controller) namespaceMeshDefaults(namespace string) *appmeshv1beta1.MeshDefaults {
+	if c.meshDefaultsLister == nil {
+		return nil
+	}
+	list, err := c.meshDefaultsLister.MeshDefaultses(namespace).List(labels.Everything())
+	if err != nil {
+		klog.Errorf("error listing mesh defaults in namespace %s: %s", namespace, err)
+		return nil
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	if len(list) > 1 {
+		klog.V(2).Infof("Namespace %s has %d mesh defaults, using %s", namespace, len(list), list[0].Name)
+	}
+	return list[0]
+}
+
+// mergeMeshDefaults fills in the backend defaults, logging and listener health checks that a virtual node does not
+// set from the defaults of its namespace.
+func mergeMeshDefaults(vnode *appmeshv1beta1.VirtualNode, defaults *appmeshv1beta1.MeshDefaults) {
+	if defaults == nil {
+		return
+	}
+	spec := defaults.Spec
+
+	if spec.BackendDefaults != nil {
+		if vnode.Spec.BackendDefaults == nil {
+			vnode.Spec.BackendDefaults = spec.BackendDefaults.DeepCopy()
+		} else if vnode.Spec.BackendDefaults.ClientPolicy == nil {
+			vnode.Spec.BackendDefaults.ClientPolicy = spec.BackendDefaults.ClientPolicy.DeepCopy()
+		}
+	}
+
+	if vnode.Spec.Logging == nil && spec.Logging != nil {
+		vnode.Spec.Logging = spec.Logging.DeepCopy()
+	}
+
+	if spec.HealthCheck != nil {
+		for i := range vnode.Spec.Listeners {
+			listener := &vnode.Spec.Listeners[i]
+			if listener.HealthCheck == nil {
+				listener.HealthCheck = spec.HealthCheck.DeepCopy()
+			} else {
+				mergeHealthCheckPolicy(listener.HealthCheck, spec.HealthCheck)
+			}
+		}
+	}
+}
+
+// mergeHealthCheckPolicy fills in the fields of a health check that are not set from the defaults
+func mergeHealthCheckPolicy(healthCheck *appmeshv1beta1.HealthCheckPolicy, defaults *appmeshv1beta1.HealthCheckPolicy) {
+	defaults = defaults.DeepCopy()
+	if healthCheck.HealthyThreshold == nil {
+		healthCheck.HealthyThreshold = defaults.HealthyThreshold
+	}
+	if healthCheck.IntervalMillis == nil {
+		healthCheck.IntervalMillis = defaults.IntervalMillis
+	}
+	if healthCheck.Path == nil {
+		healthCheck.Path = defaults.Path
+	}
+	if healthCheck.Port == nil {
+		healthCheck.Port = defaults.Port
+	}
+	if healthCheck.Protocol == nil {
+		healthCheck.Protocol = defaults.Protocol
+	}
+	if healthCheck.TimeoutMillis == nil {
+		healthCheck.TimeoutMillis = defaults.TimeoutMillis
+	}
+	if healthCheck.UnhealthyThreshold == nil {
+		healthCheck.UnhealthyThreshold = defaults.UnhealthyThreshold
+	}
+}
+
+func (c *Controller) meshDefaultsAdded(obj interface{}) {
+	c.enqueueForMeshDefaults(obj)
+}
+
+func (c *Controller) meshDefaultsUpdated(old interface{}, new interface{}) {
+	c.enqueueForMeshDefaults(new)
+}
+
+func (c *Controller) meshDefaultsDeleted(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	c.enqueueForMeshDefaults(obj)
+}
+
+// enqueueForMeshDefaults enqueues the virtual nodes in the namespace of the defaults, since their desired spec may
+// have changed.
+func (c *Controller) enqueueForMeshDefaults(obj interface{}) {
+	defaults, ok := obj.(*appmeshv1beta1.MeshDefaults)
+	if !ok {
+		return
+	}
+
+	vnodes, err := c.listVirtualNodes()
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("error listing virtual nodes for mesh defaults %s/%s: %s", defaults.Namespace, defaults.Name, err))
+		return
+	}
+	for _, vnode := range vnodes {
+		if vnode.Namespace != defaults.Namespace {
+			continue
+		}
+		key := vnode.Namespace + "/" + vnode.Name
+		c.driftChecks.forget(driftCheckVirtualNode, key)
+		c.nq.Add(key)
+		klog.V(4).Infof("Enqueued virtual node %s for mesh defaults %s/%s", key, defaults.Namespace, defaults.Name)
+	}
+}
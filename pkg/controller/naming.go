@@ -0,0 +1,259 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	api "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+)
+
+const (
+	// NamingStrategyLegacy appends the namespace to names, or replaces the "." of names that contain one
+	NamingStrategyLegacy = "legacy"
+	// NamingStrategyHashed appends the namespace and a hash of the namespace and name, so that names never
+	// collide and fit the App Mesh name length limit
+	NamingStrategyHashed = "hashed"
+	// DefaultNamingStrategy keeps the names the controller always used
+	DefaultNamingStrategy = NamingStrategyLegacy
+
+	// maxAWSNameLength is the longest name App Mesh accepts for virtual nodes, virtual routers and routes
+	maxAWSNameLength = 255
+	hashSuffixLength = 8
+
+	// awsNameIndex indexes virtual nodes and virtual services by the mesh/name keys of their virtual node and
+	// virtual router in App Mesh
+	awsNameIndex = "awsName"
+
+	nameCollisionReason = "NameCollision"
+	nameYieldedReason   = "NameYielded"
+	uniqueNameReason    = "UniqueName"
+)
+
+// ValidNamingStrategy reports whether strategy is a known naming strategy.
+func ValidNamingStrategy(strategy string) bool {
+	return strategy == NamingStrategyLegacy || strategy == NamingStrategyHashed
+}
+
+// namingStrategy maps the name and namespace of a custom resource, or of a reference to one, onto an App Mesh
+// name.
+type namingStrategy interface {
+	awsName(name string, namespace string) string
+}
+
+func newNamingStrategy(strategy string) (namingStrategy, error) {
+	switch strategy {
+	case NamingStrategyLegacy, "":
+		return legacyNaming{}, nil
+	case NamingStrategyHashed:
+		return hashedNaming{}, nil
+	}
+	return nil, fmt.Errorf("invalid naming strategy %q, must be %s or %s", strategy, NamingStrategyLegacy, NamingStrategyHashed)
+}
+
+// legacyNaming is namespacedResourceName. "a-b" in namespace "c" and "a" in namespace "b-c" both map onto
+// "a-b-c", and long names can exceed the App Mesh limit.
+type legacyNaming struct{}
+
+func (legacyNaming) awsName(name string, namespace string) string {
+	return namespacedResourceName(name, namespace)
+}
+
+// hashedNaming keeps names readable, but appends a hash of the namespace and name, and truncates the readable
+// part to fit the App Mesh limit. The name is hashed as it is, dots included, so that "red.colors" in namespace
+// "ns" and "red" in namespace "colors" get different names. References in the name.namespace form are split by
+// targetReference before they are mapped.
+type hashedNaming struct{}
+
+func (hashedNaming) awsName(name string, namespace string) string {
+	sum := sha256.Sum256([]byte(namespace + "/" + name))
+	suffix := "-" + hex.EncodeToString(sum[:])[:hashSuffixLength]
+	readable := strings.ReplaceAll(name+"-"+namespace, ".", "-")
+	if limit := maxAWSNameLength - len(suffix); len(readable) > limit {
+		readable = readable[:limit]
+	}
+	return readable + suffix
+}
+
+// namer returns the naming strategy of the controller, which is the legacy one unless configured otherwise.
+func (c *Controller) namer() namingStrategy {
+	if c.naming == nil {
+		return legacyNaming{}
+	}
+	return c.naming
+}
+
+// virtualNodeAWSName returns the App Mesh name of the virtual node custom resource.
+func (c *Controller) virtualNodeAWSName(vnode *appmeshv1beta1.VirtualNode) string {
+	if name := awssdk.StringValue(vnode.Spec.AWSName); name != "" {
+		return name
+	}
+	return c.namer().awsName(vnode.Name, vnode.Namespace)
+}
+
+// virtualNodeRefAWSName returns the App Mesh name of the virtual node that a reference to name in namespace
+// resolves to, honoring the override of its custom resource if it has one.
+func (c *Controller) virtualNodeRefAWSName(name string, namespace string) string {
+	if c.virtualNodeLister != nil {
+		if vnode, err := c.virtualNodeLister.VirtualNodes(namespace).Get(name); err == nil {
+			return c.virtualNodeAWSName(vnode)
+		}
+	}
+	return c.namer().awsName(name, namespace)
+}
+
+// virtualRouterAWSName returns the App Mesh name of the virtual router of the virtual service, which is named
+// after the virtual service unless the spec names it.
+func (c *Controller) virtualRouterAWSName(vservice *appmeshv1beta1.VirtualService) string {
+	var name string
+	if vservice.Spec.VirtualRouter != nil {
+		if awsName := awssdk.StringValue(vservice.Spec.VirtualRouter.AWSName); awsName != "" {
+			return awsName
+		}
+		name = strings.TrimSpace(vservice.Spec.VirtualRouter.Name)
+	}
+	if len(name) == 0 {
+		name = vservice.Name
+	}
+	return c.namer().awsName(name, vservice.Namespace)
+}
+
+// indexByAWSName indexes virtual nodes and virtual services by the App Mesh names of their virtual node or
+// virtual router.
+func (c *Controller) indexByAWSName(obj interface{}) ([]string, error) {
+	switch o := obj.(type) {
+	case *appmeshv1beta1.VirtualNode:
+		if meshName := vnodeMeshName(o); len(meshName) > 0 {
+			return []string{meshResourceKey(meshName, c.virtualNodeAWSName(o))}, nil
+		}
+	case *appmeshv1beta1.VirtualService:
+		if meshName := vserviceMeshName(o); len(meshName) > 0 {
+			return []string{meshResourceKey(meshName, c.virtualRouterAWSName(o))}, nil
+		}
+	}
+	return []string{}, nil
+}
+
+// nameCollisions returns the namespace/name keys of the other objects in the index that map onto the same App
+// Mesh name as obj.
+func nameCollisions(index cache.Indexer, obj interface{}, indexFunc cache.IndexFunc) []string {
+	if index == nil {
+		return nil
+	}
+	self, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return nil
+	}
+	if _, ok := index.GetIndexers()[awsNameIndex]; !ok {
+		return nil
+	}
+	names, _ := indexFunc(obj)
+	var keys []string
+	for _, name := range names {
+		objects, err := index.ByIndex(awsNameIndex, name)
+		if err != nil {
+			klog.Errorf("%s index error for %s: %s", awsNameIndex, name, err)
+			continue
+		}
+		for _, other := range objects {
+			if key, err := cache.MetaNamespaceKeyFunc(other); err == nil && key != self {
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// nameCollisionCondition returns the status, reason and message of the NameCollision condition. Objects that
+// yield the name get a reason of their own, since they are not reconciled.
+func nameCollisionCondition(kind string, awsName string, collisions []string, yields bool) (api.ConditionStatus, string, string) {
+	if len(collisions) == 0 {
+		return api.ConditionFalse, uniqueNameReason, ""
+	}
+	reason := nameCollisionReason
+	if yields {
+		reason = nameYieldedReason
+	}
+	return api.ConditionTrue, reason, fmt.Sprintf("App Mesh %s name %s is also used by %s", kind, awsName, strings.Join(collisions, ", "))
+}
+
+// yieldsName reports whether obj has to leave an App Mesh name it collides on to the other objects, which is
+// the case unless it is the oldest of them. Ties are broken by key.
+func yieldsName(index cache.Indexer, obj interface{}, collisions []string) bool {
+	self, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return true
+	}
+	selfMeta, err := scopeAccessor(obj)
+	if err != nil {
+		return true
+	}
+	for _, key := range collisions {
+		other, exists, err := index.GetByKey(key)
+		if err != nil || !exists {
+			continue
+		}
+		otherMeta, err := scopeAccessor(other)
+		if err != nil {
+			continue
+		}
+		selfCreated, otherCreated := selfMeta.GetCreationTimestamp(), otherMeta.GetCreationTimestamp()
+		if otherCreated.Before(&selfCreated) || (otherCreated.Equal(&selfCreated) && key < self) {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueueNameCollisions enqueues the other objects that map onto the App Mesh name of obj, since their
+// NameCollision condition may have changed.
+func (c *Controller) enqueueNameCollisions(index cache.Indexer, queue workqueue.RateLimitingInterface, kind string, obj interface{}) {
+	if index == nil {
+		return
+	}
+	for _, key := range nameCollisions(index, obj, c.indexByAWSName) {
+		c.driftChecks.forget(kind, key)
+		queue.Add(key)
+		klog.V(4).Infof("Enqueued %s %s, which shares its App Mesh name", kind, key)
+	}
+}
+
+// handleVNodeNameCollision updates the NameCollision condition of the virtual node, and reports whether it
+// yields its App Mesh name to an older virtual node and must not be reconciled.
+func (c *Controller) handleVNodeNameCollision(vnode *appmeshv1beta1.VirtualNode, key string) (bool, error) {
+	collisions := nameCollisions(c.virtualNodeIndex, vnode, c.indexByAWSName)
+	yields := len(collisions) > 0 && yieldsName(c.virtualNodeIndex, vnode, collisions)
+	status, reason, message := nameCollisionCondition("virtual node", c.virtualNodeAWSName(vnode), collisions, yields)
+	if _, err := c.setVNodeReasonCondition(vnode, appmeshv1beta1.VirtualNodeNameCollision, status, reason, message); err != nil {
+		return false, reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual node status: %s", err)
+	}
+	if yields {
+		klog.Warningf("Not reconciling virtual node %s: %s", key, message)
+		return true, nil
+	}
+	return false, nil
+}
+
+// handleVServiceNameCollision updates the NameCollision condition of the virtual service, and reports whether
+// it yields the App Mesh name of its virtual router to an older virtual service and must not be reconciled.
+func (c *Controller) handleVServiceNameCollision(vservice *appmeshv1beta1.VirtualService, key string) (bool, error) {
+	collisions := nameCollisions(c.virtualServiceIndex, vservice, c.indexByAWSName)
+	yields := len(collisions) > 0 && yieldsName(c.virtualServiceIndex, vservice, collisions)
+	status, reason, message := nameCollisionCondition("virtual router", c.virtualRouterAWSName(vservice), collisions, yields)
+	if _, err := c.setVServiceReasonCondition(vservice, appmeshv1beta1.VirtualServiceNameCollision, status, reason, message); err != nil {
+		return false, reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual service status: %s", err)
+	}
+	if yields {
+		klog.Warningf("Not reconciling virtual service %s: %s", key, message)
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestNamingStrategies(t *testing.T) {
+	if _, err := newNamingStrategy("other"); err == nil {
+		t.Error("expected an invalid naming strategy to be rejected")
+	}
+
+	legacy, _ := newNamingStrategy(NamingStrategyLegacy)
+	if legacy.awsName("a-b", "c") != legacy.awsName("a", "b-c") {
+		t.Error("expected the legacy strategy to map a-b/c and a/b-c onto the same name")
+	}
+
+	hashed, _ := newNamingStrategy(NamingStrategyHashed)
+	if hashed.awsName("a-b", "c") == hashed.awsName("a", "b-c") {
+		t.Error("expected the hashed strategy to map a-b/c and a/b-c onto different names")
+	}
+	if name := hashed.awsName("red", "colors"); !strings.HasPrefix(name, "red-colors-") || len(name) != len("red-colors-")+hashSuffixLength {
+		t.Errorf("unexpected hashed name %s", name)
+	}
+	if hashed.awsName("red.colors", "ns") == hashed.awsName("red", "colors") {
+		t.Error("expected the hashed strategy to map red.colors/ns and red/colors onto different names")
+	}
+	if name := hashed.awsName(strings.Repeat("a", 253), "ns"); len(name) != maxAWSNameLength {
+		t.Errorf("expected long names to be truncated to %d characters, got %d", maxAWSNameLength, len(name))
+	}
+
+	// References in the name.namespace form resolve to the virtual node in the other namespace
+	red := newDependencyVNode("red", api.ConditionTrue)
+	red.Namespace = "colors"
+	c := &Controller{naming: hashed}
+	reference := appmeshv1beta1.WeightedTarget{VirtualNodeName: "red.colors"}
+	if name := c.virtualNodeRefAWSName(targetReference(reference, "ns")); name != c.virtualNodeAWSName(red) {
+		t.Errorf("expected red.colors to refer to red in namespace colors, got %s", name)
+	}
+}
+
+func TestAWSNameOverride(t *testing.T) {
+	vnode := newDependencyVNode("red", api.ConditionTrue)
+	vnode.Spec.AWSName = awssdk.String("crimson")
+	c := newDependencyController(nil, []*appmeshv1beta1.VirtualNode{vnode})
+
+	if name := c.virtualNodeAWSName(vnode); name != "crimson" {
+		t.Errorf("expected the override, got %s", name)
+	}
+	if name := c.virtualNodeRefAWSName("red", "ns"); name != "crimson" {
+		t.Errorf("expected references to follow the override, got %s", name)
+	}
+	if name := c.virtualNodeRefAWSName("blue", "ns"); name != "blue-ns" {
+		t.Errorf("expected references to unknown virtual nodes to use the naming strategy, got %s", name)
+	}
+
+	vservice := newDependencyVService(api.ConditionTrue)
+	if name := c.virtualRouterAWSName(vservice); name != "color-ns-svc-cluster-local" {
+		t.Errorf("unexpected virtual router name %s", name)
+	}
+	vservice.Spec.VirtualRouter = &appmeshv1beta1.VirtualRouter{Name: "router", AWSName: awssdk.String("color-router")}
+	if name := c.virtualRouterAWSName(vservice); name != "color-router" {
+		t.Errorf("expected the override, got %s", name)
+	}
+}
+
+func TestNameCollisions(t *testing.T) {
+	older := newDependencyVNode("a-b", api.ConditionTrue)
+	older.Namespace = "c"
+	older.CreationTimestamp = metav1.NewTime(time.Unix(100, 0))
+	newer := newDependencyVNode("a", api.ConditionTrue)
+	newer.Namespace = "b-c"
+	newer.CreationTimestamp = metav1.NewTime(time.Unix(200, 0))
+	other := newDependencyVNode("red", api.ConditionTrue)
+
+	c := &Controller{
+		nq: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+	c.virtualNodeIndex = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{awsNameIndex: c.indexByAWSName})
+	for _, vnode := range []*appmeshv1beta1.VirtualNode{older, newer, other} {
+		c.virtualNodeIndex.Add(vnode)
+	}
+
+	collisions := nameCollisions(c.virtualNodeIndex, newer, c.indexByAWSName)
+	if !reflect.DeepEqual(collisions, []string{"c/a-b"}) {
+		t.Fatalf("unexpected collisions %v", collisions)
+	}
+	if !yieldsName(c.virtualNodeIndex, newer, collisions) {
+		t.Error("expected the newer virtual node to yield")
+	}
+	if yieldsName(c.virtualNodeIndex, older, nameCollisions(c.virtualNodeIndex, older, c.indexByAWSName)) {
+		t.Error("expected the older virtual node to keep the name")
+	}
+	if collisions := nameCollisions(c.virtualNodeIndex, other, c.indexByAWSName); len(collisions) != 0 {
+		t.Errorf("expected no collisions, got %v", collisions)
+	}
+
+	status, reason, message := nameCollisionCondition("virtual node", "a-b-c", collisions, true)
+	if status != api.ConditionTrue || reason != nameYieldedReason || message != "App Mesh virtual node name a-b-c is also used by c/a-b" {
+		t.Errorf("unexpected condition %s, %s, %s", status, reason, message)
+	}
+
+	c.enqueueNameCollisions(c.virtualNodeIndex, c.nq, driftCheckVirtualNode, newer)
+	if key, _ := c.nq.Get(); key != "c/a-b" {
+		t.Errorf("expected the colliding virtual node to be enqueued, got %v", key)
+	}
+
+	c.naming = hashedNaming{}
+	hashedIndex := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{awsNameIndex: c.indexByAWSName})
+	hashedIndex.Add(older)
+	hashedIndex.Add(newer)
+	if collisions := nameCollisions(hashedIndex, newer, c.indexByAWSName); len(collisions) != 0 {
+		t.Errorf("expected no collisions with the hashed strategy, got %v", collisions)
+	}
+}
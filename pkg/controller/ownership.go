@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+const (
+	// tagKeyClusterID and tagKeyOwnerUID tag the App Mesh resources the controller creates with the cluster and
+	// the custom resource they belong to. Meshes are not tagged, since several clusters commonly share a mesh.
+	tagKeyClusterID = "appmesh.k8s.aws/cluster-id"
+	tagKeyOwnerUID  = "appmesh.k8s.aws/owner-uid"
+
+	// annotationAdopt lets a virtual node or virtual service take over App Mesh resources of the same name that
+	// belong to another resource or cluster, or that were not created by the controller
+	annotationAdopt = "appmesh.k8s.aws/adopt"
+
+	ownershipConflictReason = "OwnershipConflict"
+	ownedReason             = "Owned"
+	adoptedReason           = "Adopted"
+)
+
+// ResolveClusterID returns the configured cluster ID, or else the UID of the kube-system namespace, which lasts
+// as long as the cluster does.
+func ResolveClusterID(kubeclientset kubernetes.Interface, configured string) (string, error) {
+	if configured != "" {
+		return configured, nil
+	}
+	namespace, err := kubeclientset.CoreV1().Namespaces().Get(metav1.NamespaceSystem, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("error getting namespace %s to identify the cluster, set a cluster ID instead: %s", metav1.NamespaceSystem, err)
+	}
+	return string(namespace.UID), nil
+}
+
+// ownershipConflictError is returned when an App Mesh resource exists, but does not belong to the custom
+// resource being reconciled.
+type ownershipConflictError struct {
+	kind      string
+	name      string
+	clusterID string
+	ownerUID  string
+}
+
+func (e *ownershipConflictError) Error() string {
+	if e.clusterID == "" && e.ownerUID == "" {
+		return fmt.Sprintf("%s %s was not created by the controller, annotate the resource with %s=true to adopt it", e.kind, e.name, annotationAdopt)
+	}
+	return fmt.Sprintf("%s %s belongs to the resource with UID %s in cluster %s, annotate the resource with %s=true to adopt it", e.kind, e.name, e.ownerUID, e.clusterID, annotationAdopt)
+}
+
+func isOwnershipConflict(err error) bool {
+	_, ok := err.(*ownershipConflictError)
+	return ok
+}
+
+// owners remembers the App Mesh resources known to belong to a custom resource, by ARN, so that their tags are
+// not listed on every reconcile.
+type owners struct {
+	mu   sync.Mutex
+	uids map[string]types.UID
+}
+
+func newOwners() *owners {
+	return &owners{
+		uids: map[string]types.UID{},
+	}
+}
+
+func (o *owners) owns(arn string, uid types.UID) bool {
+	if o == nil {
+		return false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	owner, ok := o.uids[arn]
+	return ok && owner == uid
+}
+
+func (o *owners) set(arn string, uid types.UID) {
+	if o == nil || arn == "" {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.uids[arn] = uid
+}
+
+func (o *owners) forget(arn string) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.uids, arn)
+}
+
+// ownershipEnabled reports whether App Mesh resources are tagged and checked for ownership, which requires a
+// cluster ID.
+func (c *Controller) ownershipEnabled() bool {
+	return c.clusterID != ""
+}
+
+// ownerTags returns the tags of App Mesh resources created for obj.
+func (c *Controller) ownerTags(obj metav1.Object) map[string]string {
+	if !c.ownershipEnabled() {
+		return nil
+	}
+	return map[string]string{
+		tagKeyClusterID: c.clusterID,
+		tagKeyOwnerUID:  string(obj.GetUID()),
+	}
+}
+
+// created remembers that the App Mesh resource was just created for obj.
+func (c *Controller) created(obj metav1.Object, metadata *appmesh.ResourceMetadata) {
+	if c.ownershipEnabled() && metadata != nil {
+		c.owners.set(awssdk.StringValue(metadata.Arn), obj.GetUID())
+	}
+}
+
+// adoptRequested reports whether obj has the adopt annotation.
+func adoptRequested(obj metav1.Object) bool {
+	return boolAnnotation(obj, annotationAdopt)
+}
+
+// checkOwnership verifies that an existing App Mesh resource belongs to obj before the controller changes or
+// deletes it. Resources of another owner are only taken over, and retagged, when obj has the adopt annotation.
+// Untagged resources are also taken over when claimUntagged is set, which is the case for resources obj
+// reconciled before ownership tagging existed. Otherwise an *ownershipConflictError is returned.
+func (c *Controller) checkOwnership(ctx context.Context, obj metav1.Object, kind string, name string, metadata *appmesh.ResourceMetadata, claimUntagged bool) error {
+	if !c.ownershipEnabled() || metadata == nil {
+		return nil
+	}
+	arn := awssdk.StringValue(metadata.Arn)
+	if c.owners.owns(arn, obj.GetUID()) {
+		return nil
+	}
+
+	tags, err := c.cloud.ListTagsForResource(ctx, arn)
+	if err != nil {
+		return fmt.Errorf("error listing tags of %s %s: %s", kind, name, err)
+	}
+	clusterID, ownerUID := tags[tagKeyClusterID], tags[tagKeyOwnerUID]
+	untagged := clusterID == "" && ownerUID == ""
+
+	switch {
+	case clusterID == c.clusterID && ownerUID == string(obj.GetUID()):
+	case adoptRequested(obj):
+		if err := c.cloud.TagResource(ctx, arn, c.ownerTags(obj)); err != nil {
+			return fmt.Errorf("error adopting %s %s: %s", kind, name, err)
+		}
+		klog.Infof("Adopted %s %s", kind, name)
+		if ro, ok := obj.(runtime.Object); ok && c.recorder != nil {
+			c.recorder.Eventf(ro, api.EventTypeNormal, adoptedReason, "Adopted %s %s", kind, name)
+		}
+	case untagged && claimUntagged:
+		if err := c.cloud.TagResource(ctx, arn, c.ownerTags(obj)); err != nil {
+			return fmt.Errorf("error tagging %s %s: %s", kind, name, err)
+		}
+		klog.Infof("Tagged %s %s, which was created before ownership tagging", kind, name)
+	default:
+		return &ownershipConflictError{
+			kind:      kind,
+			name:      name,
+			clusterID: clusterID,
+			ownerUID:  ownerUID,
+		}
+	}
+
+	c.owners.set(arn, obj.GetUID())
+	return nil
+}
+
+// deletable checks the ownership of an App Mesh resource that is about to be deleted. Resources that belong to
+// someone else are left alone, so that deleting the custom resource can still finish.
+func (c *Controller) deletable(ctx context.Context, obj metav1.Object, kind string, name string, metadata *appmesh.ResourceMetadata, claimUntagged bool) (bool, error) {
+	err := c.checkOwnership(ctx, obj, kind, name, metadata, claimUntagged)
+	if isOwnershipConflict(err) {
+		klog.Warningf("Not deleting %s %s: %s", kind, name, err)
+		if ro, ok := obj.(runtime.Object); ok && c.recorder != nil {
+			c.recorder.Eventf(ro, api.EventTypeWarning, ownershipConflictReason, "Not deleting %s %s: %s", kind, name, err)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if metadata != nil {
+		c.owners.forget(awssdk.StringValue(metadata.Arn))
+	}
+	return true, nil
+}
+
+// ownershipCondition returns the status, reason and message of the OwnershipConflict condition for err.
+func ownershipCondition(err error) (api.ConditionStatus, string, string) {
+	if err == nil {
+		return api.ConditionFalse, ownedReason, ""
+	}
+	return api.ConditionTrue, ownershipConflictReason, err.Error()
+}
+
+// handleVNodeOwnershipConflict surfaces a conflict as a condition and an event instead of retrying, since only
+// the adopt annotation or the other owner going away resolves it. Other errors are returned as is.
+func (c *Controller) handleVNodeOwnershipConflict(vnode *appmeshv1beta1.VirtualNode, key string, err error) error {
+	if !isOwnershipConflict(err) {
+		return err
+	}
+	klog.Warningf("Not reconciling virtual node %s: %s", key, err)
+	if c.recorder != nil {
+		c.recorder.Eventf(vnode, api.EventTypeWarning, ownershipConflictReason, "%s", err)
+	}
+	c.driftChecks.forget(driftCheckVirtualNode, key)
+	status, reason, message := ownershipCondition(err)
+	if _, err := c.setVNodeReasonCondition(vnode, appmeshv1beta1.VirtualNodeOwnershipConflict, status, reason, message); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual node status: %s", err)
+	}
+	return nil
+}
+
+// handleVServiceOwnershipConflict surfaces a conflict as a condition and an event instead of retrying, since
+// only the adopt annotation or the other owner going away resolves it. Other errors are returned as is.
+func (c *Controller) handleVServiceOwnershipConflict(vservice *appmeshv1beta1.VirtualService, key string, err error) error {
+	if !isOwnershipConflict(err) {
+		return err
+	}
+	klog.Warningf("Not reconciling virtual service %s: %s", key, err)
+	if c.recorder != nil {
+		c.recorder.Eventf(vservice, api.EventTypeWarning, ownershipConflictReason, "%s", err)
+	}
+	c.driftChecks.forget(driftCheckVirtualService, key)
+	status, reason, message := ownershipCondition(err)
+	if _, err := c.setVServiceReasonCondition(vservice, appmeshv1beta1.VirtualServiceOwnershipConflict, status, reason, message); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual service status: %s", err)
+	}
+	return nil
+}
+
+// vnodeReconciledBefore reports whether the virtual node was reconciled with App Mesh before, in which case an
+// untagged App Mesh virtual node of its name was created for it before ownership tagging existed.
+func vnodeReconciledBefore(vnode *appmeshv1beta1.VirtualNode) bool {
+	return vnode.Status.ObservedGeneration > 0 || getVNodeCondition(appmeshv1beta1.VirtualNodeActive, vnode.Status).Status != ""
+}
+
+// vserviceReconciledBefore reports whether the virtual service was reconciled with App Mesh before, in which case
+// untagged App Mesh resources of its names were created for it before ownership tagging existed.
+func (c *Controller) vserviceReconciledBefore(vservice *appmeshv1beta1.VirtualService) bool {
+	return vservice.Status.ObservedGeneration > 0 || c.getVServiceCondition(appmeshv1beta1.VirtualServiceActive, vservice.Status).Status != ""
+}
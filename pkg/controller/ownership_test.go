@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	ctrlawsmocks "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws/mocks"
+	meshfake "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned/fake"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckOwnership(t *testing.T) {
+	ownTags := map[string]string{tagKeyClusterID: "cluster", tagKeyOwnerUID: "uid"}
+
+	var tests = []struct {
+		name          string
+		tags          map[string]string
+		annotations   map[string]string
+		claimUntagged bool
+		wantConflict  bool
+		wantTagged    bool
+	}{
+		{name: "owned", tags: ownTags},
+		{name: "other owner", tags: map[string]string{tagKeyClusterID: "cluster", tagKeyOwnerUID: "other"}, wantConflict: true},
+		{name: "other cluster", tags: map[string]string{tagKeyClusterID: "other", tagKeyOwnerUID: "uid"}, wantConflict: true},
+		{name: "untagged", tags: map[string]string{}, wantConflict: true},
+		{name: "untagged reconciled before", tags: map[string]string{}, claimUntagged: true, wantTagged: true},
+		{name: "other owner reconciled before", tags: map[string]string{tagKeyClusterID: "other"}, claimUntagged: true, wantConflict: true},
+		{name: "adopted", tags: map[string]string{tagKeyClusterID: "other"}, annotations: map[string]string{annotationAdopt: "true"}, wantTagged: true},
+		{name: "invalid adopt annotation", tags: map[string]string{tagKeyClusterID: "other"}, annotations: map[string]string{annotationAdopt: "yes please"}, wantConflict: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cloud := new(ctrlawsmocks.CloudAPI)
+			cloud.On("ListTagsForResource", ctx, "arn").Return(tt.tags, nil).Once()
+			if tt.wantTagged {
+				cloud.On("TagResource", ctx, "arn", ownTags).Return(nil)
+			}
+			c := &Controller{cloud: cloud, clusterID: "cluster", owners: newOwners()}
+			vnode := &appmeshv1beta1.VirtualNode{
+				ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "ns", UID: "uid", Annotations: tt.annotations},
+			}
+			metadata := &appmesh.ResourceMetadata{Arn: awssdk.String("arn")}
+
+			err := c.checkOwnership(ctx, vnode, "virtual node", "node-ns", metadata, tt.claimUntagged)
+			if isOwnershipConflict(err) != tt.wantConflict {
+				t.Fatalf("got error %v, want conflict %t", err, tt.wantConflict)
+			}
+			if !tt.wantConflict && err != nil {
+				t.Fatal(err)
+			}
+			cloud.AssertExpectations(t)
+
+			// Ownership is remembered, so the tags are only listed again after a conflict
+			if !tt.wantConflict {
+				if err := c.checkOwnership(ctx, vnode, "virtual node", "node-ns", metadata, tt.claimUntagged); err != nil {
+					t.Errorf("expected the remembered owner to pass, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestOwnershipDisabled(t *testing.T) {
+	c := &Controller{}
+	vnode := &appmeshv1beta1.VirtualNode{ObjectMeta: metav1.ObjectMeta{Name: "node", UID: "uid"}}
+	if tags := c.ownerTags(vnode); tags != nil {
+		t.Errorf("expected no tags without a cluster ID, got %v", tags)
+	}
+	if err := c.checkOwnership(context.Background(), vnode, "virtual node", "node", &appmesh.ResourceMetadata{Arn: awssdk.String("arn")}, false); err != nil {
+		t.Errorf("expected no ownership check without a cluster ID, got %v", err)
+	}
+}
+
+func TestHandleVNodeOwnershipConflict(t *testing.T) {
+	vnode := &appmeshv1beta1.VirtualNode{ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "ns"}}
+	meshclientset := meshfake.NewSimpleClientset(vnode)
+	c := &Controller{meshclientset: meshclientset}
+
+	conflict := &ownershipConflictError{kind: "virtual node", name: "node-ns", clusterID: "other", ownerUID: "uid"}
+	if err := c.handleVNodeOwnershipConflict(vnode.DeepCopy(), "ns/node", conflict); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := meshclientset.AppmeshV1beta1().VirtualNodes("ns").Get("node", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	condition := getVNodeCondition(appmeshv1beta1.VirtualNodeOwnershipConflict, updated.Status)
+	if condition.Status != api.ConditionTrue || awssdk.StringValue(condition.Reason) != ownershipConflictReason || awssdk.StringValue(condition.Message) != conflict.Error() {
+		t.Errorf("unexpected condition %+v", condition)
+	}
+}
+
+func TestResolveClusterID(t *testing.T) {
+	kubeclientset := kubefake.NewSimpleClientset(&api.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "system-uid"}})
+
+	if id, err := ResolveClusterID(kubeclientset, "configured"); err != nil || id != "configured" {
+		t.Errorf("expected the configured cluster ID, got %s, %v", id, err)
+	}
+	if id, err := ResolveClusterID(kubeclientset, ""); err != nil || id != "system-uid" {
+		t.Errorf("expected the kube-system UID, got %s, %v", id, err)
+	}
+	if _, err := ResolveClusterID(kubefake.NewSimpleClientset(), ""); err == nil {
+		t.Error("expected an error without a kube-system namespace")
+	}
+}
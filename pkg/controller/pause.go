@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"fmt"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+const (
+	// annotationPaused stops the controller from changing the App Mesh resources of a mesh, virtual node or
+	// virtual service, e.g. while they are edited by hand during an incident. Pausing a mesh pauses its virtual
+	// nodes and virtual services too. Deletion finalizers still run while paused.
+	annotationPaused = "appmesh.k8s.aws/paused"
+
+	pausedReason  = "Paused"
+	resumedReason = "Resumed"
+)
+
+// pauseRequested reports whether obj has the pause annotation.
+func pauseRequested(obj metav1.Object) bool {
+	return boolAnnotation(obj, annotationPaused)
+}
+
+// pausedBy reports whether reconciling obj is paused, by its own annotation or by the one of its mesh, along
+// with a message saying which.
+func pausedBy(obj metav1.Object, mesh *appmeshv1beta1.Mesh) (bool, string) {
+	if pauseRequested(obj) {
+		return true, fmt.Sprintf("Reconciling is paused by the %s annotation", annotationPaused)
+	}
+	if mesh != nil && pauseRequested(mesh) {
+		return true, fmt.Sprintf("Reconciling is paused by the %s annotation of mesh %s", annotationPaused, mesh.Name)
+	}
+	return false, ""
+}
+
+// pausedCondition returns the status, reason and message of the Paused condition.
+func pausedCondition(paused bool, message string) (api.ConditionStatus, string, string) {
+	if !paused {
+		return api.ConditionFalse, resumedReason, ""
+	}
+	return api.ConditionTrue, pausedReason, message
+}
+
+// handleMeshPause updates the Paused condition and metric of the mesh, and reports whether reconciling it is
+// paused. A resumed mesh is reconciled right away rather than at its next drift check.
+func (c *Controller) handleMeshPause(mesh *appmeshv1beta1.Mesh, key string) (bool, error) {
+	paused, message := pausedBy(mesh, nil)
+	wasPaused := getMeshCondition(appmeshv1beta1.MeshPaused, mesh.Status).Status == api.ConditionTrue
+	c.stats.SetPaused(driftCheckMesh, mesh.Name, mesh.Name, paused)
+
+	status, reason, message := pausedCondition(paused, message)
+	if err := c.setMeshReasonCondition(mesh, appmeshv1beta1.MeshPaused, status, reason, message); err != nil {
+		return false, reconcileErrorf(reasonStatusUpdateFailed, err, "error updating mesh status: %s", err)
+	}
+	if paused {
+		klog.V(4).Infof("Skipping paused mesh %s", key)
+	} else if wasPaused {
+		klog.Infof("Resumed reconciling mesh %s", key)
+		c.driftChecks.forget(driftCheckMesh, key)
+	}
+	return paused, nil
+}
+
+// handleVNodePause updates the Paused condition and metric of the virtual node, and reports whether reconciling
+// it is paused. A resumed virtual node is reconciled right away rather than at its next drift check.
+func (c *Controller) handleVNodePause(vnode *appmeshv1beta1.VirtualNode, mesh *appmeshv1beta1.Mesh, key string) (bool, error) {
+	paused, message := pausedBy(vnode, mesh)
+	wasPaused := getVNodeCondition(appmeshv1beta1.VirtualNodePaused, vnode.Status).Status == api.ConditionTrue
+	c.stats.SetPaused(driftCheckVirtualNode, vnodeMeshName(vnode), key, paused)
+
+	status, reason, message := pausedCondition(paused, message)
+	if _, err := c.setVNodeReasonCondition(vnode, appmeshv1beta1.VirtualNodePaused, status, reason, message); err != nil {
+		return false, reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual node status: %s", err)
+	}
+	if paused {
+		klog.V(4).Infof("Skipping paused virtual node %s", key)
+	} else if wasPaused {
+		klog.Infof("Resumed reconciling virtual node %s", key)
+		c.driftChecks.forget(driftCheckVirtualNode, key)
+	}
+	return paused, nil
+}
+
+// handleVServicePause updates the Paused condition and metric of the virtual service, and reports whether
+// reconciling it is paused. A resumed virtual service is reconciled right away rather than at its next drift
+// check.
+func (c *Controller) handleVServicePause(vservice *appmeshv1beta1.VirtualService, mesh *appmeshv1beta1.Mesh, key string) (bool, error) {
+	paused, message := pausedBy(vservice, mesh)
+	wasPaused := c.getVServiceCondition(appmeshv1beta1.VirtualServicePaused, vservice.Status).Status == api.ConditionTrue
+	c.stats.SetPaused(driftCheckVirtualService, vserviceMeshName(vservice), key, paused)
+
+	status, reason, message := pausedCondition(paused, message)
+	if _, err := c.setVServiceReasonCondition(vservice, appmeshv1beta1.VirtualServicePaused, status, reason, message); err != nil {
+		return false, reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual service status: %s", err)
+	}
+	if paused {
+		klog.V(4).Infof("Skipping paused virtual service %s", key)
+	} else if wasPaused {
+		klog.Infof("Resumed reconciling virtual service %s", key)
+		c.driftChecks.forget(driftCheckVirtualService, key)
+	}
+	return paused, nil
+}
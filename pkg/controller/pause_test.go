@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	meshfake "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned/fake"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/metrics"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestPausedBy(t *testing.T) {
+	paused := map[string]string{annotationPaused: "true"}
+
+	var tests = []struct {
+		name            string
+		annotations     map[string]string
+		meshAnnotations map[string]string
+		want            bool
+	}{
+		{name: "not paused"},
+		{name: "paused", annotations: paused, want: true},
+		{name: "mesh paused", meshAnnotations: paused, want: true},
+		{name: "resumed", annotations: map[string]string{annotationPaused: "false"}},
+		{name: "invalid", annotations: map[string]string{annotationPaused: "for now"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vnode := &appmeshv1beta1.VirtualNode{ObjectMeta: metav1.ObjectMeta{Name: "node", Annotations: tt.annotations}}
+			mesh := &appmeshv1beta1.Mesh{ObjectMeta: metav1.ObjectMeta{Name: "mesh", Annotations: tt.meshAnnotations}}
+			if got, message := pausedBy(vnode, mesh); got != tt.want || (got && message == "") {
+				t.Errorf("got %t with message %q, want %t", got, message, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleVNodePause(t *testing.T) {
+	vnode := &appmeshv1beta1.VirtualNode{
+		ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "ns"},
+		Spec:       appmeshv1beta1.VirtualNodeSpec{MeshName: "mesh"},
+	}
+	mesh := &appmeshv1beta1.Mesh{ObjectMeta: metav1.ObjectMeta{Name: "mesh", Annotations: map[string]string{annotationPaused: "true"}}}
+	meshclientset := meshfake.NewSimpleClientset(vnode)
+	c := &Controller{meshclientset: meshclientset, stats: metrics.NewRecorder(false), driftChecks: newDriftChecks(time.Hour)}
+
+	getCondition := func() appmeshv1beta1.VirtualNodeCondition {
+		updated, err := meshclientset.AppmeshV1beta1().VirtualNodes("ns").Get("node", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return getVNodeCondition(appmeshv1beta1.VirtualNodePaused, updated.Status)
+	}
+
+	// A virtual node that was never paused gets no condition
+	if paused, err := c.handleVNodePause(vnode.DeepCopy(), &appmeshv1beta1.Mesh{}, "ns/node"); err != nil || paused {
+		t.Fatalf("expected the virtual node not to be paused, got %t, %v", paused, err)
+	}
+	if condition := getCondition(); condition.Status != "" {
+		t.Errorf("expected no condition, got %+v", condition)
+	}
+
+	// Pausing the mesh pauses the virtual node
+	if paused, err := c.handleVNodePause(vnode.DeepCopy(), mesh, "ns/node"); err != nil || !paused {
+		t.Fatalf("expected the virtual node to be paused, got %t, %v", paused, err)
+	}
+	condition := getCondition()
+	if condition.Status != api.ConditionTrue || awssdk.StringValue(condition.Reason) != pausedReason {
+		t.Errorf("unexpected condition %+v", condition)
+	}
+
+	// Resuming forgets the last drift check, so the virtual node is reconciled right away
+	c.driftChecks.reconciled(driftCheckVirtualNode, "ns/node")
+	vnode.Status.Conditions = []appmeshv1beta1.VirtualNodeCondition{condition}
+	if paused, err := c.handleVNodePause(vnode.DeepCopy(), &appmeshv1beta1.Mesh{}, "ns/node"); err != nil || paused {
+		t.Fatalf("expected the virtual node to be resumed, got %t, %v", paused, err)
+	}
+	if condition := getCondition(); condition.Status != api.ConditionFalse || awssdk.StringValue(condition.Reason) != resumedReason {
+		t.Errorf("unexpected condition %+v", condition)
+	}
+	if !c.driftChecks.due(driftCheckVirtualNode, "ns/node", 1, 1) {
+		t.Error("expected the resumed virtual node to be due for a drift check")
+	}
+}
+
+func TestMeshPauseEnqueuesDependents(t *testing.T) {
+	vnode := newDependencyVNode("red", api.ConditionTrue)
+	vservice := newDependencyVService(api.ConditionTrue)
+	vnodeIndex := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{"meshName": indexVNodesByMeshName})
+	vnodeIndex.Add(vnode)
+	vserviceIndex := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{"meshName": indexVServicesByMeshName})
+	vserviceIndex.Add(vservice)
+	c := &Controller{
+		virtualNodeIndex:    vnodeIndex,
+		virtualServiceIndex: vserviceIndex,
+		mq:                  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		nq:                  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		sq:                  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+
+	mesh := &appmeshv1beta1.Mesh{ObjectMeta: metav1.ObjectMeta{Name: "mesh"}}
+	paused := mesh.DeepCopy()
+	paused.Annotations = map[string]string{annotationPaused: "true"}
+	c.meshUpdated(mesh, paused)
+
+	if key, _ := c.nq.Get(); c.nq.Len() != 0 || key != "ns/red" {
+		t.Errorf("expected only the virtual node on the virtual node queue, got %v", key)
+	}
+	if key, _ := c.sq.Get(); c.sq.Len() != 0 || key != "ns/color.ns.svc.cluster.local" {
+		t.Errorf("expected the virtual service on the virtual service queue, got %v", key)
+	}
+}
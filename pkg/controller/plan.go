@@ -0,0 +1,394 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	meshclientset "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned"
+	meshfake "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned/fake"
+	meshinformers "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/informers/externalversions"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+// maxPlanPasses bounds how often a plan reconciles everything. A mesh only becomes active, and its virtual nodes
+// and services reconcilable, in the pass after it was created.
+const maxPlanPasses = 5
+
+// ReconcilePlan lists the changes to App Mesh and Cloud Map a controller would make.
+type ReconcilePlan struct {
+	Changes []aws.PlannedChange
+	// Skipped lists the objects that could not be reconciled, so their changes are missing from the plan
+	Skipped []PlanSkip
+}
+
+// PlanSkip is an object that could not be reconciled while planning.
+type PlanSkip struct {
+	Kind string
+	Key  string
+	Err  error
+}
+
+// Plan reconciles the meshes, virtual nodes, virtual services and pods in the cluster the way a controller
+// would, and returns the changes it would make. Neither the cluster nor App Mesh and Cloud Map are changed:
+// objects are only listed from the cluster, the reconcile logic works on in-memory copies of them, and cloud
+// mutations are recorded instead of made.
+func Plan(cloud aws.CloudAPI, kubeclientset kubernetes.Interface, meshclientset meshclientset.Interface, scopeOptions ScopeOptions, driftPolicy string, clusterID string, namingStrategy string) (*ReconcilePlan, error) {
+	listOptions := metav1.ListOptions{}
+	scopeOptions.TweakListOptions(&listOptions)
+	namespace := scopeOptions.InformerNamespace()
+
+	meshes, err := meshclientset.AppmeshV1beta1().Meshes().List(listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error listing meshes: %s", err)
+	}
+	vnodes, err := meshclientset.AppmeshV1beta1().VirtualNodes(namespace).List(listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error listing virtual nodes: %s", err)
+	}
+	vservices, err := meshclientset.AppmeshV1beta1().VirtualServices(namespace).List(listOptions)
+	if err != nil {
+		return nil, fmt.Errorf("error listing virtual services: %s", err)
+	}
+	// Grants are not labeled like the resources they allow references to
+	grants, err := meshclientset.AppmeshV1beta1().ReferenceGrants(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing reference grants: %s", err)
+	}
+	defaults, err := meshclientset.AppmeshV1beta1().MeshDefaultses(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing mesh defaults: %s", err)
+	}
+	pods, err := kubeclientset.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods: %s", err)
+	}
+
+	// The controller works on fake clientsets holding copies of the listed objects, so that adding finalizers and
+	// updating status only changes the copies. Its informers are never started, their indexers are filled below
+	// and kept up to date with every update instead.
+	fakeKubeClientset := kubefake.NewSimpleClientset()
+	fakeMeshClientset := meshfake.NewSimpleClientset()
+	var meshObjects, kubeObjects []runtime.Object
+	for i := range meshes.Items {
+		meshObjects = append(meshObjects, &meshes.Items[i])
+	}
+	for i := range vnodes.Items {
+		meshObjects = append(meshObjects, &vnodes.Items[i])
+	}
+	for i := range vservices.Items {
+		meshObjects = append(meshObjects, &vservices.Items[i])
+	}
+	for i := range grants.Items {
+		meshObjects = append(meshObjects, &grants.Items[i])
+	}
+	for i := range defaults.Items {
+		meshObjects = append(meshObjects, &defaults.Items[i])
+	}
+	for _, obj := range meshObjects {
+		if err := addPlanObject(fakeMeshClientset.Tracker(), obj); err != nil {
+			return nil, err
+		}
+	}
+	for i := range pods.Items {
+		kubeObjects = append(kubeObjects, &pods.Items[i])
+		if err := fakeKubeClientset.Tracker().Add(&pods.Items[i]); err != nil {
+			return nil, err
+		}
+	}
+	// Secrets are only read for the certificates virtual nodes source from them
+	for _, key := range referencedSecrets(vnodes.Items) {
+		ns, name, _ := cache.SplitMetaNamespaceKey(key)
+		secret, err := kubeclientset.CoreV1().Secrets(ns).Get(name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("error getting secret %s: %s", key, err)
+		}
+		kubeObjects = append(kubeObjects, secret)
+		if err := fakeKubeClientset.Tracker().Add(secret); err != nil {
+			return nil, err
+		}
+	}
+	// Objects without a mesh name join the mesh of their namespace, so their namespaces are copied as well
+	for _, ns := range meshlessNamespaces(vnodes.Items, vservices.Items) {
+		obj, err := kubeclientset.CoreV1().Namespaces().Get(ns, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error getting namespace %s: %s", ns, err)
+		}
+		kubeObjects = append(kubeObjects, obj)
+		if err := fakeKubeClientset.Tracker().Add(obj); err != nil {
+			return nil, err
+		}
+	}
+	kubeInformerFactory := kubeinformers.NewSharedInformerFactory(fakeKubeClientset, 0)
+	meshInformerFactory := meshinformers.NewSharedInformerFactory(fakeMeshClientset, 0)
+	podInformer := kubeInformerFactory.Core().V1().Pods()
+	secretInformer := kubeInformerFactory.Core().V1().Secrets()
+	namespaceInformer := kubeInformerFactory.Core().V1().Namespaces()
+	meshInformer := meshInformerFactory.Appmesh().V1beta1().Meshes()
+	virtualNodeInformer := meshInformerFactory.Appmesh().V1beta1().VirtualNodes()
+	virtualServiceInformer := meshInformerFactory.Appmesh().V1beta1().VirtualServices()
+	referenceGrantInformer := meshInformerFactory.Appmesh().V1beta1().ReferenceGrants()
+	meshDefaultsInformer := meshInformerFactory.Appmesh().V1beta1().MeshDefaultses()
+
+	dryRunCloud := aws.NewDryRunCloud(cloud)
+	c, err := NewController(
+		dryRunCloud,
+		fakeKubeClientset,
+		fakeMeshClientset,
+		podInformer,
+		secretInformer,
+		namespaceInformer,
+		meshInformer,
+		virtualNodeInformer,
+		virtualServiceInformer,
+		referenceGrantInformer,
+		meshDefaultsInformer,
+		metrics.NewRecorder(false),
+		false,
+		DefaultElectionID,
+		DefaultElectionNamespace,
+		false,
+		DefaultWarmStandbySyncPeriod,
+		scopeOptions,
+		ShardOptions{},
+		0,
+		driftPolicy,
+		clusterID,
+		namingStrategy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Indexers can only be filled once the controller added its own indexes
+	indexers := map[string]cache.Indexer{
+		"pods":            podInformer.Informer().GetIndexer(),
+		"secrets":         secretInformer.Informer().GetIndexer(),
+		"namespaces":      namespaceInformer.Informer().GetIndexer(),
+		"meshes":          meshInformer.Informer().GetIndexer(),
+		"virtualnodes":    virtualNodeInformer.Informer().GetIndexer(),
+		"virtualservices": virtualServiceInformer.Informer().GetIndexer(),
+		"referencegrants": referenceGrantInformer.Informer().GetIndexer(),
+		"meshdefaults":    meshDefaultsInformer.Informer().GetIndexer(),
+	}
+	for _, obj := range kubeObjects {
+		switch obj.(type) {
+		case *corev1.Pod:
+			indexers["pods"].Add(obj)
+		case *corev1.Secret:
+			indexers["secrets"].Add(obj)
+		case *corev1.Namespace:
+			indexers["namespaces"].Add(obj)
+		}
+	}
+	for _, obj := range meshObjects {
+		switch obj.(type) {
+		case *appmeshv1beta1.Mesh:
+			indexers["meshes"].Add(obj)
+		case *appmeshv1beta1.VirtualNode:
+			indexers["virtualnodes"].Add(obj)
+		case *appmeshv1beta1.VirtualService:
+			indexers["virtualservices"].Add(obj)
+		case *appmeshv1beta1.ReferenceGrant:
+			indexers["referencegrants"].Add(obj)
+		case *appmeshv1beta1.MeshDefaults:
+			indexers["meshdefaults"].Add(obj)
+		}
+	}
+	fakeMeshClientset.PrependReactor("update", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if indexer, ok := indexers[action.GetResource().Resource]; ok {
+			indexer.Update(action.(k8stesting.UpdateAction).GetObject())
+		}
+		return false, nil, nil
+	})
+	fakeMeshClientset.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		handled, obj, err := k8stesting.ObjectReaction(fakeMeshClientset.Tracker())(action)
+		if indexer, ok := indexers[action.GetResource().Resource]; ok && err == nil && obj != nil {
+			indexer.Update(obj)
+		}
+		return handled, obj, err
+	})
+
+	var skipped []PlanSkip
+	for pass := 0; pass < maxPlanPasses; pass++ {
+		planned := len(dryRunCloud.Changes())
+		previous := skipped
+		skipped = c.planPass()
+		if pass > 0 && len(dryRunCloud.Changes()) == planned && len(skipped) == len(previous) {
+			break
+		}
+	}
+
+	return &ReconcilePlan{
+		Changes: dryRunCloud.Changes(),
+		Skipped: skipped,
+	}, nil
+}
+
+// addPlanObject adds an App Mesh custom resource to the tracker of a fake clientset. The tracker would guess
+// "meshs" as the resource of meshes, so resources are named explicitly.
+func addPlanObject(tracker k8stesting.ObjectTracker, obj runtime.Object) error {
+	var resource string
+	switch obj.(type) {
+	case *appmeshv1beta1.Mesh:
+		resource = "meshes"
+	case *appmeshv1beta1.VirtualNode:
+		resource = "virtualnodes"
+	case *appmeshv1beta1.VirtualService:
+		resource = "virtualservices"
+	case *appmeshv1beta1.ReferenceGrant:
+		resource = "referencegrants"
+	case *appmeshv1beta1.MeshDefaults:
+		resource = "meshdefaults"
+	}
+	objMeta, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	return tracker.Create(appmeshv1beta1.SchemeGroupVersion.WithResource(resource), obj, objMeta.GetNamespace())
+}
+
+// referencedSecrets returns the namespace/name keys of the Secrets that virtual nodes source certificates from.
+// Secrets that MeshDefaults reference are not planned.
+func referencedSecrets(vnodes []appmeshv1beta1.VirtualNode) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for i := range vnodes {
+		refs, _ := indexVNodesBySecretRef(&vnodes[i])
+		for _, key := range refs {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// meshlessNamespaces returns the namespaces of the virtual nodes and virtual services that have not joined a mesh
+func meshlessNamespaces(vnodes []appmeshv1beta1.VirtualNode, vservices []appmeshv1beta1.VirtualService) []string {
+	seen := map[string]bool{}
+	var namespaces []string
+	add := func(namespace string) {
+		if !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	for _, vnode := range vnodes {
+		if vnodeMeshName(&vnode) == "" {
+			add(vnode.Namespace)
+		}
+	}
+	for _, vservice := range vservices {
+		if vserviceMeshName(&vservice) == "" {
+			add(vservice.Namespace)
+		}
+	}
+	return namespaces
+}
+
+// planPass reconciles every mesh, virtual node, virtual service and pod once, and returns the objects that
+// could not be reconciled.
+func (c *Controller) planPass() []PlanSkip {
+	var skipped []PlanSkip
+	handle := func(kind string, objects []interface{}, handler func(key string) error) {
+		var keys []string
+		for _, obj := range objects {
+			if !c.scope.containsResource(obj) {
+				continue
+			}
+			if key, err := cache.MetaNamespaceKeyFunc(obj); err == nil {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := handler(key); err != nil {
+				skipped = append(skipped, PlanSkip{Kind: kind, Key: key, Err: err})
+			}
+		}
+	}
+
+	handle("mesh", c.meshIndex.List(), c.handleMesh)
+	handle("virtual node", c.virtualNodeIndex.List(), c.handleVNode)
+	handle("virtual service", c.virtualServiceIndex.List(), c.handleVService)
+
+	ctx := context.Background()
+	c.syncPods(ctx)
+	c.syncInstances(ctx)
+
+	return skipped
+}
+
+// Write prints the plan, one line per change grouped by service, followed by the skipped objects and a summary.
+func (p *ReconcilePlan) Write(w io.Writer) error {
+	var b strings.Builder
+	counts := map[string]int{}
+	for _, service := range []struct{ name, title string }{
+		{aws.ServiceAppMesh, "App Mesh"},
+		{aws.ServiceCloudMap, "Cloud Map"},
+	} {
+		var lines []string
+		for _, change := range p.Changes {
+			if change.Service != service.name {
+				continue
+			}
+			counts[change.Action]++
+			name := change.Name
+			if change.Parent != "" {
+				name = change.Parent + "/" + change.Name
+			}
+			lines = append(lines, fmt.Sprintf("  %s %s %s\n", planSymbol(change.Action), change.Kind, name))
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s:\n%s\n", service.title, strings.Join(lines, ""))
+	}
+
+	if len(p.Skipped) > 0 {
+		b.WriteString("Not reconciled, changes to these objects are not planned:\n")
+		for _, skip := range p.Skipped {
+			fmt.Fprintf(&b, "  %s %s: %s\n", skip.Kind, skip.Key, skip.Err)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(p.Changes) == 0 {
+		b.WriteString("No changes.\n")
+	} else {
+		fmt.Fprintf(&b, "Plan: %d to create, %d to update, %d to delete.\n",
+			counts[aws.PlannedCreate], counts[aws.PlannedUpdate], counts[aws.PlannedDelete])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func planSymbol(action string) string {
+	switch action {
+	case aws.PlannedCreate:
+		return "+"
+	case aws.PlannedDelete:
+		return "-"
+	default:
+		return "~"
+	}
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	ctrlawsmocks "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws/mocks"
+	meshfake "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned/fake"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPlan(t *testing.T) {
+	notFound := awserr.New(appmesh.ErrCodeNotFoundException, "not found", nil)
+	cloud := new(ctrlawsmocks.CloudAPI)
+	cloud.On("GetMesh", mock.Anything, mock.Anything).Return(nil, notFound)
+	cloud.On("GetVirtualNode", mock.Anything, mock.Anything, mock.Anything).Return(nil, notFound)
+	cloud.On("GetVirtualRouter", mock.Anything, mock.Anything, mock.Anything).Return(nil, notFound)
+	cloud.On("GetRoutesForVirtualRouter", mock.Anything, mock.Anything, mock.Anything).Return(nil, notFound)
+	cloud.On("GetVirtualService", mock.Anything, mock.Anything, mock.Anything).Return(nil, notFound)
+
+	meshclientset := meshfake.NewSimpleClientset()
+	for _, obj := range []runtime.Object{
+		&appmeshv1beta1.Mesh{ObjectMeta: metav1.ObjectMeta{Name: "mesh"}},
+		&appmeshv1beta1.VirtualNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: "ns"},
+			Spec: appmeshv1beta1.VirtualNodeSpec{
+				MeshName: "mesh",
+				ServiceDiscovery: &appmeshv1beta1.ServiceDiscovery{
+					Dns: &appmeshv1beta1.DnsServiceDiscovery{HostName: "node.ns.svc.cluster.local"},
+				},
+			},
+		},
+		&appmeshv1beta1.VirtualService{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc.ns.svc.cluster.local", Namespace: "ns"},
+			Spec: appmeshv1beta1.VirtualServiceSpec{
+				MeshName:      "mesh",
+				VirtualRouter: &appmeshv1beta1.VirtualRouter{Name: "svc-router"},
+				Routes: []appmeshv1beta1.Route{
+					{
+						Name: "route",
+						Http: &appmeshv1beta1.HttpRoute{
+							Match: appmeshv1beta1.HttpRouteMatch{Prefix: "/"},
+							Action: appmeshv1beta1.HttpRouteAction{
+								WeightedTargets: []appmeshv1beta1.WeightedTarget{{VirtualNodeName: "node", Weight: 1}},
+							},
+						},
+					},
+				},
+			},
+		},
+	} {
+		if err := addPlanObject(meshclientset.Tracker(), obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kubeclientset := kubefake.NewSimpleClientset()
+
+	plan, err := Plan(cloud, kubeclientset, meshclientset, ScopeOptions{}, DriftPolicyEnforce, "cluster", DefaultNamingStrategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []aws.PlannedChange{
+		{Service: aws.ServiceAppMesh, Kind: "mesh", Name: "mesh", Action: aws.PlannedCreate},
+		{Service: aws.ServiceAppMesh, Kind: "virtual_node", Parent: "mesh", Name: "node-ns", Action: aws.PlannedCreate},
+		{Service: aws.ServiceAppMesh, Kind: "virtual_router", Parent: "mesh", Name: "svc-router-ns", Action: aws.PlannedCreate},
+		{Service: aws.ServiceAppMesh, Kind: "route", Parent: "mesh/svc-router-ns", Name: "route", Action: aws.PlannedCreate},
+		{Service: aws.ServiceAppMesh, Kind: "virtual_service", Parent: "mesh", Name: "svc.ns.svc.cluster.local", Action: aws.PlannedCreate},
+	}
+	if !reflect.DeepEqual(plan.Changes, want) {
+		t.Errorf("got changes %+v, want %+v", plan.Changes, want)
+	}
+	if len(plan.Skipped) != 0 {
+		t.Errorf("got skipped %+v", plan.Skipped)
+	}
+
+	// Planning only lists objects from the cluster
+	for _, action := range append(meshclientset.Actions(), kubeclientset.Actions()...) {
+		if action.GetVerb() != "list" {
+			t.Errorf("unexpected %s of %s", action.GetVerb(), action.GetResource().Resource)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := plan.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	wantOut := `App Mesh:
+  + mesh mesh
+  + virtual_node mesh/node-ns
+  + virtual_router mesh/svc-router-ns
+  + route mesh/svc-router-ns/route
+  + virtual_service mesh/svc.ns.svc.cluster.local
+
+Plan: 5 to create, 0 to update, 0 to delete.
+`
+	if out.String() != wantOut {
+		t.Errorf("got output\n%s\nwant\n%s", out.String(), wantOut)
+	}
+}
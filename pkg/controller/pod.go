@@ -10,9 +10,9 @@ import (
 	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
 	awssdk "github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/appmesh"
+	"github.com/aws/aws-sdk-go/service/servicediscovery"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog"
 )
@@ -60,7 +60,7 @@ func (c *Controller) syncPods(ctx context.Context) {
 	defer func() {
 		c.stats.RecordOperationDuration("podctl", "", "syncPods", time.Since(begin))
 	}()
-	pods, err := c.podsLister.List(labels.Everything())
+	pods, err := c.listPods()
 	if err != nil {
 		klog.Errorf("Error listing pods %v", err)
 		return
@@ -82,12 +82,15 @@ func (c *Controller) syncInstances(ctx context.Context) {
 
 	syncedServices := make(map[string]bool)
 
-	virtualNodes, err := c.virtualNodeLister.List(labels.Everything())
+	virtualNodes, err := c.listVirtualNodes()
 	if err != nil {
 		return
 	}
 
 	for _, virtualNode := range virtualNodes {
+		if !c.shards.owns(vnodeMeshName(virtualNode)) {
+			continue
+		}
 		if virtualNode.Spec.ServiceDiscovery == nil ||
 			virtualNode.Spec.ServiceDiscovery.CloudMap == nil {
 			continue
@@ -112,12 +115,21 @@ func (c *Controller) syncInstances(ctx context.Context) {
 		for _, instance := range instances {
 			podName := awssdk.StringValue(instance.Attributes[ctrlaws.AttrK8sPod])
 			podNamespace := awssdk.StringValue(instance.Attributes[ctrlaws.AttrK8sNamespace])
+			// Instances registered for pods outside of our scope belong to another controller
+			if !c.scope.containsNamespace(podNamespace) {
+				continue
+			}
 			_, err := c.podsLister.Pods(podNamespace).Get(podName)
 			if errors.IsNotFound(err) {
-				err = c.cloud.DeregisterInstance(ctx, awssdk.StringValue(instance.Id), appmeshCloudMapConfig)
+				instanceID := awssdk.StringValue(instance.Id)
+				owner := c.instanceVirtualNode(instance, virtualNode)
+				err = c.cloud.DeregisterInstance(ctx, instanceID, appmeshCloudMapConfig)
 				if err != nil {
 					klog.Errorf("Unable to deregister instance from cloudmap %v", err)
+					c.recordInstanceFailure(owner, nil, reconcileErrorf(reasonDeregisterFailed, err, "error deregistering instance %s of deleted pod %s/%s: %s", instanceID, podNamespace, podName, err))
+					continue
 				}
+				c.recordInstanceEvent(owner, nil, instanceDeregisteredReason, "Deregistered instance %s of deleted pod %s/%s from Cloud Map service %s", instanceID, podNamespace, podName, cloudmapConfig.ServiceName)
 			}
 		}
 
@@ -125,6 +137,32 @@ func (c *Controller) syncInstances(ctx context.Context) {
 	}
 }
 
+// instanceVirtualNode returns the virtual node an instance was registered for, found by the App Mesh mesh and
+// virtual node names in its attributes. Instances without them are attributed to the virtual node whose Cloud Map
+// service they were listed from.
+func (c *Controller) instanceVirtualNode(instance *servicediscovery.InstanceSummary, listedFrom *appmeshv1beta1.VirtualNode) *appmeshv1beta1.VirtualNode {
+	meshName := awssdk.StringValue(instance.Attributes[attributeKeyAppMeshMeshName])
+	virtualNodeName := awssdk.StringValue(instance.Attributes[attributeKeyAppMeshVirtualNodeName])
+	if vnode := c.virtualNodeByAWSName(meshName, virtualNodeName); vnode != nil {
+		return vnode
+	}
+	return listedFrom
+}
+
+// virtualNodeByAWSName returns the virtual node custom resource with the App Mesh name in the mesh, or nil if
+// there is none.
+func (c *Controller) virtualNodeByAWSName(meshName string, awsName string) *appmeshv1beta1.VirtualNode {
+	if c.virtualNodeIndex == nil || meshName == "" || awsName == "" {
+		return nil
+	}
+	objects, err := c.virtualNodeIndex.ByIndex(awsNameIndex, meshResourceKey(meshName, awsName))
+	if err != nil || len(objects) == 0 {
+		return nil
+	}
+	vnode, _ := objects[0].(*appmeshv1beta1.VirtualNode)
+	return vnode
+}
+
 func (c *Controller) syncPod(ctx context.Context, pod *corev1.Pod) error {
 	begin := time.Now()
 	defer func() {
@@ -176,6 +214,11 @@ func (c *Controller) syncPod(ctx context.Context, pod *corev1.Pod) error {
 		return nil
 	}
 
+	if !c.shards.owns(meshName) {
+		klog.V(4).Infof("Skipping pod %s in mesh %s owned by another shard", pod.Name, meshName)
+		return nil
+	}
+
 	virtualNode, err := c.cloud.GetVirtualNode(ctx, virtualNodeName, meshName)
 	if errors.IsNotFound(err) {
 		return nil
@@ -193,20 +236,37 @@ func (c *Controller) syncPod(ctx context.Context, pod *corev1.Pod) error {
 	}
 
 	cloudmapConfig := virtualNode.Data.Spec.ServiceDiscovery.AwsCloudMap
+	// Events are recorded on the virtual node custom resource that owns the instance as well as on the pod
+	owner := c.virtualNodeByAWSName(meshName, virtualNodeName)
 
 	if !pod.DeletionTimestamp.IsZero() {
 		klog.V(4).Infof("Deregistering instance %s under service %+v", pod.Name, cloudmapConfig)
 		err = c.cloud.DeregisterInstance(ctx, instanceID, cloudmapConfig)
 		if err != nil {
+			err = reconcileErrorf(reasonDeregisterFailed, err, "error deregistering instance %s of pod %s: %s", instanceID, pod.Name, err)
+			c.recordInstanceFailure(owner, pod, err)
 			return err
 		}
+		c.recordInstanceEvent(owner, pod, instanceDeregisteredReason, "Deregistered instance %s of pod %s/%s from Cloud Map service %s", instanceID, pod.Namespace, pod.Name, awssdk.StringValue(cloudmapConfig.ServiceName))
+	}
+
+	// A warm standby that just took over already knows which instances are registered
+	if c.snapshot.takeInstance(snapshotInstancesKey(cloudmapConfig), instanceID, pod) {
+		klog.V(4).Infof("Instance %s is already registered under service %+v", pod.Name, cloudmapConfig)
+		return nil
 	}
 
 	klog.V(4).Infof("Registering instance %s under service %+v", pod.Name, cloudmapConfig)
 	err = c.cloud.RegisterInstance(ctx, instanceID, pod, cloudmapConfig)
 	if err != nil {
+		err = reconcileErrorf(reasonRegisterFailed, err, "error registering instance %s of pod %s: %s", instanceID, pod.Name, err)
+		c.recordInstanceFailure(owner, pod, err)
 		return err
 	}
+	// Pods that are not running yet are skipped rather than registered
+	if pod.Status.Phase == corev1.PodRunning {
+		c.recordInstanceEvent(owner, pod, instanceRegisteredReason, "Registered instance %s of pod %s/%s in Cloud Map service %s", instanceID, pod.Namespace, pod.Name, awssdk.StringValue(cloudmapConfig.ServiceName))
+	}
 
 	return nil
 }
@@ -0,0 +1,183 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	ctrlaws "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	ctrlawsmocks "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws/mocks"
+	meshlisters "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/listers/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/metrics"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	"github.com/aws/aws-sdk-go/service/servicediscovery"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestSyncInstancesRecordsDeregistrations(t *testing.T) {
+	ctx := context.Background()
+	cloudMap := &appmeshv1beta1.CloudMapServiceDiscovery{NamespaceName: "local", ServiceName: "color"}
+	listing := newDependencyVNode("blue", api.ConditionTrue)
+	listing.Spec.ServiceDiscovery = &appmeshv1beta1.ServiceDiscovery{CloudMap: cloudMap}
+	// The owner registered its instance in the service, but is no longer configured with it
+	owner := newDependencyVNode("red", api.ConditionTrue)
+
+	recorder := record.NewFakeRecorder(10)
+	c := &Controller{
+		recorder:   recorder,
+		events:     newEventDeduper(eventDedupeWindow),
+		stats:      metrics.NewRecorder(false),
+		podsLister: corelisters.NewPodLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+	}
+	c.virtualNodeIndex = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{awsNameIndex: c.indexByAWSName})
+	c.virtualNodeLister = meshlisters.NewVirtualNodeLister(c.virtualNodeIndex)
+	c.virtualNodeIndex.Add(listing)
+	c.virtualNodeIndex.Add(owner)
+
+	instance := func(id string, attributes map[string]string) *servicediscovery.InstanceSummary {
+		summary := &servicediscovery.InstanceSummary{Id: awssdk.String(id), Attributes: map[string]*string{}}
+		for key, value := range attributes {
+			summary.Attributes[key] = awssdk.String(value)
+		}
+		return summary
+	}
+	appmeshCloudMapConfig := &appmesh.AwsCloudMapServiceDiscovery{
+		NamespaceName: awssdk.String("local"),
+		ServiceName:   awssdk.String("color"),
+	}
+	cloud := new(ctrlawsmocks.CloudAPI)
+	cloud.On("ListInstances", ctx, appmeshCloudMapConfig).Return([]*servicediscovery.InstanceSummary{
+		instance("owned", map[string]string{
+			ctrlaws.AttrK8sPod:                 "red-1",
+			ctrlaws.AttrK8sNamespace:           "ns",
+			attributeKeyAppMeshMeshName:        "mesh",
+			attributeKeyAppMeshVirtualNodeName: "red-ns",
+		}),
+		instance("unattributed", map[string]string{
+			ctrlaws.AttrK8sPod:       "blue-1",
+			ctrlaws.AttrK8sNamespace: "ns",
+		}),
+	}, nil)
+	cloud.On("DeregisterInstance", ctx, "owned", appmeshCloudMapConfig).Return(nil)
+	cloud.On("DeregisterInstance", ctx, "unattributed", appmeshCloudMapConfig).Return(errors.New("throttled"))
+	c.cloud = cloud
+
+	c.syncInstances(ctx)
+	cloud.AssertExpectations(t)
+
+	if len(recorder.Events) != 2 {
+		t.Fatalf("expected an event per deregistration, got %d events", len(recorder.Events))
+	}
+	events := []string{<-recorder.Events, <-recorder.Events}
+	want := []string{
+		"Normal InstanceDeregistered Deregistered instance owned of deleted pod ns/red-1 from Cloud Map service color",
+		"Warning DeregisterFailed error deregistering instance unattributed of deleted pod ns/blue-1: throttled",
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("got event %q, want %q", events[i], want[i])
+		}
+	}
+}
+
+func TestInstanceVirtualNode(t *testing.T) {
+	owner := newDependencyVNode("red", api.ConditionTrue)
+	listing := newDependencyVNode("blue", api.ConditionTrue)
+	c := &Controller{}
+	c.virtualNodeIndex = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{awsNameIndex: c.indexByAWSName})
+	c.virtualNodeIndex.Add(owner)
+	c.virtualNodeIndex.Add(listing)
+
+	var tests = []struct {
+		name       string
+		attributes map[string]*string
+		want       *appmeshv1beta1.VirtualNode
+	}{
+		{
+			name: "attributed",
+			attributes: map[string]*string{
+				attributeKeyAppMeshMeshName:        awssdk.String("mesh"),
+				attributeKeyAppMeshVirtualNodeName: awssdk.String("red-ns"),
+			},
+			want: owner,
+		},
+		{
+			name: "unknown virtual node",
+			attributes: map[string]*string{
+				attributeKeyAppMeshMeshName:        awssdk.String("mesh"),
+				attributeKeyAppMeshVirtualNodeName: awssdk.String("green-ns"),
+			},
+			want: listing,
+		},
+		{name: "unattributed", want: listing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := c.instanceVirtualNode(&servicediscovery.InstanceSummary{Attributes: tt.attributes}, listing)
+			if got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want.Name, got.Name)
+			}
+		})
+	}
+
+	if got := (&Controller{}).instanceVirtualNode(&servicediscovery.InstanceSummary{}, listing); got != listing {
+		t.Errorf("expected the listing virtual node without an index, got %s", got.Name)
+	}
+}
+
+func TestSyncPodRecordsOnOwner(t *testing.T) {
+	ctx := context.Background()
+	owner := newDependencyVNode("red", api.ConditionTrue)
+	pod := &api.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "red-1", Namespace: "ns", Annotations: map[string]string{
+			annotationAppMeshMeshName:        "mesh",
+			annotationAppMeshVirtualNodeName: "red-ns",
+		}},
+		Status: api.PodStatus{Phase: api.PodRunning, PodIP: "10.0.0.1"},
+	}
+	appmeshCloudMapConfig := &appmesh.AwsCloudMapServiceDiscovery{
+		NamespaceName: awssdk.String("local"),
+		ServiceName:   awssdk.String("color"),
+	}
+
+	recorder := record.NewFakeRecorder(10)
+	cloud := new(ctrlawsmocks.CloudAPI)
+	c := &Controller{
+		cloud:    cloud,
+		recorder: recorder,
+		events:   newEventDeduper(eventDedupeWindow),
+		stats:    metrics.NewRecorder(false),
+	}
+	c.virtualNodeIndex = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{awsNameIndex: c.indexByAWSName})
+	c.virtualNodeIndex.Add(owner)
+	cloud.On("GetVirtualNode", ctx, "red-ns", "mesh").Return(&ctrlaws.VirtualNode{Data: appmesh.VirtualNodeData{
+		Spec: &appmesh.VirtualNodeSpec{ServiceDiscovery: &appmesh.ServiceDiscovery{AwsCloudMap: appmeshCloudMapConfig}},
+	}}, nil)
+	cloud.On("RegisterInstance", ctx, "10.0.0.1", pod, appmeshCloudMapConfig).Return(nil)
+
+	if err := c.syncPod(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	// The repeated registration of the periodic sync is deduplicated
+	if err := c.syncPod(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	cloud.AssertExpectations(t)
+
+	if len(recorder.Events) != 2 {
+		t.Fatalf("expected the registration to be recorded on the virtual node and the pod, got %d events", len(recorder.Events))
+	}
+	want := "Normal InstanceRegistered Registered instance 10.0.0.1 of pod ns/red-1 in Cloud Map service color"
+	for i := 0; i < 2; i++ {
+		if event := <-recorder.Events; event != want {
+			t.Errorf("got event %q, want %q", event, want)
+		}
+	}
+}
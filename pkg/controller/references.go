@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// annotationWaitForRouteTargets holds off creating or updating the routes of a virtual service until every
+	// virtual node they target exists, rather than leaving the virtual router with only the routes App Mesh
+	// accepted
+	annotationWaitForRouteTargets = "appmesh.k8s.aws/waitForRouteTargets"
+
+	referencesResolvedReason   = "ReferencesResolved"
+	unresolvedReferencesReason = "UnresolvedReferences"
+)
+
+// waitForRouteTargets reports whether obj has the annotation to hold off routes with unresolved targets.
+func waitForRouteTargets(obj metav1.Object) bool {
+	return boolAnnotation(obj, annotationWaitForRouteTargets)
+}
+
+// unresolvedVNodeReferences returns the backend virtual services of the virtual node that neither have a custom
+// resource nor exist in App Mesh. Backends that are not granted are left to deniedVNodeReferences.
+func (c *Controller) unresolvedVNodeReferences(ctx context.Context, vnode *appmeshv1beta1.VirtualNode) ([]string, error) {
+	var unresolved []string
+	for _, backend := range vnode.Spec.Backends {
+		name := backend.VirtualService.VirtualServiceName
+		if !c.referenceAllowed(vnode.Namespace, c.backendNamespace(vnode, backend), appmeshv1beta1.ReferenceKindVirtualService, name) {
+			continue
+		}
+		if c.virtualServiceIndex != nil {
+			if objects, err := c.virtualServiceIndex.ByIndex(virtualServiceNameIndex, meshResourceKey(vnodeMeshName(vnode), name)); err == nil && len(objects) > 0 {
+				continue
+			}
+		}
+		if _, err := c.describeVirtualService(ctx, name, vnodeMeshName(vnode)); err != nil {
+			if !aws.IsAWSErrNotFound(err) {
+				return nil, fmt.Errorf("error describing backend virtual service %s: %s", name, err)
+			}
+			unresolved = append(unresolved, "virtual service "+name)
+		}
+	}
+	return sortedUnique(unresolved), nil
+}
+
+// unresolvedVServiceReferences returns the virtual nodes targeted by routes of the virtual service that neither
+// have a custom resource nor exist in App Mesh. Targets that are not granted are left to deniedVServiceReferences.
+func (c *Controller) unresolvedVServiceReferences(ctx context.Context, vservice *appmeshv1beta1.VirtualService) ([]string, error) {
+	var unresolved []string
+	for _, route := range vservice.Spec.Routes {
+		for _, target := range routeTargets(route) {
+			name, namespace := targetReference(target, vservice.Namespace)
+			if !c.referenceAllowed(vservice.Namespace, namespace, appmeshv1beta1.ReferenceKindVirtualNode, name) {
+				continue
+			}
+			if c.virtualNodeLister != nil {
+				if _, err := c.virtualNodeLister.VirtualNodes(namespace).Get(name); err == nil {
+					continue
+				} else if !errors.IsNotFound(err) {
+					return nil, err
+				}
+			}
+			if _, err := c.describeVirtualNode(ctx, c.virtualNodeRefAWSName(name, namespace), vserviceMeshName(vservice)); err != nil {
+				if !aws.IsAWSErrNotFound(err) {
+					return nil, fmt.Errorf("error describing route target virtual node %s: %s", name, err)
+				}
+				unresolved = append(unresolved, "virtual node "+name)
+			}
+		}
+	}
+	return sortedUnique(unresolved), nil
+}
+
+// referencesCondition returns the status, reason and message of the ReferencesResolved condition.
+func referencesCondition(unresolved []string, denied []string) (api.ConditionStatus, string, string) {
+	var messages []string
+	if len(unresolved) > 0 {
+		messages = append(messages, fmt.Sprintf("Unresolved references: %s", strings.Join(unresolved, ", ")))
+	}
+	if len(denied) > 0 {
+		messages = append(messages, fmt.Sprintf("References not granted by a ReferenceGrant: %s", strings.Join(denied, ", ")))
+	}
+	switch {
+	case len(denied) > 0:
+		return api.ConditionFalse, referenceNotGrantedReason, strings.Join(messages, "; ")
+	case len(unresolved) > 0:
+		return api.ConditionFalse, unresolvedReferencesReason, strings.Join(messages, "; ")
+	}
+	return api.ConditionTrue, referencesResolvedReason, ""
+}
+
+// updateVNodeReferences sets the ReferencesResolved condition of the virtual node, and returns the backends that
+// are not granted.
+func (c *Controller) updateVNodeReferences(ctx context.Context, vnode *appmeshv1beta1.VirtualNode) ([]string, error) {
+	unresolved, err := c.unresolvedVNodeReferences(ctx, vnode)
+	if err != nil {
+		return nil, err
+	}
+	denied := c.deniedVNodeReferences(vnode)
+	status, reason, message := referencesCondition(unresolved, denied)
+	if _, err := c.putVNodeReasonCondition(vnode, appmeshv1beta1.VirtualNodeReferencesResolved, status, reason, message); err != nil {
+		return nil, reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual node status: %s", err)
+	}
+	return denied, nil
+}
+
+// updateVServiceReferences sets the ReferencesResolved condition of the virtual service, and returns the
+// unresolved route targets along with the ones that are not granted.
+func (c *Controller) updateVServiceReferences(ctx context.Context, vservice *appmeshv1beta1.VirtualService) ([]string, []string, error) {
+	unresolved, err := c.unresolvedVServiceReferences(ctx, vservice)
+	if err != nil {
+		return nil, nil, err
+	}
+	denied := c.deniedVServiceReferences(vservice)
+	status, reason, message := referencesCondition(unresolved, denied)
+	if _, err := c.putVServiceReasonCondition(vservice, appmeshv1beta1.VirtualServiceReferencesResolved, status, reason, message); err != nil {
+		return nil, nil, reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual service status: %s", err)
+	}
+	return unresolved, denied, nil
+}
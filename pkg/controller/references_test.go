@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	ctrlawsmocks "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws/mocks"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	"github.com/stretchr/testify/mock"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUnresolvedReferences(t *testing.T) {
+	ctx := context.Background()
+	notFound := awserr.New(appmesh.ErrCodeNotFoundException, "not found", nil)
+	cloud := new(ctrlawsmocks.CloudAPI)
+	cloud.On("GetVirtualNode", mock.Anything, "blue-ns", "mesh").Return(nil, notFound)
+	cloud.On("GetVirtualService", mock.Anything, "external.example.com", "mesh").Return(&aws.VirtualService{}, nil)
+	cloud.On("GetVirtualService", mock.Anything, "missing.example.com", "mesh").Return(nil, notFound)
+
+	vservice := newDependencyVService(api.ConditionTrue)
+	c := newDependencyController(
+		[]*appmeshv1beta1.VirtualService{vservice},
+		[]*appmeshv1beta1.VirtualNode{newDependencyVNode("red", api.ConditionTrue)},
+	)
+	c.cloud = cloud
+
+	// red has a custom resource, blue exists neither in the cluster nor in App Mesh
+	unresolved, err := c.unresolvedVServiceReferences(ctx, vservice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(unresolved, []string{"virtual node blue"}) {
+		t.Errorf("expected blue to be unresolved, got %v", unresolved)
+	}
+
+	// Backends without a custom resource are resolved when they exist in App Mesh
+	vnode := newDependencyVNode("red", api.ConditionTrue)
+	vnode.Spec.Backends = append(vnode.Spec.Backends,
+		appmeshv1beta1.Backend{VirtualService: appmeshv1beta1.VirtualServiceBackend{VirtualServiceName: "external.example.com"}},
+		appmeshv1beta1.Backend{VirtualService: appmeshv1beta1.VirtualServiceBackend{VirtualServiceName: "missing.example.com"}},
+	)
+	unresolved, err = c.unresolvedVNodeReferences(ctx, vnode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(unresolved, []string{"virtual service missing.example.com"}) {
+		t.Errorf("expected missing.example.com to be unresolved, got %v", unresolved)
+	}
+
+	status, reason, message := referencesCondition(unresolved, nil)
+	if status != api.ConditionFalse || reason != unresolvedReferencesReason || message != "Unresolved references: virtual service missing.example.com" {
+		t.Errorf("unexpected condition %s, %s, %s", status, reason, message)
+	}
+	if status, reason, _ := referencesCondition(nil, nil); status != api.ConditionTrue || reason != referencesResolvedReason {
+		t.Errorf("unexpected condition %s, %s", status, reason)
+	}
+}
+
+func TestWaitForRouteTargets(t *testing.T) {
+	vservice := &appmeshv1beta1.VirtualService{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{annotationWaitForRouteTargets: "true"}}}
+	if !waitForRouteTargets(vservice) {
+		t.Error("expected routes to be held")
+	}
+	if waitForRouteTargets(&appmeshv1beta1.VirtualService{}) {
+		t.Error("expected routes not to be held without the annotation")
+	}
+}
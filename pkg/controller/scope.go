@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"fmt"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+)
+
+// scope limits the objects a controller manages. Objects outside of the watched namespaces, and App Mesh custom
+// resources that don't match the label selector, are never reconciled.
+type scope struct {
+	namespaces sets.String
+	selector   labels.Selector
+}
+
+func newScope(opts ScopeOptions) (*scope, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource label selector %q: %s", opts.LabelSelector, err)
+	}
+	return &scope{
+		namespaces: sets.NewString(opts.Namespaces...),
+		selector:   selector,
+	}, nil
+}
+
+// containsNamespace reports whether the namespace is watched. Cluster scoped objects have no namespace and are
+// always contained.
+func (s *scope) containsNamespace(namespace string) bool {
+	if s == nil || s.namespaces.Len() == 0 || namespace == "" {
+		return true
+	}
+	return s.namespaces.Has(namespace)
+}
+
+// containsResource reports whether an App Mesh custom resource is in scope.
+func (s *scope) containsResource(obj interface{}) bool {
+	if s == nil {
+		return true
+	}
+	metaobj, err := scopeAccessor(obj)
+	if err != nil {
+		return false
+	}
+	return s.containsNamespace(metaobj.GetNamespace()) && s.selector.Matches(labels.Set(metaobj.GetLabels()))
+}
+
+// containsNamespacedObject reports whether an object that is only scoped by namespace is in scope. Pods, Secrets,
+// reference grants and mesh defaults don't carry the labels of the App Mesh custom resources they apply to.
+func (s *scope) containsNamespacedObject(obj interface{}) bool {
+	if s == nil {
+		return true
+	}
+	metaobj, err := scopeAccessor(obj)
+	if err != nil {
+		return false
+	}
+	return s.containsNamespace(metaobj.GetNamespace())
+}
+
+func scopeAccessor(obj interface{}) (metav1.Object, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	return meta.Accessor(obj)
+}
+
+// listPods lists the pods in scope from the informer cache.
+func (c *Controller) listPods() ([]*corev1.Pod, error) {
+	pods, err := c.podsLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var result []*corev1.Pod
+	for _, pod := range pods {
+		if c.scope.containsNamespacedObject(pod) {
+			result = append(result, pod)
+		}
+	}
+	return result, nil
+}
+
+// listVirtualNodes lists the virtual nodes in scope from the informer cache.
+func (c *Controller) listVirtualNodes() ([]*appmeshv1beta1.VirtualNode, error) {
+	vnodes, err := c.virtualNodeLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var result []*appmeshv1beta1.VirtualNode
+	for _, vnode := range vnodes {
+		if c.scope.containsResource(vnode) {
+			result = append(result, vnode)
+		}
+	}
+	return result, nil
+}
+
+// listVirtualServices lists the virtual services in scope from the informer cache.
+func (c *Controller) listVirtualServices() ([]*appmeshv1beta1.VirtualService, error) {
+	vservices, err := c.virtualServiceLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	var result []*appmeshv1beta1.VirtualService
+	for _, vservice := range vservices {
+		if c.scope.containsResource(vservice) {
+			result = append(result, vservice)
+		}
+	}
+	return result, nil
+}
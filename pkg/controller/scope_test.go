@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+)
+
+func TestScope(t *testing.T) {
+	s, err := newScope(ScopeOptions{
+		Namespaces:    []string{"team-a", "team-b"},
+		LabelSelector: "owner=team",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	newVNode := func(namespace string, labels map[string]string) *appmeshv1beta1.VirtualNode {
+		return &appmeshv1beta1.VirtualNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "node", Namespace: namespace, Labels: labels},
+		}
+	}
+
+	var scopetests = []struct {
+		name     string
+		obj      interface{}
+		resource bool
+		pod      bool
+	}{
+		{"watched namespace with matching labels", newVNode("team-a", map[string]string{"owner": "team"}), true, true},
+		{"watched namespace without labels", newVNode("team-b", nil), false, true},
+		{"unwatched namespace with matching labels", newVNode("team-c", map[string]string{"owner": "team"}), false, false},
+		{"cluster scoped mesh with matching labels", &appmeshv1beta1.Mesh{ObjectMeta: metav1.ObjectMeta{Name: "mesh", Labels: map[string]string{"owner": "team"}}}, true, true},
+		{"pod in watched namespace", &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "team-a"}}, false, true},
+		{"tombstone in watched namespace", cache.DeletedFinalStateUnknown{Obj: newVNode("team-a", map[string]string{"owner": "team"})}, true, true},
+	}
+
+	for _, tt := range scopetests {
+		t.Run(tt.name, func(t *testing.T) {
+			if res := s.containsResource(tt.obj); res != tt.resource {
+				t.Errorf("containsResource got %v, want %v", res, tt.resource)
+			}
+			if res := s.containsNamespacedObject(tt.obj); res != tt.pod {
+				t.Errorf("containsNamespacedObject got %v, want %v", res, tt.pod)
+			}
+		})
+	}
+
+	t.Run("invalid selector", func(t *testing.T) {
+		if _, err := newScope(ScopeOptions{LabelSelector: "owner in (team"}); err == nil {
+			t.Errorf("expected error for invalid selector")
+		}
+	})
+
+	t.Run("unscoped", func(t *testing.T) {
+		var unscoped *scope
+		if !unscoped.containsResource(newVNode("team-c", nil)) {
+			t.Errorf("expected nil scope to contain everything")
+		}
+	})
+}
@@ -0,0 +1,337 @@
+package controller
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+// Certificates sourced from Secrets are sent to App Mesh as files under secretMountPath/<secret name>, where the
+// sidecar is expected to mount the Secret. Envoy reads the files itself, so a rotated Secret takes effect once the
+// kubelet updates the mounted files; the controller only watches Secrets to report when their certificates expire.
+const secretMountPath = "/etc/appmesh/secrets"
+
+// secretRefIndex indexes virtual nodes by the namespace/name of the Secrets they source certificates from
+const secretRefIndex = "secretRef"
+
+const (
+	reasonCertificateSecretInvalid = "CertificateSecretInvalid"
+	// reasonCertificateSecretTypeUnsupported is reported for Secrets that exist, but are not of type
+	// kubernetes.io/tls, which the controller does not watch
+	reasonCertificateSecretTypeUnsupported = "CertificateSecretTypeUnsupported"
+)
+
+// caCertKey is the key of the certificate authority in a Secret of type kubernetes.io/tls
+const caCertKey = "ca.crt"
+
+// TweakSecretListOptions restricts Secrets to the kubernetes.io/tls type certificates can be sourced from, so that
+// the controller does not cache other Secrets. This includes the Secrets trust is sourced from, which keep the
+// certificate authority in ca.crt next to tls.crt and tls.key, as cert-manager issues them.
+func TweakSecretListOptions(options *metav1.ListOptions) {
+	options.FieldSelector = fields.OneTermEqualSelector("type", string(corev1.SecretTypeTLS)).String()
+}
+
+// secretCertificate is a certificate a virtual node sources from a Secret
+type secretCertificate struct {
+	secretName string
+	key        string
+	// privateKey is the key of the private key that goes along with the certificate, if any
+	privateKey string
+}
+
+// secretCertificates returns the certificates a virtual node sources from Secrets, sorted by Secret and key.
+func secretCertificates(vnode *appmeshv1beta1.VirtualNode) []secretCertificate {
+	seen := map[secretCertificate]bool{}
+	var certificates []secretCertificate
+	add := func(certificate secretCertificate) {
+		if !seen[certificate] {
+			seen[certificate] = true
+			certificates = append(certificates, certificate)
+		}
+	}
+	for _, listener := range vnode.Spec.Listeners {
+		if listener.TLS != nil && listener.TLS.Certificate.SecretRef != nil {
+			add(secretCertificate{secretName: listener.TLS.Certificate.SecretRef.Name, key: corev1.TLSCertKey, privateKey: corev1.TLSPrivateKeyKey})
+		}
+	}
+	for _, clientTls := range clientTlsPolicies(vnode) {
+		if trust := clientTls.tls.Validation.Trust.SecretRef; trust != nil {
+			add(secretCertificate{secretName: trust.Name, key: caCertKey})
+		}
+		if certificate := clientTls.tls.Certificate; certificate != nil && certificate.SecretRef != nil {
+			add(secretCertificate{secretName: certificate.SecretRef.Name, key: corev1.TLSCertKey, privateKey: corev1.TLSPrivateKeyKey})
+		}
+	}
+	sort.Slice(certificates, func(i, j int) bool {
+		if certificates[i].secretName != certificates[j].secretName {
+			return certificates[i].secretName < certificates[j].secretName
+		}
+		return certificates[i].key < certificates[j].key
+	})
+	return certificates
+}
+
+// backendDefaultsUseSecret returns whether backend defaults source a certificate from the Secret
+func backendDefaultsUseSecret(backendDefaults *appmeshv1beta1.BackendDefaults, secretName string) bool {
+	vnode := &appmeshv1beta1.VirtualNode{Spec: appmeshv1beta1.VirtualNodeSpec{BackendDefaults: backendDefaults}}
+	for _, certificate := range secretCertificates(vnode) {
+		if certificate.secretName == secretName {
+			return true
+		}
+	}
+	return false
+}
+
+// secretFilePath returns the path at which the sidecar finds a key of a Secret
+func secretFilePath(secretName string, key string) string {
+	return path.Join(secretMountPath, secretName, key)
+}
+
+// resolveSecretCertificates replaces the certificates a virtual node sources from Secrets with the files the
+// sidecar finds them in.
+func resolveSecretCertificates(vnode *appmeshv1beta1.VirtualNode) {
+	for i := range vnode.Spec.Listeners {
+		tls := vnode.Spec.Listeners[i].TLS
+		if tls == nil || tls.Certificate.SecretRef == nil {
+			continue
+		}
+		name := tls.Certificate.SecretRef.Name
+		tls.Certificate.File = &appmeshv1beta1.ListenerTlsFileCertificate{
+			CertificateChain: secretFilePath(name, corev1.TLSCertKey),
+			PrivateKey:       secretFilePath(name, corev1.TLSPrivateKeyKey),
+		}
+		tls.Certificate.SecretRef = nil
+	}
+	for _, clientTls := range clientTlsPolicies(vnode) {
+		if trust := &clientTls.tls.Validation.Trust; trust.SecretRef != nil {
+			trust.File = &appmeshv1beta1.TlsValidationContextFileTrust{
+				CertificateChain: secretFilePath(trust.SecretRef.Name, caCertKey),
+			}
+			trust.SecretRef = nil
+		}
+		if certificate := clientTls.tls.Certificate; certificate != nil && certificate.SecretRef != nil {
+			name := certificate.SecretRef.Name
+			certificate.File = &appmeshv1beta1.ListenerTlsFileCertificate{
+				CertificateChain: secretFilePath(name, corev1.TLSCertKey),
+				PrivateKey:       secretFilePath(name, corev1.TLSPrivateKeyKey),
+			}
+			certificate.SecretRef = nil
+		}
+	}
+}
+
+// certificateNotAfter returns when the first certificate of a PEM encoded chain to expire expires.
+func certificateNotAfter(data []byte) (time.Time, error) {
+	var notAfter time.Time
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		data = rest
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if notAfter.IsZero() || certificate.NotAfter.Before(notAfter) {
+			notAfter = certificate.NotAfter
+		}
+	}
+	if notAfter.IsZero() {
+		return time.Time{}, fmt.Errorf("no PEM encoded certificate")
+	}
+	return notAfter, nil
+}
+
+// uncachedSecretType returns the type of a Secret that is not in the cache of kubernetes.io/tls Secrets, or "" if
+// it does not exist either.
+func (c *Controller) uncachedSecretType(namespace string, name string) corev1.SecretType {
+	if c.kubeclientset == nil {
+		return ""
+	}
+	secret, err := c.kubeclientset.CoreV1().Secrets(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		return ""
+	}
+	return secret.Type
+}
+
+// certificateStatus reads a certificate from its Secret and returns when it expires.
+func (c *Controller) certificateStatus(namespace string, certificate secretCertificate) (appmeshv1beta1.CertificateStatus, error) {
+	secret, err := c.secretsLister.Secrets(namespace).Get(certificate.secretName)
+	if errors.IsNotFound(err) {
+		// Secret types are immutable, so a Secret of another type has to be replaced, which the informer sees
+		if secretType := c.uncachedSecretType(namespace, certificate.secretName); secretType != "" && secretType != corev1.SecretTypeTLS {
+			return appmeshv1beta1.CertificateStatus{}, reconcileErrorf(reasonCertificateSecretTypeUnsupported, nil, "secret %s is of type %s, not %s", certificate.secretName, secretType, corev1.SecretTypeTLS)
+		}
+		return appmeshv1beta1.CertificateStatus{}, fmt.Errorf("secret %s of type %s does not exist", certificate.secretName, corev1.SecretTypeTLS)
+	} else if err != nil {
+		return appmeshv1beta1.CertificateStatus{}, err
+	}
+	if secret.Type != corev1.SecretTypeTLS {
+		return appmeshv1beta1.CertificateStatus{}, reconcileErrorf(reasonCertificateSecretTypeUnsupported, nil, "secret %s is of type %s, not %s", certificate.secretName, secret.Type, corev1.SecretTypeTLS)
+	}
+	if certificate.privateKey != "" && len(secret.Data[certificate.privateKey]) == 0 {
+		return appmeshv1beta1.CertificateStatus{}, fmt.Errorf("secret %s has no %s", certificate.secretName, certificate.privateKey)
+	}
+	data, ok := secret.Data[certificate.key]
+	if !ok {
+		return appmeshv1beta1.CertificateStatus{}, fmt.Errorf("secret %s has no %s", certificate.secretName, certificate.key)
+	}
+	notAfter, err := certificateNotAfter(data)
+	if err != nil {
+		return appmeshv1beta1.CertificateStatus{}, fmt.Errorf("error reading %s of secret %s: %s", certificate.key, certificate.secretName, err)
+	}
+	return appmeshv1beta1.CertificateStatus{
+		SecretName: certificate.secretName,
+		Key:        certificate.key,
+		NotAfter:   metav1.NewTime(notAfter),
+	}, nil
+}
+
+// updateVNodeCertificates reads the certificates the virtual node, or the defaults of its namespace, source from
+// Secrets, and records when they expire in its status and metrics. Virtual nodes whose Secrets are missing or
+// invalid are not created or updated, since the sidecar would not find their certificates.
+func (c *Controller) updateVNodeCertificates(vnode *appmeshv1beta1.VirtualNode) error {
+	withDefaults := vnode.DeepCopy()
+	mergeMeshDefaults(withDefaults, c.namespaceMeshDefaults(vnode.Namespace))
+	certificates := secretCertificates(withDefaults)
+	if len(certificates) == 0 && len(vnode.Status.Certificates) == 0 {
+		return nil
+	}
+
+	var statuses []appmeshv1beta1.CertificateStatus
+	for _, certificate := range certificates {
+		status, err := c.certificateStatus(vnode.Namespace, certificate)
+		if err != nil {
+			reason := reasonCertificateSecretInvalid
+			if rerr, ok := err.(*reconcileError); ok {
+				reason = rerr.reason
+			}
+			return reconcileErrorf(reason, err, "invalid certificate secret of virtual node %s: %s", vnode.Name, err)
+		}
+		statuses = append(statuses, status)
+		c.stats.SetCertificateExpiry(vnode.Namespace, vnode.Name, status.SecretName, status.Key, status.NotAfter.Time)
+	}
+	c.deleteCertificateExpiry(vnode, statuses)
+
+	if certificateStatusesEqual(statuses, vnode.Status.Certificates) {
+		return nil
+	}
+	if err := c.patchVNodeStatus(vnode, map[string]interface{}{certificatesStatusField: statuses}); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual node status: %s", err)
+	}
+	vnode.Status.Certificates = statuses
+	return nil
+}
+
+// deleteCertificateExpiry removes the expiry metrics of the certificates in the status of the virtual node that
+// are not in current.
+func (c *Controller) deleteCertificateExpiry(vnode *appmeshv1beta1.VirtualNode, current []appmeshv1beta1.CertificateStatus) {
+	for _, previous := range vnode.Status.Certificates {
+		found := false
+		for _, status := range current {
+			if status.SecretName == previous.SecretName && status.Key == previous.Key {
+				found = true
+			}
+		}
+		if !found {
+			c.stats.DeleteCertificateExpiry(vnode.Namespace, vnode.Name, previous.SecretName, previous.Key)
+		}
+	}
+}
+
+// certificateStatusesEqual compares certificate statuses, with expiry times to the second that status keeps.
+func certificateStatusesEqual(a []appmeshv1beta1.CertificateStatus, b []appmeshv1beta1.CertificateStatus) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].SecretName != b[i].SecretName || a[i].Key != b[i].Key || a[i].NotAfter.Unix() != b[i].NotAfter.Unix() {
+			return false
+		}
+	}
+	return true
+}
+
+func indexVNodesBySecretRef(obj interface{}) ([]string, error) {
+	vnode, ok := obj.(*appmeshv1beta1.VirtualNode)
+	if !ok {
+		return []string{}, nil
+	}
+	var keys []string
+	for _, certificate := range secretCertificates(vnode) {
+		key := vnode.Namespace + "/" + certificate.secretName
+		if len(keys) == 0 || keys[len(keys)-1] != key {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (c *Controller) secretAdded(obj interface{}) {
+	c.enqueueForSecret(obj)
+}
+
+func (c *Controller) secretUpdated(old interface{}, new interface{}) {
+	oldSecret, oldOk := old.(*corev1.Secret)
+	newSecret, newOk := new.(*corev1.Secret)
+	// Resyncs do not change the certificates
+	if oldOk && newOk && oldSecret.ResourceVersion == newSecret.ResourceVersion {
+		return
+	}
+	c.enqueueForSecret(new)
+}
+
+func (c *Controller) secretDeleted(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	c.enqueueForSecret(obj)
+}
+
+// enqueueForSecret enqueues the virtual nodes that source certificates from the Secret, so that their expiry is
+// read again.
+func (c *Controller) enqueueForSecret(obj interface{}) {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return
+	}
+	if defaults := c.namespaceMeshDefaults(secret.Namespace); defaults != nil {
+		if backendDefaultsUseSecret(defaults.Spec.BackendDefaults, secret.Name) {
+			c.enqueueForMeshDefaults(defaults)
+		}
+	}
+
+	key := secret.Namespace + "/" + secret.Name
+	objects, err := c.virtualNodeIndex.ByIndex(secretRefIndex, key)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("secretRef index error for %s: %s", key, err))
+		return
+	}
+	for _, obj := range objects {
+		vnode, ok := obj.(*appmeshv1beta1.VirtualNode)
+		if !ok || !c.scope.containsResource(vnode) {
+			continue
+		}
+		vnodeKey := vnode.Namespace + "/" + vnode.Name
+		c.driftChecks.forget(driftCheckVirtualNode, vnodeKey)
+		c.nq.Add(vnodeKey)
+		klog.V(4).Infof("Enqueued virtual node %s for secret %s", vnodeKey, key)
+	}
+}
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned/fake"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/metrics"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newTestCertificate(t *testing.T, notAfter time.Time) []byte {
+	certificate, _ := newTestKeyPair(t, notAfter)
+	return certificate
+}
+
+// newTestKeyPair returns a PEM encoded self-signed certificate along with its private key.
+func newTestKeyPair(t *testing.T, notAfter time.Time) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "color.ns.svc.cluster.local"},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func newSecretVNode() *appmeshv1beta1.VirtualNode {
+	vnode := newDependencyVNode("red", api.ConditionTrue)
+	vnode.Spec.Listeners = []appmeshv1beta1.Listener{{
+		PortMapping: appmeshv1beta1.PortMapping{Port: 8080, Protocol: "http"},
+		TLS: &appmeshv1beta1.ListenerTls{
+			Mode:        "STRICT",
+			Certificate: appmeshv1beta1.ListenerTlsCertificate{SecretRef: &appmeshv1beta1.TlsSecretReference{Name: "red-tls"}},
+		},
+	}}
+	vnode.Spec.BackendDefaults = &appmeshv1beta1.BackendDefaults{ClientPolicy: &appmeshv1beta1.ClientPolicy{
+		TLS: &appmeshv1beta1.ClientPolicyTls{Validation: appmeshv1beta1.TlsValidationContext{Trust: appmeshv1beta1.TlsValidationContextTrust{
+			SecretRef: &appmeshv1beta1.TlsSecretReference{Name: "mesh-ca"},
+		}}},
+	}}
+	return vnode
+}
+
+func TestResolveSecretCertificates(t *testing.T) {
+	vnode := newSecretVNode()
+	vnode.Spec.Backends[0].VirtualService.ClientPolicy = &appmeshv1beta1.ClientPolicy{
+		TLS: &appmeshv1beta1.ClientPolicyTls{Validation: appmeshv1beta1.TlsValidationContext{Trust: appmeshv1beta1.TlsValidationContextTrust{
+			SecretRef: &appmeshv1beta1.TlsSecretReference{Name: "color-ca"},
+		}}, Certificate: &appmeshv1beta1.ClientTlsCertificate{
+			SecretRef: &appmeshv1beta1.TlsSecretReference{Name: "red-client-tls"},
+		}},
+	}
+	if certificates := secretCertificates(vnode); len(certificates) != 4 || certificates[0].secretName != "color-ca" || certificates[2].privateKey != api.TLSPrivateKeyKey {
+		t.Errorf("expected the certificates of the backend client policy to be included, got %+v", certificates)
+	}
+	resolveSecretCertificates(vnode)
+
+	certificate := vnode.Spec.Listeners[0].TLS.Certificate
+	if certificate.SecretRef != nil || certificate.File == nil {
+		t.Fatalf("expected the secret to be replaced by a file, got %+v", certificate)
+	}
+	if certificate.File.CertificateChain != "/etc/appmesh/secrets/red-tls/tls.crt" || certificate.File.PrivateKey != "/etc/appmesh/secrets/red-tls/tls.key" {
+		t.Errorf("unexpected certificate files %+v", certificate.File)
+	}
+	trust := vnode.Spec.BackendDefaults.ClientPolicy.TLS.Validation.Trust
+	if trust.SecretRef != nil || trust.File == nil || trust.File.CertificateChain != "/etc/appmesh/secrets/mesh-ca/ca.crt" {
+		t.Errorf("unexpected trust %+v", trust)
+	}
+	backendTrust := vnode.Spec.Backends[0].VirtualService.ClientPolicy.TLS.Validation.Trust
+	if backendTrust.SecretRef != nil || backendTrust.File == nil || backendTrust.File.CertificateChain != "/etc/appmesh/secrets/color-ca/ca.crt" {
+		t.Errorf("unexpected backend trust %+v", backendTrust)
+	}
+	clientCertificate := vnode.Spec.Backends[0].VirtualService.ClientPolicy.TLS.Certificate
+	if clientCertificate.SecretRef != nil || clientCertificate.File == nil || clientCertificate.File.PrivateKey != "/etc/appmesh/secrets/red-client-tls/tls.key" {
+		t.Errorf("unexpected client certificate %+v", clientCertificate)
+	}
+}
+
+func TestCertificateNotAfter(t *testing.T) {
+	soon := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := soon.Add(365 * 24 * time.Hour)
+	chain := append(newTestCertificate(t, later), newTestCertificate(t, soon)...)
+
+	notAfter, err := certificateNotAfter(chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !notAfter.Equal(soon) {
+		t.Errorf("expected the first certificate to expire to be reported, got %s", notAfter)
+	}
+	if _, err := certificateNotAfter([]byte("not a certificate")); err == nil {
+		t.Error("expected an error without a certificate")
+	}
+}
+
+func TestUpdateVNodeCertificates(t *testing.T) {
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	vnode := newSecretVNode()
+	secretIndex := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	secretIndex.Add(&api.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "red-tls", Namespace: "ns"},
+		Type:       api.SecretTypeTLS,
+		Data:       map[string][]byte{api.TLSCertKey: newTestCertificate(t, notAfter), api.TLSPrivateKeyKey: []byte("key")},
+	})
+	c := &Controller{
+		meshclientset: fake.NewSimpleClientset(vnode),
+		secretsLister: corev1listers.NewSecretLister(secretIndex),
+		stats:         metrics.NewRecorder(false),
+	}
+
+	// The trusted certificate authority is missing
+	err := c.updateVNodeCertificates(vnode)
+	if reason, _ := errorCondition(err); err == nil || reason != reasonCertificateSecretInvalid {
+		t.Fatalf("expected reason %s, got %v", reasonCertificateSecretInvalid, err)
+	}
+
+	// The certificate authority is in an Opaque Secret, which the informer does not see
+	opaque := &api.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "mesh-ca", Namespace: "ns"},
+		Type:       api.SecretTypeOpaque,
+		Data:       map[string][]byte{caCertKey: newTestCertificate(t, notAfter.Add(time.Hour))},
+	}
+	c.kubeclientset = kubefake.NewSimpleClientset(opaque)
+	err = c.updateVNodeCertificates(vnode)
+	if reason, _ := errorCondition(err); err == nil || reason != reasonCertificateSecretTypeUnsupported {
+		t.Fatalf("expected reason %s, got %v", reasonCertificateSecretTypeUnsupported, err)
+	}
+	secretIndex.Add(opaque)
+	err = c.updateVNodeCertificates(vnode)
+	if reason, _ := errorCondition(err); err == nil || reason != reasonCertificateSecretTypeUnsupported {
+		t.Fatalf("expected reason %s for a cached Secret, got %v", reasonCertificateSecretTypeUnsupported, err)
+	}
+
+	secretIndex.Update(&api.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "mesh-ca", Namespace: "ns"},
+		Type:       api.SecretTypeTLS,
+		Data:       map[string][]byte{caCertKey: newTestCertificate(t, notAfter.Add(time.Hour))},
+	})
+	if err := c.updateVNodeCertificates(vnode); err != nil {
+		t.Fatal(err)
+	}
+	statuses := vnode.Status.Certificates
+	if len(statuses) != 2 || statuses[0].SecretName != "mesh-ca" || statuses[1].SecretName != "red-tls" || !statuses[1].NotAfter.Time.Equal(notAfter) {
+		t.Errorf("unexpected certificate statuses %+v", statuses)
+	}
+}
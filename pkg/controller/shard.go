@@ -0,0 +1,442 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+const (
+	// DefaultShardLeaseDuration is how long a replica remains a shard member after it last renewed its lease
+	DefaultShardLeaseDuration = 30 * time.Second
+
+	// shardGroupLabel groups the membership leases of replicas that share ownership of the same meshes.
+	// Its value is the election ID, which already tells controller deployments apart.
+	shardGroupLabel = "appmesh.k8s.aws/shard-group"
+
+	// shardMeshLabel marks the leases that give a replica of the shard group, named in its value, the mesh
+	shardMeshLabel = "appmesh.k8s.aws/shard-mesh"
+
+	// shardRingReplicas is the number of points each member gets on the hash ring, so that meshes spread
+	// evenly and only about 1/n of them move when a member joins or leaves.
+	shardRingReplicas = 64
+)
+
+// hashRing assigns meshes to shard members by consistent hashing of the mesh name.
+type hashRing struct {
+	points []uint32
+	owners map[uint32]string
+}
+
+func newHashRing(members []string) *hashRing {
+	ring := &hashRing{owners: map[uint32]string{}}
+	for _, member := range members {
+		for i := 0; i < shardRingReplicas; i++ {
+			point := hashKey(member + "#" + strconv.Itoa(i))
+			// On the unlikely collision the smallest member wins, so that every replica agrees.
+			if owner, ok := ring.owners[point]; ok && owner < member {
+				continue
+			} else if !ok {
+				ring.points = append(ring.points, point)
+			}
+			ring.owners[point] = member
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+	return ring
+}
+
+// owner returns the member owning the key, or "" if the ring has no members.
+func (r *hashRing) owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	point := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= point })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]]
+}
+
+// hashKey uses sha256 rather than fnv since mesh and member names tend to differ only in a short suffix,
+// which fnv spreads poorly around the ring.
+func hashKey(key string) uint32 {
+	sum := sha256.Sum256([]byte(key))
+	return binary.BigEndian.Uint32(sum[:4])
+}
+
+// shardMembership tracks the live shard members and decides which meshes this replica owns. The ring assigns
+// meshes to members, but a member only owns a mesh while it also holds the lease of the mesh, so that two replicas
+// never reconcile the same mesh while membership changes.
+type shardMembership struct {
+	identity string
+
+	mu      sync.RWMutex
+	members []string
+	ring    *hashRing
+	// leases maps the meshes whose lease this replica holds to the time until which it may act on them
+	leases map[string]time.Time
+}
+
+func newShardMembership(identity string) *shardMembership {
+	return &shardMembership{
+		identity: identity,
+		ring:     newHashRing(nil),
+		leases:   map[string]time.Time{},
+	}
+}
+
+// owns reports whether this replica reconciles the mesh. Without sharding every mesh is owned.
+func (m *shardMembership) owns(meshName string) bool {
+	if m == nil {
+		return true
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.ring.owner(meshName) == m.identity && time.Now().Before(m.leases[meshName])
+}
+
+// assigned reports whether the ring assigns the mesh to this replica, whether or not it holds its lease yet.
+func (m *shardMembership) assigned(meshName string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.ring.owner(meshName) == m.identity
+}
+
+// holdLease records that this replica holds the lease of the mesh until validUntil, and reports whether it did
+// not hold it before.
+func (m *shardMembership) holdLease(meshName string, validUntil time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	acquired := !time.Now().Before(m.leases[meshName])
+	m.leases[meshName] = validUntil
+	return acquired
+}
+
+// dropLease stops this replica from acting on the mesh.
+func (m *shardMembership) dropLease(meshName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.leases, meshName)
+}
+
+// heldLeases returns the meshes whose lease this replica holds.
+func (m *shardMembership) heldLeases() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var meshNames []string
+	for meshName := range m.leases {
+		meshNames = append(meshNames, meshName)
+	}
+	sort.Strings(meshNames)
+	return meshNames
+}
+
+// setMembers rebuilds the ring and reports whether membership changed.
+func (m *shardMembership) setMembers(members []string) bool {
+	sort.Strings(members)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if equalStrings(m.members, members) {
+		return false
+	}
+	m.members = members
+	m.ring = newHashRing(members)
+	return true
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func shardIdentity() string {
+	// The pod name keeps leases readable; fall back to a random identity outside of a pod.
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	return string(uuid.NewUUID())
+}
+
+func (c *Controller) shardLeaseName() string {
+	return c.leaderElectionID + "-shard-" + c.shards.identity
+}
+
+// runWorkersWithSharding joins the shard group and runs workers that only reconcile the meshes owned by this
+// replica. When members join or leave, the ring assigns meshes to other members. The previous owner stops
+// reconciling a mesh as soon as it is assigned elsewhere and stops renewing its lease, and the new owner takes
+// over once the lease expired, so that reconciles the previous owner had in flight have a lease duration to end.
+// Every object is requeued when membership changes and when this replica takes over a mesh.
+func (c *Controller) runWorkersWithSharding(ctx context.Context, threadiness int) error {
+	leaseNamespace := c.sharding.LeaseNamespace
+	if leaseNamespace == "" {
+		var err error
+		if leaseNamespace, err = getInClusterNamespace(); err != nil {
+			return err
+		}
+	}
+
+	// Workers must not start before this replica knows its share of the meshes.
+	if err := c.renewShardLease(leaseNamespace); err != nil {
+		return fmt.Errorf("failed to join shard group %s: %s", c.leaderElectionID, err)
+	}
+	if err := c.syncShardMembers(leaseNamespace); err != nil {
+		return fmt.Errorf("failed to list shard members of %s: %s", c.leaderElectionID, err)
+	}
+	c.syncMeshLeases(leaseNamespace)
+	klog.Infof("Joined shard group %s as %s", c.leaderElectionID, c.shards.identity)
+
+	go wait.Until(func() {
+		if err := c.renewShardLease(leaseNamespace); err != nil {
+			klog.Errorf("Error renewing shard lease: %s", err)
+		}
+		if err := c.syncShardMembers(leaseNamespace); err != nil {
+			klog.Errorf("Error syncing shard members: %s", err)
+		}
+		c.syncMeshLeases(leaseNamespace)
+	}, c.sharding.LeaseDuration/3, ctx.Done())
+
+	c.runWorkers(ctx, threadiness)
+
+	// Leave the group right away instead of waiting for the leases to expire. The workers have stopped, so the
+	// meshes can be handed over without waiting.
+	c.releaseMeshLeases(leaseNamespace)
+	err := c.kubeclientset.CoordinationV1().Leases(leaseNamespace).Delete(c.shardLeaseName(), &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("Error deleting shard lease: %s", err)
+	}
+	return nil
+}
+
+func (c *Controller) meshLeaseName(meshName string) string {
+	return c.leaderElectionID + "-mesh-" + meshName
+}
+
+// syncMeshLeases acquires or renews the leases of the meshes the ring assigns to this replica, and stops acting
+// on the others right away. Their leases are not released but expire, since reconciles may still be in flight.
+// This replica acts on a mesh until two thirds of the lease duration after it last renewed the lease, which
+// leaves the rest of the duration as margin for clock skew before another replica considers the lease expired.
+func (c *Controller) syncMeshLeases(namespace string) {
+	acquired := false
+	for _, obj := range c.meshIndex.List() {
+		mesh, ok := obj.(*appmeshv1beta1.Mesh)
+		if !ok || !c.scope.containsResource(mesh) {
+			continue
+		}
+		if !c.shards.assigned(mesh.Name) {
+			c.shards.dropLease(mesh.Name)
+			continue
+		}
+		begin := time.Now()
+		held, err := c.acquireMeshLease(namespace, mesh.Name, begin)
+		if err != nil {
+			klog.Errorf("Error renewing lease of mesh %s: %s", mesh.Name, err)
+			continue
+		}
+		if !held {
+			klog.V(4).Infof("Waiting for the lease of mesh %s to expire before taking it over", mesh.Name)
+			continue
+		}
+		if c.shards.holdLease(mesh.Name, begin.Add(c.sharding.LeaseDuration*2/3)) {
+			klog.Infof("Took over mesh %s", mesh.Name)
+			acquired = true
+		}
+	}
+	if acquired {
+		c.enqueueAll()
+	}
+}
+
+// acquireMeshLease acquires or renews the lease of the mesh, and reports whether this replica holds it. A lease
+// held by another replica is only taken over once it expired.
+func (c *Controller) acquireMeshLease(namespace string, meshName string, now time.Time) (bool, error) {
+	leases := c.kubeclientset.CoordinationV1().Leases(namespace)
+	renewTime := metav1.NewMicroTime(now)
+	duration := int32(c.sharding.LeaseDuration.Seconds())
+
+	lease, err := leases.Get(c.meshLeaseName(meshName), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = leases.Create(&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.meshLeaseName(meshName),
+				Namespace: namespace,
+				Labels:    map[string]string{shardMeshLabel: c.leaderElectionID},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &c.shards.identity,
+				LeaseDurationSeconds: &duration,
+				AcquireTime:          &renewTime,
+				RenewTime:            &renewTime,
+			},
+		})
+		if errors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if holder := lease.Spec.HolderIdentity; holder == nil || *holder != c.shards.identity {
+		if holder != nil && *holder != "" && !leaseExpired(lease.Spec, now) {
+			return false, nil
+		}
+		lease.Spec.AcquireTime = &renewTime
+		transitions := int32(1)
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions + 1
+		}
+		lease.Spec.LeaseTransitions = &transitions
+	}
+	lease.Spec.HolderIdentity = &c.shards.identity
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.RenewTime = &renewTime
+	// The update fails with a conflict if another replica took the lease over since it was read
+	if _, err := leases.Update(lease); errors.IsConflict(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// releaseMeshLeases deletes the leases this replica holds, once it stopped reconciling.
+func (c *Controller) releaseMeshLeases(namespace string) {
+	leases := c.kubeclientset.CoordinationV1().Leases(namespace)
+	for _, meshName := range c.shards.heldLeases() {
+		c.shards.dropLease(meshName)
+		lease, err := leases.Get(c.meshLeaseName(meshName), metav1.GetOptions{})
+		if err != nil || lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != c.shards.identity {
+			continue
+		}
+		err = leases.Delete(lease.Name, &metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &lease.UID}})
+		if err != nil && !errors.IsNotFound(err) {
+			klog.Errorf("Error deleting lease of mesh %s: %s", meshName, err)
+		}
+	}
+}
+
+func (c *Controller) renewShardLease(namespace string) error {
+	leases := c.kubeclientset.CoordinationV1().Leases(namespace)
+	now := metav1.NewMicroTime(time.Now())
+	duration := int32(c.sharding.LeaseDuration.Seconds())
+
+	lease, err := leases.Get(c.shardLeaseName(), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = leases.Create(&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.shardLeaseName(),
+				Namespace: namespace,
+				Labels:    map[string]string{shardGroupLabel: c.leaderElectionID},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &c.shards.identity,
+				LeaseDurationSeconds: &duration,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	lease.Spec.HolderIdentity = &c.shards.identity
+	lease.Spec.LeaseDurationSeconds = &duration
+	lease.Spec.RenewTime = &now
+	_, err = leases.Update(lease)
+	return err
+}
+
+func (c *Controller) syncShardMembers(namespace string) error {
+	list, err := c.kubeclientset.CoordinationV1().Leases(namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{shardGroupLabel: c.leaderElectionID}).String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	members := liveShardMembers(list.Items, time.Now())
+	if !c.shards.setMembers(members) {
+		return nil
+	}
+	klog.Infof("Shard members of %s changed to %v", c.leaderElectionID, members)
+	c.stats.SetShardMembers(len(members))
+	c.enqueueAll()
+	return nil
+}
+
+// liveShardMembers returns the holders of the leases that have not expired at now.
+func liveShardMembers(leases []coordinationv1.Lease, now time.Time) []string {
+	members := []string{}
+	for _, lease := range leases {
+		if lease.Spec.HolderIdentity == nil || leaseExpired(lease.Spec, now) {
+			continue
+		}
+		members = append(members, *lease.Spec.HolderIdentity)
+	}
+	return members
+}
+
+// leaseExpired reports whether the lease was not renewed within its duration before now.
+func leaseExpired(spec coordinationv1.LeaseSpec, now time.Time) bool {
+	if spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return now.After(spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second))
+}
+
+// enqueueAll requeues every mesh, virtual node and virtual service in scope. Workers drop the objects whose
+// mesh is owned by another replica.
+func (c *Controller) enqueueAll() {
+	for _, obj := range c.meshIndex.List() {
+		if !c.scope.containsResource(obj) {
+			continue
+		}
+		if key, err := cache.MetaNamespaceKeyFunc(obj); err == nil {
+			c.mq.Add(key)
+		}
+	}
+	for _, obj := range c.virtualNodeIndex.List() {
+		if !c.scope.containsResource(obj) {
+			continue
+		}
+		if key, err := cache.MetaNamespaceKeyFunc(obj); err == nil {
+			c.nq.Add(key)
+		}
+	}
+	for _, obj := range c.virtualServiceIndex.List() {
+		if !c.scope.containsResource(obj) {
+			continue
+		}
+		if key, err := cache.MetaNamespaceKeyFunc(obj); err == nil {
+			c.sq.Add(key)
+		}
+	}
+}
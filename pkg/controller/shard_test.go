@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	meshfake "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned/fake"
+	meshlisters "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/listers/appmesh/v1beta1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestHashRing(t *testing.T) {
+	meshes := []string{}
+	for i := 0; i < 1000; i++ {
+		meshes = append(meshes, fmt.Sprintf("mesh-%d", i))
+	}
+
+	t.Run("empty ring", func(t *testing.T) {
+		if owner := newHashRing(nil).owner("mesh"); owner != "" {
+			t.Errorf("expected no owner, got %s", owner)
+		}
+	})
+
+	t.Run("member order does not matter", func(t *testing.T) {
+		a := newHashRing([]string{"a", "b", "c"})
+		b := newHashRing([]string{"c", "a", "b"})
+		for _, mesh := range meshes {
+			if a.owner(mesh) != b.owner(mesh) {
+				t.Fatalf("rings disagree on owner of %s", mesh)
+			}
+		}
+	})
+
+	t.Run("meshes spread across members", func(t *testing.T) {
+		ring := newHashRing([]string{"a", "b", "c"})
+		counts := map[string]int{}
+		for _, mesh := range meshes {
+			counts[ring.owner(mesh)]++
+		}
+		for _, member := range []string{"a", "b", "c"} {
+			if counts[member] < 200 {
+				t.Errorf("expected member %s to own a fair share of meshes, got %d", member, counts[member])
+			}
+		}
+	})
+
+	t.Run("joining member only takes meshes", func(t *testing.T) {
+		before := newHashRing([]string{"a", "b", "c"})
+		after := newHashRing([]string{"a", "b", "c", "d"})
+		for _, mesh := range meshes {
+			if owner := after.owner(mesh); owner != "d" && owner != before.owner(mesh) {
+				t.Errorf("mesh %s moved from %s to %s", mesh, before.owner(mesh), owner)
+			}
+		}
+	})
+}
+
+func TestShardMembership(t *testing.T) {
+	var unsharded *shardMembership
+	if !unsharded.owns("mesh") {
+		t.Errorf("expected every mesh to be owned without sharding")
+	}
+
+	m := newShardMembership("a")
+	if m.owns("mesh") {
+		t.Errorf("expected no mesh to be owned before joining")
+	}
+	if !m.setMembers([]string{"a"}) {
+		t.Errorf("expected membership to change")
+	}
+	if m.owns("mesh") || !m.assigned("mesh") {
+		t.Errorf("expected the mesh to be assigned, but not owned without its lease")
+	}
+	if !m.holdLease("mesh", time.Now().Add(time.Minute)) {
+		t.Errorf("expected the lease to be newly held")
+	}
+	if !m.owns("mesh") {
+		t.Errorf("expected single member to own every mesh")
+	}
+	if m.setMembers([]string{"a"}) {
+		t.Errorf("expected membership to be unchanged")
+	}
+	if m.holdLease("mesh", time.Now().Add(time.Minute)) {
+		t.Errorf("expected a renewed lease not to be newly held")
+	}
+	m.holdLease("mesh", time.Now().Add(-time.Second))
+	if m.owns("mesh") {
+		t.Errorf("expected the mesh not to be owned once the lease lapsed")
+	}
+}
+
+func TestMeshLeases(t *testing.T) {
+	clientset := kubefake.NewSimpleClientset()
+	meshIndex := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	meshIndex.Add(&appmeshv1beta1.Mesh{ObjectMeta: metav1.ObjectMeta{Name: "mesh"}})
+	newReplica := func(identity string) *Controller {
+		c := &Controller{
+			kubeclientset:       clientset,
+			leaderElectionID:    "controller",
+			sharding:            ShardOptions{LeaseDuration: DefaultShardLeaseDuration},
+			shards:              newShardMembership(identity),
+			meshIndex:           meshIndex,
+			virtualNodeIndex:    cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}),
+			virtualServiceIndex: cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{}),
+			mq:                  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+			nq:                  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+			sq:                  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		}
+		c.shards.setMembers([]string{"a", "b"})
+		return c
+	}
+	a, b := newReplica("a"), newReplica("b")
+	owner, other := a, b
+	if !a.shards.assigned("mesh") {
+		owner, other = b, a
+	}
+
+	owner.syncMeshLeases("ns")
+	if !owner.shards.owns("mesh") || owner.mq.Len() != 1 {
+		t.Fatalf("expected the assigned replica to take over the mesh and requeue it")
+	}
+
+	// The mesh moves to the other replica, which waits for the lease to expire
+	owner.shards.setMembers([]string{other.shards.identity})
+	other.shards.setMembers([]string{other.shards.identity})
+	owner.syncMeshLeases("ns")
+	other.syncMeshLeases("ns")
+	if owner.shards.owns("mesh") {
+		t.Errorf("expected the previous owner to stop right away")
+	}
+	if other.shards.owns("mesh") {
+		t.Errorf("expected the new owner to wait for the lease of the previous owner to expire")
+	}
+
+	lease, err := clientset.CoordinationV1().Leases("ns").Get(owner.meshLeaseName("mesh"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := metav1.NewMicroTime(time.Now().Add(-time.Minute))
+	lease.Spec.RenewTime = &expired
+	if _, err := clientset.CoordinationV1().Leases("ns").Update(lease); err != nil {
+		t.Fatal(err)
+	}
+	other.syncMeshLeases("ns")
+	if !other.shards.owns("mesh") {
+		t.Errorf("expected the new owner to take over the expired lease")
+	}
+
+	// A stopping replica hands its meshes over right away
+	other.releaseMeshLeases("ns")
+	if other.shards.owns("mesh") {
+		t.Errorf("expected released meshes not to be owned")
+	}
+	if _, err := clientset.CoordinationV1().Leases("ns").Get(owner.meshLeaseName("mesh"), metav1.GetOptions{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the lease to be deleted, got %v", err)
+	}
+}
+
+func TestLiveShardMembers(t *testing.T) {
+	now := time.Now()
+	newLease := func(holder string, renewed time.Time) coordinationv1.Lease {
+		duration := int32(30)
+		renewTime := metav1.NewMicroTime(renewed)
+		return coordinationv1.Lease{
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: &duration,
+				RenewTime:            &renewTime,
+			},
+		}
+	}
+
+	members := liveShardMembers([]coordinationv1.Lease{
+		newLease("live", now.Add(-10*time.Second)),
+		newLease("expired", now.Add(-time.Minute)),
+		{},
+	}, now)
+	if len(members) != 1 || members[0] != "live" {
+		t.Errorf("expected only the live member, got %v", members)
+	}
+}
+
+func TestShardOwnsNamespaceMember(t *testing.T) {
+	vnode := newDependencyVNode("red", api.ConditionTrue)
+	vnode.Spec.MeshName = ""
+	vnodeIndex := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	vnodeIndex.Add(vnode)
+	clientset := meshfake.NewSimpleClientset(vnode)
+	c := &Controller{
+		meshclientset:     clientset,
+		virtualNodeLister: meshlisters.NewVirtualNodeLister(vnodeIndex),
+		namespaceLister: newNamespaceLister(
+			&api.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns", Labels: map[string]string{labelMeshMembership: "mesh"}}},
+			&api.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unselected"}},
+		),
+		meshLister: newMeshLister(),
+		shards:     newShardMembership("a"),
+	}
+	c.shards.setMembers([]string{"a"})
+
+	if _, owned := c.shardOwnsMember("", "ns"); owned {
+		t.Errorf("expected the mesh of the namespace not to be owned without its lease")
+	}
+	if _, owned := c.shardOwnsMember("", "unselected"); !owned {
+		t.Errorf("expected the replica assigned the empty mesh name to report a namespace no mesh selects")
+	}
+
+	c.shards.holdLease("mesh", time.Now().Add(time.Minute))
+	if meshName, owned := c.shardOwnsMember("", "ns"); !owned || meshName != "mesh" {
+		t.Fatalf("expected the mesh of the namespace to be owned, got %q, %t", meshName, owned)
+	}
+	if err := c.handleVNode("ns/red"); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := clientset.AppmeshV1beta1().VirtualNodes("ns").Get("red", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status.MeshName != "mesh" {
+		t.Errorf("expected the owner of the mesh to record the membership, got %q", updated.Status.MeshName)
+	}
+}
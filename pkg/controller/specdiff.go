@@ -0,0 +1,235 @@
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+)
+
+// Resources are compared with App Mesh by converting the App Mesh resource back into our API type and
+// normalizing both sides into a canonical form: defaults App Mesh fills in are applied, unordered lists are
+// sorted and fields App Mesh does not keep are dropped. What remains is compared field by field.
+
+// meshSpecDiff returns the fields in which the App Mesh mesh (target) differs from the desired spec.
+func meshSpecDiff(desired *appmeshv1beta1.Mesh, target *aws.Mesh) []string {
+	return diffFields("spec", normalizeMeshSpec(desired.Spec), normalizeMeshSpec(target.Spec()))
+}
+
+// vnodeSpecDiff returns the fields in which the App Mesh virtual node (target) differs from the desired spec.
+func vnodeSpecDiff(desired *appmeshv1beta1.VirtualNode, target *aws.VirtualNode) []string {
+	return diffFields("spec", normalizeVirtualNodeSpec(desired.Spec), normalizeVirtualNodeSpec(target.Spec()))
+}
+
+// vrouterDiff returns the fields in which the App Mesh virtual router (target) differs from the desired one.
+func vrouterDiff(desired *appmeshv1beta1.VirtualRouter, target *aws.VirtualRouter) []string {
+	return diffFields("spec.virtualRouter", normalizeVirtualRouter(*desired), normalizeVirtualRouter(target.Spec()))
+}
+
+// routeDiff returns the fields in which the App Mesh route (target) differs from the desired one.
+func routeDiff(desired appmeshv1beta1.Route, target aws.Route) []string {
+	path := fmt.Sprintf("spec.routes[%s]", desired.Name)
+	return diffFields(path, normalizeRoute(desired), normalizeRoute(target.Spec()))
+}
+
+func normalizeMeshSpec(spec appmeshv1beta1.MeshSpec) appmeshv1beta1.MeshSpec {
+	// Service discovery type only matters to the controller
+	return appmeshv1beta1.MeshSpec{
+		EgressFilter: spec.DeepCopy().EgressFilter,
+	}
+}
+
+func normalizeVirtualNodeSpec(spec appmeshv1beta1.VirtualNodeSpec) appmeshv1beta1.VirtualNodeSpec {
+	normalized := spec.DeepCopy()
+	// The mesh name identifies the virtual node rather than being part of its spec, and the deletion policy
+	// only matters to the controller, and the override is already applied to the name
+	normalized.MeshName = ""
+	normalized.DeletionPolicy = ""
+	normalized.AWSName = nil
+
+	for i := range normalized.Listeners {
+		listener := &normalized.Listeners[i]
+		if listener.HealthCheck != nil {
+			mergeHealthCheckDefaults(listener.HealthCheck, listener.PortMapping)
+		}
+	}
+
+	if sd := normalized.ServiceDiscovery; sd != nil {
+		// DNS takes precedence over Cloud Map, and a service discovery without either is not sent
+		if sd.Dns != nil {
+			sd.CloudMap = nil
+		} else if sd.CloudMap == nil {
+			normalized.ServiceDiscovery = nil
+		}
+	}
+
+	// App Mesh does not keep the order of backends
+	sort.SliceStable(normalized.Backends, func(i, j int) bool {
+		return normalized.Backends[i].VirtualService.VirtualServiceName < normalized.Backends[j].VirtualService.VirtualServiceName
+	})
+	for i := range normalized.Backends {
+		normalizeClientPolicy(normalized.Backends[i].VirtualService.ClientPolicy)
+		// The namespace of a backend is only used for grants
+		normalized.Backends[i].VirtualService.Namespace = ""
+	}
+	if normalized.BackendDefaults != nil {
+		normalizeClientPolicy(normalized.BackendDefaults.ClientPolicy)
+	}
+
+	// Logging is only sent with an access log file
+	if logging := normalized.Logging; logging != nil && (logging.AccessLog == nil || logging.AccessLog.File == nil) {
+		normalized.Logging = nil
+	}
+
+	return *normalized
+}
+
+func mergeHealthCheckDefaults(healthCheck *appmeshv1beta1.HealthCheckPolicy, portMapping appmeshv1beta1.PortMapping) {
+	healthCheck.Port = defaultInt64(healthCheck.Port, portMapping.Port)
+	healthCheck.Protocol = defaultString(healthCheck.Protocol, portMapping.Protocol)
+	healthCheck.HealthyThreshold = defaultInt64(healthCheck.HealthyThreshold, defaultHealthyThreshold)
+	healthCheck.IntervalMillis = defaultInt64(healthCheck.IntervalMillis, defaultIntervalMillis)
+	healthCheck.TimeoutMillis = defaultInt64(healthCheck.TimeoutMillis, defaultTimeoutMillis)
+	healthCheck.UnhealthyThreshold = defaultInt64(healthCheck.UnhealthyThreshold, defaultUnhealthyThreshold)
+}
+
+func normalizeClientPolicy(clientPolicy *appmeshv1beta1.ClientPolicy) {
+	if clientPolicy != nil && clientPolicy.TLS != nil {
+		mergeTlsClientPolicyDefaults(clientPolicy.TLS)
+		// A client certificate is only sent with a file
+		if certificate := clientPolicy.TLS.Certificate; certificate != nil && certificate.File == nil {
+			clientPolicy.TLS.Certificate = nil
+		}
+	}
+}
+
+func normalizeVirtualRouter(vrouter appmeshv1beta1.VirtualRouter) appmeshv1beta1.VirtualRouter {
+	normalized := vrouter.DeepCopy()
+	// The override is already applied to the name
+	normalized.AWSName = nil
+	return *normalized
+}
+
+func normalizeRoute(route appmeshv1beta1.Route) appmeshv1beta1.Route {
+	normalized := route.DeepCopy()
+
+	// A route has a single type, the first one set is sent
+	switch {
+	case normalized.Http != nil:
+		normalized.Tcp, normalized.Http2, normalized.Grpc = nil, nil, nil
+		sortWeightedTargets(normalized.Http.Action.WeightedTargets)
+	case normalized.Tcp != nil:
+		normalized.Http2, normalized.Grpc = nil, nil
+		sortWeightedTargets(normalized.Tcp.Action.WeightedTargets)
+	case normalized.Http2 != nil:
+		normalized.Grpc = nil
+		sortWeightedTargets(normalized.Http2.Action.WeightedTargets)
+	case normalized.Grpc != nil:
+		sortWeightedTargets(normalized.Grpc.Action.WeightedTargets)
+	}
+	// The namespace of a target is part of its App Mesh virtual node name
+	targets := routeTargets(*normalized)
+	for i := range targets {
+		targets[i].Namespace = ""
+	}
+
+	return *normalized
+}
+
+// sortWeightedTargets sorts targets since App Mesh does not keep their order
+func sortWeightedTargets(targets []appmeshv1beta1.WeightedTarget) {
+	sort.SliceStable(targets, func(i, j int) bool {
+		if targets[i].VirtualNodeName != targets[j].VirtualNodeName {
+			return targets[i].VirtualNodeName < targets[j].VirtualNodeName
+		}
+		return targets[i].Weight < targets[j].Weight
+	})
+}
+
+// diffFields compares desired with observed field by field and describes every difference as
+// "path: desired <value>, observed <value>", using the JSON names of the fields. Nil and empty slices and
+// maps are treated as equal.
+func diffFields(path string, desired interface{}, observed interface{}) []string {
+	return diffValues(path, reflect.ValueOf(desired), reflect.ValueOf(observed))
+}
+
+func diffValues(path string, desired reflect.Value, observed reflect.Value) []string {
+	if !desired.IsValid() || !observed.IsValid() {
+		if desired.IsValid() == observed.IsValid() {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: desired %s, observed %s", path, formatValue(desired), formatValue(observed))}
+	}
+
+	switch desired.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if desired.IsNil() || observed.IsNil() {
+			if desired.IsNil() && observed.IsNil() {
+				return nil
+			}
+			return []string{fmt.Sprintf("%s: desired %s, observed %s", path, formatValue(desired), formatValue(observed))}
+		}
+		return diffValues(path, desired.Elem(), observed.Elem())
+	case reflect.Struct:
+		var diffs []string
+		for i := 0; i < desired.NumField(); i++ {
+			field := desired.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			diffs = append(diffs, diffValues(path+"."+jsonFieldName(field), desired.Field(i), observed.Field(i))...)
+		}
+		return diffs
+	case reflect.Slice:
+		if desired.Len() != observed.Len() {
+			return []string{fmt.Sprintf("%s: desired %d items, observed %d items", path, desired.Len(), observed.Len())}
+		}
+		var diffs []string
+		for i := 0; i < desired.Len(); i++ {
+			diffs = append(diffs, diffValues(fmt.Sprintf("%s[%d]", path, i), desired.Index(i), observed.Index(i))...)
+		}
+		return diffs
+	case reflect.Map:
+		var diffs []string
+		keys := desired.MapKeys()
+		for _, key := range observed.MapKeys() {
+			if !desired.MapIndex(key).IsValid() {
+				keys = append(keys, key)
+			}
+		}
+		// Map iteration order is random, sort to keep messages stable
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			diffs = append(diffs, diffValues(fmt.Sprintf("%s[%v]", path, key), desired.MapIndex(key), observed.MapIndex(key))...)
+		}
+		return diffs
+	default:
+		if desired.Interface() != observed.Interface() {
+			return []string{fmt.Sprintf("%s: desired %s, observed %s", path, formatValue(desired), formatValue(observed))}
+		}
+		return nil
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
+
+func formatValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "<unset>"
+	}
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return "<unset>"
+	}
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
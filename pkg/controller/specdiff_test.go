@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+)
+
+func TestDiffFields(t *testing.T) {
+	var tests = []struct {
+		name     string
+		desired  interface{}
+		observed interface{}
+		want     []string
+	}{
+		{
+			name:     "equal",
+			desired:  appmeshv1beta1.PortMapping{Port: 8080, Protocol: "http"},
+			observed: appmeshv1beta1.PortMapping{Port: 8080, Protocol: "http"},
+		},
+		{
+			name:     "field differs",
+			desired:  appmeshv1beta1.PortMapping{Port: 8080, Protocol: "http"},
+			observed: appmeshv1beta1.PortMapping{Port: 9090, Protocol: "http"},
+			want:     []string{"spec.port: desired 8080, observed 9090"},
+		},
+		{
+			name:     "pointer unset",
+			desired:  &appmeshv1beta1.MeshEgressFilter{Type: "DROP_ALL"},
+			observed: (*appmeshv1beta1.MeshEgressFilter)(nil),
+			want:     []string{"spec: desired {DROP_ALL}, observed <unset>"},
+		},
+		{
+			name:     "nil and empty slices are equal",
+			desired:  appmeshv1beta1.VirtualNodeSpec{Listeners: []appmeshv1beta1.Listener{}},
+			observed: appmeshv1beta1.VirtualNodeSpec{},
+		},
+		{
+			name:     "slice length differs",
+			desired:  appmeshv1beta1.VirtualNodeSpec{Listeners: []appmeshv1beta1.Listener{{}}},
+			observed: appmeshv1beta1.VirtualNodeSpec{},
+			want:     []string{"spec.listeners: desired 1 items, observed 0 items"},
+		},
+		{
+			name:     "map value differs",
+			desired:  map[string]string{"a": "1", "b": "2"},
+			observed: map[string]string{"a": "1", "c": "3"},
+			want:     []string{"spec[b]: desired 2, observed <unset>", "spec[c]: desired <unset>, observed 3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffFields("spec", tt.desired, tt.observed); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeshSpecDiff(t *testing.T) {
+	desired := &appmeshv1beta1.Mesh{
+		Spec: appmeshv1beta1.MeshSpec{
+			EgressFilter: &appmeshv1beta1.MeshEgressFilter{Type: appmeshv1beta1.MeshEgressFilterTypeDropAll},
+		},
+	}
+	target := &aws.Mesh{
+		Data: appmesh.MeshData{
+			Spec: &appmesh.MeshSpec{
+				EgressFilter: &appmesh.EgressFilter{Type: awssdk.String(appmeshv1beta1.MeshEgressFilterTypeAllowAll)},
+			},
+		},
+	}
+
+	want := []string{"spec.egressFilter.type: desired DROP_ALL, observed ALLOW_ALL"}
+	if got := meshSpecDiff(desired, target); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestVNodeSpecDiffIgnoresBackendOrder(t *testing.T) {
+	desired := newAPIVirtualNode([]int64{80}, []string{"http"}, []string{"a.local", "b.local"}, "foo.local", nil)
+	target := newAWSVirtualNode([]int64{80}, []string{"http"}, []string{"b.local", "a.local"}, "foo.local", nil)
+
+	if diff := vnodeSpecDiff(desired, target); len(diff) != 0 {
+		t.Errorf("expected no diff, got %q", diff)
+	}
+}
+
+// The property tests below convert random specs to App Mesh and back. Once normalized, the result must equal
+// the input, and converting it again must not change it any further.
+
+var roundTripConfig = &quick.Config{MaxCount: 200}
+
+func TestMeshSpecRoundTrip(t *testing.T) {
+	roundTrip := func(spec appmeshv1beta1.MeshSpec) appmeshv1beta1.MeshSpec {
+		sdkSpec := aws.BuildMeshSpec(&appmeshv1beta1.Mesh{Spec: spec})
+		return (&aws.Mesh{Data: appmesh.MeshData{Spec: sdkSpec}}).Spec()
+	}
+	property := func(spec appmeshv1beta1.MeshSpec) bool {
+		once := roundTrip(spec)
+		return equalNormalized(t, normalizeMeshSpec(spec), normalizeMeshSpec(once)) &&
+			equalNormalized(t, once, roundTrip(once)) &&
+			equalNormalized(t, normalizeMeshSpec(spec), normalizeMeshSpec(normalizeMeshSpec(spec)))
+	}
+	if err := quick.Check(property, roundTripConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVirtualNodeSpecRoundTrip(t *testing.T) {
+	roundTrip := func(spec appmeshv1beta1.VirtualNodeSpec) appmeshv1beta1.VirtualNodeSpec {
+		sdkSpec := aws.BuildVirtualNodeSpec(&appmeshv1beta1.VirtualNode{Spec: spec})
+		return (&aws.VirtualNode{Data: appmesh.VirtualNodeData{MeshName: awssdk.String(spec.MeshName), Spec: sdkSpec}}).Spec()
+	}
+	property := func(spec appmeshv1beta1.VirtualNodeSpec) bool {
+		once := roundTrip(spec)
+		return equalNormalized(t, normalizeVirtualNodeSpec(spec), normalizeVirtualNodeSpec(once)) &&
+			equalNormalized(t, once, roundTrip(once)) &&
+			equalNormalized(t, normalizeVirtualNodeSpec(spec), normalizeVirtualNodeSpec(normalizeVirtualNodeSpec(spec)))
+	}
+	if err := quick.Check(property, roundTripConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVirtualRouterRoundTrip(t *testing.T) {
+	roundTrip := func(vrouter appmeshv1beta1.VirtualRouter) appmeshv1beta1.VirtualRouter {
+		sdkSpec := aws.BuildVirtualRouterSpec(&vrouter)
+		return (&aws.VirtualRouter{Data: appmesh.VirtualRouterData{VirtualRouterName: awssdk.String(vrouter.Name), Spec: sdkSpec}}).Spec()
+	}
+	property := func(vrouter appmeshv1beta1.VirtualRouter) bool {
+		once := roundTrip(vrouter)
+		return equalNormalized(t, normalizeVirtualRouter(vrouter), normalizeVirtualRouter(once)) &&
+			equalNormalized(t, once, roundTrip(once))
+	}
+	if err := quick.Check(property, roundTripConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRouteRoundTrip(t *testing.T) {
+	roundTrip := func(route appmeshv1beta1.Route) appmeshv1beta1.Route {
+		sdkSpec := aws.BuildRouteSpec(&route)
+		return (&aws.Route{Data: appmesh.RouteData{RouteName: awssdk.String(route.Name), Spec: sdkSpec}}).Spec()
+	}
+	property := func(route appmeshv1beta1.Route) bool {
+		// A route without any type is never sent to App Mesh
+		if route.Http == nil && route.Tcp == nil && route.Http2 == nil && route.Grpc == nil {
+			return true
+		}
+		once := roundTrip(route)
+		return equalNormalized(t, normalizeRoute(route), normalizeRoute(once)) &&
+			equalNormalized(t, once, roundTrip(once)) &&
+			equalNormalized(t, normalizeRoute(route), normalizeRoute(normalizeRoute(route)))
+	}
+	if err := quick.Check(property, roundTripConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func equalNormalized(t *testing.T, want interface{}, got interface{}) bool {
+	if diff := diffFields("spec", want, got); len(diff) > 0 {
+		t.Logf("round trip changed %q", diff)
+		return false
+	}
+	return true
+}
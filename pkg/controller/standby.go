@@ -0,0 +1,273 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+)
+
+// stateSnapshot is a read-only view of the App Mesh and Cloud Map state backing the custom resources in the
+// cluster. Warm-standby replicas keep it primed while they are not leading, so that after a takeover the first
+// reconcile of each object can start from known state instead of describing every resource again.
+type stateSnapshot struct {
+	mu sync.Mutex
+
+	// validUntil bounds how long a snapshot taken by a standby may be served after it was last refreshed.
+	validUntil time.Time
+
+	meshes          map[string]*aws.Mesh
+	virtualNodes    map[string]*aws.VirtualNode
+	virtualServices map[string]*aws.VirtualService
+	virtualRouters  map[string]*aws.VirtualRouter
+	routes          map[string]aws.Routes
+	// instances maps a Cloud Map service key to the IDs of the instances registered under it, and each ID to the
+	// namespace/name of the pod it was registered for
+	instances map[string]map[string]string
+}
+
+func newStateSnapshot() *stateSnapshot {
+	return &stateSnapshot{
+		meshes:          map[string]*aws.Mesh{},
+		virtualNodes:    map[string]*aws.VirtualNode{},
+		virtualServices: map[string]*aws.VirtualService{},
+		virtualRouters:  map[string]*aws.VirtualRouter{},
+		routes:          map[string]aws.Routes{},
+		instances:       map[string]map[string]string{},
+	}
+}
+
+// replace swaps in the contents of a freshly built snapshot.
+func (s *stateSnapshot) replace(fresh *stateSnapshot, validUntil time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validUntil = validUntil
+	s.meshes = fresh.meshes
+	s.virtualNodes = fresh.virtualNodes
+	s.virtualServices = fresh.virtualServices
+	s.virtualRouters = fresh.virtualRouters
+	s.routes = fresh.routes
+	s.instances = fresh.instances
+}
+
+// valid must be called with the lock held.
+func (s *stateSnapshot) valid() bool {
+	return time.Now().Before(s.validUntil)
+}
+
+func (s *stateSnapshot) takeMesh(name string) (*aws.Mesh, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mesh, ok := s.meshes[name]
+	delete(s.meshes, name)
+	return mesh, ok && s.valid()
+}
+
+func (s *stateSnapshot) takeVirtualNode(meshName string, name string) (*aws.VirtualNode, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := snapshotKey(meshName, name)
+	vnode, ok := s.virtualNodes[key]
+	delete(s.virtualNodes, key)
+	return vnode, ok && s.valid()
+}
+
+func (s *stateSnapshot) takeVirtualService(meshName string, name string) (*aws.VirtualService, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := snapshotKey(meshName, name)
+	vservice, ok := s.virtualServices[key]
+	delete(s.virtualServices, key)
+	return vservice, ok && s.valid()
+}
+
+func (s *stateSnapshot) takeVirtualRouter(meshName string, name string) (*aws.VirtualRouter, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := snapshotKey(meshName, name)
+	vrouter, ok := s.virtualRouters[key]
+	delete(s.virtualRouters, key)
+	return vrouter, ok && s.valid()
+}
+
+func (s *stateSnapshot) takeRoutes(meshName string, routerName string) (aws.Routes, bool) {
+	if s == nil {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := snapshotKey(meshName, routerName)
+	routes, ok := s.routes[key]
+	delete(s.routes, key)
+	return routes, ok && s.valid()
+}
+
+// takeInstance reports whether the instance was already registered for the pod with the Cloud Map service when
+// the snapshot was taken. Instance IDs are pod IPs, which a new pod may reuse, so an instance registered for
+// another pod does not count. Each instance is only reported once so that later syncs register it as usual.
+func (s *stateSnapshot) takeInstance(serviceKey string, instanceID string, pod *corev1.Pod) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	registeredFor, ok := s.instances[serviceKey][instanceID]
+	if !ok {
+		return false
+	}
+	delete(s.instances[serviceKey], instanceID)
+	return registeredFor == pod.Namespace+"/"+pod.Name && s.valid()
+}
+
+func snapshotKey(meshName string, name string) string {
+	return meshName + "/" + name
+}
+
+func snapshotInstancesKey(cloudmapConfig *appmesh.AwsCloudMapServiceDiscovery) string {
+	return awssdk.StringValue(cloudmapConfig.ServiceName) + "@" + awssdk.StringValue(cloudmapConfig.NamespaceName)
+}
+
+// runStandby refreshes the state snapshot until ctx is cancelled, which happens once this replica starts leading.
+func (c *Controller) runStandby(ctx context.Context) {
+	klog.Infof("Running as warm standby, refreshing state snapshot every %s", c.standbySyncPeriod)
+	wait.Until(func() {
+		c.refreshSnapshot(ctx)
+	}, c.standbySyncPeriod, ctx.Done())
+	klog.Info("Stopped warm standby")
+}
+
+// refreshSnapshot describes the App Mesh and Cloud Map resources for every custom resource in the informer
+// caches. It never mutates anything.
+func (c *Controller) refreshSnapshot(ctx context.Context) {
+	begin := time.Now()
+	defer func() {
+		c.stats.RecordOperationDuration("standby", "", "refreshSnapshot", time.Since(begin))
+	}()
+
+	fresh := newStateSnapshot()
+
+	meshes, err := c.meshLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Error listing meshes for standby snapshot: %s", err)
+		return
+	}
+	for _, mesh := range meshes {
+		if target, err := c.cloud.GetMesh(ctx, mesh.Name); err == nil {
+			fresh.meshes[mesh.Name] = target
+		}
+	}
+
+	vnodes, err := c.listVirtualNodes()
+	if err != nil {
+		klog.Errorf("Error listing virtual nodes for standby snapshot: %s", err)
+		return
+	}
+	for _, shared := range vnodes {
+		vnode := shared.DeepCopy()
+		c.mutateVirtualNodeForProcessing(vnode)
+		if target, err := c.cloud.GetVirtualNode(ctx, vnode.Name, vnodeMeshName(vnode)); err == nil {
+			fresh.virtualNodes[snapshotKey(vnodeMeshName(vnode), vnode.Name)] = target
+		}
+
+		if vnode.Spec.ServiceDiscovery == nil || vnode.Spec.ServiceDiscovery.CloudMap == nil {
+			continue
+		}
+		cloudmapConfig := &appmesh.AwsCloudMapServiceDiscovery{
+			NamespaceName: awssdk.String(vnode.Spec.ServiceDiscovery.CloudMap.NamespaceName),
+			ServiceName:   awssdk.String(vnode.Spec.ServiceDiscovery.CloudMap.ServiceName),
+		}
+		serviceKey := snapshotInstancesKey(cloudmapConfig)
+		if _, ok := fresh.instances[serviceKey]; ok {
+			continue
+		}
+		instances, err := c.cloud.ListInstances(ctx, cloudmapConfig)
+		if err != nil {
+			continue
+		}
+		ids := map[string]string{}
+		for _, instance := range instances {
+			podNamespace := awssdk.StringValue(instance.Attributes[aws.AttrK8sNamespace])
+			podName := awssdk.StringValue(instance.Attributes[aws.AttrK8sPod])
+			ids[awssdk.StringValue(instance.Id)] = podNamespace + "/" + podName
+		}
+		fresh.instances[serviceKey] = ids
+	}
+
+	vservices, err := c.listVirtualServices()
+	if err != nil {
+		klog.Errorf("Error listing virtual services for standby snapshot: %s", err)
+		return
+	}
+	for _, vservice := range vservices {
+		meshName := vserviceMeshName(vservice)
+		routerName := c.virtualRouterAWSName(vservice)
+		if target, err := c.cloud.GetVirtualRouter(ctx, routerName, meshName); err == nil {
+			fresh.virtualRouters[snapshotKey(meshName, routerName)] = target
+		}
+		if routes, err := c.cloud.GetRoutesForVirtualRouter(ctx, routerName, meshName); err == nil {
+			fresh.routes[snapshotKey(meshName, routerName)] = routes
+		}
+		if target, err := c.cloud.GetVirtualService(ctx, vservice.Name, meshName); err == nil {
+			fresh.virtualServices[snapshotKey(meshName, vservice.Name)] = target
+		}
+	}
+
+	// Keep serving the snapshot for two refresh periods so a takeover shortly before the next refresh
+	// still finds it valid.
+	c.snapshot.replace(fresh, time.Now().Add(2*c.standbySyncPeriod))
+	klog.V(4).Infof("Refreshed standby snapshot in %s", time.Since(begin))
+}
+
+func (c *Controller) describeMesh(ctx context.Context, name string) (*aws.Mesh, error) {
+	if mesh, ok := c.snapshot.takeMesh(name); ok {
+		return mesh, nil
+	}
+	return c.cloud.GetMesh(ctx, name)
+}
+
+func (c *Controller) describeVirtualNode(ctx context.Context, name string, meshName string) (*aws.VirtualNode, error) {
+	if vnode, ok := c.snapshot.takeVirtualNode(meshName, name); ok {
+		return vnode, nil
+	}
+	return c.cloud.GetVirtualNode(ctx, name, meshName)
+}
+
+func (c *Controller) describeVirtualService(ctx context.Context, name string, meshName string) (*aws.VirtualService, error) {
+	if vservice, ok := c.snapshot.takeVirtualService(meshName, name); ok {
+		return vservice, nil
+	}
+	return c.cloud.GetVirtualService(ctx, name, meshName)
+}
+
+func (c *Controller) describeVirtualRouter(ctx context.Context, name string, meshName string) (*aws.VirtualRouter, error) {
+	if vrouter, ok := c.snapshot.takeVirtualRouter(meshName, name); ok {
+		return vrouter, nil
+	}
+	return c.cloud.GetVirtualRouter(ctx, name, meshName)
+}
+
+func (c *Controller) describeRoutes(ctx context.Context, routerName string, meshName string) (aws.Routes, error) {
+	if routes, ok := c.snapshot.takeRoutes(meshName, routerName); ok {
+		return routes, nil
+	}
+	return c.cloud.GetRoutesForVirtualRouter(ctx, routerName, meshName)
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
+)
+
+func TestStateSnapshot(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+	newPrimed := func(validUntil time.Time) *stateSnapshot {
+		fresh := newStateSnapshot()
+		fresh.virtualNodes[snapshotKey("mesh", "node-ns")] = &aws.VirtualNode{
+			Data: appmesh.VirtualNodeData{VirtualNodeName: awssdk.String("node-ns")},
+		}
+		fresh.instances["svc@ns.local"] = map[string]string{"10.0.0.1": "ns/pod"}
+		s := newStateSnapshot()
+		s.replace(fresh, validUntil)
+		return s
+	}
+
+	t.Run("take consumes entry", func(t *testing.T) {
+		s := newPrimed(time.Now().Add(time.Minute))
+		if vnode, ok := s.takeVirtualNode("mesh", "node-ns"); !ok || vnode.Name() != "node-ns" {
+			t.Errorf("expected take to return virtual node, got %v, %v", vnode, ok)
+		}
+		if _, ok := s.takeVirtualNode("mesh", "node-ns"); ok {
+			t.Errorf("expected second take to miss")
+		}
+	})
+
+	t.Run("expired snapshot is not served", func(t *testing.T) {
+		s := newPrimed(time.Now().Add(-time.Second))
+		if _, ok := s.takeVirtualNode("mesh", "node-ns"); ok {
+			t.Errorf("expected expired snapshot to miss")
+		}
+		if s.takeInstance("svc@ns.local", "10.0.0.1", pod) {
+			t.Errorf("expected expired snapshot to miss instance")
+		}
+	})
+
+	t.Run("instance reported once", func(t *testing.T) {
+		s := newPrimed(time.Now().Add(time.Minute))
+		if !s.takeInstance("svc@ns.local", "10.0.0.1", pod) {
+			t.Errorf("expected instance to be registered")
+		}
+		if s.takeInstance("svc@ns.local", "10.0.0.1", pod) {
+			t.Errorf("expected instance to be reported only once")
+		}
+		if s.takeInstance("svc@ns.local", "10.0.0.2", pod) {
+			t.Errorf("expected unknown instance to miss")
+		}
+	})
+
+	t.Run("instance registered for another pod", func(t *testing.T) {
+		s := newPrimed(time.Now().Add(time.Minute))
+		// A new pod that reuses the IP of a deleted pod must be registered with its own attributes
+		reused := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "new-pod", Namespace: "ns"}}
+		if s.takeInstance("svc@ns.local", "10.0.0.1", reused) {
+			t.Errorf("expected instance of another pod to miss")
+		}
+	})
+
+	t.Run("nil snapshot", func(t *testing.T) {
+		var s *stateSnapshot
+		if _, ok := s.takeVirtualNode("mesh", "node-ns"); ok {
+			t.Errorf("expected nil snapshot to miss")
+		}
+		if s.takeInstance("svc@ns.local", "10.0.0.1", pod) {
+			t.Errorf("expected nil snapshot to miss instance")
+		}
+	})
+}
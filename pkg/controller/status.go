@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"encoding/json"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Status is written with JSON merge patches of the status subresource that carry only the fields being set, so
+// that writes of conditions, ARNs, Cloud Map IDs and observed generations do not overwrite each other. Merge patches
+// replace lists as a whole, so conditions are always written as the full list, computed from an object that may
+// come from a lagging informer cache or that another replica may have written while the mesh moved between shards.
+// Every patch therefore carries the resource version the object was read at, which makes the API server reject it
+// with a conflict if the object changed since; the reconcile then ends and the key is requeued to start over from
+// a fresh copy. A successful patch updates the resource version of the object, so that the reconcile can go on
+// writing it.
+
+// Status fields, by their JSON names
+const (
+	meshConditionsField          = "meshCondition"
+	conditionsField              = "conditions"
+	observedGenerationField      = "observedGeneration"
+	cloudMapServiceField         = "cloudmapService"
+	meshArnStatusField           = "meshArn"
+	virtualNodeArnStatusField    = "virtualNodeArn"
+	virtualServiceArnStatusField = "virtualServiceArn"
+	certificatesStatusField      = "certificates"
+	meshNameStatusField          = "meshName"
+)
+
+// statusMergePatch returns a merge patch setting the given status fields of an object read at resourceVersion
+func statusMergePatch(resourceVersion string, fields map[string]interface{}) ([]byte, error) {
+	patch := map[string]interface{}{"status": fields}
+	if resourceVersion != "" {
+		patch["metadata"] = map[string]interface{}{"resourceVersion": resourceVersion}
+	}
+	return json.Marshal(patch)
+}
+
+// isConflict reports whether err, or the error that caused it, is a conflict with a newer version of the object
+func isConflict(err error) bool {
+	if rerr, ok := err.(*reconcileError); ok {
+		err = rerr.cause
+	}
+	return err != nil && errors.IsConflict(err)
+}
+
+func (c *Controller) patchMeshStatus(mesh *appmeshv1beta1.Mesh, fields map[string]interface{}) error {
+	data, err := statusMergePatch(mesh.ResourceVersion, fields)
+	if err != nil {
+		return err
+	}
+	patched, err := c.meshclientset.AppmeshV1beta1().Meshes().Patch(mesh.Name, types.MergePatchType, data, "status")
+	if err != nil {
+		return err
+	}
+	mesh.ResourceVersion = patched.ResourceVersion
+	return nil
+}
+
+func (c *Controller) patchVNodeStatus(vnode *appmeshv1beta1.VirtualNode, fields map[string]interface{}) error {
+	data, err := statusMergePatch(vnode.ResourceVersion, fields)
+	if err != nil {
+		return err
+	}
+	patched, err := c.meshclientset.AppmeshV1beta1().VirtualNodes(vnode.Namespace).Patch(vnode.Name, types.MergePatchType, data, "status")
+	if err != nil {
+		return err
+	}
+	vnode.ResourceVersion = patched.ResourceVersion
+	return nil
+}
+
+func (c *Controller) patchVServiceStatus(vservice *appmeshv1beta1.VirtualService, fields map[string]interface{}) error {
+	data, err := statusMergePatch(vservice.ResourceVersion, fields)
+	if err != nil {
+		return err
+	}
+	patched, err := c.meshclientset.AppmeshV1beta1().VirtualServices(vservice.Namespace).Patch(vservice.Name, types.MergePatchType, data, "status")
+	if err != nil {
+		return err
+	}
+	vservice.ResourceVersion = patched.ResourceVersion
+	return nil
+}
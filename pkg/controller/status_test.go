@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned/fake"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestStatusMergePatch(t *testing.T) {
+	data, err := statusMergePatch("5", map[string]interface{}{observedGenerationField: int64(3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"metadata":{"resourceVersion":"5"},"status":{"observedGeneration":3}}`; string(data) != want {
+		t.Errorf("got patch %s, want %s", data, want)
+	}
+}
+
+func TestPatchVNodeStatusConflict(t *testing.T) {
+	vnode := newDependencyVNode("red", api.ConditionTrue)
+	vnode.ResourceVersion = "1"
+	clientset := fake.NewSimpleClientset(vnode)
+	// The fake clientset does not check resource versions, the API server rejects patches of older versions
+	current := "2"
+	clientset.PrependReactor("patch", "virtualnodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		var patch struct {
+			Metadata metav1.ObjectMeta `json:"metadata"`
+		}
+		if err := json.Unmarshal(action.(k8stesting.PatchAction).GetPatch(), &patch); err != nil {
+			return true, nil, err
+		}
+		if patch.Metadata.ResourceVersion != current {
+			return true, nil, errors.NewConflict(appmeshv1beta1.Resource("virtualnodes"), "red", fmt.Errorf("the object has been modified"))
+		}
+		patched := vnode.DeepCopy()
+		patched.ResourceVersion = "3"
+		current = "3"
+		return true, patched, nil
+	})
+	c := &Controller{meshclientset: clientset}
+
+	// Conditions computed from a stale copy must not overwrite the ones written since
+	stale := vnode.DeepCopy()
+	stale.Status.Conditions = nil
+	err := c.updateVNodeReadiness(stale, nil)
+	if !isConflict(err) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if !isConflict(reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual node status: %s", err)) {
+		t.Error("expected a reconcile error caused by a conflict to be a conflict")
+	}
+
+	// A fresh copy can write, and write again at the version the patch returned
+	fresh := vnode.DeepCopy()
+	fresh.ResourceVersion = "2"
+	fresh.Generation = 1
+	if err := c.updateVNodeReadiness(fresh, nil); err != nil {
+		t.Fatal(err)
+	}
+	if fresh.ResourceVersion != "3" {
+		t.Errorf("expected the resource version returned by the patch, got %s", fresh.ResourceVersion)
+	}
+	if err := c.setVirtualNodeObservedGeneration(fresh); err != nil {
+		t.Errorf("expected the patch at the returned version to succeed, got %v", err)
+	}
+}
+
+func TestPatchVNodeStatus(t *testing.T) {
+	vnode := newDependencyVNode("red", api.ConditionTrue)
+	vnode.Generation = 2
+	vnode.Status.VirtualNodeArn = awssdk.String("arn")
+	clientset := fake.NewSimpleClientset(vnode)
+	c := &Controller{meshclientset: clientset}
+
+	// A stale copy without the ARN and conditions must not clear them
+	stale := vnode.DeepCopy()
+	stale.Status = appmeshv1beta1.VirtualNodeStatus{}
+	if err := c.setVirtualNodeObservedGeneration(stale); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.setVirtualNodeStatusCloudMapService(stale, &appmeshv1beta1.CloudMapServiceStatus{ServiceID: awssdk.String("srv")}); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := clientset.AppmeshV1beta1().VirtualNodes("ns").Get("red", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status.ObservedGeneration != 2 || awssdk.StringValue(updated.Status.CloudMapService.ServiceID) != "srv" {
+		t.Errorf("expected the patched fields to be set, got %+v", updated.Status)
+	}
+	if awssdk.StringValue(updated.Status.VirtualNodeArn) != "arn" || len(updated.Status.Conditions) != len(vnode.Status.Conditions) {
+		t.Errorf("expected the other status fields to be kept, got %+v", updated.Status)
+	}
+
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "update" {
+			t.Errorf("expected status to be patched, got %v", action)
+		} else if patch, ok := action.(k8stesting.PatchAction); ok && patch.GetSubresource() != "status" {
+			t.Errorf("expected the status subresource to be patched, got %s", patch.GetSubresource())
+		}
+	}
+}
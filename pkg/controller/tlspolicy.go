@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/appmesh"
+	api "k8s.io/api/core/v1"
+)
+
+// Reasons of the TLSPolicyCompliant condition
+const (
+	tlsPolicyCompliantReason = "TLSPolicyCompliant"
+	tlsPolicyViolatedReason  = "TLSPolicyViolated"
+)
+
+// clientTlsPolicy is the TLS client policy of the backend defaults, or of a backend with its own client policy
+type clientTlsPolicy struct {
+	// subject describes the backends the policy applies to in violations
+	subject string
+	// backend is the virtual service name of the backend, or empty for the backend defaults
+	backend string
+	tls     *appmeshv1beta1.ClientPolicyTls
+}
+
+func (p clientTlsPolicy) verb(plural string, singular string) string {
+	if p.backend == "" {
+		return plural
+	}
+	return singular
+}
+
+func backendDefaultsClientTls(vnode *appmeshv1beta1.VirtualNode) clientTlsPolicy {
+	clientTls := clientTlsPolicy{subject: "backends"}
+	if vnode.Spec.BackendDefaults != nil && vnode.Spec.BackendDefaults.ClientPolicy != nil {
+		clientTls.tls = vnode.Spec.BackendDefaults.ClientPolicy.TLS
+	}
+	return clientTls
+}
+
+func backendOwnClientTls(backend appmeshv1beta1.Backend) clientTlsPolicy {
+	name := backend.VirtualService.VirtualServiceName
+	return clientTlsPolicy{subject: "backend " + name, backend: name, tls: backend.VirtualService.ClientPolicy.TLS}
+}
+
+// backendClientTls returns the TLS client policy that applies to each backend of a virtual node. A backend with its
+// own client policy uses it instead of the backend defaults.
+func backendClientTls(vnode *appmeshv1beta1.VirtualNode) []clientTlsPolicy {
+	var policies []clientTlsPolicy
+	for _, backend := range vnode.Spec.Backends {
+		if backend.VirtualService.ClientPolicy != nil {
+			policies = append(policies, backendOwnClientTls(backend))
+		} else {
+			policies = append(policies, backendDefaultsClientTls(vnode))
+		}
+	}
+	return policies
+}
+
+// clientTlsPolicies returns the TLS client policies of the backend defaults and of the backends of a virtual node.
+func clientTlsPolicies(vnode *appmeshv1beta1.VirtualNode) []clientTlsPolicy {
+	var policies []clientTlsPolicy
+	if defaults := backendDefaultsClientTls(vnode); defaults.tls != nil {
+		policies = append(policies, defaults)
+	}
+	for _, backend := range vnode.Spec.Backends {
+		if backend.VirtualService.ClientPolicy != nil && backend.VirtualService.ClientPolicy.TLS != nil {
+			policies = append(policies, backendOwnClientTls(backend))
+		}
+	}
+	return policies
+}
+
+// tlsPolicyViolations returns how a virtual node violates the TLS policy of its mesh. The virtual node is expected
+// to have the defaults of its namespace merged, since they may supply its client policy.
+func tlsPolicyViolations(vnode *appmeshv1beta1.VirtualNode, policy *appmeshv1beta1.MeshTLSPolicy) []string {
+	if policy == nil {
+		return nil
+	}
+	var violations []string
+
+	if policy.RequireStrictListenerTLS {
+		for _, listener := range vnode.Spec.Listeners {
+			if listener.TLS == nil || listener.TLS.Mode != appmesh.ListenerTlsModeStrict {
+				violations = append(violations, fmt.Sprintf("listener on port %d does not use %s TLS", listener.PortMapping.Port, appmesh.ListenerTlsModeStrict))
+			}
+		}
+	}
+
+	if policy.RequireBackendValidation {
+		seen := map[string]bool{}
+		for _, clientTls := range backendClientTls(vnode) {
+			if seen[clientTls.subject] {
+				continue
+			}
+			seen[clientTls.subject] = true
+			switch {
+			case clientTls.tls == nil:
+				violations = append(violations, fmt.Sprintf("%s %s not use a TLS client policy", clientTls.subject, clientTls.verb("do", "does")))
+			case !awssdk.BoolValue(clientTls.tls.Enforce) && clientTls.tls.Enforce != nil:
+				violations = append(violations, fmt.Sprintf("the TLS client policy of %s is not enforced", clientTls.subject))
+			case clientTls.tls.Validation.Trust.ACM == nil && clientTls.tls.Validation.Trust.File == nil:
+				violations = append(violations, fmt.Sprintf("the TLS client policy of %s has no trust", clientTls.subject))
+			}
+		}
+	}
+
+	if len(policy.TrustedCertificateAuthorityArns) > 0 {
+		trusted := map[string]bool{}
+		for _, arn := range policy.TrustedCertificateAuthorityArns {
+			trusted[arn] = true
+		}
+		for _, clientTls := range clientTlsPolicies(vnode) {
+			trust := clientTls.tls.Validation.Trust
+			if trust.File != nil {
+				violations = append(violations, fmt.Sprintf("%s %s a certificate chain file instead of the trusted certificate authorities", clientTls.subject, clientTls.verb("trust", "trusts")))
+			}
+			if trust.ACM != nil {
+				for _, arn := range trust.ACM.CertificateAuthorityArns {
+					if !trusted[arn] {
+						violations = append(violations, fmt.Sprintf("%s %s certificate authority %s, which the mesh does not", clientTls.subject, clientTls.verb("trust", "trusts"), arn))
+					}
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+// tlsPolicyCondition returns the status, reason and message of the TLSPolicyCompliant condition.
+func tlsPolicyCondition(violations []string) (api.ConditionStatus, string, string) {
+	if len(violations) > 0 {
+		return api.ConditionFalse, tlsPolicyViolatedReason, fmt.Sprintf("Violates the TLS policy of the mesh: %s", strings.Join(violations, "; "))
+	}
+	return api.ConditionTrue, tlsPolicyCompliantReason, ""
+}
+
+// updateVNodeTLSPolicy sets the TLSPolicyCompliant condition of the virtual node from the desired spec, and returns
+// the violations of the TLS policy of its mesh. Virtual nodes of meshes without a policy do not get the condition.
+func (c *Controller) updateVNodeTLSPolicy(copy *appmeshv1beta1.VirtualNode, desired *appmeshv1beta1.VirtualNode, mesh *appmeshv1beta1.Mesh) ([]string, error) {
+	if mesh.Spec.TLSPolicy == nil && getVNodeCondition(appmeshv1beta1.VirtualNodeTLSPolicyCompliant, copy.Status).Status == "" {
+		return nil, nil
+	}
+	violations := tlsPolicyViolations(desired, mesh.Spec.TLSPolicy)
+	status, reason, message := tlsPolicyCondition(violations)
+	if _, err := c.putVNodeReasonCondition(copy, appmeshv1beta1.VirtualNodeTLSPolicyCompliant, status, reason, message); err != nil {
+		return nil, reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual node status: %s", err)
+	}
+	return violations, nil
+}
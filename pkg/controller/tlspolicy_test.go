@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/client/clientset/versioned/fake"
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	api "k8s.io/api/core/v1"
+)
+
+func newTLSPolicyVNode(mode string, clientTls *appmeshv1beta1.ClientPolicyTls) *appmeshv1beta1.VirtualNode {
+	vnode := newDependencyVNode("red", api.ConditionTrue)
+	listener := appmeshv1beta1.Listener{PortMapping: appmeshv1beta1.PortMapping{Port: 8080, Protocol: "http"}}
+	if mode != "" {
+		listener.TLS = &appmeshv1beta1.ListenerTls{Mode: mode}
+	}
+	vnode.Spec.Listeners = []appmeshv1beta1.Listener{listener}
+	if clientTls != nil {
+		vnode.Spec.BackendDefaults = &appmeshv1beta1.BackendDefaults{ClientPolicy: &appmeshv1beta1.ClientPolicy{TLS: clientTls}}
+	}
+	return vnode
+}
+
+func TestTLSPolicyViolations(t *testing.T) {
+	acmTrust := func(arns ...string) *appmeshv1beta1.ClientPolicyTls {
+		return &appmeshv1beta1.ClientPolicyTls{Validation: appmeshv1beta1.TlsValidationContext{Trust: appmeshv1beta1.TlsValidationContextTrust{
+			ACM: &appmeshv1beta1.TlsValidationContextAcmTrust{CertificateAuthorityArns: arns},
+		}}}
+	}
+	strict := &appmeshv1beta1.MeshTLSPolicy{
+		RequireStrictListenerTLS:        true,
+		RequireBackendValidation:        true,
+		TrustedCertificateAuthorityArns: []string{"ca"},
+	}
+	var tests = []struct {
+		name       string
+		vnode      *appmeshv1beta1.VirtualNode
+		policy     *appmeshv1beta1.MeshTLSPolicy
+		violations []string
+	}{
+		{name: "no policy", vnode: newTLSPolicyVNode("", nil)},
+		{name: "compliant", vnode: newTLSPolicyVNode("STRICT", acmTrust("ca")), policy: strict},
+		{name: "permissive listener", vnode: newTLSPolicyVNode("PERMISSIVE", acmTrust("ca")), policy: strict, violations: []string{"listener on port 8080 does not use STRICT TLS"}},
+		{name: "no client policy", vnode: newTLSPolicyVNode("STRICT", nil), policy: strict, violations: []string{"backends do not use a TLS client policy"}},
+		{name: "not enforced", vnode: func() *appmeshv1beta1.VirtualNode {
+			clientTls := acmTrust("ca")
+			clientTls.Enforce = awssdk.Bool(false)
+			return newTLSPolicyVNode("STRICT", clientTls)
+		}(), policy: strict, violations: []string{"the TLS client policy of backends is not enforced"}},
+		{name: "untrusted authority", vnode: newTLSPolicyVNode("STRICT", acmTrust("ca", "other")), policy: strict, violations: []string{"backends trust certificate authority other, which the mesh does not"}},
+		{name: "file trust", vnode: newTLSPolicyVNode("STRICT", &appmeshv1beta1.ClientPolicyTls{Validation: appmeshv1beta1.TlsValidationContext{Trust: appmeshv1beta1.TlsValidationContextTrust{
+			File: &appmeshv1beta1.TlsValidationContextFileTrust{CertificateChain: "/certs/ca.pem"},
+		}}}), policy: strict, violations: []string{"backends trust a certificate chain file instead of the trusted certificate authorities"}},
+		{name: "backend client policy", vnode: func() *appmeshv1beta1.VirtualNode {
+			vnode := newTLSPolicyVNode("STRICT", acmTrust("ca"))
+			vnode.Spec.Backends = append(vnode.Spec.Backends, appmeshv1beta1.Backend{VirtualService: appmeshv1beta1.VirtualServiceBackend{
+				VirtualServiceName: "shape.ns.svc.cluster.local",
+				ClientPolicy:       &appmeshv1beta1.ClientPolicy{TLS: acmTrust("other")},
+			}})
+			return vnode
+		}(), policy: strict, violations: []string{"backend shape.ns.svc.cluster.local trusts certificate authority other, which the mesh does not"}},
+		{name: "backend without TLS", vnode: func() *appmeshv1beta1.VirtualNode {
+			vnode := newTLSPolicyVNode("STRICT", acmTrust("ca"))
+			vnode.Spec.Backends[0].VirtualService.ClientPolicy = &appmeshv1beta1.ClientPolicy{}
+			return vnode
+		}(), policy: strict, violations: []string{"backend color.ns.svc.cluster.local does not use a TLS client policy"}},
+		{name: "no backends", vnode: func() *appmeshv1beta1.VirtualNode {
+			vnode := newTLSPolicyVNode("STRICT", nil)
+			vnode.Spec.Backends = nil
+			return vnode
+		}(), policy: strict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if violations := tlsPolicyViolations(tt.vnode, tt.policy); !reflect.DeepEqual(violations, tt.violations) {
+				t.Errorf("got violations %v, want %v", violations, tt.violations)
+			}
+		})
+	}
+}
+
+func TestUpdateVNodeTLSPolicy(t *testing.T) {
+	vnode := newTLSPolicyVNode("PERMISSIVE", nil)
+	c := &Controller{meshclientset: fake.NewSimpleClientset(vnode)}
+	mesh := &appmeshv1beta1.Mesh{Spec: appmeshv1beta1.MeshSpec{TLSPolicy: &appmeshv1beta1.MeshTLSPolicy{RequireStrictListenerTLS: true}}}
+
+	violations, err := c.updateVNodeTLSPolicy(vnode, vnode, mesh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 {
+		t.Errorf("expected one violation, got %v", violations)
+	}
+	condition := getVNodeCondition(appmeshv1beta1.VirtualNodeTLSPolicyCompliant, vnode.Status)
+	if condition.Status != api.ConditionFalse || awssdk.StringValue(condition.Reason) != tlsPolicyViolatedReason {
+		t.Errorf("expected the virtual node not to comply, got %+v", condition)
+	}
+	if held, ok := (readinessInput{conditions: vnodeStatusConditions(vnode.Status.Conditions)}).heldBy(); !ok || held.Reason != tlsPolicyViolatedReason {
+		t.Errorf("expected the violation to hold off the virtual node, got %+v", held)
+	}
+
+	// Removing the policy clears the condition
+	mesh.Spec.TLSPolicy = nil
+	if _, err := c.updateVNodeTLSPolicy(vnode, vnode, mesh); err != nil {
+		t.Fatal(err)
+	}
+	if condition := getVNodeCondition(appmeshv1beta1.VirtualNodeTLSPolicyCompliant, vnode.Status); condition.Status != api.ConditionTrue {
+		t.Errorf("expected the virtual node to comply without a policy, got %+v", condition)
+	}
+}
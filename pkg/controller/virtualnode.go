@@ -3,7 +3,7 @@ package controller
 import (
 	"context"
 	"fmt"
-	"reflect"
+	"strings"
 
 	awssdk "github.com/aws/aws-sdk-go/aws"
 
@@ -13,9 +13,7 @@ import (
 	api "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/util/retry"
 	"k8s.io/klog"
 )
 
@@ -29,7 +27,7 @@ const (
 	defaultClientPolicyTlsEnforce      = true
 )
 
-func (c *Controller) handleVNode(key string) error {
+func (c *Controller) handleVNode(key string) (err error) {
 	ctx := context.Background()
 
 	namespace, name, err := cache.SplitMetaNamespaceKey(key)
@@ -44,6 +42,10 @@ func (c *Controller) handleVNode(key string) error {
 	if err != nil {
 		return err
 	}
+	if meshName, owned := c.shardOwnsMember(vnodeMeshName(shared), namespace); !owned {
+		klog.V(4).Infof("Skipping virtual node %s in mesh %s owned by another shard", key, meshName)
+		return nil
+	}
 
 	// Make copy here so we never update the shared copy
 	vnode := shared.DeepCopy()
@@ -52,11 +54,24 @@ func (c *Controller) handleVNode(key string) error {
 
 	// Make copy for updates so we don't save namespaced resource names
 	copy := shared.DeepCopy()
+	defer func() {
+		if !isConflict(err) {
+			c.recordFailure(copy, err)
+			if readinessErr := c.updateVNodeReadiness(copy, err); err == nil && isConflict(readinessErr) {
+				err = readinessErr
+			}
+		}
+		// A conflict means the reconcile worked on an outdated copy, it starts over from a fresh one once requeued
+		if isConflict(err) {
+			c.driftChecks.forget(driftCheckVirtualNode, key)
+		}
+	}()
 
 	// Resources with finalizers are not deleted immediately,
 	// instead the deletion timestamp is set when a client deletes them.
 	if !vnode.DeletionTimestamp.IsZero() {
-		c.stats.SetVirtualNodeInactive(vnode.Name, vnode.Spec.MeshName)
+		c.stats.SetVirtualNodeInactive(vnode.Name, vnodeMeshName(vnode))
+		c.deleteCertificateExpiry(copy, nil)
 		// Resource is being deleted, process finalizers
 		return c.handleVNodeDelete(ctx, vnode, copy)
 	}
@@ -64,68 +79,152 @@ func (c *Controller) handleVNode(key string) error {
 	// This is not a delete, add the deletion finalizer if it doesn't exist
 	if yes, _ := containsFinalizer(copy, virtualNodeDeletionFinalizerName); !yes {
 		if err := addFinalizer(copy, virtualNodeDeletionFinalizerName); err != nil {
-			return fmt.Errorf("error adding finalizer %s to virtual node %s: %s", virtualNodeDeletionFinalizerName, vnode.Name, err)
+			return reconcileErrorf(reasonFinalizerUpdateFailed, err, "error adding finalizer %s to virtual node %s: %s", virtualNodeDeletionFinalizerName, vnode.Name, err)
 		}
 		if updated, err := c.updateVNodeResource(copy); err != nil {
-			return fmt.Errorf("error adding finalizer %s to virtual node %s: %s", virtualNodeDeletionFinalizerName, vnode.Name, err)
+			return reconcileErrorf(reasonFinalizerUpdateFailed, err, "error adding finalizer %s to virtual node %s: %s", virtualNodeDeletionFinalizerName, vnode.Name, err)
 		} else if updated != nil {
 			copy = updated
 		}
 	}
 
+	// A virtual node without a mesh name joins the mesh of its namespace, the status patch enqueues it again
+	if vnodeMeshName(copy) == "" {
+		return c.resolveVNodeMeshMembership(copy)
+	}
+
 	if processVNode := c.handleVNodeMeshDeleting(ctx, copy); !processVNode {
 		klog.Infof("skipping processing virtual node %s", vnode.Name)
 		return nil
 	}
 
 	// Get Mesh for virtual node
-	meshName := vnode.Spec.MeshName
-	if vnode.Spec.MeshName == "" {
-		return fmt.Errorf("'MeshName' is a required field")
-	}
+	meshName := vnodeMeshName(vnode)
 
 	mesh, err := c.meshLister.Get(meshName)
 	if errors.IsNotFound(err) {
-		return fmt.Errorf("mesh %s for virtual node %s does not exist", meshName, name)
+		return reconcileErrorf(reasonMeshNotFound, err, "mesh %s for virtual node %s does not exist", meshName, name)
 	}
 
 	if !checkMeshActive(mesh) {
-		return fmt.Errorf("mesh %s must be active for virtual node %s", meshName, name)
+		return reconcileErrorf(reasonMeshNotActive, nil, "mesh %s must be active for virtual node %s", meshName, name)
+	}
+
+	if paused, err := c.handleVNodePause(copy, mesh, key); err != nil || paused {
+		return err
+	}
+
+	if !c.driftChecks.due(driftCheckVirtualNode, key, copy.Generation, copy.Status.ObservedGeneration) {
+		klog.V(4).Infof("Skipping unchanged virtual node %s until its next drift check", key)
+		return nil
+	}
+
+	if yields, err := c.handleVNodeNameCollision(copy, key); err != nil || yields {
+		return err
+	}
+
+	// Backends in other namespaces that no grant allows would let the virtual node reach virtual services it
+	// may not use, so it is not created or updated until they are granted
+	if denied, err := c.updateVNodeReferences(ctx, copy); err != nil {
+		return err
+	} else if len(denied) > 0 {
+		klog.Infof("Holding off virtual node %s until its backends %s are granted", key, strings.Join(denied, ", "))
+		return nil
+	}
+
+	// Virtual nodes that violate the TLS policy of their mesh are not created or updated until they comply
+	if violations, err := c.updateVNodeTLSPolicy(copy, vnode, mesh); err != nil {
+		return err
+	} else if len(violations) > 0 {
+		klog.Infof("Holding off virtual node %s, it violates the TLS policy of mesh %s: %s", key, meshName, strings.Join(violations, "; "))
+		return nil
+	}
+
+	if err := c.updateVNodeCertificates(copy); err != nil {
+		return err
+	}
+
+	// Virtual nodes are enqueued again once the virtual services they wait for change
+	waiting := c.vnodeWaitingFor(copy)
+	if len(waiting) > 0 {
+		status, reason, message := dependencyCondition(waiting)
+		klog.Infof("Virtual node %s: %s", key, message)
+		if _, err := c.setVNodeReasonCondition(copy, appmeshv1beta1.VirtualNodeWaitingForDependency, status, reason, message); err != nil {
+			return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual node status: %s", err)
+		}
+		return nil
 	}
 
 	// Create virtual node if it does not exist
-	targetNode, err := c.cloud.GetVirtualNode(ctx, vnode.Name, meshName)
+	var drift []string
+	targetNode, err := c.describeVirtualNode(ctx, vnode.Name, meshName)
 	if err != nil {
 		if aws.IsAWSErrNotFound(err) {
-			if targetNode, err = c.cloud.CreateVirtualNode(ctx, vnode); err != nil {
-				return fmt.Errorf("error creating virtual node: %s", err)
+			if targetNode, err = c.cloud.CreateVirtualNode(ctx, vnode, c.ownerTags(copy)); err != nil {
+				return reconcileErrorf(reasonCreateFailed, err, "error creating virtual node: %s", err)
 			}
+			c.created(copy, targetNode.Data.Metadata)
 			klog.Infof("Created virtual node %s", vnode.Name)
+			c.recordMutation(copy, createdReason, "Created App Mesh virtual node %s", vnode.Name)
 		} else {
-			return fmt.Errorf("error describing virtual node: %s", err)
+			return reconcileErrorf(reasonDescribeFailed, err, "error describing virtual node: %s", err)
 		}
 	} else {
+		if err := c.checkOwnership(ctx, copy, "virtual node", vnode.Name, targetNode.Data.Metadata, vnodeReconciledBefore(copy)); err != nil {
+			return c.handleVNodeOwnershipConflict(copy, key, err)
+		}
 		if vnodeNeedsUpdate(vnode, targetNode) {
-			if targetNode, err = c.cloud.UpdateVirtualNode(ctx, vnode); err != nil {
-				return fmt.Errorf("error updating virtual node: %s", err)
+			if c.driftPolicyFor(copy) == DriftPolicyReport {
+				drift = vnodeSpecDiff(vnode, targetNode)
+				c.reportDrift(copy, driftCheckVirtualNode, meshName, vnode.Name, drift)
+			} else {
+				if targetNode, err = c.cloud.UpdateVirtualNode(ctx, vnode); err != nil {
+					return reconcileErrorf(reasonUpdateFailed, err, "error updating virtual node: %s", err)
+				}
+				klog.Infof("Updated virtual node %s", vnode.Name)
+				c.recordMutation(copy, updatedReason, "Updated App Mesh virtual node %s", vnode.Name)
 			}
-			klog.Infof("Updated virtual node %s", vnode.Name)
 		}
 	}
 
-	c.stats.SetVirtualNodeActive(vnode.Name, vnode.Spec.MeshName)
+	c.stats.SetVirtualNodeActive(vnode.Name, vnodeMeshName(vnode))
 
 	updated, err := c.updateVNodeStatus(copy, targetNode)
 	if err != nil {
-		return fmt.Errorf("error updating virtual node status: %s", err)
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual node status: %s", err)
+	} else if updated != nil {
+		copy = updated
+	}
+
+	if updated, err := c.setVNodeDrifted(copy, drift); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual node status: %s", err)
+	} else if updated != nil {
+		copy = updated
+	}
+
+	status, reason, message := ownershipCondition(nil)
+	if updated, err := c.setVNodeReasonCondition(copy, appmeshv1beta1.VirtualNodeOwnershipConflict, status, reason, message); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual node status: %s", err)
+	} else if updated != nil {
+		copy = updated
+	}
+
+	status, reason, message = dependencyCondition(nil)
+	if updated, err := c.setVNodeReasonCondition(copy, appmeshv1beta1.VirtualNodeWaitingForDependency, status, reason, message); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual node status: %s", err)
 	} else if updated != nil {
 		copy = updated
 	}
 
 	err = c.handleServiceDiscovery(ctx, vnode, copy)
 	if err != nil {
-		return fmt.Errorf("Error handling cloudmap service discovery for virtual node %s: %s", vnode.Name, err)
+		return reconcileErrorf(reasonServiceDiscoveryFailed, err, "Error handling cloudmap service discovery for virtual node %s: %s", vnode.Name, err)
+	}
+
+	if err := c.setVirtualNodeObservedGeneration(copy); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual node status: %s", err)
 	}
+	c.driftChecks.reconciled(driftCheckVirtualNode, key)
 
 	return nil
 }
@@ -135,7 +234,12 @@ func (c *Controller) updateVNodeResource(vnode *appmeshv1beta1.VirtualNode) (*ap
 }
 
 func (c *Controller) updateVNodeStatus(vnode *appmeshv1beta1.VirtualNode, target *aws.VirtualNode) (*appmeshv1beta1.VirtualNode, error) {
-	vnode.Status.VirtualNodeArn = target.Data.Metadata.Arn
+	if awssdk.StringValue(vnode.Status.VirtualNodeArn) != awssdk.StringValue(target.Data.Metadata.Arn) {
+		vnode.Status.VirtualNodeArn = target.Data.Metadata.Arn
+		if err := c.patchVNodeStatus(vnode, map[string]interface{}{virtualNodeArnStatusField: vnode.Status.VirtualNodeArn}); err != nil {
+			return nil, err
+		}
+	}
 	switch target.Status() {
 	case appmesh.VirtualNodeStatusCodeActive:
 		return c.updateVNodeActive(vnode, api.ConditionTrue)
@@ -171,168 +275,96 @@ func (c *Controller) updateVNodeCondition(vnode *appmeshv1beta1.VirtualNode, con
 		// condition exists and not set to status
 		condition.Status = status
 		condition.LastTransitionTime = &now
+		vnode.Status.Conditions = setVNodeConditionInList(vnode.Status.Conditions, condition)
 	}
 
 	err := c.setVirtualNodeStatusConditions(vnode, vnode.Status.Conditions)
 	return vnode, err
 }
 
-func (c *Controller) setVirtualNodeStatusConditions(vnode *appmeshv1beta1.VirtualNode, conditions []appmeshv1beta1.VirtualNodeCondition) error {
-	firstTry := true
-	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
-		var getErr error
-		if !firstTry {
-			vnode, getErr = c.meshclientset.AppmeshV1beta1().VirtualNodes(vnode.Namespace).Get(vnode.GetName(), metav1.GetOptions{})
-			if getErr != nil {
-				return getErr
-			}
-		}
-		vnodeCopy := vnode.DeepCopy()
-		vnodeCopy.Status.Conditions = conditions
-		_, err := c.meshclientset.AppmeshV1beta1().VirtualNodes(vnode.Namespace).UpdateStatus(vnodeCopy)
-		firstTry = false
-		return err
-	})
-}
-
-func getVNodeCondition(conditionType appmeshv1beta1.VirtualNodeConditionType, status appmeshv1beta1.VirtualNodeStatus) appmeshv1beta1.VirtualNodeCondition {
-	for _, condition := range status.Conditions {
-		if condition.Type == conditionType {
-			return condition
-		}
+// setVNodeReasonCondition sets a condition with a reason and message. A condition that would be False and was
+// never set is left out, so that conditions only show up on virtual nodes they ever applied to.
+func (c *Controller) setVNodeReasonCondition(vnode *appmeshv1beta1.VirtualNode, conditionType appmeshv1beta1.VirtualNodeConditionType, status api.ConditionStatus, reason string, message string) (*appmeshv1beta1.VirtualNode, error) {
+	if getVNodeCondition(conditionType, vnode.Status) == (appmeshv1beta1.VirtualNodeCondition{}) && status == api.ConditionFalse {
+		return nil, nil
 	}
-
-	return appmeshv1beta1.VirtualNodeCondition{}
+	return c.putVNodeReasonCondition(vnode, conditionType, status, reason, message)
 }
 
-// vnodeNeedsUpdate compares the App Mesh API result (target) with the desired spec (desired) and
-// determines if there is any drift that requires an update.
-func vnodeNeedsUpdate(desired *appmeshv1beta1.VirtualNode, target *aws.VirtualNode) bool {
-	if desired.Spec.ServiceDiscovery != nil {
-		if target.Data.Spec.ServiceDiscovery == nil {
-			return true
-		}
-		if desired.Spec.ServiceDiscovery.Dns != nil {
-			// If Service discovery is desired, verify target is equal
-			if desired.Spec.ServiceDiscovery.Dns.HostName != target.HostName() {
-				return true
-			}
-		} else if desired.Spec.ServiceDiscovery.CloudMap != nil {
-			if target.Data.Spec.ServiceDiscovery.AwsCloudMap == nil {
-				return true
-			}
-			if desired.Spec.ServiceDiscovery.CloudMap.ServiceName !=
-				awssdk.StringValue(target.Data.Spec.ServiceDiscovery.AwsCloudMap.ServiceName) {
-				return true
-			}
-			if desired.Spec.ServiceDiscovery.CloudMap.NamespaceName !=
-				awssdk.StringValue(target.Data.Spec.ServiceDiscovery.AwsCloudMap.NamespaceName) {
-				return true
-			}
-			if len(desired.Spec.ServiceDiscovery.CloudMap.Attributes) != len(target.Data.Spec.ServiceDiscovery.AwsCloudMap.Attributes) {
-				return true
-			}
-			for _, attr := range target.Data.Spec.ServiceDiscovery.AwsCloudMap.Attributes {
-				val, ok := desired.Spec.ServiceDiscovery.CloudMap.Attributes[awssdk.StringValue(attr.Key)]
-				if !ok {
-					return true
-				}
-				if val != awssdk.StringValue(attr.Value) {
-					return true
-				}
-			}
-		} else {
-			klog.Errorf("Unknown servicediscovery %v is defined for virtual-node %s", desired.Spec.ServiceDiscovery, target.Name())
-		}
-	} else {
-		// If no desired Service Discovery, verify target is not set
-		if target.Data.Spec.ServiceDiscovery != nil {
-			return true
-		}
+// putVNodeReasonCondition sets a condition with a reason and message, whether or not the virtual node had it
+// before.
+func (c *Controller) putVNodeReasonCondition(vnode *appmeshv1beta1.VirtualNode, conditionType appmeshv1beta1.VirtualNodeConditionType, status api.ConditionStatus, reason string, message string) (*appmeshv1beta1.VirtualNode, error) {
+	current := getVNodeCondition(conditionType, vnode.Status)
+	if current.Status == status && awssdk.StringValue(current.Reason) == reason && awssdk.StringValue(current.Message) == message {
+		return nil, nil
 	}
 
-	if desired.Spec.Listeners != nil {
-		if !reflect.DeepEqual(desired.Spec.Listeners, target.Listeners()) {
-			return true
-		}
-	} else {
-		// If the spec doesn't have any listeners, make sure target is not set
-		if len(target.Listeners()) != 0 {
-			return true
-		}
+	now := metav1.Now()
+	condition := appmeshv1beta1.VirtualNodeCondition{
+		Type:               conditionType,
+		Status:             status,
+		LastTransitionTime: current.LastTransitionTime,
+		Reason:             awssdk.String(reason),
+		Message:            awssdk.String(message),
+	}
+	if current.Status != status {
+		condition.LastTransitionTime = &now
 	}
 
-	// Since API may not guarantee order, convert backends to a map for equality checking
-	if desired.Spec.Backends != nil {
-		desiredBackendMap := make(map[string]appmeshv1beta1.Backend, len(desired.Spec.Backends))
-		for _, val := range desired.Spec.Backends {
-			desiredBackendMap[val.VirtualService.VirtualServiceName] = val
-		}
-		targetBackends := target.Backends()
-		targetBackendMap := make(map[string]appmeshv1beta1.Backend, len(targetBackends))
-		for _, val := range targetBackends {
-			targetBackendMap[val.VirtualService.VirtualServiceName] = val
-		}
-		if !reflect.DeepEqual(desiredBackendMap, targetBackendMap) {
-			return true
-		}
-	} else {
-		// If the spec doesn't have any backends, make sure target is not set
-		if len(target.Backends()) != 0 {
-			return true
+	replaced := false
+	for i := range vnode.Status.Conditions {
+		if vnode.Status.Conditions[i].Type == condition.Type {
+			vnode.Status.Conditions[i] = condition
+			replaced = true
 		}
 	}
-
-	if !reflect.DeepEqual(desired.Spec.BackendDefaults, target.BackendDefaults()) {
-		return true
+	if !replaced {
+		vnode.Status.Conditions = append(vnode.Status.Conditions, condition)
 	}
 
-	if vnodeLoggingNeedsUpdate(desired, target) {
-		return true
-	}
+	err := c.setVirtualNodeStatusConditions(vnode, vnode.Status.Conditions)
+	return vnode, err
+}
 
-	return false
+func (c *Controller) setVirtualNodeStatusConditions(vnode *appmeshv1beta1.VirtualNode, conditions []appmeshv1beta1.VirtualNodeCondition) error {
+	return c.patchVNodeStatus(vnode, map[string]interface{}{conditionsField: conditions})
 }
 
-func vnodeLoggingNeedsUpdate(desired *appmeshv1beta1.VirtualNode, target *aws.VirtualNode) bool {
-	if desired.Spec.Logging != nil {
-		//target is missing logging so update is required
-		if target.Data.Spec.Logging == nil {
-			return true
-		}
-		if desired.Spec.Logging.AccessLog != nil {
-			//target is missing access-log config so update is required
-			if target.Data.Spec.Logging.AccessLog == nil {
-				return true
-			}
-			if desired.Spec.Logging.AccessLog.File != nil {
-				//target is missing access-log file config so update is required
-				if target.Data.Spec.Logging.AccessLog.File == nil ||
-					target.Data.Spec.Logging.AccessLog.File.Path == nil {
-					return true
-				}
-				//path exists but differs
-				if desired.Spec.Logging.AccessLog.File.Path != awssdk.StringValue(target.Data.Spec.Logging.AccessLog.File.Path) {
-					return true
-				}
-			}
+func getVNodeCondition(conditionType appmeshv1beta1.VirtualNodeConditionType, status appmeshv1beta1.VirtualNodeStatus) appmeshv1beta1.VirtualNodeCondition {
+	for _, condition := range status.Conditions {
+		if condition.Type == conditionType {
+			return condition
 		}
-	} else if target.Data.Spec.Logging != nil {
-		//target has logging config but desired spec doesn't
-		return true
 	}
-	return false
+
+	return appmeshv1beta1.VirtualNodeCondition{}
+}
+
+// vnodeNeedsUpdate compares the App Mesh API result (target) with the desired spec (desired) and
+// determines if there is any drift that requires an update.
+func vnodeNeedsUpdate(desired *appmeshv1beta1.VirtualNode, target *aws.VirtualNode) bool {
+	return len(vnodeSpecDiff(desired, target)) > 0
 }
 
 func (c *Controller) handleVNodeDelete(ctx context.Context, vnode *appmeshv1beta1.VirtualNode, copy *appmeshv1beta1.VirtualNode) error {
 	if yes, _ := containsFinalizer(vnode, virtualNodeDeletionFinalizerName); yes {
-		if err := c.deregisterInstancesForVirtualNode(ctx, vnode); err != nil {
+		if err := c.deregisterInstancesForVirtualNode(ctx, copy); err != nil {
 			return err
 		}
 
-		if _, err := c.cloud.DeleteVirtualNode(ctx, vnode.Name, vnode.Spec.MeshName); err != nil {
-			if !aws.IsAWSErrNotFound(err) {
-				return fmt.Errorf("failed to clean up virtual node %s during deletion finalizer: %s", vnode.Name, err)
+		if c.effectiveDeletionPolicy(vnode.Spec.DeletionPolicy, vnodeMeshName(vnode)) == appmeshv1beta1.DeletionPolicyRetain {
+			if err := c.releaseVirtualNode(ctx, vnode, copy); err != nil {
+				return fmt.Errorf("failed to release virtual node %s during deletion finalizer: %s", vnode.Name, err)
+			}
+		} else if deletable, err := c.virtualNodeDeletable(ctx, vnode, copy); err != nil {
+			return fmt.Errorf("failed to clean up virtual node %s during deletion finalizer: %s", vnode.Name, err)
+		} else if deletable {
+			if _, err := c.cloud.DeleteVirtualNode(ctx, vnode.Name, vnodeMeshName(vnode)); err != nil {
+				if !aws.IsAWSErrNotFound(err) {
+					return reconcileErrorf(reasonDeleteFailed, err, "failed to clean up virtual node %s during deletion finalizer: %s", vnode.Name, err)
+				}
+			} else {
+				c.recordMutation(copy, deletedReason, "Deleted App Mesh virtual node %s", vnode.Name)
 			}
 		}
 		if err := removeFinalizer(copy, virtualNodeDeletionFinalizerName); err != nil {
@@ -345,6 +377,21 @@ func (c *Controller) handleVNodeDelete(ctx context.Context, vnode *appmeshv1beta
 	return nil
 }
 
+// virtualNodeDeletable reports whether the App Mesh virtual node belongs to the virtual node being deleted.
+func (c *Controller) virtualNodeDeletable(ctx context.Context, vnode *appmeshv1beta1.VirtualNode, copy *appmeshv1beta1.VirtualNode) (bool, error) {
+	if !c.ownershipEnabled() {
+		return true, nil
+	}
+	target, err := c.describeVirtualNode(ctx, vnode.Name, vnodeMeshName(vnode))
+	if err != nil {
+		if aws.IsAWSErrNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return c.deletable(ctx, copy, "virtual node", vnode.Name, target.Data.Metadata, vnodeReconciledBefore(copy))
+}
+
 // deregisterInstancesForVirtualNode uses serviceDiscovery configuration
 // from virtualNode spec to deregister instances from AWS CloudMap
 func (c *Controller) deregisterInstancesForVirtualNode(ctx context.Context, vnode *appmeshv1beta1.VirtualNode) error {
@@ -365,21 +412,22 @@ func (c *Controller) deregisterInstancesForVirtualNode(ctx context.Context, vnod
 	for _, instance := range instances {
 		meshName := awssdk.StringValue(instance.Attributes[attributeKeyAppMeshMeshName])
 		virtualNodeName := awssdk.StringValue(instance.Attributes[attributeKeyAppMeshVirtualNodeName])
-		if meshName != vnode.Spec.MeshName ||
-			virtualNodeName != namespacedResourceName(vnode.Name, vnode.Namespace) {
+		if meshName != vnodeMeshName(vnode) ||
+			virtualNodeName != c.virtualNodeAWSName(vnode) {
 			continue
 		}
 		err = c.cloud.DeregisterInstance(ctx, awssdk.StringValue(instance.Id), appmeshCloudMapConfig)
 		if err != nil {
-			return err
+			return reconcileErrorf(reasonDeregisterFailed, err, "error deregistering instance %s of virtual node %s: %s", awssdk.StringValue(instance.Id), vnode.Name, err)
 		}
+		c.recordInstanceEvent(vnode, nil, instanceDeregisteredReason, "Deregistered instance %s from Cloud Map service %s", awssdk.StringValue(instance.Id), cloudmapConfig.ServiceName)
 	}
 	return nil
 }
 
 // handleVNodeMeshDeleting deletes virtualNode when mesh is deleted (cascade)
 func (c *Controller) handleVNodeMeshDeleting(ctx context.Context, vnode *appmeshv1beta1.VirtualNode) (processVNode bool) {
-	mesh, err := c.meshLister.Get(vnode.Spec.MeshName)
+	mesh, err := c.meshLister.Get(vnodeMeshName(vnode))
 
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -397,7 +445,7 @@ func (c *Controller) handleVNodeMeshDeleting(ctx context.Context, vnode *appmesh
 			klog.Errorf("Deletion failed for virtual node: %s - %s", vnode.Name, err)
 			return false
 		}
-		klog.Infof("Deleted App Mesh virtual node %s because mesh %s is being deleted", vnode.Name, vnode.Spec.MeshName)
+		klog.Infof("Deleted App Mesh virtual node %s because mesh %s is being deleted", vnode.Name, vnodeMeshName(vnode))
 	}
 
 	return true
@@ -435,6 +483,10 @@ func (c *Controller) handleServiceDiscovery(ctx context.Context, vnode *appmeshv
 	}
 
 	klog.V(4).Infof("Created CloudMap service %s (id:%s)", cloudmapServiceName, cloudmapService.ServiceID)
+	// Creating the service is idempotent, it is only new to the virtual node if its status does not have it yet
+	if status := copyForUpdate.Status.CloudMapService; status == nil || awssdk.StringValue(status.ServiceID) != cloudmapService.ServiceID {
+		c.recordMutation(copyForUpdate, createdReason, "Created Cloud Map service %s in namespace %s (id %s)", cloudmapServiceName, cloudmapNamespaceName, cloudmapService.ServiceID)
+	}
 
 	statusErr := c.setVirtualNodeStatusCloudMapService(copyForUpdate, &appmeshv1beta1.CloudMapServiceStatus{
 		NamespaceID: awssdk.String(cloudmapService.NamespaceID),
@@ -451,32 +503,29 @@ func (c *Controller) handleServiceDiscovery(ctx context.Context, vnode *appmeshv
 
 // setVirtualNodeStatusCloudMapService updates the status of virtualNode with CloudMap service details
 func (c *Controller) setVirtualNodeStatusCloudMapService(vnode *appmeshv1beta1.VirtualNode, cloudmapService *appmeshv1beta1.CloudMapServiceStatus) error {
-	firstTry := true
-	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
-		var getErr error
-		if !firstTry {
-			vnode, getErr = c.meshclientset.AppmeshV1beta1().VirtualNodes(vnode.Namespace).Get(vnode.GetName(), metav1.GetOptions{})
-			if getErr != nil {
-				return getErr
-			}
-		}
-		vnodeCopy := vnode.DeepCopy()
-		vnodeCopy.Status.CloudMapService = cloudmapService
-		_, err := c.meshclientset.AppmeshV1beta1().VirtualNodes(vnode.Namespace).UpdateStatus(vnodeCopy)
-		firstTry = false
-		return err
-	})
+	return c.patchVNodeStatus(vnode, map[string]interface{}{cloudMapServiceField: cloudmapService})
+}
+
+// setVirtualNodeObservedGeneration records the generation of the virtualNode spec that was reconciled with App Mesh
+func (c *Controller) setVirtualNodeObservedGeneration(vnode *appmeshv1beta1.VirtualNode) error {
+	if vnode.Status.ObservedGeneration == vnode.Generation {
+		return nil
+	}
+	return c.patchVNodeStatus(vnode, map[string]interface{}{observedGenerationField: vnode.Generation})
 }
 
 // reconcileServices reconciles the external _service_ resources corresponding to virtualNode
 // using its serviceDiscovery configuration.
 func (c *Controller) reconcileServices(ctx context.Context) error {
-	virtualNodes, err := c.virtualNodeLister.List(labels.Everything())
+	virtualNodes, err := c.listVirtualNodes()
 	if err != nil {
 		return err
 	}
 
 	for _, originalVNode := range virtualNodes {
+		if !c.shards.owns(vnodeMeshName(originalVNode)) {
+			continue
+		}
 		vnode := originalVNode.DeepCopy()
 		copyForUpdate := originalVNode.DeepCopy()
 		c.handleServiceDiscovery(ctx, vnode, copyForUpdate)
@@ -487,7 +536,11 @@ func (c *Controller) reconcileServices(ctx context.Context) error {
 }
 
 func (c *Controller) mutateVirtualNodeForProcessing(vnode *appmeshv1beta1.VirtualNode) {
-	vnode.Name = namespacedResourceName(vnode.Name, vnode.Namespace)
+	vnode.Spec.MeshName = vnodeMeshName(vnode)
+	mergeMeshDefaults(vnode, c.namespaceMeshDefaults(vnode.Namespace))
+	resolveSecretCertificates(vnode)
+	vnode.Name = c.virtualNodeAWSName(vnode)
+	vnode.Spec.AWSName = nil
 	if vnode.Spec.ServiceDiscovery != nil && vnode.Spec.ServiceDiscovery.CloudMap != nil {
 		if vnode.Spec.ServiceDiscovery.CloudMap.Attributes == nil {
 			vnode.Spec.ServiceDiscovery.CloudMap.Attributes = map[string]string{}
@@ -499,14 +552,7 @@ func (c *Controller) mutateVirtualNodeForProcessing(vnode *appmeshv1beta1.Virtua
 	if vnode.Spec.Listeners != nil {
 		for _, listener := range vnode.Spec.Listeners {
 			if listener.HealthCheck != nil {
-				//if port-mapping is not set for health-check we default it to listener's port-mapping
-				listener.HealthCheck.Port = defaultInt64(listener.HealthCheck.Port, listener.PortMapping.Port)
-				listener.HealthCheck.Protocol = defaultString(listener.HealthCheck.Protocol, listener.PortMapping.Protocol)
-				//below are some sane defaults for majority of applications
-				listener.HealthCheck.HealthyThreshold = defaultInt64(listener.HealthCheck.HealthyThreshold, defaultHealthyThreshold)
-				listener.HealthCheck.IntervalMillis = defaultInt64(listener.HealthCheck.IntervalMillis, defaultIntervalMillis)
-				listener.HealthCheck.TimeoutMillis = defaultInt64(listener.HealthCheck.TimeoutMillis, defaultTimeoutMillis)
-				listener.HealthCheck.UnhealthyThreshold = defaultInt64(listener.HealthCheck.UnhealthyThreshold, defaultUnhealthyThreshold)
+				mergeHealthCheckDefaults(listener.HealthCheck, listener.PortMapping)
 			}
 		}
 	}
@@ -514,6 +560,10 @@ func (c *Controller) mutateVirtualNodeForProcessing(vnode *appmeshv1beta1.Virtua
 	// The following overrides facilitate matching the spec to the API response.
 	// They can be removed if the API response matches the input spec.
 	if vnode.Spec.Backends != nil {
+		// The namespace of a backend only decides whether it is granted, App Mesh does not know it
+		for i := range vnode.Spec.Backends {
+			vnode.Spec.Backends[i].VirtualService.Namespace = ""
+		}
 		for _, backend := range vnode.Spec.Backends {
 			if backend.VirtualService.ClientPolicy != nil {
 				clientPolicy := backend.VirtualService.ClientPolicy
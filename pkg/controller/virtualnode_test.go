@@ -11,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/appmesh"
 	"github.com/aws/aws-sdk-go/service/servicediscovery"
 	"github.com/stretchr/testify/mock"
+	"k8s.io/apimachinery/pkg/types"
 
 	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
 	"github.com/aws/aws-app-mesh-controller-for-k8s/pkg/aws"
@@ -544,9 +545,12 @@ func TestHandleCloudMapServiceDiscovery(t *testing.T) {
 					mock.Anything,
 				).Return(mockVirtualNodeInterface)
 				mockVirtualNodeInterface.On(
-					"UpdateStatus",
-					mock.AnythingOfType("*v1beta1.VirtualNode"),
-				).Return(nil, nil)
+					"Patch",
+					tt.spec.Name,
+					types.MergePatchType,
+					mock.Anything,
+					"status",
+				).Return(tt.spec.DeepCopy(), nil)
 			}
 			err := c.handleServiceDiscovery(ctx, tt.spec, tt.spec.DeepCopy())
 			if err != nil && !tt.errExpected {
@@ -1041,6 +1045,32 @@ func TestVnodeClientPoliciesNeedsUpdate(t *testing.T) {
 			Validation: &appmesh.TlsValidationContext{
 				Trust: &sdkAcmTrust,
 			}}})
+		mutualTlsNodeSpec = func(san string, certificateChain string) *appmeshv1beta1.VirtualNode {
+			return newCRDVirtualNodeWithTlsClientPolicy([]appmeshv1beta1.ClientPolicyTls{appmeshv1beta1.ClientPolicyTls{
+				Enforce: awssdk.Bool(true),
+				Ports:   tlsPorts,
+				Validation: appmeshv1beta1.TlsValidationContext{
+					Trust: crdFileTrust,
+					SubjectAlternativeNames: &appmeshv1beta1.SubjectAlternativeNames{
+						Match: appmeshv1beta1.SubjectAlternativeNameMatchers{Exact: []string{san}},
+					},
+				},
+				Certificate: &appmeshv1beta1.ClientTlsCertificate{
+					File: &appmeshv1beta1.ListenerTlsFileCertificate{CertificateChain: certificateChain, PrivateKey: "/path/to/key.pem"},
+				}}})
+		}
+		mutualTlsNodeResult = newSDKVirtualNodeWithTlsClientPolicy([]appmesh.ClientPolicyTls{appmesh.ClientPolicyTls{
+			Enforce: awssdk.Bool(true),
+			Ports:   sdkTlsPorts,
+			Validation: &appmesh.TlsValidationContext{
+				Trust: &sdkFileTrust,
+				SubjectAlternativeNames: &appmesh.SubjectAlternativeNames{
+					Match: &appmesh.SubjectAlternativeNameMatchers{Exact: []*string{awssdk.String("color.ns.svc.cluster.local")}},
+				},
+			},
+			Certificate: &appmesh.ClientTlsCertificate{
+				File: &appmesh.ListenerTlsFileCertificate{CertificateChain: awssdk.String("/path/to/client.pem"), PrivateKey: awssdk.String("/path/to/key.pem")},
+			}}})
 	)
 
 	var vnodetests = []struct {
@@ -1056,6 +1086,11 @@ func TestVnodeClientPoliciesNeedsUpdate(t *testing.T) {
 		{"change trust type acm to file", acmTrustNodeSpec, fileTrustNodeResult, true},
 		{"change trust type file to acm", fileTrustNodeSpec, acmTrustNodeResult, true},
 		{"enforce changed to false", acmTrustNodeSpec, enforceDisabledNodeResult, true},
+		{"no changes mutual tls", mutualTlsNodeSpec("color.ns.svc.cluster.local", "/path/to/client.pem"), mutualTlsNodeResult, false},
+		{"add subject alternative names and certificate", mutualTlsNodeSpec("color.ns.svc.cluster.local", "/path/to/client.pem"), fileTrustNodeResult, true},
+		{"subject alternative name changed", mutualTlsNodeSpec("shape.ns.svc.cluster.local", "/path/to/client.pem"), mutualTlsNodeResult, true},
+		{"certificate changed", mutualTlsNodeSpec("color.ns.svc.cluster.local", "/path/to/other.pem"), mutualTlsNodeResult, true},
+		{"remove subject alternative names and certificate", fileTrustNodeSpec, mutualTlsNodeResult, true},
 	}
 
 	for _, tt := range vnodetests {
@@ -3,7 +3,6 @@ package controller
 import (
 	"context"
 	"fmt"
-	"reflect"
 	"strings"
 
 	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
@@ -15,11 +14,10 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/util/retry"
 	"k8s.io/klog"
 )
 
-func (c *Controller) handleVService(key string) error {
+func (c *Controller) handleVService(key string) (err error) {
 	ctx := context.Background()
 
 	namespace, name, err := cache.SplitMetaNamespaceKey(key)
@@ -34,43 +32,57 @@ func (c *Controller) handleVService(key string) error {
 	if err != nil {
 		return err
 	}
+	if meshName, owned := c.shardOwnsMember(vserviceMeshName(shared), namespace); !owned {
+		klog.V(4).Infof("Skipping virtual service %s in mesh %s owned by another shard", key, meshName)
+		return nil
+	}
 
 	// Make copy here so we never update the shared copy
 	vservice := shared.DeepCopy()
+	vservice.Spec.MeshName = vserviceMeshName(vservice)
 	// Make copy for updates so we don't save namespaced resource names
 	copy := shared.DeepCopy()
 	copy.Spec.VirtualRouter = c.getVirtualRouter(copy)
+	defer func() {
+		if !isConflict(err) {
+			c.recordFailure(copy, err)
+			if readinessErr := c.updateVServiceReadiness(copy, err); err == nil && isConflict(readinessErr) {
+				err = readinessErr
+			}
+		}
+		// A conflict means the reconcile worked on an outdated copy, it starts over from a fresh one once requeued
+		if isConflict(err) {
+			c.driftChecks.forget(driftCheckVirtualService, key)
+		}
+	}()
 
 	// Namespace resource names for use against App Mesh API
 	if vservice.Spec.VirtualRouter == nil {
 		vservice.Spec.VirtualRouter = &appmeshv1beta1.VirtualRouter{
-			Name: getNamespacedVirtualRouterName(vservice),
+			Name: c.virtualRouterAWSName(vservice),
 		}
 	} else {
-		vservice.Spec.VirtualRouter.Name = getNamespacedVirtualRouterName(vservice)
+		vservice.Spec.VirtualRouter.Name = c.virtualRouterAWSName(vservice)
+		vservice.Spec.VirtualRouter.AWSName = nil
 	}
 
 	for i := range vservice.Spec.Routes {
 		route := vservice.Spec.Routes[i]
 		route.Name = namespacedResourceName(route.Name, vservice.Namespace)
-		var targets []appmeshv1beta1.WeightedTarget
-		if route.Http != nil {
-			targets = route.Http.Action.WeightedTargets
-		} else if route.Tcp != nil {
-			targets = route.Tcp.Action.WeightedTargets
-		}
+		targets := routeTargets(route)
 		for j := range targets {
-			targets[j].VirtualNodeName = namespacedResourceName(targets[j].VirtualNodeName, vservice.Namespace)
+			targets[j].VirtualNodeName = c.virtualNodeRefAWSName(targetReference(targets[j], vservice.Namespace))
+			targets[j].Namespace = ""
 		}
 	}
 
 	// Add the deletion finalizer if it doesn't exist
 	if yes, _ := containsFinalizer(copy, virtualServiceDeletionFinalizerName); !yes {
 		if err := addFinalizer(copy, virtualServiceDeletionFinalizerName); err != nil {
-			return fmt.Errorf("error adding finalizer %s to virtual service %s: %s", virtualServiceDeletionFinalizerName, vservice.Name, err)
+			return reconcileErrorf(reasonFinalizerUpdateFailed, err, "error adding finalizer %s to virtual service %s: %s", virtualServiceDeletionFinalizerName, vservice.Name, err)
 		}
 		if updated, err := c.updateVServiceResource(copy); err != nil {
-			return fmt.Errorf("error updating resource while adding finalizer %s to virtual service %s: %s", virtualServiceDeletionFinalizerName, vservice.Name, err)
+			return reconcileErrorf(reasonFinalizerUpdateFailed, err, "error updating resource while adding finalizer %s to virtual service %s: %s", virtualServiceDeletionFinalizerName, vservice.Name, err)
 		} else if updated != nil {
 			copy = updated
 		}
@@ -79,64 +91,105 @@ func (c *Controller) handleVService(key string) error {
 	// Resources with finalizers are not deleted immediately,
 	// instead the deletion timestamp is set when a client deletes them.
 	if !vservice.DeletionTimestamp.IsZero() {
-		c.stats.SetVirtualServiceInactive(vservice.Name, vservice.Spec.MeshName)
+		c.stats.SetVirtualServiceInactive(vservice.Name, vserviceMeshName(vservice))
 		// Resource is being deleted, process finalizers
 		return c.handleVServiceDelete(ctx, vservice, copy)
 	}
 
+	// A virtual service without a mesh name joins the mesh of its namespace, the status patch enqueues it again
+	if vserviceMeshName(copy) == "" {
+		return c.resolveVServiceMeshMembership(copy)
+	}
+
 	if processVService := c.handleVServiceMeshDeleting(ctx, vservice); !processVService {
 		klog.Infof("skipping processing virtual service %s", vservice.Name)
 		return nil
 	}
 
 	// Get Mesh for virtual service
-	meshName := vservice.Spec.MeshName
-	if vservice.Spec.MeshName == "" {
-		return fmt.Errorf("'MeshName' is a required field")
-	}
+	meshName := vserviceMeshName(vservice)
 
 	mesh, err := c.meshLister.Get(meshName)
 	if errors.IsNotFound(err) {
-		return fmt.Errorf("mesh %s for virtual service %s does not exist", meshName, name)
+		return reconcileErrorf(reasonMeshNotFound, err, "mesh %s for virtual service %s does not exist", meshName, name)
 	}
 
 	if !checkMeshActive(mesh) {
-		return fmt.Errorf("mesh %s must be active for virtual service %s", meshName, name)
+		return reconcileErrorf(reasonMeshNotActive, nil, "mesh %s must be active for virtual service %s", meshName, name)
+	}
+
+	if paused, err := c.handleVServicePause(copy, mesh, key); err != nil || paused {
+		return err
 	}
 
+	if !c.driftChecks.due(driftCheckVirtualService, key, copy.Generation, copy.Status.ObservedGeneration) {
+		klog.V(4).Infof("Skipping unchanged virtual service %s until its next drift check", key)
+		return nil
+	}
+
+	// Routes to targets in other namespaces that no grant allows are always held
+	unresolved, denied, err := c.updateVServiceReferences(ctx, copy)
+	if err != nil {
+		return err
+	}
+	holdRoutes := len(denied) > 0 || (len(unresolved) > 0 && waitForRouteTargets(copy))
+
 	virtualRouter := c.getVirtualRouter(vservice)
+	policy := c.driftPolicyFor(copy)
+	claimUntagged := c.vserviceReconciledBefore(copy)
+	var drift []string
 
 	// Create virtual router if it does not exist
-	if targetRouter, err := c.cloud.GetVirtualRouter(ctx, virtualRouter.Name, meshName); err != nil {
+	if targetRouter, err := c.describeVirtualRouter(ctx, virtualRouter.Name, meshName); err != nil {
 		if aws.IsAWSErrNotFound(err) {
-			if targetRouter, err = c.cloud.CreateVirtualRouter(ctx, virtualRouter, meshName); err != nil {
-				return fmt.Errorf("error creating virtual router: %s", err)
+			if targetRouter, err = c.cloud.CreateVirtualRouter(ctx, virtualRouter, meshName, c.ownerTags(copy)); err != nil {
+				return reconcileErrorf(reasonCreateFailed, err, "error creating virtual router: %s", err)
 			}
+			c.created(copy, targetRouter.Data.Metadata)
 			klog.Infof("Created virtual router %s", targetRouter.Name())
+			c.recordMutation(copy, createdReason, "Created App Mesh virtual router %s", targetRouter.Name())
 		} else {
-			return fmt.Errorf("error describing virtual router: %s", err)
+			return reconcileErrorf(reasonDescribeFailed, err, "error describing virtual router: %s", err)
 		}
 		if updated, err := c.updateVRouterStatus(copy, targetRouter); err != nil {
-			return fmt.Errorf("error updating virtual service status for virtual router: %s", err)
+			return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual service status for virtual router: %s", err)
 		} else if updated != nil {
 			copy = updated
 		}
 	} else {
+		if err := c.checkOwnership(ctx, copy, "virtual router", virtualRouter.Name, targetRouter.Data.Metadata, claimUntagged); err != nil {
+			return c.handleVServiceOwnershipConflict(copy, key, err)
+		}
 		if vrouterNeedsUpdate(virtualRouter, targetRouter) {
-			if targetRouter, err = c.cloud.UpdateVirtualRouter(ctx, virtualRouter, meshName); err != nil {
-				return fmt.Errorf("error updating virtual router: %s", err)
+			if policy == DriftPolicyReport {
+				drift = append(drift, vrouterDiff(virtualRouter, targetRouter)...)
+			} else {
+				if targetRouter, err = c.cloud.UpdateVirtualRouter(ctx, virtualRouter, meshName); err != nil {
+					return reconcileErrorf(reasonUpdateFailed, err, "error updating virtual router: %s", err)
+				}
+				klog.Infof("Updated virtual router %s", virtualRouter.Name)
+				c.recordMutation(copy, updatedReason, "Updated App Mesh virtual router %s", virtualRouter.Name)
 			}
-			klog.Infof("Updated virtual router %s", virtualRouter.Name)
 		}
 	}
 
-	desiredRoutes := getRoutes(vservice)
-	existingRoutes, err := c.cloud.GetRoutesForVirtualRouter(ctx, virtualRouter.Name, meshName)
-	if err != nil {
-		return fmt.Errorf("error getting routes for virtual service %s: %s", vservice.Name, err)
-	}
-	if err = c.updateRoutes(ctx, meshName, virtualRouter.Name, desiredRoutes, existingRoutes); err != nil {
-		return fmt.Errorf("error updating routes for virtual service %s: %s", vservice.Name, err)
+	// Routes are left alone until the virtual nodes they target are active, or exist when holding routes, while
+	// the virtual router and virtual service are still created, since virtual nodes may in turn wait for the
+	// virtual service. The virtual service is enqueued again once the virtual nodes change.
+	waiting := c.vserviceWaitingFor(copy)
+	if len(waiting) == 0 && !holdRoutes {
+		desiredRoutes := getRoutes(vservice)
+		existingRoutes, err := c.describeRoutes(ctx, virtualRouter.Name, meshName)
+		if err != nil {
+			return reconcileErrorf(reasonDescribeFailed, err, "error getting routes for virtual service %s: %s", vservice.Name, err)
+		}
+		routesDrift, err := c.updateRoutes(ctx, copy, claimUntagged, meshName, virtualRouter.Name, desiredRoutes, existingRoutes, policy)
+		if isOwnershipConflict(err) {
+			return c.handleVServiceOwnershipConflict(copy, key, err)
+		} else if err != nil {
+			return reconcileErrorf(reasonUpdateFailed, err, "error updating routes for virtual service %s: %s", vservice.Name, err)
+		}
+		drift = append(drift, routesDrift...)
 	}
 
 	routes, err := c.cloud.GetRoutesForVirtualRouter(ctx, virtualRouter.Name, meshName)
@@ -150,42 +203,89 @@ func (c *Controller) handleVService(key string) error {
 			status = api.ConditionFalse
 		}
 		if updated, err := c.updateRoutesActive(copy, status); err != nil {
-			return fmt.Errorf("error updating routes status: %s", err)
+			return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating routes status: %s", err)
 		} else if updated != nil {
 			copy = updated
 		}
 	}
 
 	// Create virtual service if it does not exist
-	targetService, err := c.cloud.GetVirtualService(ctx, vservice.Name, meshName)
+	targetService, err := c.describeVirtualService(ctx, vservice.Name, meshName)
 	if err != nil {
 		if aws.IsAWSErrNotFound(err) {
-			if targetService, err = c.cloud.CreateVirtualService(ctx, vservice); err != nil {
-				return fmt.Errorf("error creating virtual service: %s", err)
+			if targetService, err = c.cloud.CreateVirtualService(ctx, vservice, c.ownerTags(copy)); err != nil {
+				return reconcileErrorf(reasonCreateFailed, err, "error creating virtual service: %s", err)
 			}
+			c.created(copy, targetService.Data.Metadata)
+			klog.Infof("Created virtual service %s", vservice.Name)
+			c.recordMutation(copy, createdReason, "Created App Mesh virtual service %s", vservice.Name)
 		} else {
-			return fmt.Errorf("error describing virtual service: %s", err)
+			return reconcileErrorf(reasonDescribeFailed, err, "error describing virtual service: %s", err)
 		}
 	} else {
+		if err := c.checkOwnership(ctx, copy, "virtual service", vservice.Name, targetService.Data.Metadata, claimUntagged); err != nil {
+			return c.handleVServiceOwnershipConflict(copy, key, err)
+		}
 		if vserviceNeedsUpdate(vservice, targetService) {
-			if targetService, err = c.cloud.UpdateVirtualService(ctx, vservice); err != nil {
-				return fmt.Errorf("error updating virtual service: %s", err)
+			if policy == DriftPolicyReport {
+				drift = append(drift, virtualServiceDrift(vservice, targetService)...)
+			} else {
+				if targetService, err = c.cloud.UpdateVirtualService(ctx, vservice); err != nil {
+					return reconcileErrorf(reasonUpdateFailed, err, "error updating virtual service: %s", err)
+				}
+				klog.Infof("Updated virtual service %s", vservice.Name)
+				c.recordMutation(copy, updatedReason, "Updated App Mesh virtual service %s", vservice.Name)
 			}
-			klog.Infof("Updated virtual service %s", vservice.Name)
 		}
 	}
+	if len(drift) > 0 {
+		c.reportDrift(copy, driftCheckVirtualService, meshName, vservice.Name, drift)
+	}
 
-	c.stats.SetVirtualServiceActive(vservice.Name, vservice.Spec.MeshName)
+	c.stats.SetVirtualServiceActive(vservice.Name, vserviceMeshName(vservice))
 
 	if updated, err := c.updateVServiceStatus(copy, targetService); err != nil {
-		return fmt.Errorf("error updating virtual service status: %s", err)
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual service status: %s", err)
+	} else if updated != nil {
+		copy = updated
+	}
+
+	if updated, err := c.setVServiceDrifted(copy, drift); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual service status: %s", err)
 	} else if updated != nil {
 		copy = updated
 	}
 
+	status, reason, message := ownershipCondition(nil)
+	if updated, err := c.setVServiceReasonCondition(copy, appmeshv1beta1.VirtualServiceOwnershipConflict, status, reason, message); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual service status: %s", err)
+	} else if updated != nil {
+		copy = updated
+	}
+
+	status, reason, message = dependencyCondition(waiting)
+	if updated, err := c.setVServiceReasonCondition(copy, appmeshv1beta1.VirtualServiceWaitingForDependency, status, reason, message); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual service status: %s", err)
+	} else if updated != nil {
+		copy = updated
+	}
+	if len(waiting) > 0 {
+		klog.Infof("Virtual service %s: %s", key, message)
+		return nil
+	}
+	if holdRoutes {
+		klog.Infof("Holding off routes of virtual service %s until their targets exist and are granted", key)
+		return nil
+	}
+
 	// TODO(nic) Need to determine if we need to clean up the old router here.  This needs to happen if we switched
 	// routers for the service.  For now, the old router will be orphaned if the user changes a router name.
 
+	if err := c.setVirtualServiceObservedGeneration(copy); err != nil {
+		return reconcileErrorf(reasonStatusUpdateFailed, err, "error updating virtual service status: %s", err)
+	}
+	c.driftChecks.reconciled(driftCheckVirtualService, key)
+
 	return nil
 }
 
@@ -194,6 +294,12 @@ func (c *Controller) updateVServiceResource(vservice *appmeshv1beta1.VirtualServ
 }
 
 func (c *Controller) updateVServiceStatus(vservice *appmeshv1beta1.VirtualService, target *aws.VirtualService) (*appmeshv1beta1.VirtualService, error) {
+	if awssdk.StringValue(vservice.Status.VirtualServiceArn) != awssdk.StringValue(target.Data.Metadata.Arn) {
+		vservice.Status.VirtualServiceArn = target.Data.Metadata.Arn
+		if err := c.patchVServiceStatus(vservice, map[string]interface{}{virtualServiceArnStatusField: vservice.Status.VirtualServiceArn}); err != nil {
+			return nil, err
+		}
+	}
 	switch target.Status() {
 	case appmesh.VirtualServiceStatusCodeActive:
 		return c.updateVServiceActive(vservice, api.ConditionTrue)
@@ -249,6 +355,7 @@ func (c *Controller) updateVServiceCondition(vservice *appmeshv1beta1.VirtualSer
 		// condition exists and not set to status
 		condition.Status = status
 		condition.LastTransitionTime = &now
+		vservice.Status.Conditions = setVServiceConditionInList(vservice.Status.Conditions, condition)
 	}
 
 	err := c.setVirtualServiceStatusConditions(vservice, vservice.Status.Conditions)
@@ -259,22 +366,62 @@ func (c *Controller) updateVServiceCondition(vservice *appmeshv1beta1.VirtualSer
 	return vservice, nil
 }
 
-func (c *Controller) setVirtualServiceStatusConditions(vservice *appmeshv1beta1.VirtualService, conditions []appmeshv1beta1.VirtualServiceCondition) error {
-	firstTry := true
-	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
-		var getErr error
-		if !firstTry {
-			vservice, getErr = c.meshclientset.AppmeshV1beta1().VirtualServices(vservice.Namespace).Get(vservice.GetName(), metav1.GetOptions{})
-			if getErr != nil {
-				return getErr
-			}
+// setVServiceReasonCondition sets a condition with a reason and message. A condition that would be False and
+// was never set is left out, so that conditions only show up on virtual services they ever applied to.
+func (c *Controller) setVServiceReasonCondition(vservice *appmeshv1beta1.VirtualService, conditionType appmeshv1beta1.VirtualServiceConditionType, status api.ConditionStatus, reason string, message string) (*appmeshv1beta1.VirtualService, error) {
+	if c.getVServiceCondition(conditionType, vservice.Status) == (appmeshv1beta1.VirtualServiceCondition{}) && status == api.ConditionFalse {
+		return nil, nil
+	}
+	return c.putVServiceReasonCondition(vservice, conditionType, status, reason, message)
+}
+
+// putVServiceReasonCondition sets a condition with a reason and message, whether or not the virtual service had
+// it before.
+func (c *Controller) putVServiceReasonCondition(vservice *appmeshv1beta1.VirtualService, conditionType appmeshv1beta1.VirtualServiceConditionType, status api.ConditionStatus, reason string, message string) (*appmeshv1beta1.VirtualService, error) {
+	current := c.getVServiceCondition(conditionType, vservice.Status)
+	if current.Status == status && awssdk.StringValue(current.Reason) == reason && awssdk.StringValue(current.Message) == message {
+		return nil, nil
+	}
+
+	now := metav1.Now()
+	condition := appmeshv1beta1.VirtualServiceCondition{
+		Type:               conditionType,
+		Status:             status,
+		LastTransitionTime: current.LastTransitionTime,
+		Reason:             awssdk.String(reason),
+		Message:            awssdk.String(message),
+	}
+	if current.Status != status {
+		condition.LastTransitionTime = &now
+	}
+
+	replaced := false
+	for i := range vservice.Status.Conditions {
+		if vservice.Status.Conditions[i].Type == condition.Type {
+			vservice.Status.Conditions[i] = condition
+			replaced = true
 		}
-		copy := vservice.DeepCopy()
-		copy.Status.Conditions = conditions
-		_, err := c.meshclientset.AppmeshV1beta1().VirtualServices(vservice.Namespace).UpdateStatus(copy)
-		firstTry = false
-		return err
-	})
+	}
+	if !replaced {
+		vservice.Status.Conditions = append(vservice.Status.Conditions, condition)
+	}
+
+	if err := c.setVirtualServiceStatusConditions(vservice, vservice.Status.Conditions); err != nil {
+		return nil, err
+	}
+	return vservice, nil
+}
+
+func (c *Controller) setVirtualServiceStatusConditions(vservice *appmeshv1beta1.VirtualService, conditions []appmeshv1beta1.VirtualServiceCondition) error {
+	return c.patchVServiceStatus(vservice, map[string]interface{}{conditionsField: conditions})
+}
+
+// setVirtualServiceObservedGeneration records the generation of the virtualService spec that was reconciled with App Mesh
+func (c *Controller) setVirtualServiceObservedGeneration(vservice *appmeshv1beta1.VirtualService) error {
+	if vservice.Status.ObservedGeneration == vservice.Generation {
+		return nil
+	}
+	return c.patchVServiceStatus(vservice, map[string]interface{}{observedGenerationField: vservice.Generation})
 }
 
 func (c *Controller) getVServiceCondition(conditionType appmeshv1beta1.VirtualServiceConditionType, status appmeshv1beta1.VirtualServiceStatus) appmeshv1beta1.VirtualServiceCondition {
@@ -329,23 +476,9 @@ func (c *Controller) getListenerFromRouteTarget(originalVirtualService *appmeshv
 	}
 
 	var candidateVnodeName string
-	route := vservice.Spec.Routes[0]
-	if route.Http != nil {
-		if len(route.Http.Action.WeightedTargets) > 0 {
-			candidateVnodeName = route.Http.Action.WeightedTargets[0].VirtualNodeName
-		}
-	} else if route.Tcp != nil {
-		if len(route.Tcp.Action.WeightedTargets) > 0 {
-			candidateVnodeName = route.Tcp.Action.WeightedTargets[0].VirtualNodeName
-		}
-	} else if route.Http2 != nil {
-		if len(route.Http2.Action.WeightedTargets) > 0 {
-			candidateVnodeName = route.Http2.Action.WeightedTargets[0].VirtualNodeName
-		}
-	} else if route.Grpc != nil {
-		if len(route.Grpc.Action.WeightedTargets) > 0 {
-			candidateVnodeName = route.Grpc.Action.WeightedTargets[0].VirtualNodeName
-		}
+	candidateVnodeNamespace := vservice.Namespace
+	if targets := routeTargets(vservice.Spec.Routes[0]); len(targets) > 0 {
+		candidateVnodeName, candidateVnodeNamespace = targetReference(targets[0], vservice.Namespace)
 	}
 
 	if len(candidateVnodeName) == 0 {
@@ -356,7 +489,7 @@ func (c *Controller) getListenerFromRouteTarget(originalVirtualService *appmeshv
 
 	klog.Infof("Using virtual-node %s to determine the listener for virtual-service %s in namespace %s", candidateVnodeName, vservice.Name, vservice.Namespace)
 
-	vnode, err := c.meshclientset.AppmeshV1beta1().VirtualNodes(vservice.Namespace).Get(candidateVnodeName, metav1.GetOptions{})
+	vnode, err := c.meshclientset.AppmeshV1beta1().VirtualNodes(candidateVnodeNamespace).Get(candidateVnodeName, metav1.GetOptions{})
 	if err != nil {
 		klog.Errorf("Cannot determine listener for virtual-service %s in namespace %s. Error getting virtual-node with name %s, %s", vservice.Name, vservice.Namespace, candidateVnodeName, err)
 		return nil
@@ -376,17 +509,6 @@ func (c *Controller) getListenerFromRouteTarget(originalVirtualService *appmeshv
 	return &vnode.Spec.Listeners[0]
 }
 
-func getNamespacedVirtualRouterName(vservice *appmeshv1beta1.VirtualService) string {
-	var name string
-	if vservice.Spec.VirtualRouter != nil {
-		name = strings.TrimSpace(vservice.Spec.VirtualRouter.Name)
-	}
-	if len(name) == 0 {
-		name = vservice.Name
-	}
-	return namespacedResourceName(name, vservice.Namespace)
-}
-
 func getRoutes(vservice *appmeshv1beta1.VirtualService) []appmeshv1beta1.Route {
 	if vservice.Spec.Routes != nil {
 		return vservice.Spec.Routes
@@ -412,30 +534,16 @@ func vserviceNeedsUpdate(desired *appmeshv1beta1.VirtualService, target *aws.Vir
 }
 
 func vrouterNeedsUpdate(desired *appmeshv1beta1.VirtualRouter, target *aws.VirtualRouter) bool {
-	if desired.Name != target.Name() {
-		return true
-	}
-
-	if len(desired.Listeners) != len(target.Data.Spec.Listeners) {
-		return true
-	}
-
-	if len(desired.Listeners) > 0 {
-		//there should be only one listener
-		desiredListener := desired.Listeners[0]
-		targetListener := target.Data.Spec.Listeners[0]
-		if desiredListener.PortMapping.Port != awssdk.Int64Value(targetListener.PortMapping.Port) {
-			return true
-		}
-
-		if desiredListener.PortMapping.Protocol != awssdk.StringValue(targetListener.PortMapping.Protocol) {
-			return true
-		}
-	}
-	return false
+	return len(vrouterDiff(desired, target)) > 0
 }
 
-func (c *Controller) updateRoutes(ctx context.Context, meshName string, routerName string, desired []appmeshv1beta1.Route, existing aws.Routes) error {
+// updateRoutes creates, updates and deletes routes of the virtual router to match the desired routes. With the
+// report drift policy existing routes are left alone, and how they differ is returned instead. Routes are only
+// updated or deleted when they belong to owner, otherwise an ownership conflict is returned once the other
+// routes are done.
+func (c *Controller) updateRoutes(ctx context.Context, owner *appmeshv1beta1.VirtualService, claimUntagged bool, meshName string, routerName string, desired []appmeshv1beta1.Route, existing aws.Routes, policy string) ([]string, error) {
+	var drift []string
+	var conflict error
 	routeNamesWithErrors := []string{}
 	existingNames := existing.RouteNamesSet()
 	desiredNames := set.NewSet()
@@ -450,33 +558,61 @@ func (c *Controller) updateRoutes(ctx context.Context, meshName string, routerNa
 			// There exists a route by the desired name, check if it needs to be updated
 			e := existing.RouteByName(d.Name)
 			if routeNeedsUpdate(d, e) {
-				if _, err := c.cloud.UpdateRoute(ctx, &d, routerName, meshName); err != nil {
+				if policy == DriftPolicyReport {
+					drift = append(drift, routeDiff(d, e)...)
+				} else if err := c.checkOwnership(ctx, owner, "route", d.Name, e.Data.Metadata, claimUntagged); err != nil {
+					if isOwnershipConflict(err) {
+						conflict = err
+					} else {
+						routeNamesWithErrors = append(routeNamesWithErrors, d.Name)
+						klog.Errorf("Error updating route %s: %s", d.Name, err)
+					}
+				} else if _, err := c.cloud.UpdateRoute(ctx, &d, routerName, meshName); err != nil {
 					routeNamesWithErrors = append(routeNamesWithErrors, d.Name)
 					klog.Errorf("Error updating route %s: %s", d.Name, err)
+					c.recordFailure(owner, reconcileErrorf(reasonUpdateFailed, err, "error updating route %s: %s", d.Name, err))
+				} else {
+					c.recordMutation(owner, updatedReason, "Updated App Mesh route %s of virtual router %s", d.Name, routerName)
 				}
 			}
 		} else {
 			// Create route because no existing route exists by the desired name
-			if _, err := c.cloud.CreateRoute(ctx, &d, routerName, meshName); err != nil {
+			if created, err := c.cloud.CreateRoute(ctx, &d, routerName, meshName, c.ownerTags(owner)); err != nil {
 				routeNamesWithErrors = append(routeNamesWithErrors, d.Name)
 				klog.Errorf("Error creating route %s: %s", d.Name, err)
+				c.recordFailure(owner, reconcileErrorf(reasonCreateFailed, err, "error creating route %s: %s", d.Name, err))
+			} else if created != nil {
+				c.created(owner, created.Data.Metadata)
+				c.recordMutation(owner, createdReason, "Created App Mesh route %s of virtual router %s", d.Name, routerName)
 			}
 		}
 	}
 
 	for _, ex := range existing {
 		if !desiredNames.Contains(ex.Name()) {
-			if _, err := c.cloud.DeleteRoute(ctx, ex.Name(), routerName, meshName); err != nil {
+			if policy == DriftPolicyReport {
+				drift = append(drift, fmt.Sprintf("spec.routes[%s]: desired <unset>, observed route", ex.Name()))
+			} else if err := c.checkOwnership(ctx, owner, "route", ex.Name(), ex.Data.Metadata, claimUntagged); err != nil {
+				if isOwnershipConflict(err) {
+					conflict = err
+				} else {
+					routeNamesWithErrors = append(routeNamesWithErrors, ex.Name())
+					klog.Errorf("Error deleting route %s: %s", ex.Name(), err)
+				}
+			} else if _, err := c.cloud.DeleteRoute(ctx, ex.Name(), routerName, meshName); err != nil {
 				routeNamesWithErrors = append(routeNamesWithErrors, ex.Name())
 				klog.Errorf("Error deleting route %s: %s", ex.Name(), err)
+				c.recordFailure(owner, reconcileErrorf(reasonDeleteFailed, err, "error deleting route %s: %s", ex.Name(), err))
+			} else {
+				c.recordMutation(owner, deletedReason, "Deleted App Mesh route %s of virtual router %s", ex.Name(), routerName)
 			}
 		}
 	}
 
 	if len(routeNamesWithErrors) > 0 {
-		return fmt.Errorf("error updating routes: %s", strings.Join(routeNamesWithErrors, " "))
+		return drift, fmt.Errorf("error updating routes: %s", strings.Join(routeNamesWithErrors, " "))
 	}
-	return nil
+	return drift, conflict
 }
 
 func allRoutesActive(routes aws.Routes) bool {
@@ -489,142 +625,17 @@ func allRoutesActive(routes aws.Routes) bool {
 }
 
 func routeNeedsUpdate(desired appmeshv1beta1.Route, target aws.Route) bool {
-	if diffInt64Value(desired.Priority, target.Data.Spec.Priority) {
-		return true
-	}
-
-	if desired.Http != nil {
-		if target.Data.Spec.HttpRoute == nil {
-			return true
-		}
-
-		if target.Data.Spec.HttpRoute.Action == nil {
-			return true
-		}
-
-		if desired.Http.Action.WeightedTargets != nil {
-			if target.Data.Spec.HttpRoute.Action.WeightedTargets == nil {
-				return true
-			}
-
-			desiredSet := set.NewSet()
-			for _, target := range desired.Http.Action.WeightedTargets {
-				desiredSet.Add(appmeshv1beta1.WeightedTarget{VirtualNodeName: target.VirtualNodeName, Weight: target.Weight})
-			}
-			currSet := target.WeightedTargetSet()
-			if !desiredSet.Equal(currSet) {
-				return true
-			}
-		}
-
-		targetRouteMatch := target.HttpRouteMatch()
-		if !reflect.DeepEqual(desired.Http.Match, *targetRouteMatch) {
-			return true
-		}
-
-		targetRouteRetryPolicy := target.HttpRouteRetryPolicy()
-		if !reflect.DeepEqual(desired.Http.RetryPolicy, targetRouteRetryPolicy) {
-			return true
-		}
-	} else if target.Data.Spec.HttpRoute != nil {
-		return true
-	}
-
-	if desired.Tcp != nil {
-		if desired.Tcp.Action.WeightedTargets != nil {
-			desiredSet := set.NewSet()
-			for _, target := range desired.Tcp.Action.WeightedTargets {
-				desiredSet.Add(appmeshv1beta1.WeightedTarget{VirtualNodeName: target.VirtualNodeName, Weight: target.Weight})
-			}
-			currSet := target.WeightedTargetSet()
-			if !desiredSet.Equal(currSet) {
-				return true
-			}
-		}
-	}
-
-	if desired.Http2 != nil {
-		if target.Data.Spec.Http2Route == nil {
-			return true
-		}
-
-		if target.Data.Spec.Http2Route.Action == nil {
-			return true
-		}
-
-		if desired.Http2.Action.WeightedTargets != nil {
-			if target.Data.Spec.Http2Route.Action.WeightedTargets == nil {
-				return true
-			}
-
-			desiredSet := set.NewSet()
-			for _, target := range desired.Http2.Action.WeightedTargets {
-				desiredSet.Add(appmeshv1beta1.WeightedTarget{VirtualNodeName: target.VirtualNodeName, Weight: target.Weight})
-			}
-			currSet := target.WeightedTargetSet()
-			if !desiredSet.Equal(currSet) {
-				return true
-			}
-		}
-
-		targetRouteMatch := target.Http2RouteMatch()
-		if !reflect.DeepEqual(desired.Http2.Match, *targetRouteMatch) {
-			return true
-		}
-
-		// maybe this needs to be Http2RouteRetryPolicy??
-		targetRouteRetryPolicy := target.Http2RouteRetryPolicy()
-		if !reflect.DeepEqual(desired.Http2.RetryPolicy, targetRouteRetryPolicy) {
-			return true
-		}
-	} else if target.Data.Spec.Http2Route != nil {
-		return true
-	}
-
-	if desired.Grpc != nil {
-		if target.Data.Spec.GrpcRoute == nil {
-			return true
-		}
-
-		if target.Data.Spec.GrpcRoute.Action == nil {
-			return true
-		}
-
-		if desired.Grpc.Action.WeightedTargets != nil {
-			if target.Data.Spec.GrpcRoute.Action.WeightedTargets == nil {
-				return true
-			}
-
-			desiredSet := set.NewSet()
-			for _, target := range desired.Grpc.Action.WeightedTargets {
-				desiredSet.Add(appmeshv1beta1.WeightedTarget{VirtualNodeName: target.VirtualNodeName, Weight: target.Weight})
-			}
-			currSet := target.WeightedTargetSet()
-			if !desiredSet.Equal(currSet) {
-				return true
-			}
-		}
-
-		targetRouteMatch := target.GrpcRouteMatch()
-		if !reflect.DeepEqual(desired.Grpc.Match, *targetRouteMatch) {
-			return true
-		}
-
-		targetRouteRetryPolicy := target.GrpcRouteRetryPolicy()
-		if !reflect.DeepEqual(desired.Grpc.RetryPolicy, targetRouteRetryPolicy) {
-			return true
-		}
-	} else if target.Data.Spec.GrpcRoute != nil {
-		return true
-	}
-
-	return false
+	return len(routeDiff(desired, target)) > 0
 }
 
 func (c *Controller) handleVServiceDelete(ctx context.Context, vservice *appmeshv1beta1.VirtualService, copy *appmeshv1beta1.VirtualService) error {
 	if yes, _ := containsFinalizer(vservice, virtualServiceDeletionFinalizerName); yes {
 
-		if err := c.deleteVServiceResources(ctx, vservice); err != nil {
+		if c.effectiveDeletionPolicy(vservice.Spec.DeletionPolicy, vserviceMeshName(vservice)) == appmeshv1beta1.DeletionPolicyRetain {
+			if err := c.releaseVServiceResources(ctx, vservice); err != nil {
+				return err
+			}
+		} else if err := c.deleteVServiceResources(ctx, vservice); err != nil {
 			return err
 		}
 		if err := removeFinalizer(copy, virtualServiceDeletionFinalizerName); err != nil {
@@ -638,7 +649,7 @@ func (c *Controller) handleVServiceDelete(ctx context.Context, vservice *appmesh
 }
 
 func (c *Controller) handleVServiceMeshDeleting(ctx context.Context, vservice *appmeshv1beta1.VirtualService) (processVService bool) {
-	mesh, err := c.meshLister.Get(vservice.Spec.MeshName)
+	mesh, err := c.meshLister.Get(vserviceMeshName(vservice))
 
 	if err != nil {
 		if errors.IsNotFound(err) {
@@ -656,53 +667,96 @@ func (c *Controller) handleVServiceMeshDeleting(ctx context.Context, vservice *a
 			klog.Errorf("Deletion failed for virtual service: %s - %s", vservice.Name, err)
 			return false
 		}
-		klog.Infof("Deleted virtual service %s because mesh %s is being deleted", vservice.Name, vservice.Spec.MeshName)
+		klog.Infof("Deleted virtual service %s because mesh %s is being deleted", vservice.Name, vserviceMeshName(vservice))
 	}
 
 	return true
 }
 
 func (c *Controller) deleteVServiceResources(ctx context.Context, vservice *appmeshv1beta1.VirtualService) error {
+	routerName := vservice.Spec.VirtualRouter.Name
+	meshName := vserviceMeshName(vservice)
+
 	// Cleanup routes
 	for _, r := range vservice.Spec.Routes {
-		if _, err := c.cloud.DeleteRoute(ctx, r.Name, vservice.Spec.VirtualRouter.Name, vservice.Spec.MeshName); err != nil {
+		name := r.Name
+		if deletable, err := c.vserviceResourceDeletable(ctx, vservice, "route", name, func() (*appmesh.ResourceMetadata, error) {
+			target, err := c.cloud.GetRoute(ctx, name, routerName, meshName)
+			if err != nil {
+				return nil, err
+			}
+			return target.Data.Metadata, nil
+		}); err != nil {
+			return fmt.Errorf("failed to clean up route %s for virtual service %s during deletion: %s", r.Name, vservice.Name, err)
+		} else if !deletable {
+			continue
+		}
+		if _, err := c.cloud.DeleteRoute(ctx, r.Name, vservice.Spec.VirtualRouter.Name, vserviceMeshName(vservice)); err != nil {
 			if !aws.IsAWSErrNotFound(err) {
-				return fmt.Errorf("failed to clean up route %s for virtual service %s during deletion: %s", r.Name, vservice.Name, err)
+				return reconcileErrorf(reasonDeleteFailed, err, "failed to clean up route %s for virtual service %s during deletion: %s", r.Name, vservice.Name, err)
 			}
+		} else {
+			c.recordMutation(vservice, deletedReason, "Deleted App Mesh route %s of virtual router %s", r.Name, routerName)
 		}
 	}
 
 	// TODO(nic): if we support a force delete, we can delete the rest of the routes attached to the virtual router here
 
 	// Cleanup virtual service
-	if _, err := c.cloud.DeleteVirtualService(ctx, vservice.Name, vservice.Spec.MeshName); err != nil {
-		if !aws.IsAWSErrNotFound(err) {
-			return fmt.Errorf("failed to clean up virtual service %s during deletion: %s", vservice.Name, err)
+	if deletable, err := c.vserviceResourceDeletable(ctx, vservice, "virtual service", vservice.Name, func() (*appmesh.ResourceMetadata, error) {
+		target, err := c.describeVirtualService(ctx, vservice.Name, meshName)
+		if err != nil {
+			return nil, err
+		}
+		return target.Data.Metadata, nil
+	}); err != nil {
+		return fmt.Errorf("failed to clean up virtual service %s during deletion: %s", vservice.Name, err)
+	} else if deletable {
+		if _, err := c.cloud.DeleteVirtualService(ctx, vservice.Name, vserviceMeshName(vservice)); err != nil {
+			if !aws.IsAWSErrNotFound(err) {
+				return reconcileErrorf(reasonDeleteFailed, err, "failed to clean up virtual service %s during deletion: %s", vservice.Name, err)
+			}
+		} else {
+			c.recordMutation(vservice, deletedReason, "Deleted App Mesh virtual service %s", vservice.Name)
 		}
 	}
 
 	// Cleanup virtual router
-	if _, err := c.cloud.DeleteVirtualRouter(ctx, vservice.Spec.VirtualRouter.Name, vservice.Spec.MeshName); err != nil {
+	if deletable, err := c.vserviceResourceDeletable(ctx, vservice, "virtual router", routerName, func() (*appmesh.ResourceMetadata, error) {
+		target, err := c.describeVirtualRouter(ctx, routerName, meshName)
+		if err != nil {
+			return nil, err
+		}
+		return target.Data.Metadata, nil
+	}); err != nil {
+		return fmt.Errorf("failed to clean up virtual router %s for virtual service %s during deletion: %s", routerName, vservice.Name, err)
+	} else if !deletable {
+		return nil
+	}
+	if _, err := c.cloud.DeleteVirtualRouter(ctx, vservice.Spec.VirtualRouter.Name, vserviceMeshName(vservice)); err != nil {
 		if aws.IsAWSErrNotFound(err) || aws.IsAWSErrResourceInUse(err) {
 			klog.Warningf("Virtual router %s was not deleted during cleanup: %s", vservice.Spec.VirtualRouter.Name, err)
 		} else {
-			return fmt.Errorf("failed to clean up virtual router %s for virtual service %s during deletion: %s", vservice.Spec.VirtualRouter.Name, vservice.Name, err)
+			return reconcileErrorf(reasonDeleteFailed, err, "failed to clean up virtual router %s for virtual service %s during deletion: %s", vservice.Spec.VirtualRouter.Name, vservice.Name, err)
 		}
+	} else {
+		c.recordMutation(vservice, deletedReason, "Deleted App Mesh virtual router %s", vservice.Spec.VirtualRouter.Name)
 	}
 	return nil
 }
 
-func diffInt64Value(l *int64, r *int64) bool {
-	if l != nil {
-		if r == nil {
-			return true
-		}
-		if awssdk.Int64Value(l) != awssdk.Int64Value(r) {
-			return true
+// vserviceResourceDeletable reports whether an App Mesh resource of the virtual service being deleted belongs to
+// it. Resources that no longer exist need no deleting either.
+func (c *Controller) vserviceResourceDeletable(ctx context.Context, vservice *appmeshv1beta1.VirtualService, kind string, name string, describe func() (*appmesh.ResourceMetadata, error)) (bool, error) {
+	if !c.ownershipEnabled() {
+		return true, nil
+	}
+	metadata, err := describe()
+	if err != nil {
+		if aws.IsAWSErrNotFound(err) {
+			return false, nil
 		}
-	} else if r != nil {
-		return true
+		return false, err
 	}
-
-	return false
+	return c.deletable(ctx, vservice, kind, name, metadata, c.vserviceReconciledBefore(vservice))
 }
@@ -0,0 +1,224 @@
+package controller
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+const (
+	// DefaultWebhookAddress is the address the admission webhook is served on
+	DefaultWebhookAddress = ":9443"
+	// DefaultWebhookCertDir is where the serving certificate of the admission webhook is mounted
+	DefaultWebhookCertDir = "/etc/webhook/certs"
+)
+
+// validateVirtualNodePath is the path the ValidatingWebhookConfiguration of virtual nodes calls.
+const validateVirtualNodePath = "/validate-appmesh-k8s-aws-v1beta1-virtualnode"
+
+// maxAdmissionReviewBytes bounds the admission reviews the webhook reads.
+const maxAdmissionReviewBytes = 3 << 20
+
+// webhookCertFiles returns the serving certificate and key in the certificate directory, or false if there are
+// none, in which case the webhook is not served.
+func webhookCertFiles(certDir string) (string, string, bool) {
+	if certDir == "" {
+		return "", "", false
+	}
+	certFile := filepath.Join(certDir, "tls.crt")
+	keyFile := filepath.Join(certDir, "tls.key")
+	for _, file := range []string{certFile, keyFile} {
+		if _, err := os.Stat(file); err != nil {
+			return "", "", false
+		}
+	}
+	return certFile, keyFile, true
+}
+
+// certificateReloader serves the certificate in a pair of files, and loads it again once the files change, so
+// that certificates rotated in the mounted Secret are picked up without a restart.
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	lock        sync.Mutex
+	certificate *tls.Certificate
+	modTime     time.Time
+}
+
+// GetCertificate returns the current certificate, for tls.Config.
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var modTime time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			if r.certificate != nil {
+				return r.certificate, nil
+			}
+			return nil, err
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	if r.certificate != nil && !modTime.After(r.modTime) {
+		return r.certificate, nil
+	}
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		// The files may be mid-update, keep serving the previous certificate until they are complete
+		if r.certificate != nil {
+			klog.Errorf("error reloading the webhook serving certificate: %s", err)
+			return r.certificate, nil
+		}
+		return nil, err
+	}
+	if r.certificate != nil {
+		klog.Infof("Reloaded the webhook serving certificate from %s", r.certFile)
+	}
+	r.certificate = &certificate
+	r.modTime = modTime
+	return r.certificate, nil
+}
+
+// RunWebhookServer serves the validating admission webhook of virtual nodes over TLS until it fails. It returns
+// immediately if the certificate directory holds no serving certificate.
+func (c *Controller) RunWebhookServer(cfg WebhookOptions) error {
+	certFile, keyFile, ok := webhookCertFiles(cfg.CertDir)
+	if !ok {
+		klog.Infof("Not serving the admission webhook, there is no serving certificate in %q", cfg.CertDir)
+		return nil
+	}
+	reloader := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := reloader.GetCertificate(nil); err != nil {
+		return fmt.Errorf("error loading the webhook serving certificate: %s", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(validateVirtualNodePath, c.serveValidateVirtualNode)
+	server := &http.Server{
+		Handler:   mux,
+		Addr:      cfg.Address,
+		TLSConfig: &tls.Config{GetCertificate: reloader.GetCertificate},
+	}
+	klog.Infof("Serving the admission webhook on %s", cfg.Address)
+	return server.ListenAndServeTLS("", "")
+}
+
+// webhookCachesSynced reports whether the caches admission reviews are validated against have synced. Until they
+// have, meshes are not known yet, and virtual nodes violating their policy would be allowed.
+func (c *Controller) webhookCachesSynced() bool {
+	for _, synced := range []cache.InformerSynced{c.meshSynced, c.namespaceSynced, c.meshDefaultsSynced} {
+		if synced != nil && !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// serveValidateVirtualNode answers an admission review of a virtual node.
+func (c *Controller) serveValidateVirtualNode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "admission reviews must be posted", http.StatusMethodNotAllowed)
+		return
+	}
+	// The API server fails the review, and with the Fail policy rejects the virtual node, until the caches synced
+	if !c.webhookCachesSynced() {
+		http.Error(w, "the controller caches have not synced yet", http.StatusServiceUnavailable)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxAdmissionReviewBytes))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error reading admission review: %s", err), http.StatusBadRequest)
+		return
+	}
+	review := &admissionv1beta1.AdmissionReview{}
+	if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+		http.Error(w, "malformed admission review", http.StatusBadRequest)
+		return
+	}
+
+	response := c.validateVirtualNode(review.Request)
+	response.UID = review.Request.UID
+	review.Response = response
+	review.Request = nil
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(review); err != nil {
+		klog.Errorf("error writing admission review: %s", err)
+	}
+}
+
+// validateVirtualNode denies virtual nodes that are created, or updated to a spec, that violates the TLS policy of
+// their mesh. Updates that leave the spec unchanged are allowed, so that finalizers and labels of virtual nodes that
+// violate a policy added later can still change. Virtual nodes whose mesh is not known yet are left to the
+// controller to hold off.
+func (c *Controller) validateVirtualNode(request *admissionv1beta1.AdmissionRequest) *admissionv1beta1.AdmissionResponse {
+	vnode := &appmeshv1beta1.VirtualNode{}
+	if err := json.Unmarshal(request.Object.Raw, vnode); err != nil {
+		return &admissionv1beta1.AdmissionResponse{
+			Result: &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Message: fmt.Sprintf("error decoding virtual node: %s", err),
+				Reason:  metav1.StatusReasonBadRequest,
+				Code:    http.StatusBadRequest,
+			},
+		}
+	}
+	allowed := &admissionv1beta1.AdmissionResponse{Allowed: true}
+	if vnode.Namespace == "" {
+		vnode.Namespace = request.Namespace
+	}
+	if !vnode.DeletionTimestamp.IsZero() {
+		return allowed
+	}
+	if request.Operation == admissionv1beta1.Update {
+		old := &appmeshv1beta1.VirtualNode{}
+		if err := json.Unmarshal(request.OldObject.Raw, old); err == nil && equality.Semantic.DeepEqual(old.Spec, vnode.Spec) {
+			return allowed
+		}
+	}
+
+	meshName := vnodeMeshName(vnode)
+	if meshName == "" {
+		resolved, err := c.namespaceMesh(vnode.Namespace)
+		if err != nil {
+			return allowed
+		}
+		meshName = resolved
+	}
+	mesh, err := c.meshLister.Get(meshName)
+	if err != nil || mesh.Spec.TLSPolicy == nil {
+		return allowed
+	}
+
+	mergeMeshDefaults(vnode, c.namespaceMeshDefaults(vnode.Namespace))
+	resolveSecretCertificates(vnode)
+	violations := tlsPolicyViolations(vnode, mesh.Spec.TLSPolicy)
+	if len(violations) == 0 {
+		return allowed
+	}
+	return &admissionv1beta1.AdmissionResponse{
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: fmt.Sprintf("virtual node %s violates the TLS policy of mesh %s: %s", vnode.Name, meshName, strings.Join(violations, "; ")),
+			Reason:  metav1.StatusReasonForbidden,
+			Code:    http.StatusForbidden,
+		},
+	}
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	appmeshv1beta1 "github.com/aws/aws-app-mesh-controller-for-k8s/pkg/apis/appmesh/v1beta1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newAdmissionRequest(t *testing.T, operation admissionv1beta1.Operation, vnode *appmeshv1beta1.VirtualNode, old *appmeshv1beta1.VirtualNode) *admissionv1beta1.AdmissionRequest {
+	raw := func(obj *appmeshv1beta1.VirtualNode) runtime.RawExtension {
+		if obj == nil {
+			return runtime.RawExtension{}
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runtime.RawExtension{Raw: data}
+	}
+	return &admissionv1beta1.AdmissionRequest{
+		UID:       types.UID("uid"),
+		Operation: operation,
+		Name:      vnode.Name,
+		Namespace: vnode.Namespace,
+		Object:    raw(vnode),
+		OldObject: raw(old),
+	}
+}
+
+func TestValidateVirtualNode(t *testing.T) {
+	policyMesh := &appmeshv1beta1.Mesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "mesh"},
+		Spec:       appmeshv1beta1.MeshSpec{TLSPolicy: &appmeshv1beta1.MeshTLSPolicy{RequireStrictListenerTLS: true}},
+	}
+	openMesh := &appmeshv1beta1.Mesh{ObjectMeta: metav1.ObjectMeta{Name: "open"}}
+	c := &Controller{meshLister: newMeshLister(policyMesh, openMesh)}
+
+	compliant := newTLSPolicyVNode("STRICT", nil)
+	violating := newTLSPolicyVNode("PERMISSIVE", nil)
+	inOpenMesh := newTLSPolicyVNode("PERMISSIVE", nil)
+	inOpenMesh.Spec.MeshName = "open"
+	inUnknownMesh := newTLSPolicyVNode("PERMISSIVE", nil)
+	inUnknownMesh.Spec.MeshName = "unknown"
+	relabeled := violating.DeepCopy()
+	relabeled.Labels = map[string]string{"app": "red"}
+	deleting := violating.DeepCopy()
+	now := metav1.Now()
+	deleting.DeletionTimestamp = &now
+
+	var tests = []struct {
+		name    string
+		request *admissionv1beta1.AdmissionRequest
+		allowed bool
+	}{
+		{name: "compliant create", request: newAdmissionRequest(t, admissionv1beta1.Create, compliant, nil), allowed: true},
+		{name: "violating create", request: newAdmissionRequest(t, admissionv1beta1.Create, violating, nil)},
+		{name: "violating update", request: newAdmissionRequest(t, admissionv1beta1.Update, violating, compliant)},
+		{name: "update leaving the spec unchanged", request: newAdmissionRequest(t, admissionv1beta1.Update, relabeled, violating), allowed: true},
+		{name: "update while deleting", request: newAdmissionRequest(t, admissionv1beta1.Update, deleting, compliant), allowed: true},
+		{name: "mesh without a policy", request: newAdmissionRequest(t, admissionv1beta1.Create, inOpenMesh, nil), allowed: true},
+		{name: "unknown mesh", request: newAdmissionRequest(t, admissionv1beta1.Create, inUnknownMesh, nil), allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := c.validateVirtualNode(tt.request)
+			if response.Allowed != tt.allowed {
+				t.Fatalf("expected allowed %t, got %+v", tt.allowed, response.Result)
+			}
+			if !tt.allowed && !strings.Contains(response.Result.Message, "listener on port 8080 does not use STRICT TLS") {
+				t.Errorf("expected the violations in the message, got %q", response.Result.Message)
+			}
+		})
+	}
+}
+
+func TestServeValidateVirtualNode(t *testing.T) {
+	mesh := &appmeshv1beta1.Mesh{
+		ObjectMeta: metav1.ObjectMeta{Name: "mesh"},
+		Spec:       appmeshv1beta1.MeshSpec{TLSPolicy: &appmeshv1beta1.MeshTLSPolicy{RequireStrictListenerTLS: true}},
+	}
+	c := &Controller{meshLister: newMeshLister(mesh)}
+	review := admissionv1beta1.AdmissionReview{Request: newAdmissionRequest(t, admissionv1beta1.Create, newTLSPolicyVNode("", nil), nil)}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	c.serveValidateVirtualNode(recorder, httptest.NewRequest(http.MethodPost, validateVirtualNodePath, bytes.NewReader(body)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	answered := admissionv1beta1.AdmissionReview{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &answered); err != nil {
+		t.Fatal(err)
+	}
+	if answered.Response == nil || answered.Response.UID != "uid" || answered.Response.Allowed {
+		t.Errorf("expected the review to be denied for request uid, got %+v", answered.Response)
+	}
+
+	recorder = httptest.NewRecorder()
+	c.serveValidateVirtualNode(recorder, httptest.NewRequest(http.MethodPost, validateVirtualNodePath, strings.NewReader("{")))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected a malformed review to be rejected, got %d", recorder.Code)
+	}
+}
+
+func TestServeValidateVirtualNodeBeforeSync(t *testing.T) {
+	c := &Controller{
+		meshLister: newMeshLister(),
+		meshSynced: func() bool { return false },
+	}
+	review := admissionv1beta1.AdmissionReview{Request: newAdmissionRequest(t, admissionv1beta1.Create, newTLSPolicyVNode("", nil), nil)}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	c.serveValidateVirtualNode(recorder, httptest.NewRequest(http.MethodPost, validateVirtualNodePath, bytes.NewReader(body)))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected reviews to fail until the caches synced, got %d", recorder.Code)
+	}
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webhook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(notAfter time.Time, modTime time.Time) {
+		certificate, key := newTestKeyPair(t, notAfter)
+		for file, data := range map[string][]byte{"tls.crt": certificate, "tls.key": key} {
+			path := filepath.Join(dir, file)
+			if err := ioutil.WriteFile(path, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	notAfter := func(certificate *tls.Certificate) time.Time {
+		parsed, err := x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed.NotAfter
+	}
+
+	first := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	write(first, time.Unix(1000, 0))
+	certFile, keyFile, ok := webhookCertFiles(dir)
+	if !ok {
+		t.Fatal("expected the serving certificate to be found")
+	}
+	reloader := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	certificate, err := reloader.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !notAfter(certificate).Equal(first) {
+		t.Fatalf("unexpected certificate expiring %s", notAfter(certificate))
+	}
+
+	// The rotated certificate is served once the files change
+	rotated := first.Add(24 * time.Hour)
+	write(rotated, time.Unix(2000, 0))
+	if certificate, err = reloader.GetCertificate(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !notAfter(certificate).Equal(rotated) {
+		t.Errorf("expected the rotated certificate, got one expiring %s", notAfter(certificate))
+	}
+
+	// Incomplete files keep the previous certificate
+	if err := ioutil.WriteFile(keyFile, []byte("partial"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(keyFile, time.Unix(3000, 0), time.Unix(3000, 0))
+	if certificate, err = reloader.GetCertificate(nil); err != nil || !notAfter(certificate).Equal(rotated) {
+		t.Errorf("expected the previous certificate while the files are incomplete, got %v", err)
+	}
+}
@@ -17,6 +17,12 @@ type Recorder struct {
 	operationDuration   *prometheus.HistogramVec
 	awsAPIRequestError  *prometheus.CounterVec
 	awsAPIRequestCount  *prometheus.CounterVec
+	awsAPIThrottleCount *prometheus.CounterVec
+	describeCacheCount  *prometheus.CounterVec
+	shardMembers        prometheus.Gauge
+	driftCount          *prometheus.CounterVec
+	paused              *prometheus.GaugeVec
+	certificateExpiry   *prometheus.GaugeVec
 }
 
 // NewRecorder registers the App Mesh metrics
@@ -65,6 +71,42 @@ func NewRecorder(register bool) *Recorder {
 		Help:      "Cumulative number of requests made to the AWS API",
 	}, []string{"service", "operation"})
 
+	awsAPIThrottleCount := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: Subsystem,
+		Name:      "aws_api_throttles",
+		Help:      "Cumulative number of throttling errors from the AWS API",
+	}, []string{"service", "operation"})
+
+	describeCacheCount := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: Subsystem,
+		Name:      "describe_cache_lookups",
+		Help:      "Cumulative number of App Mesh describe calls served from the cache, coalesced or missed",
+	}, []string{"kind", "result"})
+
+	shardMembers := prometheus.NewGauge(prometheus.GaugeOpts{
+		Subsystem: Subsystem,
+		Name:      "shard_members",
+		Help:      "Number of controller replicas sharing ownership of meshes.",
+	})
+
+	driftCount := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: Subsystem,
+		Name:      "drift_detected",
+		Help:      "Cumulative number of times an App Mesh resource was found to differ from its spec and left unchanged",
+	}, []string{"kind", "mesh", "name"})
+
+	paused := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: Subsystem,
+		Name:      "paused",
+		Help:      "Whether reconciling a mesh, virtual node or virtual service is paused.",
+	}, []string{"kind", "mesh", "name"})
+
+	certificateExpiry := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Subsystem: Subsystem,
+		Name:      "certificate_expiry_timestamp_seconds",
+		Help:      "Unix time at which a certificate a virtual node sources from a Secret expires.",
+	}, []string{"namespace", "name", "secret", "key"})
+
 	if register {
 		prometheus.MustRegister(meshState)
 		prometheus.MustRegister(virtualNodeState)
@@ -73,6 +115,12 @@ func NewRecorder(register bool) *Recorder {
 		prometheus.MustRegister(operationDuration)
 		prometheus.MustRegister(awsAPIRequestError)
 		prometheus.MustRegister(awsAPIRequestCount)
+		prometheus.MustRegister(awsAPIThrottleCount)
+		prometheus.MustRegister(describeCacheCount)
+		prometheus.MustRegister(shardMembers)
+		prometheus.MustRegister(driftCount)
+		prometheus.MustRegister(paused)
+		prometheus.MustRegister(certificateExpiry)
 	}
 
 	return &Recorder{
@@ -83,6 +131,12 @@ func NewRecorder(register bool) *Recorder {
 		operationDuration:   operationDuration,
 		awsAPIRequestError:  awsAPIRequestError,
 		awsAPIRequestCount:  awsAPIRequestCount,
+		awsAPIThrottleCount: awsAPIThrottleCount,
+		describeCacheCount:  describeCacheCount,
+		shardMembers:        shardMembers,
+		driftCount:          driftCount,
+		paused:              paused,
+		certificateExpiry:   certificateExpiry,
 	}
 }
 
@@ -94,6 +148,12 @@ func (r *Recorder) clearRegistry() {
 	prometheus.Unregister(r.operationDuration)
 	prometheus.Unregister(r.awsAPIRequestError)
 	prometheus.Unregister(r.awsAPIRequestCount)
+	prometheus.Unregister(r.awsAPIThrottleCount)
+	prometheus.Unregister(r.describeCacheCount)
+	prometheus.Unregister(r.shardMembers)
+	prometheus.Unregister(r.driftCount)
+	prometheus.Unregister(r.paused)
+	prometheus.Unregister(r.certificateExpiry)
 }
 
 // SetMeshActive sets the mesh gauge to 1
@@ -147,3 +207,44 @@ func (r *Recorder) RecordAWSAPIRequestError(service string, operation string, er
 func (r *Recorder) RecordAWSAPIRequestCount(service string, operation string) {
 	r.awsAPIRequestCount.WithLabelValues(service, operation).Inc()
 }
+
+// RecordAWSAPIThrottle records count of AWS API calls rejected with a throttling error
+func (r *Recorder) RecordAWSAPIThrottle(service string, operation string) {
+	r.awsAPIThrottleCount.WithLabelValues(service, operation).Inc()
+}
+
+// RecordDescribeCacheLookup records whether an App Mesh describe call was a cache hit, miss or coalesced
+// with a concurrent call
+func (r *Recorder) RecordDescribeCacheLookup(kind string, result string) {
+	r.describeCacheCount.WithLabelValues(kind, result).Inc()
+}
+
+// SetShardMembers sets the number of replicas sharing ownership of meshes
+func (r *Recorder) SetShardMembers(count int) {
+	r.shardMembers.Set(float64(count))
+}
+
+// RecordDrift records that an App Mesh resource differs from its spec and was reported instead of updated
+func (r *Recorder) RecordDrift(kind string, mesh string, name string) {
+	r.driftCount.WithLabelValues(kind, mesh, name).Inc()
+}
+
+// SetPaused sets the paused gauge of a mesh, virtual node or virtual service to 1 while reconciling it is paused,
+// and to 0 otherwise
+func (r *Recorder) SetPaused(kind string, mesh string, name string, paused bool) {
+	value := 0.0
+	if paused {
+		value = 1
+	}
+	r.paused.WithLabelValues(kind, mesh, name).Set(value)
+}
+
+// SetCertificateExpiry sets the expiry of a certificate that the virtual node namespace/name sources from a Secret
+func (r *Recorder) SetCertificateExpiry(namespace string, name string, secret string, key string, notAfter time.Time) {
+	r.certificateExpiry.WithLabelValues(namespace, name, secret, key).Set(float64(notAfter.Unix()))
+}
+
+// DeleteCertificateExpiry removes the expiry of a certificate the virtual node no longer sources from a Secret
+func (r *Recorder) DeleteCertificateExpiry(namespace string, name string, secret string, key string) {
+	r.certificateExpiry.DeleteLabelValues(namespace, name, secret, key)
+}
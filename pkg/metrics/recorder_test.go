@@ -91,6 +91,50 @@ func TestRecorder_SetVirtualService(t *testing.T) {
 	}
 }
 
+func TestRecorder_SetPaused(t *testing.T) {
+	stats.SetPaused("VirtualNode", "test-mesh", "test-vn", true)
+
+	name := "appmesh_paused"
+	metric, err := lookupMetric(name, promdto.MetricType_GAUGE, "kind", "VirtualNode", "mesh", "test-mesh", "name", "test-vn")
+	if err != nil {
+		t.Fatalf("Error collecting %s metric: %v", name, err)
+	}
+
+	if int(*metric.Gauge.Value) != 1 {
+		t.Errorf("%s expected value %v got %v", name, 1, *metric.Gauge.Value)
+	}
+
+	stats.SetPaused("VirtualNode", "test-mesh", "test-vn", false)
+	metric, err = lookupMetric(name, promdto.MetricType_GAUGE, "kind", "VirtualNode", "mesh", "test-mesh", "name", "test-vn")
+	if err != nil {
+		t.Fatalf("Error collecting %s metric: %v", name, err)
+	}
+
+	if int(*metric.Gauge.Value) != 0 {
+		t.Errorf("%s expected value %v got %v", name, 0, *metric.Gauge.Value)
+	}
+}
+
+func TestRecorder_SetCertificateExpiry(t *testing.T) {
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	stats.SetCertificateExpiry("test-ns", "test-vn", "test-secret", "tls.crt", notAfter)
+
+	name := "appmesh_certificate_expiry_timestamp_seconds"
+	metric, err := lookupMetric(name, promdto.MetricType_GAUGE, "namespace", "test-ns", "name", "test-vn", "secret", "test-secret", "key", "tls.crt")
+	if err != nil {
+		t.Fatalf("Error collecting %s metric: %v", name, err)
+	}
+
+	if int64(*metric.Gauge.Value) != notAfter.Unix() {
+		t.Errorf("%s expected value %v got %v", name, notAfter.Unix(), *metric.Gauge.Value)
+	}
+
+	stats.DeleteCertificateExpiry("test-ns", "test-vn", "test-secret", "tls.crt")
+	if _, err := lookupMetric(name, promdto.MetricType_GAUGE); err == nil {
+		t.Errorf("%s expected to be deleted", name)
+	}
+}
+
 func TestRecorder_RecordOperationDuration(t *testing.T) {
 	stats.RecordOperationDuration("test-op-kind", "test-op-object", "test-op-name", 2*time.Second)
 
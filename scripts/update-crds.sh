@@ -0,0 +1,30 @@
+#!/usr/bin/env bash
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+SCRIPT_ROOT=$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd)
+# v0.2.5 is the last controller-gen that generates apiextensions.k8s.io/v1beta1 CRDs, which the clusters older than
+# v1.16 that the controller supports require
+CONTROLLER_GEN_VERSION=v0.2.5
+GOBIN=${GOBIN:-$(go env GOPATH)/bin}
+CONTROLLER_GEN=${CONTROLLER_GEN:-${GOBIN}/controller-gen}
+
+if [[ ! -x ${CONTROLLER_GEN} ]] || ! ${CONTROLLER_GEN} --version | grep -q "${CONTROLLER_GEN_VERSION}"; then
+    echo ">> Installing controller-gen ${CONTROLLER_GEN_VERSION}"
+    # go get outside of this module, so that go.mod does not pick up controller-tools
+    (cd "$(mktemp -d)" && GO111MODULE=on GOBIN=${GOBIN} go get sigs.k8s.io/controller-tools/cmd/controller-gen@${CONTROLLER_GEN_VERSION})
+    CONTROLLER_GEN=${GOBIN}/controller-gen
+fi
+
+# CRDs are generated from the kubebuilder markers in pkg/apis/appmesh/v1beta1/types.go
+cd "${SCRIPT_ROOT}"
+rm -f deploy/crds/*.yaml
+${CONTROLLER_GEN} crd:trivialVersions=true,crdVersions=v1beta1 paths=./pkg/apis/... output:crd:dir=deploy/crds
+
+# deploy/all.yaml installs the CRDs along with the controller
+{
+    echo "# Generated by scripts/update-crds.sh from deploy/crds and deploy/controller.yaml, DO NOT EDIT."
+    cat deploy/crds/*.yaml deploy/controller.yaml
+} > deploy/all.yaml